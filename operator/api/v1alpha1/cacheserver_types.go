@@ -43,6 +43,51 @@ type CacheServerSpec struct {
 	// +kubebuilder:validation:Enum=RollingUpdate;Recreate
 	// +kubebuilder:default=RollingUpdate
 	DeploymentStrategy string `json:"deploymentStrategy"`
+
+	// Mode of the cache server workload. In Deployment mode the replicas are interchangeable and
+	// keep the KV cache in memory. In StatefulSet mode every replica is a shard, addressed through a
+	// headless Service, that can keep its KV cache on a volume of its own. Every VLLMRuntime
+	// referencing the CacheServer is assigned the shard its model hashes onto, so runtimes serving
+	// the same model share their KV cache and resizing moves only the models of the shards added
+	// or removed.
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet
+	// +kubebuilder:default=Deployment
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// Persistence of the KV cache of every replica on a volume of its own, which survives restarts
+	// of the replica. StatefulSet mode only.
+	// +optional
+	Persistence CacheServerPersistence `json:"persistence,omitempty"`
+
+	// Scheduling of the cache server pods
+	// +optional
+	Scheduling SchedulingConfig `json:"scheduling,omitempty"`
+
+	// Probes of the cache server container. The cache server has TCP readiness and liveness
+	// probes on its port by default.
+	// +optional
+	Probes ProbesConfig `json:"probes,omitempty"`
+}
+
+// CacheServerPersistence defines the volume every cache server replica keeps its KV cache on
+type CacheServerPersistence struct {
+	// Enable a volume per replica
+	Enabled bool `json:"enabled,omitempty"`
+
+	// StorageClassName is the name of the storage class of the volumes
+	// +optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+
+	// Size of the volume of every replica
+	// +kubebuilder:default="50Gi"
+	// +optional
+	Size string `json:"size,omitempty"`
+
+	// MountPath is the path the volume is mounted at in the cache server container
+	// +kubebuilder:default="/cache"
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
 }
 
 // CacheServerStatus defines the observed state of CacheServer
@@ -52,10 +97,48 @@ type CacheServerStatus struct {
 
 	// Current status of the cache server
 	Status string `json:"status,omitempty"`
+
+	// Conditions represent the latest available observations of the cache server's state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Replicas is the number of pods of the cache server Deployment or StatefulSet
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of pods that are ready
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// AvailableReplicas is the number of pods that have been ready for at least minReadySeconds
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of pods running the latest pod template
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// RemoteURL is the LMCache remote URL runtimes reach the cache server at. Deployment mode only.
+	// +optional
+	RemoteURL string `json:"remoteUrl,omitempty"`
+
+	// ShardURLs are the LMCache remote URLs of the shards in ordinal order. StatefulSet mode only,
+	// the shard of a VLLMRuntime is reported in its status.cacheServerUrl.
+	// +optional
+	ShardURLs []string `json:"shardUrls,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Available",type="string",JSONPath=`.status.conditions[?(@.type=="Available")].status`
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.status"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
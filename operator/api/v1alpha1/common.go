@@ -16,11 +16,21 @@ limitations under the License.
 
 package v1alpha1
 
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
 // ResourceRequirements defines the resource requirements
 type ResourceRequirements struct {
 	CPU    string `json:"cpu,omitempty"`
 	Memory string `json:"memory,omitempty"`
 	GPU    string `json:"gpu,omitempty"`
+
+	// GPUResourceName is the extended resource the GPU count is requested as, e.g. amd.com/gpu,
+	// habana.ai/gaudi or a MIG slice like nvidia.com/mig-1g.10gb. Defaults to nvidia.com/gpu.
+	// No accelerator is requested while GPU is not set.
+	// +optional
+	GPUResourceName string `json:"gpuResourceName,omitempty"`
 }
 
 // ImageSpec defines the container image configuration
@@ -30,3 +40,104 @@ type ImageSpec struct {
 	PullPolicy     string `json:"pullPolicy,omitempty"`
 	PullSecretName string `json:"pullSecretName,omitempty"`
 }
+
+// SchedulingConfig defines which nodes the pods of a resource are scheduled on
+type SchedulingConfig struct {
+	// NodeSelector restricts the pods to nodes with these labels
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations let the pods run on tainted nodes, e.g. dedicated GPU nodes
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity holds the node, pod and pod anti-affinity rules of the pods
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints spread the pods across zones, nodes or other topology domains
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// PriorityClassName is the priority class of the pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// RuntimeClassName is the runtime class the containers run with, e.g. nvidia
+	// +optional
+	RuntimeClassName string `json:"runtimeClassName,omitempty"`
+}
+
+// ProbesConfig defines the probes of the main container of a component. Every probe starts from
+// the defaults of the component, and only the fields set here override them.
+type ProbesConfig struct {
+	// Readiness probe, which takes the pod out of its Service while failing
+	// +optional
+	Readiness ProbeConfig `json:"readiness,omitempty"`
+
+	// Liveness probe, which restarts the container while failing
+	// +optional
+	Liveness ProbeConfig `json:"liveness,omitempty"`
+
+	// Startup probe, which holds the other probes until it succeeds. Its initial delay plus period
+	// times failure threshold is the time the container gets to start.
+	// +optional
+	Startup ProbeConfig `json:"startup,omitempty"`
+}
+
+// ProbeConfig overrides the check and timing of a probe. Setting any field of a probe the
+// component has no default for adds it. At most one of path, exec and grpc may be set.
+type ProbeConfig struct {
+	// Disabled removes the probe
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	// Path checked with an HTTP GET on the container port
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Exec runs a command in the container, which passes when it exits with 0
+	// +optional
+	Exec []string `json:"exec,omitempty"`
+
+	// GRPC calls the gRPC health checking protocol
+	// +optional
+	GRPC *GRPCProbe `json:"grpc,omitempty"`
+
+	// Seconds after the container started before the probe runs first
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// Seconds between probes
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// Seconds after which a probe times out
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// Consecutive successes for the probe to pass after having failed, 1 for liveness and
+	// startup probes
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SuccessThreshold *int32 `json:"successThreshold,omitempty"`
+
+	// Consecutive failures for the probe to fail
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// GRPCProbe defines a gRPC health check
+type GRPCProbe struct {
+	// Port of the gRPC server. Defaults to the container port.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Service name passed in the health check request
+	// +optional
+	Service string `json:"service,omitempty"`
+}
@@ -31,6 +31,14 @@ type LoraAdapterSpec struct {
 	// BaseModel is the name of the base model this adapter is for.
 	// +kubebuilder:validation:Required
 	BaseModel string `json:"baseModel"`
+	// VLLMRuntimeRef references the VLLMRuntime in the same namespace whose pods load the adapter.
+	// The vLLM port is taken from the runtime spec.
+	// +optional
+	VLLMRuntimeRef *VLLMRuntimeReference `json:"vllmRuntimeRef,omitempty"`
+	// PodSelector selects the vLLM pods in the same namespace that load the adapter.
+	// When neither PodSelector nor VLLMRuntimeRef is set, pods are selected by a model label matching BaseModel.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
 	// DeploymentConfig defines how the adapter should be deployed
 	LoraAdapterDeploymentConfig LoraAdapterDeploymentConfig `json:"loraAdapterDeploymentConfig,omitempty"`
 	// VLLMApiKey defines the configuration for vLLM API key authentication
@@ -39,18 +47,42 @@ type LoraAdapterSpec struct {
 
 type AdapterSource struct {
 	// AdapterName is the name of the adapter to apply.
+	// When Pattern is set it only names the group, each discovered adapter is served under its own name.
 	// +kubebuilder:validation:Required
 	AdapterName string `json:"adapterName"`
-	// AdapterPath is the path to the LoRA adapter weights. For local sources: required, specifies the path to the adapter For remote sources: optional, will be updated by the controller with the download path
+	// AdapterPath is the path to the LoRA adapter weights. Required for local sources and not used
+	// by remote ones, whose download path is reported in status.download.
 	AdapterPath string `json:"adapterPath,omitempty"`
 	// CredentialsSecretRef references a secret containing storage credentials.
+	// For huggingface sources Key selects the token. For s3 sources the secret must
+	// hold AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (and optionally AWS_SESSION_TOKEN).
+	// For http sources Key selects a bearer token; without a Key the secret's username
+	// and password entries are sent as basic auth.
 	CredentialsSecretRef *SecretRef `json:"credentialsSecretRef,omitempty"`
-	// MaxAdapters is the maximum number of adapters to load.
+	// Endpoint is the URL of the S3-compatible object store for s3 sources (e.g. a MinIO service).
+	// Defaults to AWS S3 when unset.
+	Endpoint string `json:"endpoint,omitempty"`
+	// MaxAdapters is the maximum number of adapters discovered through Pattern to load.
+	// Matches are taken in name order. Unlimited when unset.
+	// +kubebuilder:validation:Minimum=0
 	MaxAdapters int32 `json:"maxAdapters,omitempty"`
-	// Pattern is the pattern to use for the adapter name.
+	// Pattern enables discovery of every adapter whose name matches it, instead of the single
+	// adapter named by AdapterName. Candidates are the subdirectories of AdapterPath for local
+	// sources, the prefixes directly under Repository for s3 sources and the models of the
+	// Repository organization for huggingface sources. Pattern is a glob such as customer-*,
+	// or a regular expression matching the whole name when prefixed with regex:.
+	// Local AdapterPath must be inside the sidecar's LORA_DOWNLOAD_BASE_DIR, and adapters
+	// already selected by another LoraAdapter in the namespace are skipped.
 	Pattern string `json:"pattern,omitempty"`
+	// Region is the region of the bucket for s3 sources.
+	Region string `json:"region,omitempty"`
 	// Repository is the repository to get the LoRA adapter from.
+	// For s3 sources this is the bucket and prefix holding the adapter files, e.g. s3://bucket/path/to/adapter.
+	// For http sources this is the URL of a tarball (.tar, .tar.gz, .tgz) or of a directory index listing the adapter files.
 	Repository *string `json:"repository,omitempty"`
+	// SHA256 is the expected hex-encoded SHA256 checksum of the downloaded tarball for http sources.
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{64}$`
+	SHA256 string `json:"sha256,omitempty"`
 	// Type is the type of the adapter source.
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:Enum=local;s3;http;huggingface
@@ -62,20 +94,39 @@ type SecretRef struct {
 	// Name of the secret
 	// +kubebuilder:validation:Required
 	Name string `json:"name"`
-	// Key in the secret containing the value
-	// +kubebuilder:validation:Required
-	Key string `json:"key"`
+	// Key in the secret containing the value. Required for huggingface sources, not used by s3
+	// sources.
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
 type LoraAdapterDeploymentConfig struct {
-	// Algorithm specifies which placement algorithm to use.
+	// Algorithm specifies which placement algorithm to use. default places each adapter on the
+	// first Replicas pods by name, ordered fills pods in creation order and equalized balances the
+	// number of adapters per pod across all LoraAdapters sharing the base model. usage scrapes the
+	// vLLM metrics of every pod and loads busy adapters on more replicas and idle ones on fewer,
+	// between MinReplicas and Replicas, preferring pods with free KV cache. No algorithm places
+	// more adapters on a pod than its VLLMRuntime keeps in CPU memory, VLLMConfig.MaxCPULoras or
+	// MaxLoras when unset.
 	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Enum=default;ordered;equalized
+	// +kubebuilder:validation:Enum=default;ordered;equalized;usage
 	// +kubebuilder:default=default
 	Algorithm string `json:"algorithm"`
 	// Replicas is the number of replicas that should load this adapter.
+	// With the usage algorithm it is the maximum number of replicas.
 	// +kubebuilder:validation:Minimum=0
 	Replicas *int32 `json:"replicas,omitempty"`
+	// MinReplicas is the number of replicas the usage algorithm keeps an idle adapter loaded on.
+	// Defaults to 1.
+	// +kubebuilder:validation:Minimum=0
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+}
+
+// VLLMRuntimeReference references a VLLMRuntime by name
+type VLLMRuntimeReference struct {
+	// Name of the VLLMRuntime
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
 }
 
 // VLLMApiKeySecretRef defines the reference to a secret containing the API key
@@ -90,43 +141,41 @@ type VLLMApiKeySecretRef struct {
 
 // LoraAdapterStatus defines the observed state of LoraAdapter.
 type LoraAdapterStatus struct {
-	// Condition contains details for one aspect of the current state of this API Resource.
-	Conditions []Condition `json:"conditions,omitempty"`
+	// Conditions represent the latest available observations of the adapter's state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Download records where the adapter of a remote source was downloaded to. The adapter is
+	// downloaded again when the Repository or SHA256 of the source no longer match it.
+	// +optional
+	Download *AdapterDownload `json:"download,omitempty"`
 	// LoadedAdapters tracks the loading status of adapters and their pod assignments.
 	LoadedAdapters []LoadedAdapter `json:"loadedAdapters,omitempty"`
 	// Message provides additional information about the current phase.
 	Message string `json:"message,omitempty"`
 	// Phase represents the current phase of the adapter deployment.
 	Phase string `json:"phase,omitempty"`
+	// Placements records the pods the placement algorithm selected for each adapter.
+	Placements []AdapterPlacement `json:"placements,omitempty"`
+	// SelectedAdapters are the adapters discovered through Pattern that this LoraAdapter owns.
+	// Loaded adapters matching the pattern but not selected here are left to their own LoraAdapter.
+	SelectedAdapters []string `json:"selectedAdapters,omitempty"`
 	// ObservedGeneration represents the .metadata.generation that the condition was set based upon.
 	// +kubebuilder:validation:Minimum=0
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
-// Condition contains details for one aspect of the current state of this API Resource.
-type Condition struct {
-	// LastTransitionTime is the last time the condition transitioned from one status to another.
-	// +kubebuilder:validation:Format=date-time
-	// +kubebuilder:validation:Required
-	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
-	// Message is a human-readable message indicating details about why the current state is set.
-	// +kubebuilder:validation:MaxLength=32768
-	// +kubebuilder:validation:Required
-	Message string `json:"message"`
-	// Reason is a brief reason for the condition's current status.
-	// +kubebuilder:validation:MaxLength=1024
-	// +kubebuilder:validation:MinLength=1
+// AdapterDownload identifies the source an adapter was downloaded from and its local path
+type AdapterDownload struct {
+	// Repository is the Repository of the source the adapter was downloaded from
 	// +kubebuilder:validation:Required
-	Reason string `json:"reason"`
-	// Status is the status of the condition.
-	// +kubebuilder:validation:Enum=True;False;Unknown
+	Repository string `json:"repository"`
+	// SHA256 is the checksum the download was verified against, if any
+	SHA256 string `json:"sha256,omitempty"`
+	// Path is the local path of the downloaded adapter on the shared volume
 	// +kubebuilder:validation:Required
-	Status string `json:"status"`
-	// type of condition in CamelCase.
-	// +kubebuilder:validation:MaxLength=316
-	// +kubebuilder:validation:Pattern= ^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$
-	// +kubebuilder:validation:Required
-	Type string `json:"type"`
+	Path string `json:"path"`
 }
 
 // LoadedAdapter represents an adapter that has been loaded into a pod
@@ -147,6 +196,19 @@ type LoadedAdapter struct {
 	Status string `json:"status"`
 }
 
+// AdapterPlacement records the placement decision for a single adapter
+type AdapterPlacement struct {
+	// Name is the name of the adapter
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+	// DesiredReplicas is the number of pods the algorithm requested for the adapter
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+	// Pods are the names of the pods selected to load the adapter
+	Pods []string `json:"pods,omitempty"`
+	// Message explains why the adapter was placed on fewer pods than requested
+	Message string `json:"message,omitempty"`
+}
+
 // PodAssignment represents a pod that has been assigned to load this adapter
 type PodAssignment struct {
 	// Pod represents the pod information
@@ -180,6 +242,7 @@ type PodAssignment struct {
 
 // LoraAdapter is the Schema for the loraadapters API.
 // +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type LoraAdapter struct {
 	metav1.TypeMeta   `json:",inline"`
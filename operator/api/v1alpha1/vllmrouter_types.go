@@ -43,19 +43,49 @@ type VLLMRouterSpec struct {
 	// +kubebuilder:validation:RequiredWhen=ServiceDiscovery=k8s
 	K8sLabelSelector string `json:"k8sLabelSelector,omitempty"`
 
-	// StaticBackends is required when using static service discovery
+	// StaticBackends is required when using static service discovery, unless the dynamic config
+	// takes the backends from the VLLMRuntimes
 	// +kubebuilder:validation:RequiredWhen=ServiceDiscovery=static
 	StaticBackends string `json:"staticBackends,omitempty"`
 
-	// StaticModels is required when using static service discovery
+	// StaticModels is required when using static service discovery, unless the dynamic config
+	// takes the backends from the VLLMRuntimes
 	// +kubebuilder:validation:RequiredWhen=ServiceDiscovery=static
 	StaticModels string `json:"staticModels,omitempty"`
 
-	// RoutingLogic specifies the routing strategy
-	// +kubebuilder:validation:Enum=roundrobin;session
+	// RuntimeRefs names the VLLMRuntimes in the namespace of the router it routes to. With k8s
+	// service discovery the router discovers their pods, with static service discovery it routes
+	// to their Services. Cannot be combined with k8sLabelSelector or the static backends. Without
+	// the dynamic config, a change to the selected runtimes restarts the router pods.
+	// +optional
+	RuntimeRefs []corev1.LocalObjectReference `json:"runtimeRefs,omitempty"`
+
+	// RuntimeSelector selects the VLLMRuntimes in the namespace of the router it routes to by
+	// their labels, in addition to runtimeRefs
+	// +optional
+	RuntimeSelector *metav1.LabelSelector `json:"runtimeSelector,omitempty"`
+
+	// DynamicConfig moves the service discovery and routing settings of the router into a
+	// ConfigMap it reloads, so changing them does not restart the router pods
+	// +optional
+	DynamicConfig RouterDynamicConfig `json:"dynamicConfig,omitempty"`
+
+	// RoutingLogic specifies the routing strategy. kvaware routes a request to the backend
+	// LMCache reports the longest cached prefix of the prompt for, prefixaware to the backend that
+	// served the longest matching prefix before, and disaggregated_prefill sends every request to a
+	// prefill backend first and then to a decode backend.
+	// +kubebuilder:validation:Enum=roundrobin;session;kvaware;prefixaware;disaggregated_prefill
 	// +kubebuilder:default=roundrobin
 	RoutingLogic string `json:"routingLogic,omitempty"`
 
+	// KVAware configures kvaware routing
+	// +optional
+	KVAware KVAwareRoutingConfig `json:"kvAware,omitempty"`
+
+	// DisaggregatedPrefill configures disaggregated_prefill routing
+	// +optional
+	DisaggregatedPrefill DisaggregatedPrefillRoutingConfig `json:"disaggregatedPrefill,omitempty"`
+
 	// SessionKey for session-based routing
 	// +kubebuilder:validation:RequiredWhen=RoutingLogic=session
 	// +kubebuilder:default=""
@@ -67,19 +97,44 @@ type VLLMRouterSpec struct {
 	// RequestStatsWindow for request statistics
 	RequestStatsWindow int32 `json:"requestStatsWindow,omitempty"`
 
+	// ColdStartTimeoutSeconds is how long the router holds a request for a model that is scaled
+	// to zero while it scales up. Zero fails such requests right away. Requests are only held
+	// with k8s service discovery, static backends including those derived from RuntimeRefs or
+	// RuntimeSelector stay listed while their pods are scaled to zero.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ColdStartTimeoutSeconds int32 `json:"coldStartTimeoutSeconds,omitempty"`
+
 	// ExtraArgs for additional router arguments
 	ExtraArgs []string `json:"extraArgs,omitempty"`
 
-	// NodeSelectorTerms for pod scheduling
+	// NodeSelectorTerms for pod scheduling, required node affinity terms. Ignored when the
+	// scheduling affinity sets required node affinity itself.
 	NodeSelectorTerms []corev1.NodeSelectorTerm `json:"nodeSelectorTerms,omitempty"`
 
-	// ServiceAccountName for the router pod
+	// Scheduling of the router pods
+	// +optional
+	Scheduling SchedulingConfig `json:"scheduling,omitempty"`
+
+	// Probes of the router container. The router has a liveness probe on /health by default.
+	// +optional
+	Probes ProbesConfig `json:"probes,omitempty"`
+
+	// ServiceAccountName of the router pods, created when it does not exist. Defaults to a
+	// ServiceAccount named after the router. The operator binds it to a Role named
+	// <router>-router that grants only what the service discovery needs.
+	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 
 	// ContainerPort for the router service
 	// +kubebuilder:default=80
 	Port int32 `json:"port,omitempty"`
 
+	// Exposure configures the Service of the router and how it is reached from outside the
+	// cluster. The URL the router is reachable at is reported in status.url.
+	// +optional
+	Exposure RouterExposure `json:"exposure,omitempty"`
+
 	// Image configuration
 	Image ImageSpec `json:"image"`
 
@@ -94,6 +149,151 @@ type VLLMRouterSpec struct {
 	VLLMApiKeyName   string                      `json:"vllmApiKeyName,omitempty"`
 }
 
+// RouterDynamicConfig defines the dynamic config the operator renders for the router. It holds
+// the service discovery and the routing logic with its session key. kvaware and
+// disaggregated_prefill routing take parameters the router only reads at startup, so they cannot
+// be used with the dynamic config.
+type RouterDynamicConfig struct {
+	// Enabled renders the dynamic config into the <router>-dynamic-config ConfigMap. With static
+	// service discovery and no staticBackends, the backends are the ready pods of the VLLMRuntimes
+	// matching k8sLabelSelector, serving their models and the LoRA adapters loaded on them.
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// RouterExposure defines the Service of a router and the Ingress or Gateway API HTTPRoute that
+// exposes it. At most one of the Ingress and the HTTPRoute can be enabled.
+type RouterExposure struct {
+	// Service configures the Service of the router, which serves it on port 80
+	// +optional
+	Service RouterServiceConfig `json:"service,omitempty"`
+
+	// Ingress exposes the router through an Ingress named after it
+	// +optional
+	Ingress RouterIngressConfig `json:"ingress,omitempty"`
+
+	// HTTPRoute exposes the router through a Gateway API HTTPRoute named after it. The Gateway API
+	// CRDs have to be installed in the cluster.
+	// +optional
+	HTTPRoute RouterHTTPRouteConfig `json:"httpRoute,omitempty"`
+}
+
+// RouterServiceConfig defines the Service of a router
+type RouterServiceConfig struct {
+	// Type of the Service. A LoadBalancer Service is reachable at the address of its load balancer.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +kubebuilder:default=ClusterIP
+	// +optional
+	Type corev1.ServiceType `json:"type,omitempty"`
+
+	// Annotations of the Service, e.g. to configure the load balancer of a cloud provider
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// RouterIngressConfig defines the Ingress of a router
+type RouterIngressConfig struct {
+	// Enabled creates the Ingress
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// ClassName is the IngressClass of the Ingress, the default class of the cluster when empty
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
+	// Host the Ingress serves the router on. Without a host it serves the router on every host,
+	// reachable at the address of its load balancer.
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Path prefix the Ingress serves the router under
+	// +kubebuilder:default="/"
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// TLSSecretName names the Secret holding the TLS certificate of the host, which serves the
+	// router over HTTPS
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+
+	// Annotations of the Ingress, e.g. to configure the ingress controller
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// RouterHTTPRouteConfig defines the Gateway API HTTPRoute of a router
+type RouterHTTPRouteConfig struct {
+	// Enabled creates the HTTPRoute
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// GatewayName is the Gateway the HTTPRoute attaches to
+	// +optional
+	GatewayName string `json:"gatewayName,omitempty"`
+
+	// GatewayNamespace is the namespace of the Gateway, the namespace of the router when empty. A
+	// Gateway in another namespace has to allow routes from the namespace of the router.
+	// +optional
+	GatewayNamespace string `json:"gatewayNamespace,omitempty"`
+
+	// SectionName is the listener of the Gateway the HTTPRoute attaches to, all of its listeners
+	// when empty
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+
+	// Hostnames the HTTPRoute serves the router on, the hostnames of the Gateway listeners when
+	// empty
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+
+	// Path prefix the HTTPRoute serves the router under
+	// +kubebuilder:default="/"
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// KVAwareRoutingConfig defines how the router finds the KV cache of a prompt. The router runs an
+// LMCache controller the runtimes register their KV cache with, through their
+// spec.lmCacheConfig.controllerUrl.
+type KVAwareRoutingConfig struct {
+	// Threshold is the number of tokens of a prompt that may miss the KV cache of a backend for the
+	// request to still be routed to it. Requests missing more are routed by session or load.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=2000
+	// +optional
+	Threshold int32 `json:"threshold,omitempty"`
+
+	// LMCacheControllerPort is the port the LMCache controller of the router listens on
+	// +kubebuilder:default=9000
+	// +optional
+	LMCacheControllerPort int32 `json:"lmcacheControllerPort,omitempty"`
+}
+
+// DisaggregatedPrefillRoutingConfig defines the backends disaggregated_prefill routing sends the
+// prefill and decode of every request to
+type DisaggregatedPrefillRoutingConfig struct {
+	// PrefillModelLabels are the model labels of the prefill backends, e.g. the
+	// status.topology.prefill.modelLabel of a VLLMRuntime
+	PrefillModelLabels []string `json:"prefillModelLabels,omitempty"`
+
+	// DecodeModelLabels are the model labels of the decode backends, e.g. the
+	// status.topology.decode.modelLabel of a VLLMRuntime
+	DecodeModelLabels []string `json:"decodeModelLabels,omitempty"`
+}
+
+// RouterRuntimeStatus is the observed state of a VLLMRuntime a router routes to
+type RouterRuntimeStatus struct {
+	// Name of the VLLMRuntime
+	Name string `json:"name"`
+
+	// Models are the model names the runtime serves
+	// +optional
+	Models []string `json:"models,omitempty"`
+
+	// Ready reports whether the runtime is available
+	Ready bool `json:"ready"`
+}
+
 // VLLMRouterStatus defines the observed state of VLLMRouter
 type VLLMRouterStatus struct {
 	// Router status
@@ -102,12 +302,54 @@ type VLLMRouterStatus struct {
 	// Last updated timestamp
 	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
 
-	// Number of active runtimes
+	// URL the router is reachable at: the host or address of its Ingress or HTTPRoute, the address
+	// of its LoadBalancer Service, or else the in-cluster address of its Service
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// ActiveRuntimes is the number of ready VLLMRuntimes the router routes to
 	ActiveRuntimes int32 `json:"activeRuntimes,omitempty"`
+
+	// Runtimes are the VLLMRuntimes the router routes to: those selected by runtimeRefs and
+	// runtimeSelector, or whose pods match k8sLabelSelector
+	// +optional
+	Runtimes []RouterRuntimeStatus `json:"runtimes,omitempty"`
+
+	// Conditions represent the latest available observations of the router's state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Replicas is the number of pods of the router Deployment
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of pods that are ready
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// AvailableReplicas is the number of pods that have been ready for at least minReadySeconds
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of pods running the latest pod template
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Available",type="string",JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.status"
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.url",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // VLLMRouter is the Schema for the vllmrouters API
 type VLLMRouter struct {
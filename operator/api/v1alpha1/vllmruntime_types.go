@@ -61,6 +61,160 @@ type VLLMRuntimeSpec struct {
 
 	// Deployment configuration
 	DeploymentConfig DeploymentConfig `json:"deploymentConfig"`
+
+	// Scheduling of the vLLM pods
+	// +optional
+	Scheduling SchedulingConfig `json:"scheduling,omitempty"`
+
+	// Probes of the vLLM container. The startup budget defaults from the size hint of the model.
+	// +optional
+	Probes ProbesConfig `json:"probes,omitempty"`
+
+	// Autoscaling configuration. When enabled, the operator sets the replica count of the
+	// Deployment from the engine metrics instead of DeploymentConfig.Replicas.
+	// +optional
+	Autoscaling AutoscalingConfig `json:"autoscaling,omitempty"`
+
+	// ScaleToZero configuration. When enabled, the operator scales the Deployment to zero
+	// replicas while the model receives no requests and back up once requests arrive.
+	// +optional
+	ScaleToZero ScaleToZeroConfig `json:"scaleToZero,omitempty"`
+
+	// MultiNode configuration. When enabled, every replica is served by a leader/worker group of
+	// StatefulSet pods instead of a single Deployment pod.
+	// +optional
+	MultiNode MultiNodeConfig `json:"multiNode,omitempty"`
+
+	// Topology configuration. When disaggregated, the model is served by separate prefill and
+	// decode Deployments that hand the KV cache over through LMCache.
+	// +optional
+	Topology TopologyConfig `json:"topology,omitempty"`
+}
+
+// TopologyConfig defines how prefill and decode are split across pods. In disaggregated mode the
+// prefill pods compute the KV cache of a prompt and store it in LMCache (kv_producer), and the
+// decode pods retrieve it and generate the tokens (kv_consumer). Both groups share the LMCache
+// remote backend. A VLLMRouter with the disaggregated_prefill routing logic sends every request
+// through both groups, which it tells apart by the model label of their pods.
+type TopologyConfig struct {
+	// Enable disaggregated prefill and decode
+	Disaggregated bool `json:"disaggregated,omitempty"`
+
+	// Prefill group, computing the KV cache of the prompts
+	// +optional
+	Prefill ReplicaGroup `json:"prefill,omitempty"`
+
+	// Decode group, generating the tokens from the KV cache of the prefill group
+	// +optional
+	Decode ReplicaGroup `json:"decode,omitempty"`
+}
+
+// ReplicaGroup defines the pods serving one stage of a disaggregated topology
+type ReplicaGroup struct {
+	// Replicas of the group
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=1
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Resource requirements of the vLLM container. Defaults to DeploymentConfig.Resources.
+	// +optional
+	Resources ResourceRequirements `json:"resources,omitempty"`
+
+	// ModelLabel is the value of the model label on the pods of the group, which the router
+	// passes in --prefill-model-labels or --decode-model-labels. Defaults to the runtime name
+	// suffixed with -prefill or -decode.
+	// +optional
+	ModelLabel string `json:"modelLabel,omitempty"`
+}
+
+// MultiNodeConfig defines how a model is served by a group of pods on different nodes. Every
+// replica becomes a group of one leader pod, which runs the Ray head and the vLLM server, and
+// workers that join its Ray cluster and host the remaining GPUs. The pods of a group share the
+// storage volume, which then needs the ReadWriteMany access mode.
+type MultiNodeConfig struct {
+	// Enable the multi-node mode
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Size is the number of pods in a group, the leader included. Defaults to the pipeline
+	// parallel size, one node per pipeline stage.
+	// +kubebuilder:validation:Minimum=2
+	// +optional
+	Size int32 `json:"size,omitempty"`
+
+	// RayPort is the port of the Ray head on the leader
+	// +kubebuilder:default=6379
+	// +optional
+	RayPort int32 `json:"rayPort,omitempty"`
+}
+
+// AutoscalingConfig defines how the number of replicas follows the load on the engines. The
+// operator scrapes the metrics endpoint of every ready pod and sizes the Deployment so that the
+// average value per replica meets the target.
+type AutoscalingConfig struct {
+	// Enable autoscaling
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Minimum number of replicas
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+
+	// Maximum number of replicas
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+
+	// Metric the replicas are sized by: queueLength is the number of waiting requests
+	// (vllm:num_requests_waiting), kvCacheUsage the fraction of the KV cache in use and
+	// tokensPerSecond the rate of generated tokens (vllm:generation_tokens_total)
+	// +kubebuilder:validation:Enum=queueLength;kvCacheUsage;tokensPerSecond
+	// +kubebuilder:default=queueLength
+	// +optional
+	Metric string `json:"metric,omitempty"`
+
+	// Target average value of the metric per replica, e.g. "5" waiting requests, "0.8" KV cache
+	// usage or "2000" tokens per second
+	Target string `json:"target,omitempty"`
+
+	// Seconds scale up recommendations must persist before the replicas grow
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	ScaleUpStabilizationSeconds int32 `json:"scaleUpStabilizationSeconds,omitempty"`
+
+	// Seconds scale down recommendations must persist before the replicas shrink
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=300
+	// +optional
+	ScaleDownStabilizationSeconds int32 `json:"scaleDownStabilizationSeconds,omitempty"`
+
+	// Seconds between metric scrapes
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=15
+	// +optional
+	SyncPeriodSeconds int32 `json:"syncPeriodSeconds,omitempty"`
+}
+
+// ScaleToZeroConfig defines when the Deployment is scaled to zero replicas. Requests are observed
+// in the engine metrics of the pods and in the request metrics of the VLLMRouters in the namespace.
+// While the model is scaled to zero, requests counted by a router scale it back up; routers with a
+// cold start timeout hold these requests until the model is served again.
+type ScaleToZeroConfig struct {
+	// Enable scaling to zero
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Minutes without requests after which the Deployment is scaled to zero
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=15
+	// +optional
+	IdleMinutes int32 `json:"idleMinutes,omitempty"`
+
+	// Seconds between checks for requests, which bounds the delay before a cold start begins
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	// +optional
+	SyncPeriodSeconds int32 `json:"syncPeriodSeconds,omitempty"`
 }
 
 // VLLMConfig defines the vLLM server configuration
@@ -74,12 +228,23 @@ type VLLMConfig struct {
 	// Tensor parallel size
 	TensorParallelSize int32 `json:"tensorParallelSize,omitempty"`
 
+	// Pipeline parallel size, the number of pipeline stages the model layers are split into
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PipelineParallelSize int32 `json:"pipelineParallelSize,omitempty"`
+
 	// GPU memory utilization
 	GpuMemoryUtilization string `json:"gpuMemoryUtilization,omitempty"`
 
 	// Maximum number of LoRAs
 	MaxLoras int32 `json:"maxLoras,omitempty"`
 
+	// Maximum number of LoRAs kept in CPU memory, passed as --max-cpu-loras. vLLM defaults it
+	// to MaxLoras and evicts the least recently used adapter to load another beyond it.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxCPULoras int32 `json:"maxCPULoras,omitempty"`
+
 	// Extra arguments for vllm serve
 	ExtraArgs []string `json:"extraArgs,omitempty"`
 
@@ -99,6 +264,11 @@ type ModelSpec struct {
 	// Model URL
 	ModelURL string `json:"modelURL"`
 
+	// Revision of the model on Hugging Face to serve, a branch, tag or commit hash. Pin a commit
+	// hash to keep every pod on the same weights.
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
 	// HuggingFace token secret
 	HFTokenSecret corev1.LocalObjectReference `json:"hfTokenSecret,omitempty"`
 	// +kubebuilder:default=token
@@ -122,6 +292,48 @@ type ModelSpec struct {
 
 	// Maximum number of sequences
 	MaxNumSeqs int32 `json:"maxNumSeqs,omitempty"`
+
+	// SizeHint is the approximate size of the model, which sets how long the vLLM container gets
+	// to load it before the startup probe fails: Small up to 10B parameters, Medium up to 40B,
+	// Large up to 100B and XLarge beyond. Unset keeps a budget of about 35 minutes.
+	// +kubebuilder:validation:Enum=Small;Medium;Large;XLarge
+	// +optional
+	SizeHint string `json:"sizeHint,omitempty"`
+
+	// Prewarm configuration. When enabled, the model is downloaded into the storage volume before
+	// the vLLM pods start and served from there.
+	// +optional
+	Prewarm PrewarmConfig `json:"prewarm,omitempty"`
+}
+
+// PrewarmConfig defines how the model weights are downloaded into the storage volume ahead of
+// serving. The model is stored under models/<modelURL>/<revision> in the volume, and vllm serve
+// loads it from there instead of downloading it at startup. Requires storage to be enabled.
+type PrewarmConfig struct {
+	// Enable pre-warming
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Mode of the download: Job downloads the model once in a Job while the vLLM pods wait for it
+	// in an init container, InitContainer downloads it in an init container of every vLLM pod,
+	// skipping files already in the volume
+	// +kubebuilder:validation:Enum=Job;InitContainer
+	// +kubebuilder:default=Job
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// Image of the downloader, which needs Python and huggingface_hub. Defaults to the vLLM image.
+	// +optional
+	Image *ImageSpec `json:"image,omitempty"`
+
+	// Resource requirements of the downloader
+	// +optional
+	Resources ResourceRequirements `json:"resources,omitempty"`
+
+	// Number of retries before the download Job is marked as failed
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=6
+	// +optional
+	BackoffLimit int32 `json:"backoffLimit,omitempty"`
 }
 
 // LMCacheConfig defines the LM Cache configuration
@@ -141,8 +353,74 @@ type LMCacheConfig struct {
 	// RemoteURL is the URL of the remote cache server
 	RemoteURL string `json:"remoteUrl,omitempty"`
 
+	// CacheServerRef names a CacheServer in the namespace of the runtime to use as the remote
+	// cache, instead of setting RemoteURL
+	// +optional
+	CacheServerRef *corev1.LocalObjectReference `json:"cacheServerRef,omitempty"`
+
 	// RemoteSerde is the serialization format for the remote cache
 	RemoteSerde string `json:"remoteSerde,omitempty"`
+
+	// ChunkSize is the number of tokens the KV cache is stored and looked up in
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ChunkSize int32 `json:"chunkSize,omitempty"`
+
+	// LocalDevice is the device the local KV cache is kept on
+	// +kubebuilder:validation:Enum=cpu;cuda
+	// +optional
+	LocalDevice string `json:"localDevice,omitempty"`
+
+	// LogLevel of LMCache
+	// +kubebuilder:validation:Enum=DEBUG;INFO;WARNING;ERROR
+	// +kubebuilder:default=INFO
+	// +optional
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// Blending configuration, which reuses the KV cache of text that is not a prefix of the prompt
+	// (CacheBlend)
+	// +optional
+	Blending LMCacheBlendingConfig `json:"blending,omitempty"`
+
+	// P2P configuration, which lets the pods retrieve the KV cache from each other
+	// +optional
+	P2P LMCacheP2PConfig `json:"p2p,omitempty"`
+
+	// ControllerURL is the host:port of the LMCache controller the pods register with, which
+	// tracks the KV cache of every pod. Empty disables the controller.
+	// +optional
+	ControllerURL string `json:"controllerUrl,omitempty"`
+}
+
+// LMCacheBlendingConfig defines how the KV cache of non-prefix text is blended into a prompt
+type LMCacheBlendingConfig struct {
+	// Enable blending
+	Enabled bool `json:"enabled,omitempty"`
+
+	// RecomputeRatio is the share of the tokens whose KV cache is recomputed to blend, e.g. "0.15"
+	// +optional
+	RecomputeRatio string `json:"recomputeRatio,omitempty"`
+
+	// MinTokens is the minimum number of tokens of a text for its KV cache to be blended
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinTokens int32 `json:"minTokens,omitempty"`
+}
+
+// LMCacheP2PConfig defines how the pods share the KV cache with each other. Every pod serves its
+// KV cache to its peers, which find the pod holding a chunk through the lookup server.
+type LMCacheP2PConfig struct {
+	// Enable P2P sharing
+	Enabled bool `json:"enabled,omitempty"`
+
+	// LookupURL is the host:port of the lookup server that tracks which pod holds a chunk
+	// +optional
+	LookupURL string `json:"lookupUrl,omitempty"`
+
+	// Port the pods serve their KV cache to peers on
+	// +kubebuilder:default=8200
+	// +optional
+	Port int32 `json:"port,omitempty"`
 }
 
 // StorageConfig defines the storage configuration
@@ -151,14 +429,16 @@ type StorageConfig struct {
 	// +kubebuilder:default=false
 	Enabled bool `json:"enabled,omitempty"`
 
-	// StorageClassName is the name of the storage class to use
+	// StorageClassName is the name of the storage class to use. It cannot change once the PVC
+	// is created.
 	StorageClassName string `json:"storageClassName,omitempty"`
 
-	// Size is the size of the persistent volume claim
+	// Size is the size of the persistent volume claim. It can only grow once the PVC is created.
 	// +kubebuilder:default="10Gi"
 	Size string `json:"size,omitempty"`
 
-	// AccessMode is the access mode for the persistent volume claim
+	// AccessMode is the access mode for the persistent volume claim. It cannot change once the
+	// PVC is created.
 	// +kubebuilder:default=ReadWriteMany
 	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadOnlyMany;ReadWriteMany
 	AccessMode string `json:"accessMode,omitempty"`
@@ -215,11 +495,143 @@ type VLLMRuntimeStatus struct {
 
 	// Last updated timestamp
 	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
+
+	// Conditions represent the latest available observations of the runtime's state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the status was computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Replicas is the number of pods of the runtime Deployment
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of pods that are ready
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// AvailableReplicas is the number of pods that have been ready for at least minReadySeconds
+	// +optional
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of pods running the latest pod template
+	// +optional
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+
+	// Autoscaling reports the decisions of the autoscaler
+	// +optional
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// LastRequestTime is the last time requests for the model were observed while scaling to zero
+	// is enabled
+	// +optional
+	LastRequestTime *metav1.Time `json:"lastRequestTime,omitempty"`
+
+	// MultiNode reports the readiness of the leader/worker groups in multi-node mode
+	// +optional
+	MultiNode *MultiNodeStatus `json:"multiNode,omitempty"`
+
+	// Topology reports the prefill and decode groups in disaggregated mode
+	// +optional
+	Topology *TopologyStatus `json:"topology,omitempty"`
+
+	// ModelDownload reports the download of the model into the storage volume when pre-warming
+	// is enabled
+	// +optional
+	ModelDownload *ModelDownloadStatus `json:"modelDownload,omitempty"`
+
+	// CacheServerURL is the remote cache URL resolved from spec.lmCacheConfig.cacheServerRef, the
+	// URL of the shard of the model when the CacheServer is sharded
+	// +optional
+	CacheServerURL string `json:"cacheServerUrl,omitempty"`
+}
+
+// ModelDownloadStatus defines the observed state of the model download
+type ModelDownloadStatus struct {
+	// Phase of the download: Pending, Downloading, Completed or Failed
+	Phase string `json:"phase,omitempty"`
+
+	// Revision is the revision of the model being downloaded
+	Revision string `json:"revision,omitempty"`
+
+	// LocalPath is the path of the model in the vLLM container
+	LocalPath string `json:"localPath,omitempty"`
+
+	// DownloadedBytes is the size of the files downloaded so far
+	// +optional
+	DownloadedBytes int64 `json:"downloadedBytes,omitempty"`
+
+	// TotalBytes is the size of all files of the model
+	// +optional
+	TotalBytes int64 `json:"totalBytes,omitempty"`
+
+	// Progress is the downloaded share of the model, e.g. "42%"
+	// +optional
+	Progress string `json:"progress,omitempty"`
+
+	// Message describes the state of the download
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// TopologyStatus defines the observed state of the prefill and decode groups
+type TopologyStatus struct {
+	// Prefill reports the prefill group
+	Prefill ReplicaGroupStatus `json:"prefill,omitempty"`
+
+	// Decode reports the decode group
+	Decode ReplicaGroupStatus `json:"decode,omitempty"`
+}
+
+// ReplicaGroupStatus defines the observed state of the Deployment of a group
+type ReplicaGroupStatus struct {
+	// ModelLabel is the model label of the pods of the group, to configure the router with
+	ModelLabel string `json:"modelLabel,omitempty"`
+
+	// Replicas is the number of pods of the group
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// ReadyReplicas is the number of pods of the group that are ready
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+}
+
+// MultiNodeStatus defines the observed state of the leader/worker groups
+type MultiNodeStatus struct {
+	// Groups is the number of leader/worker groups
+	Groups int32 `json:"groups,omitempty"`
+
+	// ReadyGroups is the number of groups whose leader and workers are all ready
+	ReadyGroups int32 `json:"readyGroups,omitempty"`
+
+	// ReadyWorkers is the number of worker pods that are ready
+	ReadyWorkers int32 `json:"readyWorkers,omitempty"`
+}
+
+// AutoscalingStatus defines the observed state of the autoscaler
+type AutoscalingStatus struct {
+	// DesiredReplicas is the replica count chosen by the autoscaler
+	DesiredReplicas int32 `json:"desiredReplicas,omitempty"`
+
+	// CurrentValue is the average value of the metric per replica at the last scrape
+	CurrentValue string `json:"currentValue,omitempty"`
+
+	// LastScaleTime is the last time the autoscaler changed the replica count
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=vr
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas"
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".status.replicas"
+// +kubebuilder:printcolumn:name="Available",type="string",JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.modelStatus"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // VLLMRuntime is the Schema for the vllmruntimes API
 type VLLMRuntime struct {
@@ -21,10 +21,46 @@ limitations under the License.
 package v1alpha1
 
 import (
-	"k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdapterDownload) DeepCopyInto(out *AdapterDownload) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdapterDownload.
+func (in *AdapterDownload) DeepCopy() *AdapterDownload {
+	if in == nil {
+		return nil
+	}
+	out := new(AdapterDownload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdapterPlacement) DeepCopyInto(out *AdapterPlacement) {
+	*out = *in
+	if in.Pods != nil {
+		in, out := &in.Pods, &out.Pods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdapterPlacement.
+func (in *AdapterPlacement) DeepCopy() *AdapterPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(AdapterPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AdapterSource) DeepCopyInto(out *AdapterSource) {
 	*out = *in
@@ -50,12 +86,46 @@ func (in *AdapterSource) DeepCopy() *AdapterSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingConfig) DeepCopyInto(out *AutoscalingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingConfig.
+func (in *AutoscalingConfig) DeepCopy() *AutoscalingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingStatus) DeepCopyInto(out *AutoscalingStatus) {
+	*out = *in
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingStatus.
+func (in *AutoscalingStatus) DeepCopy() *AutoscalingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CacheServer) DeepCopyInto(out *CacheServer) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -109,11 +179,29 @@ func (in *CacheServerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CacheServerPersistence) DeepCopyInto(out *CacheServerPersistence) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheServerPersistence.
+func (in *CacheServerPersistence) DeepCopy() *CacheServerPersistence {
+	if in == nil {
+		return nil
+	}
+	out := new(CacheServerPersistence)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CacheServerSpec) DeepCopyInto(out *CacheServerSpec) {
 	*out = *in
 	out.Image = in.Image
 	out.Resources = in.Resources
+	out.Persistence = in.Persistence
+	in.Scheduling.DeepCopyInto(&out.Scheduling)
+	in.Probes.DeepCopyInto(&out.Probes)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheServerSpec.
@@ -130,6 +218,18 @@ func (in *CacheServerSpec) DeepCopy() *CacheServerSpec {
 func (in *CacheServerStatus) DeepCopyInto(out *CacheServerStatus) {
 	*out = *in
 	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ShardURLs != nil {
+		in, out := &in.ShardURLs, &out.ShardURLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CacheServerStatus.
@@ -143,35 +243,44 @@ func (in *CacheServerStatus) DeepCopy() *CacheServerStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Condition) DeepCopyInto(out *Condition) {
+func (in *DeploymentConfig) DeepCopyInto(out *DeploymentConfig) {
 	*out = *in
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	out.Resources = in.Resources
+	out.Image = in.Image
+	in.SidecarConfig.DeepCopyInto(&out.SidecarConfig)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
-func (in *Condition) DeepCopy() *Condition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentConfig.
+func (in *DeploymentConfig) DeepCopy() *DeploymentConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(Condition)
+	out := new(DeploymentConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DeploymentConfig) DeepCopyInto(out *DeploymentConfig) {
+func (in *DisaggregatedPrefillRoutingConfig) DeepCopyInto(out *DisaggregatedPrefillRoutingConfig) {
 	*out = *in
-	out.Resources = in.Resources
-	out.Image = in.Image
-	in.SidecarConfig.DeepCopyInto(&out.SidecarConfig)
+	if in.PrefillModelLabels != nil {
+		in, out := &in.PrefillModelLabels, &out.PrefillModelLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DecodeModelLabels != nil {
+		in, out := &in.DecodeModelLabels, &out.DecodeModelLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentConfig.
-func (in *DeploymentConfig) DeepCopy() *DeploymentConfig {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DisaggregatedPrefillRoutingConfig.
+func (in *DisaggregatedPrefillRoutingConfig) DeepCopy() *DisaggregatedPrefillRoutingConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(DeploymentConfig)
+	out := new(DisaggregatedPrefillRoutingConfig)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -191,6 +300,21 @@ func (in *EnvVar) DeepCopy() *EnvVar {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GRPCProbe) DeepCopyInto(out *GRPCProbe) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GRPCProbe.
+func (in *GRPCProbe) DeepCopy() *GRPCProbe {
+	if in == nil {
+		return nil
+	}
+	out := new(GRPCProbe)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageSpec) DeepCopyInto(out *ImageSpec) {
 	*out = *in
@@ -206,9 +330,46 @@ func (in *ImageSpec) DeepCopy() *ImageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KVAwareRoutingConfig) DeepCopyInto(out *KVAwareRoutingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KVAwareRoutingConfig.
+func (in *KVAwareRoutingConfig) DeepCopy() *KVAwareRoutingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KVAwareRoutingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMCacheBlendingConfig) DeepCopyInto(out *LMCacheBlendingConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMCacheBlendingConfig.
+func (in *LMCacheBlendingConfig) DeepCopy() *LMCacheBlendingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LMCacheBlendingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LMCacheConfig) DeepCopyInto(out *LMCacheConfig) {
 	*out = *in
+	if in.CacheServerRef != nil {
+		in, out := &in.CacheServerRef, &out.CacheServerRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+	out.Blending = in.Blending
+	out.P2P = in.P2P
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMCacheConfig.
@@ -221,6 +382,21 @@ func (in *LMCacheConfig) DeepCopy() *LMCacheConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LMCacheP2PConfig) DeepCopyInto(out *LMCacheP2PConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LMCacheP2PConfig.
+func (in *LMCacheP2PConfig) DeepCopy() *LMCacheP2PConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(LMCacheP2PConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LoadedAdapter) DeepCopyInto(out *LoadedAdapter) {
 	*out = *in
@@ -273,6 +449,11 @@ func (in *LoraAdapterDeploymentConfig) DeepCopyInto(out *LoraAdapterDeploymentCo
 		*out = new(int32)
 		**out = **in
 	}
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoraAdapterDeploymentConfig.
@@ -321,6 +502,16 @@ func (in *LoraAdapterList) DeepCopyObject() runtime.Object {
 func (in *LoraAdapterSpec) DeepCopyInto(out *LoraAdapterSpec) {
 	*out = *in
 	in.AdapterSource.DeepCopyInto(&out.AdapterSource)
+	if in.VLLMRuntimeRef != nil {
+		in, out := &in.VLLMRuntimeRef, &out.VLLMRuntimeRef
+		*out = new(VLLMRuntimeReference)
+		**out = **in
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.LoraAdapterDeploymentConfig.DeepCopyInto(&out.LoraAdapterDeploymentConfig)
 	if in.VLLMApiKey != nil {
 		in, out := &in.VLLMApiKey, &out.VLLMApiKey
@@ -344,11 +535,16 @@ func (in *LoraAdapterStatus) DeepCopyInto(out *LoraAdapterStatus) {
 	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
-		*out = make([]Condition, len(*in))
+		*out = make([]v1.Condition, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Download != nil {
+		in, out := &in.Download, &out.Download
+		*out = new(AdapterDownload)
+		**out = **in
+	}
 	if in.LoadedAdapters != nil {
 		in, out := &in.LoadedAdapters, &out.LoadedAdapters
 		*out = make([]LoadedAdapter, len(*in))
@@ -356,6 +552,18 @@ func (in *LoraAdapterStatus) DeepCopyInto(out *LoraAdapterStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Placements != nil {
+		in, out := &in.Placements, &out.Placements
+		*out = make([]AdapterPlacement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SelectedAdapters != nil {
+		in, out := &in.SelectedAdapters, &out.SelectedAdapters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoraAdapterStatus.
@@ -368,10 +576,26 @@ func (in *LoraAdapterStatus) DeepCopy() *LoraAdapterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModelDownloadStatus) DeepCopyInto(out *ModelDownloadStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelDownloadStatus.
+func (in *ModelDownloadStatus) DeepCopy() *ModelDownloadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ModelDownloadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModelSpec) DeepCopyInto(out *ModelSpec) {
 	*out = *in
 	out.HFTokenSecret = in.HFTokenSecret
+	in.Prewarm.DeepCopyInto(&out.Prewarm)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModelSpec.
@@ -384,6 +608,36 @@ func (in *ModelSpec) DeepCopy() *ModelSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MultiNodeConfig) DeepCopyInto(out *MultiNodeConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiNodeConfig.
+func (in *MultiNodeConfig) DeepCopy() *MultiNodeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MultiNodeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MultiNodeStatus) DeepCopyInto(out *MultiNodeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultiNodeStatus.
+func (in *MultiNodeStatus) DeepCopy() *MultiNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MultiNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodAssignment) DeepCopyInto(out *PodAssignment) {
 	*out = *in
@@ -399,6 +653,126 @@ func (in *PodAssignment) DeepCopy() *PodAssignment {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrewarmConfig) DeepCopyInto(out *PrewarmConfig) {
+	*out = *in
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ImageSpec)
+		**out = **in
+	}
+	out.Resources = in.Resources
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrewarmConfig.
+func (in *PrewarmConfig) DeepCopy() *PrewarmConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(PrewarmConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbeConfig) DeepCopyInto(out *ProbeConfig) {
+	*out = *in
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GRPC != nil {
+		in, out := &in.GRPC, &out.GRPC
+		*out = new(GRPCProbe)
+		**out = **in
+	}
+	if in.InitialDelaySeconds != nil {
+		in, out := &in.InitialDelaySeconds, &out.InitialDelaySeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PeriodSeconds != nil {
+		in, out := &in.PeriodSeconds, &out.PeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SuccessThreshold != nil {
+		in, out := &in.SuccessThreshold, &out.SuccessThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.FailureThreshold != nil {
+		in, out := &in.FailureThreshold, &out.FailureThreshold
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeConfig.
+func (in *ProbeConfig) DeepCopy() *ProbeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProbesConfig) DeepCopyInto(out *ProbesConfig) {
+	*out = *in
+	in.Readiness.DeepCopyInto(&out.Readiness)
+	in.Liveness.DeepCopyInto(&out.Liveness)
+	in.Startup.DeepCopyInto(&out.Startup)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbesConfig.
+func (in *ProbesConfig) DeepCopy() *ProbesConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProbesConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaGroup) DeepCopyInto(out *ReplicaGroup) {
+	*out = *in
+	out.Resources = in.Resources
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaGroup.
+func (in *ReplicaGroup) DeepCopy() *ReplicaGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaGroupStatus) DeepCopyInto(out *ReplicaGroupStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaGroupStatus.
+func (in *ReplicaGroupStatus) DeepCopy() *ReplicaGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceRequirements) DeepCopyInto(out *ResourceRequirements) {
 	*out = *in
@@ -414,6 +788,179 @@ func (in *ResourceRequirements) DeepCopy() *ResourceRequirements {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterDynamicConfig) DeepCopyInto(out *RouterDynamicConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterDynamicConfig.
+func (in *RouterDynamicConfig) DeepCopy() *RouterDynamicConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterDynamicConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterExposure) DeepCopyInto(out *RouterExposure) {
+	*out = *in
+	in.Service.DeepCopyInto(&out.Service)
+	in.Ingress.DeepCopyInto(&out.Ingress)
+	in.HTTPRoute.DeepCopyInto(&out.HTTPRoute)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterExposure.
+func (in *RouterExposure) DeepCopy() *RouterExposure {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterExposure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterHTTPRouteConfig) DeepCopyInto(out *RouterHTTPRouteConfig) {
+	*out = *in
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterHTTPRouteConfig.
+func (in *RouterHTTPRouteConfig) DeepCopy() *RouterHTTPRouteConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterHTTPRouteConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterIngressConfig) DeepCopyInto(out *RouterIngressConfig) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterIngressConfig.
+func (in *RouterIngressConfig) DeepCopy() *RouterIngressConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterIngressConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterRuntimeStatus) DeepCopyInto(out *RouterRuntimeStatus) {
+	*out = *in
+	if in.Models != nil {
+		in, out := &in.Models, &out.Models
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterRuntimeStatus.
+func (in *RouterRuntimeStatus) DeepCopy() *RouterRuntimeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterRuntimeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterServiceConfig) DeepCopyInto(out *RouterServiceConfig) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterServiceConfig.
+func (in *RouterServiceConfig) DeepCopy() *RouterServiceConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterServiceConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleToZeroConfig) DeepCopyInto(out *ScaleToZeroConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleToZeroConfig.
+func (in *ScaleToZeroConfig) DeepCopy() *ScaleToZeroConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleToZeroConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulingConfig) DeepCopyInto(out *SchedulingConfig) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingConfig.
+func (in *SchedulingConfig) DeepCopy() *SchedulingConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulingConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretRef) DeepCopyInto(out *SecretRef) {
 	*out = *in
@@ -476,6 +1023,40 @@ func (in *StorageConfig) DeepCopy() *StorageConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyConfig) DeepCopyInto(out *TopologyConfig) {
+	*out = *in
+	out.Prefill = in.Prefill
+	out.Decode = in.Decode
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyConfig.
+func (in *TopologyConfig) DeepCopy() *TopologyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologyStatus) DeepCopyInto(out *TopologyStatus) {
+	*out = *in
+	out.Prefill = in.Prefill
+	out.Decode = in.Decode
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologyStatus.
+func (in *TopologyStatus) DeepCopy() *TopologyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VLLMApiKeySecretRef) DeepCopyInto(out *VLLMApiKeySecretRef) {
 	*out = *in
@@ -578,6 +1159,19 @@ func (in *VLLMRouterList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VLLMRouterSpec) DeepCopyInto(out *VLLMRouterSpec) {
 	*out = *in
+	if in.RuntimeRefs != nil {
+		in, out := &in.RuntimeRefs, &out.RuntimeRefs
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.RuntimeSelector != nil {
+		in, out := &in.RuntimeSelector, &out.RuntimeSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	out.DynamicConfig = in.DynamicConfig
+	out.KVAware = in.KVAware
+	in.DisaggregatedPrefill.DeepCopyInto(&out.DisaggregatedPrefill)
 	if in.ExtraArgs != nil {
 		in, out := &in.ExtraArgs, &out.ExtraArgs
 		*out = make([]string, len(*in))
@@ -585,11 +1179,14 @@ func (in *VLLMRouterSpec) DeepCopyInto(out *VLLMRouterSpec) {
 	}
 	if in.NodeSelectorTerms != nil {
 		in, out := &in.NodeSelectorTerms, &out.NodeSelectorTerms
-		*out = make([]v1.NodeSelectorTerm, len(*in))
+		*out = make([]corev1.NodeSelectorTerm, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.Scheduling.DeepCopyInto(&out.Scheduling)
+	in.Probes.DeepCopyInto(&out.Probes)
+	in.Exposure.DeepCopyInto(&out.Exposure)
 	out.Image = in.Image
 	out.Resources = in.Resources
 	if in.Env != nil {
@@ -614,6 +1211,20 @@ func (in *VLLMRouterSpec) DeepCopy() *VLLMRouterSpec {
 func (in *VLLMRouterStatus) DeepCopyInto(out *VLLMRouterStatus) {
 	*out = *in
 	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+	if in.Runtimes != nil {
+		in, out := &in.Runtimes, &out.Runtimes
+		*out = make([]RouterRuntimeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VLLMRouterStatus.
@@ -685,14 +1296,35 @@ func (in *VLLMRuntimeList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VLLMRuntimeReference) DeepCopyInto(out *VLLMRuntimeReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VLLMRuntimeReference.
+func (in *VLLMRuntimeReference) DeepCopy() *VLLMRuntimeReference {
+	if in == nil {
+		return nil
+	}
+	out := new(VLLMRuntimeReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VLLMRuntimeSpec) DeepCopyInto(out *VLLMRuntimeSpec) {
 	*out = *in
-	out.Model = in.Model
+	in.Model.DeepCopyInto(&out.Model)
 	in.VLLMConfig.DeepCopyInto(&out.VLLMConfig)
-	out.LMCacheConfig = in.LMCacheConfig
+	in.LMCacheConfig.DeepCopyInto(&out.LMCacheConfig)
 	out.StorageConfig = in.StorageConfig
 	in.DeploymentConfig.DeepCopyInto(&out.DeploymentConfig)
+	in.Scheduling.DeepCopyInto(&out.Scheduling)
+	in.Probes.DeepCopyInto(&out.Probes)
+	out.Autoscaling = in.Autoscaling
+	out.ScaleToZero = in.ScaleToZero
+	out.MultiNode = in.MultiNode
+	out.Topology = in.Topology
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VLLMRuntimeSpec.
@@ -709,6 +1341,37 @@ func (in *VLLMRuntimeSpec) DeepCopy() *VLLMRuntimeSpec {
 func (in *VLLMRuntimeStatus) DeepCopyInto(out *VLLMRuntimeStatus) {
 	*out = *in
 	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(AutoscalingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastRequestTime != nil {
+		in, out := &in.LastRequestTime, &out.LastRequestTime
+		*out = (*in).DeepCopy()
+	}
+	if in.MultiNode != nil {
+		in, out := &in.MultiNode, &out.MultiNode
+		*out = new(MultiNodeStatus)
+		**out = **in
+	}
+	if in.Topology != nil {
+		in, out := &in.Topology, &out.Topology
+		*out = new(TopologyStatus)
+		**out = **in
+	}
+	if in.ModelDownload != nil {
+		in, out := &in.ModelDownload, &out.ModelDownload
+		*out = new(ModelDownloadStatus)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VLLMRuntimeStatus.
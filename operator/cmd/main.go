@@ -39,6 +39,7 @@ import (
 
 	productionstackv1alpha1 "production-stack/api/v1alpha1"
 	"production-stack/internal/controller"
+	webhookv1alpha1 "production-stack/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -231,6 +232,25 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "LoraAdapter")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupVLLMRouterWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VLLMRouter")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupVLLMRuntimeWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "VLLMRuntime")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupCacheServerWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CacheServer")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupLoraAdapterWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "LoraAdapter")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
@@ -0,0 +1,393 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// autoscalingMetricKVCacheUsage sizes the replicas by the fraction of the KV cache in use
+	autoscalingMetricKVCacheUsage = "kvCacheUsage"
+	// autoscalingMetricTokensPerSecond sizes the replicas by the rate of generated tokens
+	autoscalingMetricTokensPerSecond = "tokensPerSecond"
+
+	// autoscalingTolerance is the relative deviation from the target that does not trigger scaling
+	autoscalingTolerance = 0.1
+
+	// minTokenSampleInterval is the shortest interval a token rate is computed over, so reconciles
+	// triggered close together by pod events do not produce noisy rates
+	minTokenSampleInterval = 5 * time.Second
+
+	// metricsScrapeTimeout bounds the time spent scraping the metrics of a single pod
+	metricsScrapeTimeout = 5 * time.Second
+)
+
+// metricsClient scrapes pod metrics unless the reconciler is given its own client, sharing
+// connections across reconciles
+var metricsClient = &http.Client{Timeout: metricsScrapeTimeout}
+
+// engineMetrics is the load related state scraped from the vLLM metrics endpoint of a pod
+type engineMetrics struct {
+	// runningRequests is the number of requests being processed
+	runningRequests float64
+	// waitingRequests is the number of requests waiting to be scheduled
+	waitingRequests float64
+	// finishedRequests is the total number of finished requests, a counter
+	finishedRequests float64
+	// kvCacheUsage is the fraction of the KV cache in use, between 0 and 1
+	kvCacheUsage float64
+	// generationTokens is the total number of generated tokens, a counter
+	generationTokens float64
+}
+
+// parseEngineMetrics extracts the request counts, KV cache usage and generated tokens from vLLM
+// metrics in the Prometheus text format
+func parseEngineMetrics(in io.Reader) (*engineMetrics, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	metrics := &engineMetrics{}
+	if family, ok := families["vllm:num_requests_running"]; ok {
+		for _, metric := range family.GetMetric() {
+			metrics.runningRequests += metric.GetGauge().GetValue()
+		}
+	}
+	if family, ok := families["vllm:num_requests_waiting"]; ok {
+		for _, metric := range family.GetMetric() {
+			metrics.waitingRequests += metric.GetGauge().GetValue()
+		}
+	}
+	if family, ok := families["vllm:request_success_total"]; ok {
+		for _, metric := range family.GetMetric() {
+			metrics.finishedRequests += metric.GetCounter().GetValue()
+		}
+	}
+
+	// Older vLLM versions report the KV cache usage as gpu_cache_usage_perc
+	for _, name := range []string{"vllm:kv_cache_usage_perc", "vllm:gpu_cache_usage_perc"} {
+		family, ok := families[name]
+		if !ok {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			metrics.kvCacheUsage = math.Max(metrics.kvCacheUsage, metric.GetGauge().GetValue())
+		}
+		break
+	}
+
+	if family, ok := families["vllm:generation_tokens_total"]; ok {
+		for _, metric := range family.GetMetric() {
+			metrics.generationTokens += metric.GetCounter().GetValue()
+		}
+	}
+
+	return metrics, nil
+}
+
+// scaleRecommendation is a replica count the autoscaler computed at a point in time
+type scaleRecommendation struct {
+	replicas  int32
+	timestamp time.Time
+}
+
+// tokenSample is the generated token counter of a pod at a point in time, with the rate computed
+// from the sample before it
+type tokenSample struct {
+	tokens    float64
+	timestamp time.Time
+	rate      float64
+	hasRate   bool
+}
+
+// autoscaler keeps the state the scaling loop needs between reconciles: the recommendations within
+// the stabilization windows and the token counters of every pod. The state is rebuilt after an
+// operator restart, during which the stabilization windows start empty. The zero value is ready
+// for use.
+type autoscaler struct {
+	mu              sync.Mutex
+	recommendations map[types.NamespacedName][]scaleRecommendation
+	tokenSamples    map[types.NamespacedName]map[types.UID]tokenSample
+}
+
+// recommend records a recommendation for a runtime and returns the stabilized replica count.
+// Scaling up follows the lowest recommendation within the scale up window, and scaling down the
+// highest recommendation within the scale down window, so a brief spike or lull does not resize
+// the Deployment. The windows of a runtime seen for the first time start at its current count.
+func (a *autoscaler) recommend(key types.NamespacedName, current, recommended int32, upWindow, downWindow time.Duration, now time.Time) int32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.recommendations == nil {
+		a.recommendations = make(map[types.NamespacedName][]scaleRecommendation)
+	}
+
+	history, ok := a.recommendations[key]
+	if !ok {
+		history = []scaleRecommendation{{replicas: current, timestamp: now}}
+	}
+	upRecommendation, downRecommendation := recommended, recommended
+	kept := history[:0]
+	for _, rec := range history {
+		age := now.Sub(rec.timestamp)
+		if age >= max(upWindow, downWindow) {
+			continue
+		}
+		kept = append(kept, rec)
+		if age < upWindow {
+			upRecommendation = min(upRecommendation, rec.replicas)
+		}
+		if age < downWindow {
+			downRecommendation = max(downRecommendation, rec.replicas)
+		}
+	}
+	a.recommendations[key] = append(kept, scaleRecommendation{replicas: recommended, timestamp: now})
+
+	stabilized := current
+	if stabilized < upRecommendation {
+		stabilized = upRecommendation
+	}
+	if stabilized > downRecommendation {
+		stabilized = downRecommendation
+	}
+	return stabilized
+}
+
+// tokenRate returns the generated tokens per second of a pod from its counter and the sample
+// recorded at an earlier scrape, reporting false until two samples far enough apart were seen
+func (a *autoscaler) tokenRate(key types.NamespacedName, uid types.UID, tokens float64, now time.Time) (float64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.tokenSamples == nil {
+		a.tokenSamples = make(map[types.NamespacedName]map[types.UID]tokenSample)
+	}
+	if a.tokenSamples[key] == nil {
+		a.tokenSamples[key] = make(map[types.UID]tokenSample)
+	}
+
+	previous, ok := a.tokenSamples[key][uid]
+	if !ok {
+		a.tokenSamples[key][uid] = tokenSample{tokens: tokens, timestamp: now}
+		return 0, false
+	}
+	elapsed := now.Sub(previous.timestamp)
+	if elapsed < minTokenSampleInterval {
+		return previous.rate, previous.hasRate
+	}
+
+	// A counter that went down belongs to a restarted engine, start over from the new value
+	sample := tokenSample{tokens: tokens, timestamp: now}
+	if tokens >= previous.tokens {
+		sample.rate = (tokens - previous.tokens) / elapsed.Seconds()
+		sample.hasRate = true
+	}
+	a.tokenSamples[key][uid] = sample
+	return sample.rate, sample.hasRate
+}
+
+// forgetPods drops the token samples of pods that are no longer part of a runtime
+func (a *autoscaler) forgetPods(key types.NamespacedName, pods []corev1.Pod) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	current := make(map[types.UID]bool, len(pods))
+	for _, pod := range pods {
+		current[pod.UID] = true
+	}
+	for uid := range a.tokenSamples[key] {
+		if !current[uid] {
+			delete(a.tokenSamples[key], uid)
+		}
+	}
+}
+
+// forget drops all state kept for a runtime
+func (a *autoscaler) forget(key types.NamespacedName) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.recommendations, key)
+	delete(a.tokenSamples, key)
+}
+
+// parseAutoscalingTarget parses the target average value of the autoscaling metric
+func parseAutoscalingTarget(autoscaling productionstackv1alpha1.AutoscalingConfig) (float64, error) {
+	target, err := strconv.ParseFloat(autoscaling.Target, 64)
+	if err != nil || target <= 0 {
+		return 0, fmt.Errorf("invalid spec.autoscaling.target %q: must be a positive number", autoscaling.Target)
+	}
+	return target, nil
+}
+
+// desiredReplicas returns the replica count for a runtime: zero while it is scaled to zero, the
+// autoscaler's decision when autoscaling is enabled, bounded by the minimum and maximum, otherwise
+// the configured replicas
+func desiredReplicas(vllmRuntime *productionstackv1alpha1.VLLMRuntime) int32 {
+	if isScaledToZero(vllmRuntime) {
+		return 0
+	}
+	replicas := vllmRuntime.Spec.DeploymentConfig.Replicas
+	autoscaling := vllmRuntime.Spec.Autoscaling
+	if !autoscaling.Enabled {
+		return replicas
+	}
+	if status := vllmRuntime.Status.Autoscaling; status != nil && status.DesiredReplicas > 0 {
+		replicas = status.DesiredReplicas
+	}
+	return boundReplicas(replicas, autoscaling)
+}
+
+// boundReplicas limits a replica count to the minimum and maximum of the autoscaling config
+func boundReplicas(replicas int32, autoscaling productionstackv1alpha1.AutoscalingConfig) int32 {
+	minReplicas := max(autoscaling.MinReplicas, 1)
+	if autoscaling.MaxReplicas > 0 {
+		replicas = min(replicas, autoscaling.MaxReplicas)
+	}
+	return max(replicas, minReplicas)
+}
+
+// recommendReplicas returns the replica count that brings the average metric value per replica to
+// the target. Deviations within the tolerance keep the current count.
+func recommendReplicas(current int32, total float64, reporting int, target float64) int32 {
+	if reporting == 0 || target <= 0 {
+		return current
+	}
+	average := total / float64(reporting)
+	if math.Abs(average/target-1) <= autoscalingTolerance {
+		return current
+	}
+	return int32(math.Ceil(total / target))
+}
+
+// autoscale scrapes the engine metrics of the ready pods of a runtime and returns the replica
+// count the Deployment should have, along with the average metric value per replica. A runtime
+// without pods reporting metrics keeps its current replica count.
+func (r *VLLMRuntimeReconciler) autoscale(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime, target float64) (int32, string, error) {
+	autoscaling := vllmRuntime.Spec.Autoscaling
+	key := types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}
+	current := desiredReplicas(vllmRuntime)
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(vllmRuntime.Namespace),
+		client.MatchingLabels(servingLabelsForVLLMRuntime(vllmRuntime))); err != nil {
+		return current, "", fmt.Errorf("failed to list pods: %w", err)
+	}
+	r.scaler.forgetPods(key, pods.Items)
+
+	now := time.Now()
+	total := 0.0
+	reporting := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isPodReady(pod) {
+			continue
+		}
+		metrics, err := r.scrapeEngineMetrics(ctx, pod, vllmRuntime.Spec.VLLMConfig.Port)
+		if err != nil {
+			// A pod that cannot be scraped is left out of the average, like a pod that is not ready
+			continue
+		}
+		switch autoscaling.Metric {
+		case autoscalingMetricKVCacheUsage:
+			total += metrics.kvCacheUsage
+		case autoscalingMetricTokensPerSecond:
+			rate, ok := r.scaler.tokenRate(key, pod.UID, metrics.generationTokens, now)
+			if !ok {
+				continue
+			}
+			total += rate
+		default:
+			// queueLength, the default metric
+			total += metrics.waitingRequests
+		}
+		reporting++
+	}
+	if reporting == 0 {
+		return current, "", nil
+	}
+
+	recommended := boundReplicas(recommendReplicas(current, total, reporting, target), autoscaling)
+	replicas := r.scaler.recommend(key, current, recommended,
+		time.Duration(autoscaling.ScaleUpStabilizationSeconds)*time.Second,
+		time.Duration(autoscaling.ScaleDownStabilizationSeconds)*time.Second, now)
+	return replicas, strconv.FormatFloat(total/float64(reporting), 'f', 2, 64), nil
+}
+
+// scrapeEngineMetrics scrapes the vLLM metrics endpoint of a pod
+func (r *VLLMRuntimeReconciler) scrapeEngineMetrics(ctx context.Context, pod *corev1.Pod, port int32) (*engineMetrics, error) {
+	var metrics *engineMetrics
+	err := r.scrapePodMetrics(ctx, pod, port, func(in io.Reader) (err error) {
+		metrics, err = parseEngineMetrics(in)
+		return err
+	})
+	return metrics, err
+}
+
+// httpClient returns the client pod metrics are scraped with
+func (r *VLLMRuntimeReconciler) httpClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+	return metricsClient
+}
+
+// scrapePodMetrics gets the metrics endpoint of a pod and hands the response body to parse
+func (r *VLLMRuntimeReconciler) scrapePodMetrics(ctx context.Context, pod *corev1.Pod, port int32, parse func(io.Reader) error) error {
+	endpoint, err := podEndpoint(pod, "/metrics", int(port))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get metrics: status %d", resp.StatusCode)
+	}
+
+	return parse(resp.Body)
+}
+
+// isPodReady reports whether the Ready condition of a pod is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
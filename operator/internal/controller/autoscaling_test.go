@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRuntime autoscaling", func() {
+	const metricsTemplate = `# HELP vllm:num_requests_waiting Number of requests waiting to be processed.
+# TYPE vllm:num_requests_waiting gauge
+vllm:num_requests_waiting{engine="0",model_name="llama"} %s
+# HELP vllm:kv_cache_usage_perc KV-cache usage. 1 means 100 percent usage.
+# TYPE vllm:kv_cache_usage_perc gauge
+vllm:kv_cache_usage_perc{engine="0",model_name="llama"} %s
+# HELP vllm:generation_tokens_total Number of generation tokens processed.
+# TYPE vllm:generation_tokens_total counter
+vllm:generation_tokens_total{engine="0",model_name="llama"} %s
+`
+	key := types.NamespacedName{Name: "llama", Namespace: "default"}
+
+	It("Should parse the queue length, KV cache usage and generated tokens", func() {
+		metrics, err := parseEngineMetrics(strings.NewReader(fmt.Sprintf(metricsTemplate, "7", "0.5", "1200")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.waitingRequests).To(Equal(7.0))
+		Expect(metrics.kvCacheUsage).To(Equal(0.5))
+		Expect(metrics.generationTokens).To(Equal(1200.0))
+	})
+
+	It("Should size the replicas so the average meets the target", func() {
+		// 12 waiting requests over 2 pods with a target of 4 per replica
+		Expect(recommendReplicas(2, 12, 2, 4)).To(Equal(int32(3)))
+		// Within the tolerance the replicas stay
+		Expect(recommendReplicas(2, 8.4, 2, 4)).To(Equal(int32(2)))
+		Expect(recommendReplicas(4, 2, 4, 4)).To(Equal(int32(1)))
+		// Without metrics the replicas stay
+		Expect(recommendReplicas(3, 0, 0, 4)).To(Equal(int32(3)))
+	})
+
+	It("Should bound the replicas by the autoscaling config", func() {
+		autoscaling := productionstackv1alpha1.AutoscalingConfig{Enabled: true, MinReplicas: 2, MaxReplicas: 4}
+		Expect(boundReplicas(1, autoscaling)).To(Equal(int32(2)))
+		Expect(boundReplicas(9, autoscaling)).To(Equal(int32(4)))
+
+		vr := &productionstackv1alpha1.VLLMRuntime{}
+		vr.Spec.DeploymentConfig.Replicas = 1
+		Expect(desiredReplicas(vr)).To(Equal(int32(1)))
+		vr.Spec.Autoscaling = autoscaling
+		Expect(desiredReplicas(vr)).To(Equal(int32(2)))
+		vr.Status.Autoscaling = &productionstackv1alpha1.AutoscalingStatus{DesiredReplicas: 3}
+		Expect(desiredReplicas(vr)).To(Equal(int32(3)))
+	})
+
+	It("Should scale up at once and hold scale down for the stabilization window", func() {
+		var a autoscaler
+		start := time.Now()
+		up, down := time.Duration(0), 5*time.Minute
+
+		Expect(a.recommend(key, 2, 4, up, down, start)).To(Equal(int32(4)))
+		// A lull right after the spike keeps the replicas
+		Expect(a.recommend(key, 4, 1, up, down, start.Add(time.Minute))).To(Equal(int32(4)))
+		Expect(a.recommend(key, 4, 2, up, down, start.Add(4*time.Minute))).To(Equal(int32(4)))
+		// Once the spike left the window the replicas follow the highest recent recommendation
+		Expect(a.recommend(key, 4, 1, up, down, start.Add(5*time.Minute+time.Second))).To(Equal(int32(2)))
+		Expect(a.recommend(key, 2, 1, up, down, start.Add(10*time.Minute))).To(Equal(int32(1)))
+	})
+
+	It("Should hold scale up for the stabilization window", func() {
+		var a autoscaler
+		start := time.Now()
+		up, down := time.Minute, time.Duration(0)
+
+		Expect(a.recommend(key, 2, 4, up, down, start)).To(Equal(int32(2)))
+		Expect(a.recommend(key, 2, 4, up, down, start.Add(30*time.Second))).To(Equal(int32(2)))
+		Expect(a.recommend(key, 2, 4, up, down, start.Add(61*time.Second))).To(Equal(int32(4)))
+	})
+
+	It("Should compute token rates from consecutive samples", func() {
+		var a autoscaler
+		start := time.Now()
+		_, ok := a.tokenRate(key, "pod-a", 1000, start)
+		Expect(ok).To(BeFalse())
+
+		rate, ok := a.tokenRate(key, "pod-a", 3000, start.Add(10*time.Second))
+		Expect(ok).To(BeTrue())
+		Expect(rate).To(Equal(200.0))
+
+		// A restarted engine resets its counter
+		_, ok = a.tokenRate(key, "pod-a", 10, start.Add(20*time.Second))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should scrape the ready pods of the runtime", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.URL.Path).To(Equal("/metrics"))
+			_, err := fmt.Fprintf(w, metricsTemplate, "9", "0.5", "0")
+			Expect(err).NotTo(HaveOccurred())
+		}))
+		defer server.Close()
+		addr := server.Listener.Addr().(*net.TCPAddr)
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama-0", Namespace: "default"},
+			Status: corev1.PodStatus{
+				PodIP:      addr.IP.String(),
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+		r := &VLLMRuntimeReconciler{}
+		metrics, err := r.scrapeEngineMetrics(context.Background(), pod, int32(addr.Port))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.waitingRequests).To(Equal(9.0))
+		Expect(isPodReady(pod)).To(BeTrue())
+	})
+
+	It("Should scrape pods through the client of the reconciler", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = fmt.Fprintf(w, metricsTemplate, "3", "0.25", "0")
+		}))
+		defer server.Close()
+
+		// The client sends every request to the server, whatever the pod address
+		var scraped []string
+		transport := server.Client().Transport
+		httpClient := &http.Client{Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			scraped = append(scraped, req.URL.String())
+			req = req.Clone(req.Context())
+			req.URL.Host = server.Listener.Addr().String()
+			return transport.RoundTrip(req)
+		})}
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama-1", Namespace: "default"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.7"},
+		}
+		r := &VLLMRuntimeReconciler{HTTPClient: httpClient}
+		metrics, err := r.scrapeEngineMetrics(context.Background(), pod, 8000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.waitingRequests).To(Equal(3.0))
+		Expect(scraped).To(Equal([]string{"http://10.0.0.7:8000/metrics"}))
+	})
+})
+
+// roundTripperFunc adapts a function to an http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -19,12 +19,11 @@ package controller
 import (
 	"context"
 	"fmt"
-	"reflect"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -47,6 +46,7 @@ type CacheServerReconciler struct {
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=cacheservers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=cacheservers/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -88,12 +88,50 @@ func (r *CacheServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Update the service if its desired state changed
+	if svc := r.serviceForCacheServer(cacheServer); specHashChanged(foundService, svc) {
+		log.Info("Updating Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+		copyDesiredMetadata(foundService, svc)
+		// Keep the cluster IPs allocated to the live Service, they cannot be changed
+		svc.Spec.ClusterIP = foundService.Spec.ClusterIP
+		svc.Spec.ClusterIPs = foundService.Spec.ClusterIPs
+		foundService.Spec = svc.Spec
+		err = r.Update(ctx, foundService)
+		if err != nil {
+			log.Error(err, "Failed to update Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+			return ctrl.Result{}, err
+		}
+		// Service updated successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Serve every shard by a pod of a StatefulSet in StatefulSet mode
+	if cacheServer.Spec.Mode == cacheServerModeStatefulSet {
+		return r.reconcileStatefulSet(ctx, cacheServer)
+	}
+
+	// Remove the StatefulSet and headless Service left from StatefulSet mode
+	if err := r.deleteStatefulSetObjects(ctx, cacheServer); err != nil {
+		log.Error(err, "Failed to delete StatefulSet objects")
+		return ctrl.Result{}, err
+	}
+
+	// Build the desired deployment, reporting values that cannot be parsed on the CacheServer
+	dep, err := r.deploymentForCacheServer(cacheServer)
+	if err != nil {
+		log.Error(err, "Invalid CacheServer spec")
+		if err := r.reportInvalidSpec(ctx, cacheServer, err); err != nil {
+			log.Error(err, "Failed to update CacheServer status")
+			return ctrl.Result{}, err
+		}
+		// Wait for the spec to be fixed, which triggers a new reconcile
+		return ctrl.Result{}, nil
+	}
+
 	// Check if the deployment already exists, if not create a new one
 	found := &appsv1.Deployment{}
 	err = r.Get(ctx, types.NamespacedName{Name: cacheServer.Name, Namespace: cacheServer.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
-		// Define a new deployment
-		dep := r.deploymentForCacheServer(cacheServer)
 		log.Info("Creating a new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
 		err = r.Create(ctx, dep)
 		if err != nil {
@@ -107,13 +145,13 @@ func (r *CacheServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// Update the deployment if needed
-	if r.deploymentNeedsUpdate(found, cacheServer) {
+	// Update the deployment if its desired state changed
+	if specHashChanged(found, dep) {
 		log.Info("Updating Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
-		// Create new deployment spec
-		newDep := r.deploymentForCacheServer(cacheServer)
-
-		err = r.Update(ctx, newDep)
+		copyDesiredMetadata(found, dep)
+		dep.Spec.Selector = keepSelector(found.Spec.Selector, &dep.Spec.Template)
+		found.Spec = dep.Spec
+		err = r.Update(ctx, found)
 		if err != nil {
 			log.Error(err, "Failed to update Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
 			return ctrl.Result{}, err
@@ -131,26 +169,47 @@ func (r *CacheServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	return ctrl.Result{}, nil
 }
 
-// deploymentForCacheServer returns a CacheServer Deployment object
-func (r *CacheServerReconciler) deploymentForCacheServer(cacheServer *productionstackv1alpha1.CacheServer) *appsv1.Deployment {
-	labels := map[string]string{
-		"app": cacheServer.Name,
+// deploymentForCacheServer returns a CacheServer Deployment object, or an error when the spec
+// holds values that cannot be parsed
+func (r *CacheServerReconciler) deploymentForCacheServer(cacheServer *productionstackv1alpha1.CacheServer) (*appsv1.Deployment, error) {
+	template, err := podTemplateForCacheServer(cacheServer)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build resource requirements
-	resources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{},
-		Limits:   corev1.ResourceList{},
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cacheServer.Name,
+			Namespace: cacheServer.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &cacheServer.Spec.Replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: template.Labels,
+			},
+			Template: template,
+		},
 	}
 
-	if cacheServer.Spec.Resources.CPU != "" {
-		resources.Requests[corev1.ResourceCPU] = resource.MustParse(cacheServer.Spec.Resources.CPU)
-		resources.Limits[corev1.ResourceCPU] = resource.MustParse(cacheServer.Spec.Resources.CPU)
+	setSpecHash(dep, dep.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(cacheServer, dep, r.Scheme)
+	return dep, nil
+}
+
+// podTemplateForCacheServer returns the template of the cache server pods, or an error when the
+// spec holds values that cannot be parsed. A replica with a volume of its own keeps its KV cache
+// on it.
+func podTemplateForCacheServer(cacheServer *productionstackv1alpha1.CacheServer) (corev1.PodTemplateSpec, error) {
+	labels := map[string]string{
+		"app": cacheServer.Name,
 	}
 
-	if cacheServer.Spec.Resources.Memory != "" {
-		resources.Requests[corev1.ResourceMemory] = resource.MustParse(cacheServer.Spec.Resources.Memory)
-		resources.Limits[corev1.ResourceMemory] = resource.MustParse(cacheServer.Spec.Resources.Memory)
+	// Build resource requirements
+	resources, err := buildResourceRequirements("spec.resources", cacheServer.Spec.Resources)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
 	}
 
 	// Get the image from Image spec
@@ -162,76 +221,103 @@ func (r *CacheServerReconciler) deploymentForCacheServer(cacheServer *production
 		imagePullPolicy = corev1.PullPolicy(cacheServer.Spec.Image.PullPolicy)
 	}
 
-	dep := &appsv1.Deployment{
+	// The cache server speaks a TCP protocol, so it is probed by connecting to its port
+	readinessProbe, livenessProbe, startupProbe := cacheServerProbes(cacheServer)
+
+	command := []string{
+		"lmcache_experimental_server",
+		"0.0.0.0",
+		fmt.Sprintf("%d", cacheServer.Spec.Port),
+	}
+	var volumeMounts []corev1.VolumeMount
+	if isPersistent(cacheServer) {
+		mountPath := cacheMountPath(cacheServer)
+		command = append(command, mountPath)
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: cacheVolumeName, MountPath: mountPath})
+	}
+
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      cacheServer.Name,
-			Namespace: cacheServer.Namespace,
+			Labels: labels,
 		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &cacheServer.Spec.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
-				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:            "cache-server",
+					Image:           image,
+					ImagePullPolicy: imagePullPolicy,
+					Command:         command,
+					Ports: []corev1.ContainerPort{
 						{
-							Name:            "cache-server",
-							Image:           image,
-							ImagePullPolicy: imagePullPolicy,
-							Command: []string{
-								"lmcache_experimental_server",
-								"0.0.0.0",
-								fmt.Sprintf("%d", cacheServer.Spec.Port)},
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "http",
-									ContainerPort: cacheServer.Spec.Port,
-								},
-							},
-							Resources: resources,
+							Name:          "http",
+							ContainerPort: cacheServer.Spec.Port,
 						},
 					},
+					Resources:      resources,
+					VolumeMounts:   volumeMounts,
+					ReadinessProbe: readinessProbe,
+					LivenessProbe:  livenessProbe,
+					StartupProbe:   startupProbe,
 				},
 			},
 		},
 	}
-
-	// Set the owner reference
-	ctrl.SetControllerReference(cacheServer, dep, r.Scheme)
-	return dep
+	applyScheduling(&template.Spec, cacheServer.Spec.Scheduling)
+	return template, nil
 }
 
-// deploymentNeedsUpdate checks if the deployment needs to be updated
-func (r *CacheServerReconciler) deploymentNeedsUpdate(dep *appsv1.Deployment, cs *productionstackv1alpha1.CacheServer) bool {
-	// Compare replicas
-	if *dep.Spec.Replicas != cs.Spec.Replicas {
-		return true
+// cacheServerProbes returns the readiness, liveness and startup probes of the cache server
+// container, the defaults with the overrides of the probe config. A startup probe is only added
+// once configured.
+func cacheServerProbes(cacheServer *productionstackv1alpha1.CacheServer) (*corev1.Probe, *corev1.Probe, *corev1.Probe) {
+	port := cacheServer.Spec.Port
+	connect := corev1.ProbeHandler{
+		TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(port)},
 	}
-	// Generate the expected deployment
-	expectedDep := r.deploymentForCacheServer(cs)
 
-	// Compare image
-	if len(dep.Spec.Template.Spec.Containers) > 0 &&
-		expectedDep.Spec.Template.Spec.Containers[0].Image != dep.Spec.Template.Spec.Containers[0].Image {
-		return true
+	readiness := corev1.Probe{
+		ProbeHandler:        connect,
+		InitialDelaySeconds: 5,
+		PeriodSeconds:       10,
+		TimeoutSeconds:      3,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
 	}
-
-	// Compare resources
-	expectedResources := expectedDep.Spec.Template.Spec.Containers[0].Resources
-	actualResources := dep.Spec.Template.Spec.Containers[0].Resources
-	if !reflect.DeepEqual(expectedResources, actualResources) {
-		return true
+	liveness := corev1.Probe{
+		ProbeHandler:        connect,
+		InitialDelaySeconds: 15,
+		PeriodSeconds:       20,
+		TimeoutSeconds:      3,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
 	}
 
-	return false
+	probes := cacheServer.Spec.Probes
+	return buildProbe(probes.Readiness, readiness, true, port),
+		buildProbe(probes.Liveness, liveness, true, port),
+		buildProbe(probes.Startup, readiness, false, port)
 }
 
-// updateStatus updates the status of the CacheServer
-func (r *CacheServerReconciler) updateStatus(ctx context.Context, cs *productionstackv1alpha1.CacheServer, dep *appsv1.Deployment) error {
+// updateStatus updates the status of the CacheServer from the Deployment or StatefulSet serving it
+func (r *CacheServerReconciler) updateStatus(ctx context.Context, cs *productionstackv1alpha1.CacheServer, workload client.Object) error {
+	var desired, unavailable int32
+	var replicas, ready, available, updated int32
+	var conditions []metav1.Condition
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		desired = *w.Spec.Replicas
+		replicas, ready, available, updated = w.Status.Replicas, w.Status.ReadyReplicas, w.Status.AvailableReplicas, w.Status.UpdatedReplicas
+		unavailable = w.Status.UnavailableReplicas
+		conditions = deploymentConditions(cs.Generation, w)
+	case *appsv1.StatefulSet:
+		desired = *w.Spec.Replicas
+		replicas, ready, available, updated = w.Status.Replicas, w.Status.ReadyReplicas, w.Status.AvailableReplicas, w.Status.UpdatedReplicas
+		unavailable = max(desired-available, 0)
+		conditions = statefulSetConditions(cs.Generation, w)
+	default:
+		return fmt.Errorf("unexpected cache server workload %T", workload)
+	}
+
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		// Get the latest version of the CacheServer
 		latestCS := &productionstackv1alpha1.CacheServer{}
@@ -241,13 +327,29 @@ func (r *CacheServerReconciler) updateStatus(ctx context.Context, cs *production
 
 		// Update the status fields
 		latestCS.Status.LastUpdated = metav1.Now()
+		latestCS.Status.ObservedGeneration = latestCS.Generation
+		latestCS.Status.Replicas = replicas
+		latestCS.Status.ReadyReplicas = ready
+		latestCS.Status.AvailableReplicas = available
+		latestCS.Status.UpdatedReplicas = updated
+		latestCS.Status.RemoteURL = ""
+		latestCS.Status.ShardURLs = nil
+		if latestCS.Spec.Mode == cacheServerModeStatefulSet {
+			latestCS.Status.ShardURLs = cacheServerShardURLs(latestCS)
+		} else {
+			latestCS.Status.RemoteURL = cacheServerURL(latestCS.Namespace, latestCS.Name)
+		}
+		for _, condition := range conditions {
+			condition.ObservedGeneration = latestCS.Generation
+			meta.SetStatusCondition(&latestCS.Status.Conditions, condition)
+		}
 
-		// Update status based on deployment status
-		if dep.Status.AvailableReplicas == *dep.Spec.Replicas && dep.Status.UnavailableReplicas == 0 {
+		// Update status based on workload status
+		if available == desired && unavailable == 0 {
 			latestCS.Status.Status = "Ready"
-		} else if dep.Status.UpdatedReplicas > 0 && dep.Status.AvailableReplicas != *dep.Spec.Replicas && dep.Status.UnavailableReplicas > 0 {
+		} else if updated > 0 && available != desired && unavailable > 0 {
 			latestCS.Status.Status = "Updating"
-		} else if dep.Status.UnavailableReplicas > 0 {
+		} else if unavailable > 0 {
 			latestCS.Status.Status = "NotReady"
 		} else {
 			latestCS.Status.Status = "Unknown"
@@ -257,6 +359,23 @@ func (r *CacheServerReconciler) updateStatus(ctx context.Context, cs *production
 	})
 }
 
+// reportInvalidSpec records a spec that cannot be turned into a Deployment as a Degraded condition
+func (r *CacheServerReconciler) reportInvalidSpec(ctx context.Context, cs *productionstackv1alpha1.CacheServer, specErr error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestCS := &productionstackv1alpha1.CacheServer{}
+		if err := r.Get(ctx, types.NamespacedName{Name: cs.Name, Namespace: cs.Namespace}, latestCS); err != nil {
+			return err
+		}
+
+		latestCS.Status.LastUpdated = metav1.Now()
+		latestCS.Status.Status = statusInvalidSpec
+		latestCS.Status.ObservedGeneration = latestCS.Generation
+		meta.SetStatusCondition(&latestCS.Status.Conditions, degradedCondition(latestCS.Generation, specErr))
+
+		return r.Status().Update(ctx, latestCS)
+	})
+}
+
 // serviceForCacheServer returns a CacheServer Service object
 func (r *CacheServerReconciler) serviceForCacheServer(cacheServer *productionstackv1alpha1.CacheServer) *corev1.Service {
 	labels := map[string]string{
@@ -274,7 +393,7 @@ func (r *CacheServerReconciler) serviceForCacheServer(cacheServer *productionsta
 			Ports: []corev1.ServicePort{
 				{
 					Name:       "http",
-					Port:       80,
+					Port:       cacheServerServicePort,
 					TargetPort: intstr.FromInt32(cacheServer.Spec.Port),
 					Protocol:   corev1.ProtocolTCP,
 				},
@@ -282,6 +401,8 @@ func (r *CacheServerReconciler) serviceForCacheServer(cacheServer *productionsta
 		},
 	}
 
+	setSpecHash(svc, svc.Spec)
+
 	// Set the owner reference
 	ctrl.SetControllerReference(cacheServer, svc, r.Scheme)
 	return svc
@@ -292,6 +413,7 @@ func (r *CacheServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&productionstackv1alpha1.CacheServer{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
 		Owns(&corev1.Service{}).
 		Complete(r)
 }
@@ -18,11 +18,14 @@ package controller
 
 import (
 	"context"
+	"fmt"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -82,3 +85,134 @@ var _ = Describe("CacheServer Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("CacheServer builders", func() {
+	It("returns an error instead of panicking on quantities that cannot be parsed", func() {
+		r := &CacheServerReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.CacheServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "default"},
+			Spec: productionstackv1alpha1.CacheServerSpec{
+				Port:      8000,
+				Resources: productionstackv1alpha1.ResourceRequirements{CPU: "1", Memory: "two gigs"},
+			},
+		}
+		_, err := r.deploymentForCacheServer(obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.resources.memory")))
+
+		obj.Spec.Resources.Memory = "2Gi"
+		dep, err := r.deploymentForCacheServer(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("2Gi"))
+	})
+
+	It("probes the port of the cache server by default", func() {
+		r := &CacheServerReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.CacheServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "default"},
+			Spec:       productionstackv1alpha1.CacheServerSpec{Port: 8000},
+		}
+		dep, err := r.deploymentForCacheServer(obj)
+		Expect(err).NotTo(HaveOccurred())
+		container := dep.Spec.Template.Spec.Containers[0]
+		Expect(container.ReadinessProbe.TCPSocket.Port.IntValue()).To(Equal(8000))
+		Expect(container.LivenessProbe.TCPSocket.Port.IntValue()).To(Equal(8000))
+		Expect(container.StartupProbe).To(BeNil())
+
+		obj.Spec.Probes.Liveness.Disabled = true
+		dep, err = r.deploymentForCacheServer(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[0].LivenessProbe).To(BeNil())
+	})
+
+	It("schedules the pods by the scheduling constraints of the spec and rolls out changes to them", func() {
+		r := &CacheServerReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.CacheServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "default"},
+			Spec:       productionstackv1alpha1.CacheServerSpec{Port: 8000},
+		}
+		found, err := r.deploymentForCacheServer(obj)
+		Expect(err).NotTo(HaveOccurred())
+
+		obj.Spec.Scheduling = productionstackv1alpha1.SchedulingConfig{
+			NodeSelector:      map[string]string{"pool": "cpu"},
+			PriorityClassName: "cache",
+		}
+		dep, err := r.deploymentForCacheServer(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{"pool": "cpu"}))
+		Expect(dep.Spec.Template.Spec.PriorityClassName).To(Equal("cache"))
+		Expect(specHashChanged(found, dep)).To(BeTrue())
+	})
+
+	It("serves every shard by a StatefulSet pod with a volume of its own in StatefulSet mode", func() {
+		r := &CacheServerReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.CacheServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "serving"},
+			Spec: productionstackv1alpha1.CacheServerSpec{
+				Port:     8000,
+				Replicas: 3,
+				Mode:     cacheServerModeStatefulSet,
+				Persistence: productionstackv1alpha1.CacheServerPersistence{
+					Enabled: true, Size: "100Gi", StorageClassName: "fast", MountPath: "/kv",
+				},
+			},
+		}
+		sts, err := r.statefulSetForCacheServer(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sts.Spec.ServiceName).To(Equal("cache-shards"))
+		Expect(sts.Spec.VolumeClaimTemplates).To(HaveLen(1))
+		claim := sts.Spec.VolumeClaimTemplates[0]
+		Expect(claim.Spec.Resources.Requests.Storage().String()).To(Equal("100Gi"))
+		Expect(*claim.Spec.StorageClassName).To(Equal("fast"))
+
+		container := sts.Spec.Template.Spec.Containers[0]
+		Expect(container.Command).To(Equal([]string{"lmcache_experimental_server", "0.0.0.0", "8000", "/kv"}))
+		Expect(container.VolumeMounts).To(Equal([]corev1.VolumeMount{{Name: cacheVolumeName, MountPath: "/kv"}}))
+
+		svc := r.shardServiceForCacheServer(obj)
+		Expect(svc.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+		Expect(svc.Spec.Ports[0].Port).To(Equal(int32(8000)))
+	})
+
+	It("addresses the shard of the runtime's model in StatefulSet mode", func() {
+		obj := &productionstackv1alpha1.CacheServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "serving"},
+			Spec:       productionstackv1alpha1.CacheServerSpec{Port: 8000, Replicas: 2},
+		}
+		Expect(cacheServerRemoteURL(obj, "meta-llama/Llama-3.1-8B-Instruct")).To(Equal("lm://cache.serving.svc.cluster.local:80"))
+
+		obj.Spec.Mode = cacheServerModeStatefulSet
+		Expect(cacheServerShardURLs(obj)).To(Equal([]string{
+			"lm://cache-0.cache-shards.serving.svc.cluster.local:8000",
+			"lm://cache-1.cache-shards.serving.svc.cluster.local:8000",
+		}))
+		shard := cacheServerShard(2, "meta-llama/Llama-3.1-8B-Instruct")
+		Expect(cacheServerRemoteURL(obj, "meta-llama/Llama-3.1-8B-Instruct")).To(Equal(cacheServerShardURLs(obj)[shard]))
+
+		By("Spreading models over the shards and moving only those of an added shard")
+		models := make([]string, 100)
+		shards := make(map[int32]int)
+		for i := range models {
+			models[i] = fmt.Sprintf("org/model-%d", i)
+			shards[cacheServerShard(4, models[i])]++
+		}
+		Expect(shards).To(HaveLen(4))
+		for _, model := range models {
+			if moved := cacheServerShard(5, model); moved != cacheServerShard(4, model) {
+				Expect(moved).To(Equal(int32(4)))
+			}
+		}
+
+		vr := &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "serving"},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				LMCacheConfig: productionstackv1alpha1.LMCacheConfig{
+					Enabled: true, CacheServerRef: &corev1.LocalObjectReference{Name: "cache"},
+				},
+			},
+		}
+		Expect(lmCacheRemoteURL(vr)).To(Equal("lm://cache.serving.svc.cluster.local:80"))
+		vr.Status.CacheServerURL = cacheServerRemoteURL(obj, vr.Spec.Model.ModelURL)
+		Expect(lmCacheRemoteURL(vr)).To(ContainSubstring(".cache-shards.serving.svc.cluster.local:8000"))
+	})
+})
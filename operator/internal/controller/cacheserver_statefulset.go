@@ -0,0 +1,307 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// cacheServerModeStatefulSet serves every shard of a CacheServer by a pod of a StatefulSet
+	cacheServerModeStatefulSet = "StatefulSet"
+
+	// cacheVolumeName is the name of the volume claim template a persistent replica keeps its KV
+	// cache on
+	cacheVolumeName = "cache"
+	// defaultCacheMountPath is the path the cache volume is mounted at when the spec does not set one
+	defaultCacheMountPath = "/cache"
+	// defaultCacheVolumeSize is the size of the cache volume of every replica when the spec does
+	// not set one
+	defaultCacheVolumeSize = "50Gi"
+)
+
+// shardServiceNameForCacheServer returns the name of the headless Service the shards of a
+// CacheServer are addressed through
+func shardServiceNameForCacheServer(cacheServer *productionstackv1alpha1.CacheServer) string {
+	return cacheServer.Name + "-shards"
+}
+
+// isPersistent reports whether every replica of a CacheServer keeps its KV cache on a volume of
+// its own
+func isPersistent(cacheServer *productionstackv1alpha1.CacheServer) bool {
+	return cacheServer.Spec.Mode == cacheServerModeStatefulSet && cacheServer.Spec.Persistence.Enabled
+}
+
+// cacheMountPath returns the path the cache volume of a CacheServer is mounted at
+func cacheMountPath(cacheServer *productionstackv1alpha1.CacheServer) string {
+	if mountPath := cacheServer.Spec.Persistence.MountPath; mountPath != "" {
+		return mountPath
+	}
+	return defaultCacheMountPath
+}
+
+// cacheServerShardURLs returns the URL of every shard of a CacheServer in StatefulSet mode, in
+// ordinal order. A shard keeps its address across restarts, so the runtimes assigned to it find
+// their KV cache there again.
+func cacheServerShardURLs(cacheServer *productionstackv1alpha1.CacheServer) []string {
+	urls := make([]string, 0, cacheServer.Spec.Replicas)
+	for i := range cacheServer.Spec.Replicas {
+		urls = append(urls, fmt.Sprintf("lm://%s-%d.%s.%s.svc.cluster.local:%d",
+			cacheServer.Name, i, shardServiceNameForCacheServer(cacheServer), cacheServer.Namespace, cacheServer.Spec.Port))
+	}
+	return urls
+}
+
+// cacheServerRemoteURL returns the LMCache remote URL a runtime serving model reaches a CacheServer
+// at. LMCache connects to a single remote URL, so in StatefulSet mode the runtime is given the
+// shard of its model.
+func cacheServerRemoteURL(cacheServer *productionstackv1alpha1.CacheServer, model string) string {
+	if cacheServer.Spec.Mode == cacheServerModeStatefulSet && cacheServer.Spec.Replicas > 0 {
+		return cacheServerShardURLs(cacheServer)[cacheServerShard(cacheServer.Spec.Replicas, model)]
+	}
+	return cacheServerURL(cacheServer.Namespace, cacheServer.Name)
+}
+
+// cacheServerShard returns the ordinal of the shard a model is assigned to by rendezvous hashing.
+// Runtimes serving the same model share a shard and its KV cache, and adding or removing a shard
+// only moves the models whose shard it becomes or was.
+func cacheServerShard(shards int32, model string) int32 {
+	var shard int32
+	var highest uint64
+	for i := range shards {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%d", model, i)
+		if score := h.Sum64(); i == 0 || score > highest {
+			shard, highest = i, score
+		}
+	}
+	return shard
+}
+
+// shardServiceForCacheServer returns the headless Service that gives every shard of a CacheServer
+// a DNS name of its own
+func (r *CacheServerReconciler) shardServiceForCacheServer(cacheServer *productionstackv1alpha1.CacheServer) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      shardServiceNameForCacheServer(cacheServer),
+			Namespace: cacheServer.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  map[string]string{"app": cacheServer.Name},
+			// Keep the address of a restarting shard, runtimes retry it rather than failing to
+			// resolve it
+			PublishNotReadyAddresses: true,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       cacheServer.Spec.Port,
+					TargetPort: intstr.FromInt32(cacheServer.Spec.Port),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+
+	setSpecHash(svc, svc.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(cacheServer, svc, r.Scheme)
+	return svc
+}
+
+// statefulSetForCacheServer returns the StatefulSet serving the shards of a CacheServer, or an
+// error when the spec holds values that cannot be parsed. A persistent replica gets a volume of
+// its own, which is kept when the replica is deleted or scaled away.
+func (r *CacheServerReconciler) statefulSetForCacheServer(cacheServer *productionstackv1alpha1.CacheServer) (*appsv1.StatefulSet, error) {
+	template, err := podTemplateForCacheServer(cacheServer)
+	if err != nil {
+		return nil, err
+	}
+
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cacheServer.Name,
+			Namespace: cacheServer.Namespace,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &cacheServer.Spec.Replicas,
+			ServiceName: shardServiceNameForCacheServer(cacheServer),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: template.Labels,
+			},
+			// The shards are independent, so they start and stop together
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			Template:            template,
+		},
+	}
+
+	if isPersistent(cacheServer) {
+		persistence := cacheServer.Spec.Persistence
+		size := persistence.Size
+		if size == "" {
+			size = defaultCacheVolumeSize
+		}
+		quantity, err := parseQuantity("spec.persistence.size", size)
+		if err != nil {
+			return nil, err
+		}
+		claim := corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: cacheVolumeName},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: quantity},
+				},
+			},
+		}
+		if persistence.StorageClassName != "" {
+			claim.Spec.StorageClassName = &persistence.StorageClassName
+		}
+		sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{claim}
+	}
+
+	setSpecHash(sts, sts.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(cacheServer, sts, r.Scheme)
+	return sts, nil
+}
+
+// reconcileStatefulSet serves a CacheServer by a StatefulSet of shards behind a headless Service,
+// replacing the Deployment of Deployment mode
+func (r *CacheServerReconciler) reconcileStatefulSet(ctx context.Context, cacheServer *productionstackv1alpha1.CacheServer) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Remove the Deployment left from Deployment mode
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: cacheServer.Name, Namespace: cacheServer.Namespace}}
+	if err := deleteOwnedObject(ctx, r.Client, cacheServer, dep); err != nil {
+		log.Error(err, "Failed to delete Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+		return ctrl.Result{}, err
+	}
+
+	// Check if the shard service already exists, if not create a new one
+	svc := r.shardServiceForCacheServer(cacheServer)
+	foundService := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, foundService)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new Service", "Service.Namespace", svc.Namespace, "Service.Name", svc.Name)
+		if err := r.Create(ctx, svc); err != nil {
+			log.Error(err, "Failed to create new Service", "Service.Namespace", svc.Namespace, "Service.Name", svc.Name)
+			return ctrl.Result{}, err
+		}
+		// Service created successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Service")
+		return ctrl.Result{}, err
+	}
+
+	// Update the shard service if its desired state changed
+	if specHashChanged(foundService, svc) {
+		log.Info("Updating Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+		copyDesiredMetadata(foundService, svc)
+		foundService.Spec = svc.Spec
+		if err := r.Update(ctx, foundService); err != nil {
+			log.Error(err, "Failed to update Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+			return ctrl.Result{}, err
+		}
+		// Service updated successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Build the desired StatefulSet, reporting values that cannot be parsed on the CacheServer
+	sts, err := r.statefulSetForCacheServer(cacheServer)
+	if err != nil {
+		log.Error(err, "Invalid CacheServer spec")
+		if err := r.reportInvalidSpec(ctx, cacheServer, err); err != nil {
+			log.Error(err, "Failed to update CacheServer status")
+			return ctrl.Result{}, err
+		}
+		// Wait for the spec to be fixed, which triggers a new reconcile
+		return ctrl.Result{}, nil
+	}
+
+	// Check if the StatefulSet already exists, if not create a new one
+	found := &appsv1.StatefulSet{}
+	err = r.Get(ctx, types.NamespacedName{Name: sts.Name, Namespace: sts.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new StatefulSet", "StatefulSet.Namespace", sts.Namespace, "StatefulSet.Name", sts.Name)
+		if err := r.Create(ctx, sts); err != nil {
+			log.Error(err, "Failed to create new StatefulSet", "StatefulSet.Namespace", sts.Namespace, "StatefulSet.Name", sts.Name)
+			return ctrl.Result{}, err
+		}
+		// StatefulSet created successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get StatefulSet")
+		return ctrl.Result{}, err
+	}
+
+	// Update the StatefulSet if its desired state changed. The selector and volume claim templates
+	// cannot change after creation, so the live ones are kept.
+	if specHashChanged(found, sts) {
+		log.Info("Updating StatefulSet", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name)
+		copyDesiredMetadata(found, sts)
+		sts.Spec.VolumeClaimTemplates = found.Spec.VolumeClaimTemplates
+		sts.Spec.Selector = keepSelector(found.Spec.Selector, &sts.Spec.Template)
+		found.Spec = sts.Spec
+		if err := r.Update(ctx, found); err != nil {
+			log.Error(err, "Failed to update StatefulSet", "StatefulSet.Namespace", found.Namespace, "StatefulSet.Name", found.Name)
+			return ctrl.Result{}, err
+		}
+		// StatefulSet updated successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Update the status
+	if err := r.updateStatus(ctx, cacheServer, found); err != nil {
+		log.Error(err, "Failed to update CacheServer status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// deleteStatefulSetObjects removes the StatefulSet and headless Service left from StatefulSet
+// mode. The volumes of the shards are kept.
+func (r *CacheServerReconciler) deleteStatefulSetObjects(ctx context.Context, cacheServer *productionstackv1alpha1.CacheServer) error {
+	objects := []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: cacheServer.Name, Namespace: cacheServer.Namespace}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: shardServiceNameForCacheServer(cacheServer), Namespace: cacheServer.Namespace}},
+	}
+	for _, obj := range objects {
+		if err := deleteOwnedObject(ctx, r.Client, cacheServer, obj); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", obj.GetName(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,417 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// conditionTypeAvailable reports whether every desired replica is available
+	conditionTypeAvailable = "Available"
+	// conditionTypeProgressing reports whether a rollout of the owned Deployment is in progress
+	conditionTypeProgressing = "Progressing"
+	// conditionTypeDegraded reports whether the spec could be turned into Kubernetes objects and
+	// rolled out
+	conditionTypeDegraded = "Degraded"
+
+	// reasonInvalidSpec is the Degraded reason for a spec holding values that cannot be parsed
+	reasonInvalidSpec = "InvalidSpec"
+	// reasonReconciled is the Degraded reason once every object of the spec was built
+	reasonReconciled = "Reconciled"
+	// reasonProgressDeadlineExceeded is the Degraded reason for a rollout that stopped making progress
+	reasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+	// statusInvalidSpec is the status string shown while the spec is invalid
+	statusInvalidSpec = "InvalidSpec"
+
+	// specHashAnnotation records a hash of the desired state an owned object was last written from
+	specHashAnnotation = "production-stack.vllm.ai/spec-hash"
+	// managedAnnotationsAnnotation lists the annotations the spec sets on an owned object, so the
+	// ones removed from the spec are removed from the object
+	managedAnnotationsAnnotation = "production-stack.vllm.ai/managed-annotations"
+
+	// defaultGPUResourceName is the extended resource GPUs are requested as when the spec does not
+	// name one
+	defaultGPUResourceName corev1.ResourceName = "nvidia.com/gpu"
+)
+
+// parseQuantity parses a user supplied quantity, naming the spec field it came from in the error
+func parseQuantity(field, value string) (resource.Quantity, error) {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return resource.Quantity{}, fmt.Errorf("invalid %s %q: %w", field, value, err)
+	}
+	return q, nil
+}
+
+// buildResourceRequirements returns container requests and limits for the CPU and memory of a
+// resource block. field is the spec path of the block, used in errors.
+func buildResourceRequirements(field string, spec productionstackv1alpha1.ResourceRequirements) (corev1.ResourceRequirements, error) {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+
+	if spec.CPU != "" {
+		cpu, err := parseQuantity(field+".cpu", spec.CPU)
+		if err != nil {
+			return resources, err
+		}
+		resources.Requests[corev1.ResourceCPU] = cpu
+		resources.Limits[corev1.ResourceCPU] = cpu
+	}
+
+	if spec.Memory != "" {
+		memory, err := parseQuantity(field+".memory", spec.Memory)
+		if err != nil {
+			return resources, err
+		}
+		resources.Requests[corev1.ResourceMemory] = memory
+		resources.Limits[corev1.ResourceMemory] = memory
+	}
+
+	return resources, nil
+}
+
+// gpuResourceName returns the extended resource the GPUs of a resource block are requested as
+func gpuResourceName(spec productionstackv1alpha1.ResourceRequirements) corev1.ResourceName {
+	if spec.GPUResourceName != "" {
+		return corev1.ResourceName(spec.GPUResourceName)
+	}
+	return defaultGPUResourceName
+}
+
+// addGPUResources requests the GPUs of a resource block as the given extended resource. Nothing is
+// requested when the block sets no GPUs. field is the spec path of the block, used in errors.
+func addGPUResources(resources *corev1.ResourceRequirements, field string, spec productionstackv1alpha1.ResourceRequirements, name corev1.ResourceName) error {
+	if spec.GPU == "" {
+		return nil
+	}
+	// Parse GPU resource as a decimal value
+	gpuResource, err := parseQuantity(field+".gpu", spec.GPU)
+	if err != nil {
+		return err
+	}
+	resources.Requests[name] = gpuResource
+	resources.Limits[name] = gpuResource
+	return nil
+}
+
+// applyScheduling sets the scheduling constraints of a spec on the pod spec of a builder. Pod
+// specs are part of the spec hash of their owners, so any change to them is rolled out.
+func applyScheduling(podSpec *corev1.PodSpec, scheduling productionstackv1alpha1.SchedulingConfig) {
+	podSpec.NodeSelector = scheduling.NodeSelector
+	podSpec.Tolerations = scheduling.Tolerations
+	podSpec.Affinity = scheduling.Affinity.DeepCopy()
+	podSpec.TopologySpreadConstraints = scheduling.TopologySpreadConstraints
+	podSpec.PriorityClassName = scheduling.PriorityClassName
+	if scheduling.RuntimeClassName != "" {
+		runtimeClassName := scheduling.RuntimeClassName
+		podSpec.RuntimeClassName = &runtimeClassName
+	}
+}
+
+// buildProbe returns a probe from the defaults of a container and the overrides of config, or nil
+// when disabled. enabled tells whether the container has the probe by default; without it, the
+// probe is only added when config sets a field. port is the container port the HTTP and gRPC
+// checks of config use.
+func buildProbe(config productionstackv1alpha1.ProbeConfig, defaults corev1.Probe, enabled bool, port int32) *corev1.Probe {
+	if config.Disabled || (!enabled && reflect.DeepEqual(config, productionstackv1alpha1.ProbeConfig{})) {
+		return nil
+	}
+
+	probe := defaults.DeepCopy()
+	switch {
+	case len(config.Exec) > 0:
+		probe.ProbeHandler = corev1.ProbeHandler{Exec: &corev1.ExecAction{Command: config.Exec}}
+	case config.GRPC != nil:
+		grpcPort := port
+		if config.GRPC.Port != 0 {
+			grpcPort = config.GRPC.Port
+		}
+		probe.ProbeHandler = corev1.ProbeHandler{GRPC: &corev1.GRPCAction{Port: grpcPort}}
+		if config.GRPC.Service != "" {
+			service := config.GRPC.Service
+			probe.GRPC.Service = &service
+		}
+	case config.Path != "":
+		probe.ProbeHandler = corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{
+			Path:   config.Path,
+			Port:   intstr.FromInt32(port),
+			Scheme: corev1.URISchemeHTTP,
+		}}
+	}
+
+	for _, override := range []struct {
+		value  *int32
+		target *int32
+	}{
+		{config.InitialDelaySeconds, &probe.InitialDelaySeconds},
+		{config.PeriodSeconds, &probe.PeriodSeconds},
+		{config.TimeoutSeconds, &probe.TimeoutSeconds},
+		{config.SuccessThreshold, &probe.SuccessThreshold},
+		{config.FailureThreshold, &probe.FailureThreshold},
+	} {
+		if override.value != nil {
+			*override.target = *override.value
+		}
+	}
+	return probe
+}
+
+// degradedCondition returns the Degraded condition for the outcome of building the objects of a
+// spec. A nil specErr clears the condition.
+func degradedCondition(generation int64, specErr error) metav1.Condition {
+	if specErr != nil {
+		return metav1.Condition{
+			Type:               conditionTypeDegraded,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             reasonInvalidSpec,
+			Message:            specErr.Error(),
+		}
+	}
+	return metav1.Condition{
+		Type:               conditionTypeDegraded,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             reasonReconciled,
+		Message:            "All objects were built from the spec",
+	}
+}
+
+// deploymentConditions returns the Available, Progressing and Degraded conditions of a resource
+// whose pods are managed by dep
+func deploymentConditions(generation int64, dep *appsv1.Deployment) []metav1.Condition {
+	desired := int32(1)
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+	status := dep.Status
+	replicaMessage := fmt.Sprintf("%d of %d replicas available", status.AvailableReplicas, desired)
+
+	available := metav1.Condition{
+		Type:               conditionTypeAvailable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "AllReplicasAvailable",
+		Message:            replicaMessage,
+	}
+	if status.AvailableReplicas < desired {
+		available.Status = metav1.ConditionFalse
+		available.Reason = "ReplicasUnavailable"
+	}
+
+	// The rollout is done once the Deployment controller saw the latest template and every pod
+	// runs it and is available
+	rolledOut := status.ObservedGeneration >= dep.Generation &&
+		status.UpdatedReplicas == desired &&
+		status.Replicas == desired &&
+		status.AvailableReplicas == desired
+	progressing := metav1.Condition{
+		Type:               conditionTypeProgressing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "RolloutComplete",
+		Message:            replicaMessage,
+	}
+	if !rolledOut {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = "RollingOut"
+		progressing.Message = fmt.Sprintf("%d of %d replicas updated, %s", status.UpdatedReplicas, desired, replicaMessage)
+	}
+
+	degraded := degradedCondition(generation, nil)
+	for _, cond := range status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Status == corev1.ConditionFalse &&
+			cond.Reason == "ProgressDeadlineExceeded" {
+			progressing.Status = metav1.ConditionFalse
+			progressing.Reason = reasonProgressDeadlineExceeded
+			degraded.Status = metav1.ConditionTrue
+			degraded.Reason = reasonProgressDeadlineExceeded
+			degraded.Message = cond.Message
+		}
+	}
+
+	return []metav1.Condition{available, progressing, degraded}
+}
+
+// statefulSetConditions returns the Available, Progressing and Degraded conditions of a resource
+// whose pods are managed by sts
+func statefulSetConditions(generation int64, sts *appsv1.StatefulSet) []metav1.Condition {
+	desired := int32(1)
+	if sts.Spec.Replicas != nil {
+		desired = *sts.Spec.Replicas
+	}
+	status := sts.Status
+	replicaMessage := fmt.Sprintf("%d of %d replicas available", status.AvailableReplicas, desired)
+
+	available := metav1.Condition{
+		Type:               conditionTypeAvailable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "AllReplicasAvailable",
+		Message:            replicaMessage,
+	}
+	if status.AvailableReplicas < desired {
+		available.Status = metav1.ConditionFalse
+		available.Reason = "ReplicasUnavailable"
+	}
+
+	// The rollout is done once the StatefulSet controller saw the latest template and every pod
+	// runs its latest revision and is available
+	rolledOut := status.ObservedGeneration >= sts.Generation &&
+		status.UpdatedReplicas == desired &&
+		status.Replicas == desired &&
+		status.AvailableReplicas == desired
+	progressing := metav1.Condition{
+		Type:               conditionTypeProgressing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "RolloutComplete",
+		Message:            replicaMessage,
+	}
+	if !rolledOut {
+		progressing.Status = metav1.ConditionTrue
+		progressing.Reason = "RollingOut"
+		progressing.Message = fmt.Sprintf("%d of %d replicas updated, %s", status.UpdatedReplicas, desired, replicaMessage)
+	}
+
+	return []metav1.Condition{available, progressing, degradedCondition(generation, nil)}
+}
+
+// deleteOwnedObject deletes the object named like obj if it exists and is controlled by owner,
+// leaving objects created by others alone
+func deleteOwnedObject(ctx context.Context, c client.Client, owner metav1.Object, obj client.Object) error {
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, owner) {
+		return nil
+	}
+	log.FromContext(ctx).Info("Deleting object left from a previous mode", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+	return client.IgnoreNotFound(c.Delete(ctx, obj))
+}
+
+// setSpecHash records a hash of the labels and spec of a desired object in its annotations, so that
+// any change to the desired state is detected without comparing individual fields. The reconcilers
+// write the desired object over the live one whenever their hashes differ, which rolls out a
+// changed pod template. spec is the part of the object the reconciler keeps in sync.
+func setSpecHash(obj metav1.Object, spec any) {
+	// The builders only produce API types, which always encode to JSON. Map keys are encoded in
+	// sorted order, so equal desired states hash equally.
+	data, _ := json.Marshal(struct {
+		Labels map[string]string `json:"labels,omitempty"`
+		Spec   any               `json:"spec"`
+	}{obj.GetLabels(), spec})
+	sum := sha256.Sum256(data)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[specHashAnnotation] = hex.EncodeToString(sum[:])
+	obj.SetAnnotations(annotations)
+}
+
+// specHashChanged reports whether the live object found was last written from a different desired
+// state than desired. Objects written before the hash was recorded always count as changed.
+func specHashChanged(found, desired metav1.Object) bool {
+	return found.GetAnnotations()[specHashAnnotation] != desired.GetAnnotations()[specHashAnnotation]
+}
+
+// keepSelector returns the selector of a live Deployment or StatefulSet to write back with its
+// desired spec, as a selector cannot change after creation. The desired pod template keeps the
+// labels the selector matches, so objects created with a selector on other labels stay valid.
+func keepSelector(found *metav1.LabelSelector, template *corev1.PodTemplateSpec) *metav1.LabelSelector {
+	if found == nil {
+		return nil
+	}
+	if template.Labels == nil {
+		template.Labels = map[string]string{}
+	}
+	maps.Copy(template.Labels, found.MatchLabels)
+	return found
+}
+
+// copyDesiredMetadata copies the labels and spec hash of desired onto the live object found, keeping
+// annotations other controllers set on it
+func copyDesiredMetadata(found, desired metav1.Object) {
+	found.SetLabels(desired.GetLabels())
+	annotations := found.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[specHashAnnotation] = desired.GetAnnotations()[specHashAnnotation]
+	found.SetAnnotations(annotations)
+}
+
+// setDesiredAnnotations sets the annotations the spec gives an owned object on the desired object
+// and records their keys. Desired objects with annotations hash them with their spec.
+func setDesiredAnnotations(obj metav1.Object, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	all := obj.GetAnnotations()
+	if all == nil {
+		all = map[string]string{}
+	}
+	keys := make([]string, 0, len(annotations))
+	for k, v := range annotations {
+		all[k] = v
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	all[managedAnnotationsAnnotation] = strings.Join(keys, ",")
+	obj.SetAnnotations(all)
+}
+
+// copyDesiredAnnotations copies the annotations of desired onto the live object found, removing
+// those the spec set before and no longer does and keeping annotations other controllers set on it
+func copyDesiredAnnotations(found, desired metav1.Object) {
+	annotations := found.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for _, key := range strings.Split(annotations[managedAnnotationsAnnotation], ",") {
+		delete(annotations, key)
+	}
+	delete(annotations, managedAnnotationsAnnotation)
+	for k, v := range desired.GetAnnotations() {
+		annotations[k] = v
+	}
+	found.SetAnnotations(annotations)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("Deployment conditions", func() {
+	newDeployment := func(status appsv1.DeploymentStatus) *appsv1.Deployment {
+		replicas := int32(2)
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     status,
+		}
+	}
+
+	It("reports a rolled out deployment as available", func() {
+		conditions := deploymentConditions(3, newDeployment(appsv1.DeploymentStatus{
+			ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2,
+		}))
+		Expect(meta.IsStatusConditionTrue(conditions, conditionTypeAvailable)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(conditions, conditionTypeProgressing)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(conditions, conditionTypeDegraded)).To(BeTrue())
+		for _, condition := range conditions {
+			Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+		}
+	})
+
+	It("reports a rollout in progress", func() {
+		conditions := deploymentConditions(3, newDeployment(appsv1.DeploymentStatus{
+			ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, ReadyReplicas: 2, AvailableReplicas: 2,
+		}))
+		Expect(meta.IsStatusConditionTrue(conditions, conditionTypeAvailable)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(conditions, conditionTypeProgressing)).To(BeTrue())
+
+		conditions = deploymentConditions(3, newDeployment(appsv1.DeploymentStatus{ObservedGeneration: 1}))
+		Expect(meta.IsStatusConditionFalse(conditions, conditionTypeAvailable)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(conditions, conditionTypeProgressing)).To(BeTrue())
+	})
+
+	It("reports a rollout past its progress deadline as degraded", func() {
+		conditions := deploymentConditions(3, newDeployment(appsv1.DeploymentStatus{
+			ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1,
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:    appsv1.DeploymentProgressing,
+				Status:  corev1.ConditionFalse,
+				Reason:  "ProgressDeadlineExceeded",
+				Message: "ReplicaSet \"llama-abc\" has timed out progressing.",
+			}},
+		}))
+		degraded := meta.FindStatusCondition(conditions, conditionTypeDegraded)
+		Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+		Expect(degraded.Reason).To(Equal(reasonProgressDeadlineExceeded))
+		Expect(meta.IsStatusConditionFalse(conditions, conditionTypeProgressing)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Probes", func() {
+	defaults := corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(8000)},
+		},
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       20,
+		FailureThreshold:    3,
+	}
+	seconds := func(v int32) *int32 { return &v }
+
+	It("keeps the defaults unless overridden and only adds probes without defaults once configured", func() {
+		probe := buildProbe(productionstackv1alpha1.ProbeConfig{}, defaults, true, 8000)
+		Expect(*probe).To(Equal(defaults))
+
+		Expect(buildProbe(productionstackv1alpha1.ProbeConfig{}, defaults, false, 8000)).To(BeNil())
+		Expect(buildProbe(productionstackv1alpha1.ProbeConfig{Disabled: true}, defaults, true, 8000)).To(BeNil())
+
+		probe = buildProbe(productionstackv1alpha1.ProbeConfig{FailureThreshold: seconds(30), InitialDelaySeconds: seconds(0)}, defaults, false, 8000)
+		Expect(probe.TCPSocket).NotTo(BeNil())
+		Expect(probe.InitialDelaySeconds).To(BeZero())
+		Expect(probe.PeriodSeconds).To(Equal(int32(20)))
+		Expect(probe.FailureThreshold).To(Equal(int32(30)))
+	})
+
+	It("replaces the check of the defaults", func() {
+		probe := buildProbe(productionstackv1alpha1.ProbeConfig{Path: "/v1/models"}, defaults, true, 8000)
+		Expect(probe.TCPSocket).To(BeNil())
+		Expect(probe.HTTPGet.Path).To(Equal("/v1/models"))
+		Expect(probe.HTTPGet.Port).To(Equal(intstr.FromInt32(8000)))
+
+		probe = buildProbe(productionstackv1alpha1.ProbeConfig{Exec: []string{"cat", "/tmp/ready"}}, defaults, true, 8000)
+		Expect(probe.Exec.Command).To(Equal([]string{"cat", "/tmp/ready"}))
+		Expect(probe.TCPSocket).To(BeNil())
+
+		probe = buildProbe(productionstackv1alpha1.ProbeConfig{GRPC: &productionstackv1alpha1.GRPCProbe{Service: "lmcache"}}, defaults, true, 8000)
+		Expect(probe.GRPC.Port).To(Equal(int32(8000)))
+		Expect(*probe.GRPC.Service).To(Equal("lmcache"))
+	})
+})
@@ -0,0 +1,318 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// kvRoleBoth is the KV transfer role of pods that store and retrieve the KV cache
+	kvRoleBoth = "kv_both"
+	// kvRoleProducer is the KV transfer role of prefill pods, which only store the KV cache
+	kvRoleProducer = "kv_producer"
+	// kvRoleConsumer is the KV transfer role of decode pods, which only retrieve the KV cache
+	kvRoleConsumer = "kv_consumer"
+
+	// roleLabel tells the prefill and decode pods of a runtime apart
+	roleLabel = "production-stack.vllm.ai/role"
+	// runtimeLabel carries the name of the VLLMRuntime a pod serves, which routers select its pods by
+	runtimeLabel = "production-stack.vllm.ai/runtime"
+	// modelLabel is the pod label the router reads the model label of an endpoint from
+	modelLabel = "model"
+
+	// rolePrefill is the role of the pods computing the KV cache of the prompts
+	rolePrefill = "prefill"
+	// roleDecode is the role of the pods generating the tokens
+	roleDecode = "decode"
+)
+
+// servingLabelsForVLLMRuntime returns the labels shared by all pods serving a VLLMRuntime. In
+// disaggregated mode the model label tells the prefill and decode pods apart, so it is left out.
+func servingLabelsForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) map[string]string {
+	labels := labelsForVLLMRuntime(vllmRuntime)
+	if vllmRuntime.Spec.Topology.Disaggregated {
+		delete(labels, modelLabel)
+	}
+	return labels
+}
+
+// roleNameForVLLMRuntime returns the name of the Deployment serving a role of a VLLMRuntime
+func roleNameForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime, role string) string {
+	return vllmRuntime.Name + "-" + role
+}
+
+// roleGroup returns the replica group of a role
+func roleGroup(vllmRuntime *productionstackv1alpha1.VLLMRuntime, role string) productionstackv1alpha1.ReplicaGroup {
+	if role == rolePrefill {
+		return vllmRuntime.Spec.Topology.Prefill
+	}
+	return vllmRuntime.Spec.Topology.Decode
+}
+
+// roleModelLabel returns the model label of the pods of a role, which the router is configured with
+func roleModelLabel(vllmRuntime *productionstackv1alpha1.VLLMRuntime, role string) string {
+	if label := roleGroup(vllmRuntime, role).ModelLabel; label != "" {
+		return label
+	}
+	return roleNameForVLLMRuntime(vllmRuntime, role)
+}
+
+// roleLabelsForVLLMRuntime returns the labels of the pods serving a role of a VLLMRuntime
+func roleLabelsForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime, role string) map[string]string {
+	labels := servingLabelsForVLLMRuntime(vllmRuntime)
+	labels[modelLabel] = roleModelLabel(vllmRuntime, role)
+	labels[roleLabel] = role
+	return labels
+}
+
+// roleSelectorLabelsForVLLMRuntime returns the labels the Deployment of a role of a VLLMRuntime
+// selects its pods by
+func roleSelectorLabelsForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime, role string) map[string]string {
+	labels := selectorLabelsForVLLMRuntime(vllmRuntime)
+	labels[roleLabel] = role
+	return labels
+}
+
+// roleReplicas returns the replica count of the Deployment of a role
+func roleReplicas(vllmRuntime *productionstackv1alpha1.VLLMRuntime, role string) int32 {
+	if isScaledToZero(vllmRuntime) {
+		return 0
+	}
+	return roleGroup(vllmRuntime, role).Replicas
+}
+
+// roleDeploymentForVLLMRuntime returns the Deployment serving the prefill or decode role of a
+// VLLMRuntime, or an error when the spec holds values that cannot be parsed
+func (r *VLLMRuntimeReconciler) roleDeploymentForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime, role string) (*appsv1.Deployment, error) {
+	kvRole := kvRoleConsumer
+	if role == rolePrefill {
+		kvRole = kvRoleProducer
+	}
+	template, err := r.podTemplateForVLLMRuntime(vllmRuntime, kvRole)
+	if err != nil {
+		return nil, err
+	}
+	template.Labels = podLabelsForVLLMRuntime(vllmRuntime, roleLabelsForVLLMRuntime(vllmRuntime, role))
+
+	// Groups without resources of their own use the resources of the deployment config, and its
+	// accelerator unless they name one
+	group := roleGroup(vllmRuntime, role)
+	if group.Resources != (productionstackv1alpha1.ResourceRequirements{}) {
+		groupResources := group.Resources
+		if groupResources.GPUResourceName == "" {
+			groupResources.GPUResourceName = vllmRuntime.Spec.DeploymentConfig.Resources.GPUResourceName
+		}
+		resources, err := vllmResourceRequirements(fmt.Sprintf("spec.topology.%s.resources", role), groupResources)
+		if err != nil {
+			return nil, err
+		}
+		template.Spec.Containers[0].Resources = resources
+	}
+
+	replicas := roleReplicas(vllmRuntime, role)
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      roleNameForVLLMRuntime(vllmRuntime, role),
+			Namespace: vllmRuntime.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.DeploymentStrategyType(vllmRuntime.Spec.DeploymentConfig.DeployStrategy),
+			},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: roleSelectorLabelsForVLLMRuntime(vllmRuntime, role),
+			},
+			Template: template,
+		},
+	}
+
+	setSpecHash(dep, dep.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(vllmRuntime, dep, r.Scheme)
+	return dep, nil
+}
+
+// reconcileDisaggregated serves a VLLMRuntime by a prefill and a decode Deployment, replacing the
+// Deployment of the unified topology
+func (r *VLLMRuntimeReconciler) reconcileDisaggregated(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Remove the Deployment left from the unified topology
+	unified := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}}
+	if err := deleteOwnedObject(ctx, r.Client, vllmRuntime, unified); err != nil {
+		log.Error(err, "Failed to delete Deployment", "Deployment.Namespace", unified.Namespace, "Deployment.Name", unified.Name)
+		return ctrl.Result{}, err
+	}
+
+	// Build the desired deployments, reporting values that cannot be parsed on the VLLMRuntime
+	prefill, err := r.roleDeploymentForVLLMRuntime(vllmRuntime, rolePrefill)
+	if err != nil {
+		return r.handleInvalidSpec(ctx, vllmRuntime, err)
+	}
+	decode, err := r.roleDeploymentForVLLMRuntime(vllmRuntime, roleDecode)
+	if err != nil {
+		return r.handleInvalidSpec(ctx, vllmRuntime, err)
+	}
+
+	found := make([]*appsv1.Deployment, 0, 2)
+	for _, dep := range []*appsv1.Deployment{prefill, decode} {
+		// Check if the deployment already exists, if not create a new one
+		foundDep := &appsv1.Deployment{}
+		err := r.Get(ctx, types.NamespacedName{Name: dep.Name, Namespace: dep.Namespace}, foundDep)
+		if err != nil && errors.IsNotFound(err) {
+			log.Info("Creating a new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+			if err := r.Create(ctx, dep); err != nil {
+				log.Error(err, "Failed to create new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+				return ctrl.Result{}, err
+			}
+			// Deployment created successfully - return and requeue
+			return ctrl.Result{Requeue: true}, nil
+		} else if err != nil {
+			log.Error(err, "Failed to get Deployment")
+			return ctrl.Result{}, err
+		}
+
+		// Update the deployment if its desired state changed
+		if specHashChanged(foundDep, dep) {
+			log.Info("Updating Deployment", "Deployment.Namespace", foundDep.Namespace, "Deployment.Name", foundDep.Name)
+			copyDesiredMetadata(foundDep, dep)
+			dep.Spec.Selector = keepSelector(foundDep.Spec.Selector, &dep.Spec.Template)
+			foundDep.Spec = dep.Spec
+			if err := r.Update(ctx, foundDep); err != nil {
+				log.Error(err, "Failed to update Deployment", "Deployment.Namespace", foundDep.Namespace, "Deployment.Name", foundDep.Name)
+				return ctrl.Result{}, err
+			}
+			// Deployment updated successfully - return and requeue
+			return ctrl.Result{Requeue: true}, nil
+		}
+		found = append(found, foundDep)
+	}
+
+	// Update the status
+	if err := r.updateDisaggregatedStatus(ctx, vllmRuntime, found[0], found[1]); err != nil {
+		log.Error(err, "Failed to update VLLMRuntime status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: resyncPeriod(vllmRuntime)}, nil
+}
+
+// deleteDisaggregatedObjects removes the prefill and decode Deployments left from disaggregated mode
+func (r *VLLMRuntimeReconciler) deleteDisaggregatedObjects(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime) error {
+	for _, role := range []string{rolePrefill, roleDecode} {
+		dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: roleNameForVLLMRuntime(vllmRuntime, role), Namespace: vllmRuntime.Namespace}}
+		if err := deleteOwnedObject(ctx, r.Client, vllmRuntime, dep); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", dep.Name, err)
+		}
+	}
+	return nil
+}
+
+// roleConditions returns the Available, Progressing and Degraded conditions of a resource whose
+// pods are managed by several Deployments. It is available while all of them are, and progressing
+// or degraded while any of them is.
+func roleConditions(generation int64, deps ...*appsv1.Deployment) []metav1.Condition {
+	var conditions []metav1.Condition
+	for _, dep := range deps {
+		for i, condition := range deploymentConditions(generation, dep) {
+			condition.Message = dep.Name + ": " + condition.Message
+			if i == len(conditions) {
+				conditions = append(conditions, condition)
+				continue
+			}
+			bad := metav1.ConditionTrue
+			if condition.Type == conditionTypeAvailable {
+				bad = metav1.ConditionFalse
+			}
+			switch {
+			case condition.Status == conditions[i].Status:
+				conditions[i].Message += "; " + condition.Message
+			case condition.Status == bad:
+				conditions[i] = condition
+			}
+		}
+	}
+	return conditions
+}
+
+// updateDisaggregatedStatus updates the status of a VLLMRuntime served by prefill and decode
+// Deployments
+func (r *VLLMRuntimeReconciler) updateDisaggregatedStatus(ctx context.Context, vr *productionstackv1alpha1.VLLMRuntime, prefill, decode *appsv1.Deployment) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get the latest version of the VLLMRuntime
+		latestVR := &productionstackv1alpha1.VLLMRuntime{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vr.Name, Namespace: vr.Namespace}, latestVR); err != nil {
+			return err
+		}
+
+		// Update the status fields. The replicas of a runtime are those of both groups.
+		latestVR.Status.LastUpdated = metav1.Now()
+		latestVR.Status.ObservedGeneration = latestVR.Generation
+		latestVR.Status.Replicas = prefill.Status.Replicas + decode.Status.Replicas
+		latestVR.Status.ReadyReplicas = prefill.Status.ReadyReplicas + decode.Status.ReadyReplicas
+		latestVR.Status.AvailableReplicas = prefill.Status.AvailableReplicas + decode.Status.AvailableReplicas
+		latestVR.Status.UpdatedReplicas = prefill.Status.UpdatedReplicas + decode.Status.UpdatedReplicas
+		latestVR.Status.Topology = &productionstackv1alpha1.TopologyStatus{
+			Prefill: productionstackv1alpha1.ReplicaGroupStatus{
+				ModelLabel:    roleModelLabel(latestVR, rolePrefill),
+				Replicas:      prefill.Status.Replicas,
+				ReadyReplicas: prefill.Status.ReadyReplicas,
+			},
+			Decode: productionstackv1alpha1.ReplicaGroupStatus{
+				ModelLabel:    roleModelLabel(latestVR, roleDecode),
+				Replicas:      decode.Status.Replicas,
+				ReadyReplicas: decode.Status.ReadyReplicas,
+			},
+		}
+		conditions := roleConditions(latestVR.Generation, prefill, decode)
+		for _, condition := range conditions {
+			meta.SetStatusCondition(&latestVR.Status.Conditions, condition)
+		}
+		latestVR.Status.MultiNode = nil
+		clearScaleToZeroStatus(latestVR)
+
+		// Update model status based on the readiness of both groups
+		desired := *prefill.Spec.Replicas + *decode.Spec.Replicas
+		switch {
+		case isScaledToZero(latestVR) && desired == 0:
+			latestVR.Status.ModelStatus = statusScaledToZero
+		case conditions[0].Status == metav1.ConditionTrue:
+			latestVR.Status.ModelStatus = "Ready"
+		case conditions[1].Status == metav1.ConditionTrue:
+			latestVR.Status.ModelStatus = "Updating"
+		default:
+			latestVR.Status.ModelStatus = "NotReady"
+		}
+
+		return r.Status().Update(ctx, latestVR)
+	})
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRuntime disaggregated topology", func() {
+	newRuntime := func() *productionstackv1alpha1.VLLMRuntime {
+		return &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default", Labels: map[string]string{"model": "llama-8b"}},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				Model:      productionstackv1alpha1.ModelSpec{ModelURL: "meta-llama/Llama-3.1-8B"},
+				VLLMConfig: productionstackv1alpha1.VLLMConfig{Port: 8000, V1: true},
+				LMCacheConfig: productionstackv1alpha1.LMCacheConfig{
+					Enabled: true, RemoteURL: "lm://cacheserver:80", RemoteSerde: "naive",
+				},
+				DeploymentConfig: productionstackv1alpha1.DeploymentConfig{
+					Replicas: 1,
+					Resources: productionstackv1alpha1.ResourceRequirements{
+						CPU: "8", Memory: "32Gi", GPU: "1",
+					},
+				},
+				Topology: productionstackv1alpha1.TopologyConfig{
+					Disaggregated: true,
+					Prefill: productionstackv1alpha1.ReplicaGroup{
+						Replicas:  2,
+						Resources: productionstackv1alpha1.ResourceRequirements{CPU: "16", Memory: "64Gi", GPU: "2"},
+					},
+					Decode: productionstackv1alpha1.ReplicaGroup{Replicas: 3, ModelLabel: "llama-decoder"},
+				},
+			},
+		}
+	}
+	kvTransferConfig := func(dep *appsv1.Deployment) string {
+		args := dep.Spec.Template.Spec.Containers[0].Args
+		for i, arg := range args {
+			if arg == "--kv-transfer-config" {
+				return args[i+1]
+			}
+		}
+		return ""
+	}
+
+	It("builds a prefill Deployment producing the KV cache with the resources of its group", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		dep, err := r.roleDeploymentForVLLMRuntime(newRuntime(), rolePrefill)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Name).To(Equal("llama-prefill"))
+		Expect(*dep.Spec.Replicas).To(Equal(int32(2)))
+		Expect(dep.Spec.Template.Labels).To(HaveKeyWithValue("model", "llama-prefill"))
+		Expect(dep.Spec.Template.Labels).To(HaveKeyWithValue(roleLabel, rolePrefill))
+		Expect(dep.Spec.Template.Labels).To(HaveKeyWithValue(runtimeLabel, "llama"))
+		Expect(dep.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "llama", runtimeLabel: "llama", roleLabel: rolePrefill}))
+		Expect(kvTransferConfig(dep)).To(Equal(`{"kv_connector":"LMCacheConnectorV1","kv_role":"kv_producer"}`))
+
+		limits := dep.Spec.Template.Spec.Containers[0].Resources.Limits
+		Expect(limits.Cpu().String()).To(Equal("16"))
+		gpu := limits[corev1.ResourceName("nvidia.com/gpu")]
+		Expect(gpu.String()).To(Equal("2"))
+	})
+
+	It("builds a decode Deployment consuming the KV cache with the resources of the deployment config", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		dep, err := r.roleDeploymentForVLLMRuntime(newRuntime(), roleDecode)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Name).To(Equal("llama-decode"))
+		Expect(*dep.Spec.Replicas).To(Equal(int32(3)))
+		Expect(dep.Spec.Template.Labels).To(HaveKeyWithValue("model", "llama-decoder"))
+		Expect(kvTransferConfig(dep)).To(Equal(`{"kv_connector":"LMCacheConnectorV1","kv_role":"kv_consumer"}`))
+		Expect(dep.Spec.Template.Spec.Containers[0].Resources.Limits.Cpu().String()).To(Equal("8"))
+
+		vr := newRuntime()
+		vr.Spec.Topology.Decode.Resources.Memory = "a lot"
+		_, err = r.roleDeploymentForVLLMRuntime(vr, roleDecode)
+		Expect(err).To(MatchError(ContainSubstring("spec.topology.decode.resources.memory")))
+	})
+
+	It("selects the pods of both groups for requests and the decode pods for the Service", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		Expect(servingLabelsForVLLMRuntime(vr)).To(Equal(map[string]string{"app": "llama"}))
+		Expect(r.serviceForVLLMRuntime(vr).Spec.Selector).To(Equal(map[string]string{
+			"app": "llama", "model": "llama-decoder", roleLabel: roleDecode,
+		}))
+
+		vr.Spec.Topology.Disaggregated = false
+		Expect(servingLabelsForVLLMRuntime(vr)).To(HaveKeyWithValue("model", "llama-8b"))
+		Expect(r.serviceForVLLMRuntime(vr).Spec.Selector).To(Equal(labelsForVLLMRuntime(vr)))
+	})
+
+	It("scales both groups to zero while the model is idle", func() {
+		vr := newRuntime()
+		vr.Spec.ScaleToZero.Enabled = true
+		meta.SetStatusCondition(&vr.Status.Conditions, metav1.Condition{
+			Type: conditionTypeScaledToZero, Status: metav1.ConditionTrue, Reason: "Idle",
+		})
+		Expect(roleReplicas(vr, rolePrefill)).To(BeZero())
+		Expect(roleReplicas(vr, roleDecode)).To(BeZero())
+	})
+
+	It("is only available once both groups are", func() {
+		dep := func(name string, replicas, available int32) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status: appsv1.DeploymentStatus{
+					Replicas: replicas, UpdatedReplicas: replicas, AvailableReplicas: available,
+				},
+			}
+		}
+
+		conditions := roleConditions(1, dep("llama-prefill", 2, 2), dep("llama-decode", 3, 3))
+		Expect(conditions[0].Status).To(Equal(metav1.ConditionTrue))
+		Expect(conditions[0].Message).To(Equal("llama-prefill: 2 of 2 replicas available; llama-decode: 3 of 3 replicas available"))
+		Expect(conditions[1].Status).To(Equal(metav1.ConditionFalse))
+
+		conditions = roleConditions(1, dep("llama-prefill", 2, 2), dep("llama-decode", 3, 1))
+		Expect(conditions[0].Status).To(Equal(metav1.ConditionFalse))
+		Expect(conditions[0].Message).To(Equal("llama-decode: 1 of 3 replicas available"))
+		Expect(conditions[1].Status).To(Equal(metav1.ConditionTrue))
+		Expect(conditions[2].Status).To(Equal(metav1.ConditionFalse))
+	})
+})
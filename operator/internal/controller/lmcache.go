@@ -0,0 +1,256 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// conditionTypeCacheServerReady reports whether the CacheServer named by
+	// spec.lmCacheConfig.cacheServerRef is ready to serve the KV cache
+	conditionTypeCacheServerReady = "CacheServerReady"
+
+	// cacheServerServicePort is the port the Service of a CacheServer exposes it on
+	cacheServerServicePort = 80
+
+	// defaultLMCacheP2PPort is the port the pods serve their KV cache to peers on when the spec
+	// does not set one
+	defaultLMCacheP2PPort = 8200
+)
+
+// cacheServerURL returns the remote cache URL of the CacheServer with the given name and namespace
+func cacheServerURL(namespace, name string) string {
+	return fmt.Sprintf("lm://%s.%s.svc.cluster.local:%d", name, namespace, cacheServerServicePort)
+}
+
+// lmCacheRemoteURL returns the remote cache URL of a runtime. A CacheServer reference resolves to
+// the URL recorded from the CacheServer, which addresses its shards, or to its Service before the
+// CacheServer was seen.
+func lmCacheRemoteURL(vllmRuntime *productionstackv1alpha1.VLLMRuntime) string {
+	config := vllmRuntime.Spec.LMCacheConfig
+	if config.CacheServerRef != nil {
+		if vllmRuntime.Status.CacheServerURL != "" {
+			return vllmRuntime.Status.CacheServerURL
+		}
+		return cacheServerURL(vllmRuntime.Namespace, config.CacheServerRef.Name)
+	}
+	return config.RemoteURL
+}
+
+// lmCacheEnv returns the environment variables that configure LMCache in the vLLM container
+func lmCacheEnv(vllmRuntime *productionstackv1alpha1.VLLMRuntime) []corev1.EnvVar {
+	config := vllmRuntime.Spec.LMCacheConfig
+	logLevel := config.LogLevel
+	if logLevel == "" {
+		logLevel = "INFO"
+	}
+	env := []corev1.EnvVar{
+		{Name: "LMCACHE_LOG_LEVEL", Value: logLevel},
+		{Name: "LMCACHE_USE_EXPERIMENTAL", Value: "True"},
+		{Name: "VLLM_RPC_TIMEOUT", Value: "1000000"},
+	}
+
+	if config.ChunkSize > 0 {
+		env = append(env, corev1.EnvVar{Name: "LMCACHE_CHUNK_SIZE", Value: fmt.Sprintf("%d", config.ChunkSize)})
+	}
+
+	if config.LocalDevice != "" {
+		env = append(env, corev1.EnvVar{Name: "LMCACHE_LOCAL_DEVICE", Value: config.LocalDevice})
+	}
+
+	if config.CPUOffloadingBufferSize != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "LMCACHE_LOCAL_CPU", Value: "True"},
+			corev1.EnvVar{Name: "LMCACHE_MAX_LOCAL_CPU_SIZE", Value: config.CPUOffloadingBufferSize},
+		)
+	}
+
+	if config.DiskOffloadingBufferSize != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "LMCACHE_LOCAL_DISK", Value: "True"},
+			corev1.EnvVar{Name: "LMCACHE_MAX_LOCAL_DISK_SIZE", Value: config.DiskOffloadingBufferSize},
+		)
+	}
+
+	if remoteURL := lmCacheRemoteURL(vllmRuntime); remoteURL != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "LMCACHE_REMOTE_URL", Value: remoteURL},
+			corev1.EnvVar{Name: "LMCACHE_REMOTE_SERDE", Value: config.RemoteSerde},
+		)
+	}
+
+	if blending := config.Blending; blending.Enabled {
+		env = append(env, corev1.EnvVar{Name: "LMCACHE_ENABLE_BLENDING", Value: "True"})
+		if blending.RecomputeRatio != "" {
+			env = append(env, corev1.EnvVar{Name: "LMCACHE_BLEND_RECOMPUTE_RATIO", Value: blending.RecomputeRatio})
+		}
+		if blending.MinTokens > 0 {
+			env = append(env, corev1.EnvVar{Name: "LMCACHE_BLEND_MIN_TOKENS", Value: fmt.Sprintf("%d", blending.MinTokens)})
+		}
+	}
+
+	// Every pod serves its KV cache to its peers on its own IP
+	if p2p := config.P2P; p2p.Enabled {
+		env = append(env,
+			corev1.EnvVar{
+				Name: "POD_IP",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+				},
+			},
+			corev1.EnvVar{Name: "LMCACHE_ENABLE_P2P", Value: "True"},
+			corev1.EnvVar{Name: "LMCACHE_LOOKUP_URL", Value: p2p.LookupURL},
+			corev1.EnvVar{Name: "LMCACHE_DISTRIBUTED_URL", Value: fmt.Sprintf("$(POD_IP):%d", lmCacheP2PPort(vllmRuntime))},
+		)
+	}
+
+	// The controller tells the pods apart by their pod name
+	if config.ControllerURL != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "LMCACHE_ENABLE_CONTROLLER", Value: "True"},
+			corev1.EnvVar{Name: "LMCACHE_CONTROLLER_URL", Value: config.ControllerURL},
+			corev1.EnvVar{
+				Name: "LMCACHE_LMCACHE_INSTANCE_ID",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			},
+		)
+	}
+
+	return env
+}
+
+// lmCacheP2PPort returns the port the pods of a runtime serve their KV cache to peers on
+func lmCacheP2PPort(vllmRuntime *productionstackv1alpha1.VLLMRuntime) int32 {
+	if port := vllmRuntime.Spec.LMCacheConfig.P2P.Port; port > 0 {
+		return port
+	}
+	return defaultLMCacheP2PPort
+}
+
+// usesCacheServer reports whether a runtime takes its remote cache from a CacheServer
+func usesCacheServer(vllmRuntime *productionstackv1alpha1.VLLMRuntime) bool {
+	return vllmRuntime.Spec.LMCacheConfig.Enabled && vllmRuntime.Spec.LMCacheConfig.CacheServerRef != nil
+}
+
+// cacheServerCondition returns the CacheServerReady condition of a runtime for the CacheServer it
+// references, or for a missing one when cacheServer is nil. A CacheServer is ready once all of its
+// replicas are available.
+func cacheServerCondition(vllmRuntime *productionstackv1alpha1.VLLMRuntime, cacheServer *productionstackv1alpha1.CacheServer) metav1.Condition {
+	name := vllmRuntime.Spec.LMCacheConfig.CacheServerRef.Name
+	condition := metav1.Condition{
+		Type:               conditionTypeCacheServerReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: vllmRuntime.Generation,
+	}
+	switch {
+	case cacheServer == nil:
+		condition.Reason = "NotFound"
+		condition.Message = fmt.Sprintf("CacheServer %s does not exist", name)
+	case meta.IsStatusConditionTrue(cacheServer.Status.Conditions, conditionTypeAvailable):
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Ready"
+		condition.Message = fmt.Sprintf("CacheServer %s is ready", name)
+	default:
+		condition.Reason = "NotReady"
+		condition.Message = fmt.Sprintf("CacheServer %s has %d of %d replicas available",
+			name, cacheServer.Status.AvailableReplicas, cacheServer.Spec.Replicas)
+	}
+	return condition
+}
+
+// reconcileCacheServerRef records the URL and readiness of the CacheServer a runtime references,
+// so the pods built afterwards address it, or the shard of their model. The pods connect to the URL whether or not the
+// CacheServer is ready yet, so its readiness is only reported.
+func (r *VLLMRuntimeReconciler) reconcileCacheServerRef(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime) error {
+	var condition *metav1.Condition
+	url := ""
+	if usesCacheServer(vllmRuntime) {
+		cacheServer := &productionstackv1alpha1.CacheServer{}
+		key := types.NamespacedName{Name: vllmRuntime.Spec.LMCacheConfig.CacheServerRef.Name, Namespace: vllmRuntime.Namespace}
+		if err := r.Get(ctx, key, cacheServer); errors.IsNotFound(err) {
+			cacheServer = nil
+		} else if err != nil {
+			return err
+		}
+		c := cacheServerCondition(vllmRuntime, cacheServer)
+		condition = &c
+		url = cacheServerURL(key.Namespace, key.Name)
+		if cacheServer != nil {
+			url = cacheServerRemoteURL(cacheServer, vllmRuntime.Spec.Model.ModelURL)
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestVR := &productionstackv1alpha1.VLLMRuntime{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}, latestVR); err != nil {
+			return err
+		}
+
+		previous := latestVR.Status.DeepCopy()
+		latestVR.Status.CacheServerURL = url
+		if condition != nil {
+			meta.SetStatusCondition(&latestVR.Status.Conditions, *condition)
+		} else {
+			meta.RemoveStatusCondition(&latestVR.Status.Conditions, conditionTypeCacheServerReady)
+		}
+
+		vllmRuntime.Status.CacheServerURL = latestVR.Status.CacheServerURL
+		vllmRuntime.Status.Conditions = latestVR.Status.Conditions
+		if reflect.DeepEqual(previous, &latestVR.Status) {
+			return nil // No update needed
+		}
+
+		return r.Status().Update(ctx, latestVR)
+	})
+}
+
+// runtimesForCacheServer maps a CacheServer to the runtimes in its namespace that reference it, so
+// they report its readiness and follow its shards as it changes
+func (r *VLLMRuntimeReconciler) runtimesForCacheServer(ctx context.Context, obj client.Object) []reconcile.Request {
+	runtimes := &productionstackv1alpha1.VLLMRuntimeList{}
+	if err := r.List(ctx, runtimes, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list VLLMRuntimes for CacheServer", "CacheServer.Name", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, vr := range runtimes.Items {
+		if ref := vr.Spec.LMCacheConfig.CacheServerRef; ref != nil && ref.Name == obj.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: vr.Name, Namespace: vr.Namespace},
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRuntime LMCache configuration", func() {
+	newRuntime := func(config productionstackv1alpha1.LMCacheConfig) *productionstackv1alpha1.VLLMRuntime {
+		config.Enabled = true
+		return &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "serving"},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				Model:         productionstackv1alpha1.ModelSpec{ModelURL: "meta-llama/Llama-3.1-8B"},
+				VLLMConfig:    productionstackv1alpha1.VLLMConfig{Port: 8000, V1: true},
+				LMCacheConfig: config,
+				DeploymentConfig: productionstackv1alpha1.DeploymentConfig{
+					Replicas: 1,
+					Image:    productionstackv1alpha1.ImageSpec{Registry: "docker.io", Name: "lmcache/vllm-openai:latest"},
+				},
+			},
+		}
+	}
+	envOf := func(container corev1.Container) map[string]corev1.EnvVar {
+		env := map[string]corev1.EnvVar{}
+		for _, e := range container.Env {
+			env[e.Name] = e
+		}
+		return env
+	}
+
+	It("configures chunking, blending, P2P sharing and the controller without forcing debug logs", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		dep, err := r.deploymentForVLLMRuntime(newRuntime(productionstackv1alpha1.LMCacheConfig{
+			ChunkSize:     256,
+			LocalDevice:   "cpu",
+			Blending:      productionstackv1alpha1.LMCacheBlendingConfig{Enabled: true, RecomputeRatio: "0.15", MinTokens: 64},
+			P2P:           productionstackv1alpha1.LMCacheP2PConfig{Enabled: true, LookupURL: "lmcache-lookup:8100"},
+			ControllerURL: "lmcache-controller:9000",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+
+		container := dep.Spec.Template.Spec.Containers[0]
+		env := envOf(container)
+		Expect(env["LMCACHE_LOG_LEVEL"].Value).To(Equal("INFO"))
+		Expect(env["LMCACHE_CHUNK_SIZE"].Value).To(Equal("256"))
+		Expect(env["LMCACHE_LOCAL_DEVICE"].Value).To(Equal("cpu"))
+		Expect(env["LMCACHE_ENABLE_BLENDING"].Value).To(Equal("True"))
+		Expect(env["LMCACHE_BLEND_RECOMPUTE_RATIO"].Value).To(Equal("0.15"))
+		Expect(env["LMCACHE_BLEND_MIN_TOKENS"].Value).To(Equal("64"))
+		Expect(env["LMCACHE_LOOKUP_URL"].Value).To(Equal("lmcache-lookup:8100"))
+		Expect(env["LMCACHE_DISTRIBUTED_URL"].Value).To(Equal("$(POD_IP):8200"))
+		Expect(env["POD_IP"].ValueFrom.FieldRef.FieldPath).To(Equal("status.podIP"))
+		Expect(env["LMCACHE_CONTROLLER_URL"].Value).To(Equal("lmcache-controller:9000"))
+		Expect(env["LMCACHE_LMCACHE_INSTANCE_ID"].ValueFrom.FieldRef.FieldPath).To(Equal("metadata.name"))
+		Expect(env).NotTo(HaveKey("LMCACHE_REMOTE_URL"))
+		Expect(container.Ports).To(ContainElement(corev1.ContainerPort{Name: "lmcache-p2p", ContainerPort: 8200}))
+	})
+
+	It("resolves a CacheServer reference to the URL of its Service", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime(productionstackv1alpha1.LMCacheConfig{
+			CacheServerRef: &corev1.LocalObjectReference{Name: "cacheserver"},
+			RemoteSerde:    "cachegen",
+			LogLevel:       "WARNING",
+		})
+		dep, err := r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+
+		env := envOf(dep.Spec.Template.Spec.Containers[0])
+		Expect(env["LMCACHE_REMOTE_URL"].Value).To(Equal("lm://cacheserver.serving.svc.cluster.local:80"))
+		Expect(env["LMCACHE_REMOTE_SERDE"].Value).To(Equal("cachegen"))
+		Expect(env["LMCACHE_LOG_LEVEL"].Value).To(Equal("WARNING"))
+	})
+
+	It("reports whether the referenced CacheServer is ready", func() {
+		vr := newRuntime(productionstackv1alpha1.LMCacheConfig{
+			CacheServerRef: &corev1.LocalObjectReference{Name: "cacheserver"},
+		})
+		Expect(cacheServerCondition(vr, nil).Reason).To(Equal("NotFound"))
+
+		cs := &productionstackv1alpha1.CacheServer{
+			Spec:   productionstackv1alpha1.CacheServerSpec{Replicas: 2},
+			Status: productionstackv1alpha1.CacheServerStatus{AvailableReplicas: 1},
+		}
+		condition := cacheServerCondition(vr, cs)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("NotReady"))
+		Expect(condition.Message).To(ContainSubstring("1 of 2"))
+
+		cs.Status.Conditions = []metav1.Condition{{Type: conditionTypeAvailable, Status: metav1.ConditionTrue}}
+		condition = cacheServerCondition(vr, cs)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("Ready"))
+	})
+})
@@ -20,21 +20,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"net/url"
+	"path"
 	"reflect"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/prometheus/common/expfmt"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -47,19 +60,57 @@ import (
 
 const (
 	loraAdapterFinalizer = "loraadapter.production-stack.vllm.ai/finalizer"
+
+	// loraSidecarPort is the port the adapter downloader sidecar listens on in vLLM pods
+	loraSidecarPort = 30090
+
+	// runtimeOwnerDepth bounds the controllers followed from a pod to its VLLMRuntime, the
+	// longest chain being Pod, ReplicaSet, Deployment
+	runtimeOwnerDepth = 3
+
+	// conditionTypeAdapterDownloaded reports the progress of fetching a remote adapter
+	conditionTypeAdapterDownloaded = "AdapterDownloaded"
+
+	// conditionTypeAdaptersDiscovered reports the adapters matched by AdapterSource.Pattern
+	conditionTypeAdaptersDiscovered = "AdaptersDiscovered"
+
+	// conditionTypePlaced reports whether every adapter could be placed on the requested number of pods
+	conditionTypePlaced = "Placed"
+
+	// conditionTypeWaitingForPods reports that no vLLM pod is ready to load the adapter
+	conditionTypeWaitingForPods = "WaitingForPods"
+
+	// phasePending is the phase of an adapter that is not loaded on any pod yet
+	phasePending = "Pending"
+	// phaseLoaded is the phase of an adapter that is loaded on at least one pod
+	phaseLoaded = "Loaded"
 )
 
 // LoraAdapterReconciler reconciles a LoraAdapter object
 type LoraAdapterReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// SidecarPort is the port of the adapter downloader sidecar in vLLM pods, loraSidecarPort
+	// when zero
+	SidecarPort int
+}
+
+// sidecarPort returns the port the adapter downloader sidecar is reached on
+func (r *LoraAdapterReconciler) sidecarPort() int {
+	if r.SidecarPort != 0 {
+		return r.SidecarPort
+	}
+	return loraSidecarPort
 }
 
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=loraadapters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=loraadapters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=loraadapters/finalizers,verbs=update
+// +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmruntimes,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -129,17 +180,15 @@ func (r *LoraAdapterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
 
 	// Step 3: Get desired state (optimal pod placements) for this adapter
-	desiredPlacements, err := r.getOptimalPlacement(ctx, &loraAdapter)
+	readyPods, err := r.getReadyPods(ctx, &loraAdapter)
 	if err != nil {
-		logger.Error(err, "Failed to get optimal pod placements",
+		logger.Error(err, "Failed to get ready pods",
 			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
 		return ctrl.Result{}, err
 	}
-	logger.Info("Desired pod placements", "placements", desiredPlacements,
-		"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
 
 	// Check if no pods are ready yet
-	if len(desiredPlacements) == 0 {
+	if len(readyPods) == 0 {
 		logger.Info("No pods are ready yet, waiting for pods to become ready",
 			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
 
@@ -153,14 +202,37 @@ func (r *LoraAdapterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{RequeueAfter: time.Second * 60}, nil
 	}
 
+	// Resolve which adapters to place, discovering them through the sidecar when a pattern is set
+	adapterNames, err := r.resolveAdapterNames(ctx, &loraAdapter, readyPods[0].Name, readyPods[0].Namespace)
+	if err != nil {
+		logger.Error(err, "Failed to resolve adapters",
+			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+		return ctrl.Result{}, err
+	}
+
+	desiredPlacements, err := r.getOptimalPlacement(ctx, &loraAdapter, readyPods, adapterNames)
+	if err != nil {
+		logger.Error(err, "Failed to get optimal pod placements",
+			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+		return ctrl.Result{}, err
+	}
+	logger.Info("Desired pod placements", "placements", desiredPlacements,
+		"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+
 	// Step 4: Compare current and desired state
 	if needsReconciliation, err := r.compareStates(currentRegistrations, desiredPlacements); err != nil {
 		logger.Error(err, "Failed to compare states",
 			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
 		return ctrl.Result{}, err
-	} else if needsReconciliation {
+	} else if needsReconciliation || downloadOutdated(&loraAdapter) {
 		// Step 5: Reconcile to match desired state
 		if err := r.reconcileToDesiredState(ctx, &loraAdapter, currentRegistrations, desiredPlacements); err != nil {
+			var inProgress *downloadInProgressError
+			if stderrors.As(err, &inProgress) {
+				logger.Info("Waiting for adapter download to complete", "progress", inProgress.progress,
+					"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+				return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+			}
 			logger.Error(err, "Failed to reconcile to desired state",
 				"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
 			return ctrl.Result{}, err
@@ -193,226 +265,1249 @@ func (r *LoraAdapterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		logger.Info("Latest adapter registrations", "registrations", latestRegistrations,
 			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
 
-		// Update status with latest registrations
-		if err := r.updateStatusWithRegistrations(ctx, &loraAdapter, latestRegistrations); err != nil {
-			logger.Error(err, "Failed to update status with latest registrations",
-				"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
-			return ctrl.Result{}, err
+		// Update status with latest registrations
+		if err := r.updateStatusWithRegistrations(ctx, &loraAdapter, latestRegistrations); err != nil {
+			logger.Error(err, "Failed to update status with latest registrations",
+				"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+			return ctrl.Result{}, err
+		}
+		logger.Info("Updated status with latest registrations",
+			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+	} else {
+		logger.Info("No reconciliation needed",
+			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+	}
+
+	// Schedule periodic reconciliation based on whether we have waiting adapters
+	logger.Info("Reconciliation loop completed")
+	return ctrl.Result{RequeueAfter: time.Second * 60}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LoraAdapterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&productionstackv1alpha1.LoraAdapter{}, builder.WithPredicates(predicate.Funcs{
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				oldAdapter := e.ObjectOld.(*productionstackv1alpha1.LoraAdapter)
+				newAdapter := e.ObjectNew.(*productionstackv1alpha1.LoraAdapter)
+
+				// Always reconcile on deletion (when deletionTimestamp is set)
+				if !newAdapter.DeletionTimestamp.IsZero() {
+					return true
+				}
+
+				// Only reconcile on spec changes, not status changes
+				return !reflect.DeepEqual(oldAdapter.Spec, newAdapter.Spec)
+			},
+			CreateFunc: func(e event.CreateEvent) bool {
+				return true // Always reconcile on create
+			},
+			DeleteFunc: func(e event.DeleteEvent) bool {
+				return true // Always reconcile on delete
+			},
+		})).
+		// Pods becoming ready or changing labels may need adapters loaded or move between adapters
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.findLoraAdaptersForPod),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					oldPod := e.ObjectOld.(*corev1.Pod)
+					newPod := e.ObjectNew.(*corev1.Pod)
+					return isPodReady(oldPod) != isPodReady(newPod) ||
+						oldPod.Status.PodIP != newPod.Status.PodIP ||
+						!reflect.DeepEqual(oldPod.Labels, newPod.Labels)
+				},
+			})).
+		Watches(&productionstackv1alpha1.VLLMRuntime{}, handler.EnqueueRequestsFromMapFunc(r.findLoraAdaptersForRuntime),
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Named("loraadapter").
+		Complete(r)
+}
+
+// findLoraAdaptersForPod finds all LoraAdapters that should be reconciled when a pod changes
+func (r *LoraAdapterReconciler) findLoraAdaptersForPod(ctx context.Context, pod client.Object) []reconcile.Request {
+	// Get all LoraAdapters
+	loraAdapters := &productionstackv1alpha1.LoraAdapterList{}
+	if err := r.List(ctx, loraAdapters); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range loraAdapters.Items {
+		adapter := &loraAdapters.Items[i]
+		if targetsNamespace(adapter) && adapter.Namespace != pod.GetNamespace() {
+			continue
+		}
+		// Adapters whose target cannot be resolved are reconciled periodically until it can
+		selector, err := r.vllmPodSelector(ctx, adapter)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(pod.GetLabels())) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      adapter.Name,
+					Namespace: adapter.Namespace,
+				},
+			})
+		}
+	}
+
+	return requests
+}
+
+// findLoraAdaptersForRuntime finds the LoraAdapters managed on the pods of a VLLMRuntime, whose
+// placement capacity and pods follow the runtime
+func (r *LoraAdapterReconciler) findLoraAdaptersForRuntime(ctx context.Context, obj client.Object) []reconcile.Request {
+	vllmRuntime, ok := obj.(*productionstackv1alpha1.VLLMRuntime)
+	if !ok {
+		return nil
+	}
+
+	loraAdapters := &productionstackv1alpha1.LoraAdapterList{}
+	if err := r.List(ctx, loraAdapters, client.InNamespace(vllmRuntime.Namespace)); err != nil {
+		return nil
+	}
+
+	servingLabels := labels.Set(servingLabelsForVLLMRuntime(vllmRuntime))
+	var requests []reconcile.Request
+	for i := range loraAdapters.Items {
+		adapter := &loraAdapters.Items[i]
+		var targeted bool
+		if adapter.Spec.VLLMRuntimeRef != nil {
+			targeted = adapter.Spec.VLLMRuntimeRef.Name == vllmRuntime.Name
+		} else if selector, err := r.vllmPodSelector(ctx, adapter); err == nil {
+			targeted = selector.Matches(servingLabels)
+		}
+		if targeted {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: adapter.Name, Namespace: adapter.Namespace},
+			})
+		}
+	}
+
+	return requests
+}
+
+// discoverAdapter discovers the adapter from its source location
+func (r *LoraAdapterReconciler) discoverAdapter(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace string) (string, error) {
+	source := adapter.Spec.AdapterSource
+
+	// A remote adapter is only downloaded again once its source changes
+	if downloadMatchesSource(adapter.Status.Download, source) {
+		return adapter.Status.Download.Path, nil
+	}
+
+	// Handle different source types
+	switch source.Type {
+	case "local":
+		if source.AdapterPath == "" {
+			return "", fmt.Errorf("local adapter source requires AdapterPath to be set")
+		}
+		adapter.Status.Download = nil
+		return source.AdapterPath, nil
+	case "s3":
+		return r.downloadS3Adapter(ctx, adapter, podName, namespace)
+	case "http":
+		return r.downloadHTTPAdapter(ctx, adapter, podName, namespace)
+	case "huggingface":
+		return r.downloadHuggingFaceAdapter(ctx, adapter, podName, namespace)
+	default:
+		return "", fmt.Errorf("unsupported adapter source type: %s", source.Type)
+	}
+}
+
+// downloadMatchesSource reports whether download was fetched from the repository and checksum
+// the source names
+func downloadMatchesSource(download *productionstackv1alpha1.AdapterDownload, source productionstackv1alpha1.AdapterSource) bool {
+	return download != nil && source.Type != "local" && source.Repository != nil &&
+		download.Repository == *source.Repository && download.SHA256 == source.SHA256
+}
+
+// downloadOutdated reports whether the pods serve an adapter downloaded from a source that the
+// spec no longer names
+func downloadOutdated(adapter *productionstackv1alpha1.LoraAdapter) bool {
+	return adapter.Spec.AdapterSource.Pattern == "" && adapter.Status.Download != nil &&
+		!downloadMatchesSource(adapter.Status.Download, adapter.Spec.AdapterSource)
+}
+
+// downloadHuggingFaceAdapter downloads a LoRA adapter from HuggingFace Hub
+func (r *LoraAdapterReconciler) downloadHuggingFaceAdapter(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace string) (string, error) {
+	logger := logf.Log.WithName("huggingface-download")
+
+	source := adapter.Spec.AdapterSource
+
+	// Validate required fields
+	if source.Repository == nil || *source.Repository == "" {
+		return "", fmt.Errorf("repository is required for huggingface adapter source")
+	}
+
+	// Download the adapter directly using command line tools
+	adapterPath := strings.ReplaceAll(source.AdapterName, "/", "-")
+
+	// Download using sidecar
+	payload := map[string]string{
+		"model_id":  *source.Repository,
+		"local_dir": adapterPath,
+	}
+	if err := r.addHuggingFaceToken(ctx, adapter, payload); err != nil {
+		return "", err
+	}
+
+	path, err := r.downloadWithSidecar(ctx, adapter, podName, namespace, "/model/download", payload)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("Successfully downloaded HuggingFace adapter", "adapter", source.AdapterName, "path", path)
+	return path, nil
+}
+
+// addHuggingFaceToken adds the HuggingFace token from the credentials secret to a sidecar payload.
+// Public repositories need no secret.
+func (r *LoraAdapterReconciler) addHuggingFaceToken(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, payload map[string]string) error {
+	secretRef := adapter.Spec.AdapterSource.CredentialsSecretRef
+	if secretRef == nil {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      secretRef.Name,
+		Namespace: adapter.Namespace,
+	}, secret); err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	token, ok := secret.Data[secretRef.Key]
+	if !ok {
+		return fmt.Errorf("secret does not contain key %s", secretRef.Key)
+	}
+	payload["token"] = string(token)
+	return nil
+}
+
+// downloadS3Adapter downloads a LoRA adapter from an S3-compatible object store
+func (r *LoraAdapterReconciler) downloadS3Adapter(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace string) (string, error) {
+	logger := logf.Log.WithName("s3-download")
+
+	source := adapter.Spec.AdapterSource
+
+	// Validate required fields
+	if source.Repository == nil || *source.Repository == "" {
+		return "", fmt.Errorf("repository is required for s3 adapter source")
+	}
+	bucket, prefix, err := parseS3Repository(*source.Repository)
+	if err != nil {
+		return "", err
+	}
+
+	payload := map[string]string{
+		"bucket":    bucket,
+		"prefix":    prefix,
+		"local_dir": strings.ReplaceAll(source.AdapterName, "/", "-"),
+	}
+	if err := r.addS3Options(ctx, adapter, payload); err != nil {
+		return "", err
+	}
+
+	path, err := r.downloadWithSidecar(ctx, adapter, podName, namespace, "/s3/download", payload)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("Successfully downloaded S3 adapter", "adapter", source.AdapterName, "bucket", bucket, "prefix", prefix, "path", path)
+	return path, nil
+}
+
+// addS3Options adds the endpoint, region and credentials of an s3 source to a sidecar payload
+func (r *LoraAdapterReconciler) addS3Options(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, payload map[string]string) error {
+	source := adapter.Spec.AdapterSource
+	if source.Endpoint != "" {
+		payload["endpoint_url"] = source.Endpoint
+	}
+	if source.Region != "" {
+		payload["region"] = source.Region
+	}
+
+	// Without a credentials secret the sidecar falls back to its own credential chain (e.g. IRSA)
+	if source.CredentialsSecretRef == nil {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      source.CredentialsSecretRef.Name,
+		Namespace: adapter.Namespace,
+	}, secret); err != nil {
+		return fmt.Errorf("failed to get secret: %w", err)
+	}
+
+	accessKeyID, ok := secret.Data["AWS_ACCESS_KEY_ID"]
+	if !ok {
+		return fmt.Errorf("secret does not contain key AWS_ACCESS_KEY_ID")
+	}
+	secretAccessKey, ok := secret.Data["AWS_SECRET_ACCESS_KEY"]
+	if !ok {
+		return fmt.Errorf("secret does not contain key AWS_SECRET_ACCESS_KEY")
+	}
+	payload["access_key_id"] = string(accessKeyID)
+	payload["secret_access_key"] = string(secretAccessKey)
+	if sessionToken, ok := secret.Data["AWS_SESSION_TOKEN"]; ok {
+		payload["session_token"] = string(sessionToken)
+	}
+	return nil
+}
+
+// parseS3Repository splits an s3 repository of the form s3://bucket/prefix or bucket/prefix
+func parseS3Repository(repository string) (string, string, error) {
+	trimmed := strings.TrimPrefix(repository, "s3://")
+	bucket, prefix, _ := strings.Cut(trimmed, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid s3 repository %q: missing bucket", repository)
+	}
+	return bucket, strings.Trim(prefix, "/"), nil
+}
+
+// downloadHTTPAdapter downloads a LoRA adapter tarball or directory index over HTTP(S)
+func (r *LoraAdapterReconciler) downloadHTTPAdapter(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace string) (string, error) {
+	logger := logf.Log.WithName("http-download")
+
+	source := adapter.Spec.AdapterSource
+
+	// Validate required fields
+	if source.Repository == nil || *source.Repository == "" {
+		return "", fmt.Errorf("repository is required for http adapter source")
+	}
+	repositoryURL, err := url.Parse(*source.Repository)
+	if err != nil || (repositoryURL.Scheme != "http" && repositoryURL.Scheme != "https") || repositoryURL.Host == "" {
+		return "", fmt.Errorf("invalid http repository %q: must be an http or https URL", *source.Repository)
+	}
+
+	payload := map[string]string{
+		"url":       *source.Repository,
+		"local_dir": strings.ReplaceAll(source.AdapterName, "/", "-"),
+	}
+	if source.SHA256 != "" {
+		payload["sha256"] = strings.ToLower(source.SHA256)
+	}
+
+	if source.CredentialsSecretRef != nil {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, types.NamespacedName{
+			Name:      source.CredentialsSecretRef.Name,
+			Namespace: adapter.Namespace,
+		}, secret); err != nil {
+			return "", fmt.Errorf("failed to get secret: %w", err)
+		}
+
+		if key := source.CredentialsSecretRef.Key; key != "" {
+			token, ok := secret.Data[key]
+			if !ok {
+				return "", fmt.Errorf("secret does not contain key %s", key)
+			}
+			payload["token"] = string(token)
+		} else {
+			username, ok := secret.Data["username"]
+			if !ok {
+				return "", fmt.Errorf("secret does not contain key username")
+			}
+			payload["username"] = string(username)
+			payload["password"] = string(secret.Data["password"])
+		}
+	}
+
+	path, err := r.downloadWithSidecar(ctx, adapter, podName, namespace, "/http/download", payload)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Info("Successfully downloaded HTTP adapter", "adapter", source.AdapterName, "url", *source.Repository, "path", path)
+	return path, nil
+}
+
+// sidecarDownloadResponse is the response returned by the downloader sidecar
+type sidecarDownloadResponse struct {
+	// Status is only reported by asynchronous downloads, synchronous ones complete before responding
+	Status          string `json:"status,omitempty"`
+	Path            string `json:"path"`
+	Message         string `json:"message,omitempty"`
+	DownloadedBytes int64  `json:"downloaded_bytes,omitempty"`
+	TotalBytes      int64  `json:"total_bytes,omitempty"`
+}
+
+// downloadInProgressError is returned while the sidecar is still fetching an adapter
+type downloadInProgressError struct {
+	progress string
+}
+
+func (e *downloadInProgressError) Error() string {
+	return fmt.Sprintf("adapter download in progress: %s", e.progress)
+}
+
+// downloadWithSidecar asks the downloader sidecar on the pod to fetch an adapter onto the
+// shared volume and returns its local path, setting the download of the adapter status
+func (r *LoraAdapterReconciler) downloadWithSidecar(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace, path string, payload map[string]string) (string, error) {
+	downloadedPath, err := r.requestSidecarDownload(ctx, adapter, podName, namespace, path, payload)
+	if err != nil {
+		return "", err
+	}
+
+	// The download is recorded once every pod serving the adapter has loaded it
+	source := adapter.Spec.AdapterSource
+	adapter.Status.Download = &productionstackv1alpha1.AdapterDownload{
+		Repository: *source.Repository,
+		SHA256:     source.SHA256,
+		Path:       downloadedPath,
+	}
+	r.setDownloadCondition(ctx, adapter, "True", "Downloaded", fmt.Sprintf("Adapter downloaded to %s", downloadedPath))
+
+	return downloadedPath, nil
+}
+
+// updateStatusWithDownload records the download of the adapter in its status
+func (r *LoraAdapterReconciler) updateStatusWithDownload(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, download *productionstackv1alpha1.AdapterDownload) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &productionstackv1alpha1.LoraAdapter{}
+		if err := r.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: adapter.Namespace}, latest); err != nil {
+			return err
+		}
+
+		if reflect.DeepEqual(latest.Status.Download, download) {
+			return nil // No update needed
+		}
+		latest.Status.Download = download
+
+		return r.Status().Update(ctx, latest)
+	})
+}
+
+// requestSidecarDownload asks the downloader sidecar on the pod to fetch an adapter onto the
+// shared volume and returns its local path, reporting failures and progress in the adapter status
+func (r *LoraAdapterReconciler) requestSidecarDownload(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace, path string, payload map[string]string) (string, error) {
+	endpoint, err := r.getPodEndpoint(ctx, podName, namespace, path, r.sidecarPort())
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod endpoint: %w", err)
+	}
+
+	body, err := r.sendRequest(ctx, "POST", endpoint, payload, adapter)
+	if err != nil {
+		err = fmt.Errorf("failed to download adapter: %w", err)
+		r.setDownloadCondition(ctx, adapter, "False", "DownloadFailed", err.Error())
+		return "", err
+	}
+
+	var response sidecarDownloadResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	if response.Status == "downloading" {
+		progress := fmt.Sprintf("downloaded %d bytes", response.DownloadedBytes)
+		if response.TotalBytes > 0 {
+			progress = fmt.Sprintf("downloaded %d of %d bytes (%d%%)",
+				response.DownloadedBytes, response.TotalBytes, response.DownloadedBytes*100/response.TotalBytes)
+		}
+		r.setDownloadCondition(ctx, adapter, "False", "Downloading", "Adapter download in progress: "+progress)
+		return "", &downloadInProgressError{progress: progress}
+	}
+
+	return response.Path, nil
+}
+
+// resolveAdapterNames returns the names of the adapters served by this LoraAdapter. With a
+// pattern set, the candidates are listed by the sidecar on the given pod and filtered here.
+func (r *LoraAdapterReconciler) resolveAdapterNames(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace string) ([]string, error) {
+	source := adapter.Spec.AdapterSource
+	if source.Pattern == "" {
+		return []string{source.AdapterName}, nil
+	}
+
+	payload := map[string]string{"source": source.Type}
+	switch source.Type {
+	case "local":
+		if source.AdapterPath == "" {
+			return nil, fmt.Errorf("local adapter source requires AdapterPath to be set")
+		}
+		payload["path"] = source.AdapterPath
+	case "s3":
+		if source.Repository == nil || *source.Repository == "" {
+			return nil, fmt.Errorf("repository is required for s3 adapter source")
+		}
+		bucket, prefix, err := parseS3Repository(*source.Repository)
+		if err != nil {
+			return nil, err
+		}
+		payload["bucket"] = bucket
+		payload["prefix"] = prefix
+		if err := r.addS3Options(ctx, adapter, payload); err != nil {
+			return nil, err
+		}
+	case "huggingface":
+		if source.Repository == nil || strings.Trim(*source.Repository, "/") == "" {
+			return nil, fmt.Errorf("repository is required for huggingface adapter source")
+		}
+		payload["organization"] = strings.Trim(*source.Repository, "/")
+		if err := r.addHuggingFaceToken(ctx, adapter, payload); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("pattern discovery is not supported for %s adapter sources", source.Type)
+	}
+
+	endpoint, err := r.getPodEndpoint(ctx, podName, namespace, "/adapters/list", r.sidecarPort())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod endpoint: %w", err)
+	}
+	body, err := r.sendRequest(ctx, "POST", endpoint, payload, adapter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list adapters: %w", err)
+	}
+
+	var response struct {
+		Adapters []string `json:"adapters"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	// Skip adapters another LoraAdapter already owns, so two overlapping patterns do not
+	// load and unload the same adapter in turn
+	claimed, err := r.adaptersClaimedByOthers(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for _, name := range response.Adapters {
+		if !claimed[name] {
+			candidates = append(candidates, name)
+		}
+	}
+
+	names, err := selectAdapters(source, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionTypeAdaptersDiscovered,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: adapter.Generation,
+		Reason:             "PatternMatched",
+		Message:            fmt.Sprintf("Selected %d of %d adapters matching %q", len(names), len(response.Adapters), source.Pattern),
+	}
+	if len(names) == 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NoMatchingAdapters"
+		condition.Message = fmt.Sprintf("None of %d adapters match %q", len(response.Adapters), source.Pattern)
+	}
+	if skipped := len(response.Adapters) - len(candidates); skipped > 0 {
+		condition.Message += fmt.Sprintf(", %d owned by other LoraAdapters", skipped)
+	}
+	if err := r.updateStatusWithSelection(ctx, adapter, names, condition); err != nil {
+		return nil, fmt.Errorf("failed to update discovery condition: %w", err)
+	}
+	adapter.Status.SelectedAdapters = names
+
+	return names, nil
+}
+
+// adaptersClaimedByOthers returns the adapter names owned by the other LoraAdapters in the namespace
+func (r *LoraAdapterReconciler) adaptersClaimedByOthers(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) (map[string]bool, error) {
+	var adapters productionstackv1alpha1.LoraAdapterList
+	if err := r.List(ctx, &adapters, client.InNamespace(adapter.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list LoraAdapters: %w", err)
+	}
+
+	claimed := make(map[string]bool)
+	for i := range adapters.Items {
+		if adapters.Items[i].Name == adapter.Name {
+			continue
+		}
+		for _, name := range ownedAdapterNames(&adapters.Items[i]) {
+			claimed[name] = true
+		}
+	}
+	return claimed, nil
+}
+
+// updateStatusWithSelection records the adapters selected through the pattern together with the discovery condition
+func (r *LoraAdapterReconciler) updateStatusWithSelection(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, names []string, condition metav1.Condition) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get the latest version of the LoraAdapter
+		latest := &productionstackv1alpha1.LoraAdapter{}
+		if err := r.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: adapter.Namespace}, latest); err != nil {
+			return err
+		}
+
+		conditionChanged := meta.SetStatusCondition(&latest.Status.Conditions, condition)
+		if !conditionChanged && slices.Equal(latest.Status.SelectedAdapters, names) {
+			return nil // No update needed
+		}
+		latest.Status.SelectedAdapters = names
+
+		return r.Status().Update(ctx, latest)
+	})
+}
+
+// selectAdapters filters candidate adapter names by the source pattern and keeps at most
+// MaxAdapters of them in name order
+func selectAdapters(source productionstackv1alpha1.AdapterSource, candidates []string) ([]string, error) {
+	var names []string
+	for _, name := range candidates {
+		matched, err := matchAdapterPattern(source.Pattern, name)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	if source.MaxAdapters > 0 && len(names) > int(source.MaxAdapters) {
+		names = names[:source.MaxAdapters]
+	}
+	return names, nil
+}
+
+// matchAdapterPattern reports whether name matches pattern, a glob or a regex: prefixed regular expression
+func matchAdapterPattern(pattern, name string) (bool, error) {
+	if expr, ok := strings.CutPrefix(pattern, "regex:"); ok {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return false, fmt.Errorf("invalid adapter pattern %q: %w", pattern, err)
+		}
+		return re.MatchString(name), nil
+	}
+
+	matched, err := path.Match(pattern, name)
+	if err != nil {
+		return false, fmt.Errorf("invalid adapter pattern %q: %w", pattern, err)
+	}
+	return matched, nil
+}
+
+// ownedAdapterNames returns the adapters a LoraAdapter owns. With a pattern these are the
+// adapters it selected and those it still has loaded, so that an adapter dropped from the
+// selection stays owned until it is unloaded.
+func ownedAdapterNames(adapter *productionstackv1alpha1.LoraAdapter) []string {
+	if adapter.Spec.AdapterSource.Pattern == "" {
+		return []string{adapter.Spec.AdapterSource.AdapterName}
+	}
+
+	names := slices.Clone(adapter.Status.SelectedAdapters)
+	for _, loaded := range adapter.Status.LoadedAdapters {
+		if !slices.Contains(names, loaded.Name) {
+			names = append(names, loaded.Name)
+		}
+	}
+	return names
+}
+
+// servesAdapter reports whether a vLLM adapter registration belongs to this LoraAdapter
+func servesAdapter(adapter *productionstackv1alpha1.LoraAdapter, name string) bool {
+	return slices.Contains(ownedAdapterNames(adapter), name)
+}
+
+// discoverPatternAdapter resolves the local path of an adapter discovered through
+// AdapterSource.Pattern, downloading it through the sidecar for remote sources
+func (r *LoraAdapterReconciler) discoverPatternAdapter(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, name, podName, namespace string) (string, error) {
+	source := adapter.Spec.AdapterSource
+
+	var downloadPath string
+	var payload map[string]string
+	switch source.Type {
+	case "local":
+		return path.Join(source.AdapterPath, name), nil
+	case "s3":
+		if source.Repository == nil || *source.Repository == "" {
+			return "", fmt.Errorf("repository is required for s3 adapter source")
+		}
+		bucket, prefix, err := parseS3Repository(*source.Repository)
+		if err != nil {
+			return "", err
+		}
+		downloadPath = "/s3/download"
+		payload = map[string]string{
+			"bucket":    bucket,
+			"prefix":    path.Join(prefix, name),
+			"local_dir": path.Join(adapter.Namespace, adapter.Name, name),
+		}
+		if err := r.addS3Options(ctx, adapter, payload); err != nil {
+			return "", err
+		}
+	case "huggingface":
+		if source.Repository == nil || strings.Trim(*source.Repository, "/") == "" {
+			return "", fmt.Errorf("repository is required for huggingface adapter source")
+		}
+		organization := strings.Trim(*source.Repository, "/")
+		downloadPath = "/model/download"
+		payload = map[string]string{
+			"model_id":  organization + "/" + name,
+			"local_dir": organization + "-" + name,
+		}
+		if err := r.addHuggingFaceToken(ctx, adapter, payload); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("pattern discovery is not supported for %s adapter sources", source.Type)
+	}
+
+	adapterPath, err := r.requestSidecarDownload(ctx, adapter, podName, namespace, downloadPath, payload)
+	if err != nil {
+		return "", err
+	}
+	r.setDownloadCondition(ctx, adapter, "True", "Downloaded", fmt.Sprintf("Adapter %s downloaded to %s", name, adapterPath))
+
+	return adapterPath, nil
+}
+
+// setDownloadCondition records the download state of the adapter, logging instead of failing
+// so that a status conflict does not mask the outcome of the download itself
+func (r *LoraAdapterReconciler) setDownloadCondition(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, status metav1.ConditionStatus, reason, message string) {
+	condition := metav1.Condition{
+		Type:               conditionTypeAdapterDownloaded,
+		Status:             status,
+		ObservedGeneration: adapter.Generation,
+		Reason:             reason,
+		Message:            message,
+	}
+	if err := r.updateStatusCondition(ctx, adapter, condition); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to update download condition",
+			"namespace", adapter.Namespace, "name", adapter.Name)
+	}
+}
+
+// updateStatusCondition sets a condition on the adapter status, replacing any existing condition of the same type
+func (r *LoraAdapterReconciler) updateStatusCondition(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, condition metav1.Condition) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get the latest version of the LoraAdapter
+		latest := &productionstackv1alpha1.LoraAdapter{}
+		if err := r.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: adapter.Namespace}, latest); err != nil {
+			return err
+		}
+
+		if !meta.SetStatusCondition(&latest.Status.Conditions, condition) {
+			return nil // No update needed
+		}
+
+		return r.Status().Update(ctx, latest)
+	})
+}
+
+// updateStatusWithPlacements records the placement decisions and whether every adapter was fully placed
+func (r *LoraAdapterReconciler) updateStatusWithPlacements(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, decisions []productionstackv1alpha1.AdapterPlacement) error {
+	condition := metav1.Condition{
+		Type:               conditionTypePlaced,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: adapter.Generation,
+		Reason:             "Placed",
+		Message:            fmt.Sprintf("Placed %d adapters using the %s algorithm", len(decisions), adapter.Spec.LoraAdapterDeploymentConfig.Algorithm),
+	}
+	for _, decision := range decisions {
+		if decision.Message != "" {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "InsufficientCapacity"
+			condition.Message = fmt.Sprintf("Adapter %s: %s", decision.Name, decision.Message)
+			break
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get the latest version of the LoraAdapter
+		latest := &productionstackv1alpha1.LoraAdapter{}
+		if err := r.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: adapter.Namespace}, latest); err != nil {
+			return fmt.Errorf("failed to get latest LoraAdapter: %w", err)
+		}
+
+		conditionChanged := meta.SetStatusCondition(&latest.Status.Conditions, condition)
+		if !conditionChanged && reflect.DeepEqual(latest.Status.Placements, decisions) {
+			return nil // No update needed
+		}
+		latest.Status.Placements = decisions
+
+		return r.Status().Update(ctx, latest)
+	})
+}
+
+// listVLLMPods lists the vLLM pods the adapter is managed on, selected by the referenced
+// VLLMRuntime, the pod selector or the model label
+func (r *LoraAdapterReconciler) listVLLMPods(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) ([]corev1.Pod, error) {
+	selector, err := r.vllmPodSelector(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a runtime reference or pod selector, get all vLLM pods in the cluster
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: selector}}
+	if targetsNamespace(adapter) {
+		opts = append(opts, client.InNamespace(adapter.Namespace))
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list vLLM pods: %w", err)
+	}
+	return pods.Items, nil
+}
+
+// vllmPodSelector returns the selector of the vLLM pods the adapter is managed on: the serving
+// labels of the referenced VLLMRuntime, the pod selector or the model label
+func (r *LoraAdapterReconciler) vllmPodSelector(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) (labels.Selector, error) {
+	switch {
+	case adapter.Spec.VLLMRuntimeRef != nil && adapter.Spec.PodSelector != nil:
+		return nil, fmt.Errorf("only one of vllmRuntimeRef and podSelector may be set")
+	case adapter.Spec.VLLMRuntimeRef != nil:
+		vllmRuntime, err := r.getVLLMRuntime(ctx, adapter)
+		if err != nil {
+			return nil, err
+		}
+		return labels.SelectorFromSet(servingLabelsForVLLMRuntime(vllmRuntime)), nil
+	case adapter.Spec.PodSelector != nil:
+		selector, err := metav1.LabelSelectorAsSelector(adapter.Spec.PodSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod selector: %w", err)
+		}
+		return selector, nil
+	default:
+		return labels.SelectorFromSet(labels.Set{"model": adapter.Spec.BaseModel}), nil
+	}
+}
+
+// targetsNamespace reports whether the adapter only manages pods in its own namespace, which
+// is the case unless it falls back to the model label
+func targetsNamespace(adapter *productionstackv1alpha1.LoraAdapter) bool {
+	return adapter.Spec.VLLMRuntimeRef != nil || adapter.Spec.PodSelector != nil
+}
+
+// getVLLMRuntime gets the VLLMRuntime referenced by the adapter
+func (r *LoraAdapterReconciler) getVLLMRuntime(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) (*productionstackv1alpha1.VLLMRuntime, error) {
+	vllmRuntime := &productionstackv1alpha1.VLLMRuntime{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Name:      adapter.Spec.VLLMRuntimeRef.Name,
+		Namespace: adapter.Namespace,
+	}, vllmRuntime); err != nil {
+		return nil, fmt.Errorf("failed to get VLLMRuntime %s: %w", adapter.Spec.VLLMRuntimeRef.Name, err)
+	}
+	return vllmRuntime, nil
+}
+
+// getVLLMPort returns the vLLM port of the referenced VLLMRuntime as the optional port argument
+// of getPodEndpoint, which otherwise finds the port on the pod
+func (r *LoraAdapterReconciler) getVLLMPort(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) ([]int, error) {
+	if adapter.Spec.VLLMRuntimeRef == nil {
+		return nil, nil
+	}
+	vllmRuntime, err := r.getVLLMRuntime(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+	if vllmRuntime.Spec.VLLMConfig.Port == 0 {
+		return nil, nil
+	}
+	return []int{int(vllmRuntime.Spec.VLLMConfig.Port)}, nil
+}
+
+// getReadyPods returns the ready vLLM pods the adapter is managed on, sorted by name
+func (r *LoraAdapterReconciler) getReadyPods(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) ([]corev1.Pod, error) {
+	pods, err := r.listVLLMPods(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter for pods that are ready
+	var validPods []corev1.Pod
+	for _, pod := range pods {
+		// Check if pod is ready by looking at pod conditions
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				validPods = append(validPods, pod)
+				break
+			}
+		}
+	}
+
+	// Sort validPods by their name
+	sort.Slice(validPods, func(i, j int) bool {
+		return validPods[i].Name < validPods[j].Name
+	})
+
+	return validPods, nil
+}
+
+// getOptimalPlacement determines the pods each adapter should be loaded on using the configured
+// algorithm, and records the decision in the adapter status
+func (r *LoraAdapterReconciler) getOptimalPlacement(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, pods []corev1.Pod, adapterNames []string) ([]PodPlacement, error) {
+	loads, err := r.getPodLoads(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Capacities come from the VLLMRuntime serving each pod, looked up once per pod controller
+	controllerCapacities := make(map[types.UID]int)
+	candidates := make([]*placementCandidate, 0, len(pods))
+	for _, pod := range pods {
+		var controllerUID types.UID
+		if ref := metav1.GetControllerOf(&pod); ref != nil {
+			controllerUID = ref.UID
 		}
-		logger.Info("Updated status with latest registrations",
-			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
-	} else {
-		logger.Info("No reconciliation needed",
-			"namespace", loraAdapter.Namespace, "name", loraAdapter.Name)
+		capacity, ok := controllerCapacities[controllerUID]
+		if !ok || controllerUID == "" {
+			vllmRuntime, err := r.getPodVLLMRuntime(ctx, adapter, &pod)
+			if err != nil {
+				return nil, err
+			}
+			capacity = loraCapacity(vllmRuntime)
+			controllerCapacities[controllerUID] = capacity
+		}
+		candidates = append(candidates, &placementCandidate{
+			pod:      pod,
+			load:     loads[pod.Namespace+"/"+pod.Name],
+			capacity: capacity,
+		})
 	}
 
-	// Schedule periodic reconciliation based on whether we have waiting adapters
-	logger.Info("Reconciliation loop completed")
-	return ctrl.Result{RequeueAfter: time.Second * 60}, nil
-}
+	// Determine number of pods to use
+	deploymentConfig := adapter.Spec.LoraAdapterDeploymentConfig
+	maxReplicas := len(pods)
+	if deploymentConfig.Replicas != nil {
+		maxReplicas = min(int(*deploymentConfig.Replicas), len(pods))
+	}
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *LoraAdapterReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&productionstackv1alpha1.LoraAdapter{}).
-		WithEventFilter(predicate.Funcs{
-			UpdateFunc: func(e event.UpdateEvent) bool {
-				oldAdapter := e.ObjectOld.(*productionstackv1alpha1.LoraAdapter)
-				newAdapter := e.ObjectNew.(*productionstackv1alpha1.LoraAdapter)
+	// Prefer pods that already serve an adapter so equal choices do not cause churn
+	current := make(map[string]bool)
+	currentReplicas := make(map[string]int)
+	for _, loaded := range adapter.Status.LoadedAdapters {
+		current[placementKey(loaded.PodAssignments.Namespace, loaded.PodAssignments.PodName, loaded.Name)] = true
+		currentReplicas[loaded.Name]++
+	}
 
-				// Always reconcile on deletion (when deletionTimestamp is set)
-				if !newAdapter.DeletionTimestamp.IsZero() {
-					return true
-				}
+	replicas := make(map[string]int, len(adapterNames))
+	for _, name := range adapterNames {
+		replicas[name] = maxReplicas
+	}
 
-				// Only reconcile on spec changes, not status changes
-				return !reflect.DeepEqual(oldAdapter.Spec, newAdapter.Spec)
-			},
-			CreateFunc: func(e event.CreateEvent) bool {
-				return true // Always reconcile on create
-			},
-			DeleteFunc: func(e event.DeleteEvent) bool {
-				return true // Always reconcile on delete
-			},
-		}).
-		Named("loraadapter").
-		Complete(r)
-}
+	if deploymentConfig.Algorithm == "usage" {
+		minReplicas := 1
+		if deploymentConfig.MinReplicas != nil {
+			minReplicas = int(*deploymentConfig.MinReplicas)
+		}
 
-// findLoraAdaptersForPod finds all LoraAdapters that should be reconciled when a pod changes
-func (r *LoraAdapterReconciler) findLoraAdaptersForPod(ctx context.Context, pod client.Object) []reconcile.Request {
-	// Check if the pod has the model label
-	modelName, hasModel := pod.GetLabels()["model"]
-	if !hasModel {
-		return nil
+		// Count the pods each adapter is busy on, pods that cannot be scraped count as idle
+		running := make(map[string]int)
+		waiting := make(map[string]int)
+		for _, candidate := range candidates {
+			metrics, err := r.getPodMetrics(ctx, adapter, &candidate.pod)
+			if err != nil {
+				logf.FromContext(ctx).Error(err, "Failed to scrape vLLM metrics", "pod", candidate.pod.Name, "namespace", candidate.pod.Namespace)
+				continue
+			}
+			candidate.kvCacheUsage = metrics.kvCacheUsage
+			for name := range metrics.runningAdapters {
+				running[name]++
+			}
+			for name := range metrics.waitingAdapters {
+				waiting[name]++
+			}
+		}
+
+		for _, name := range adapterNames {
+			replicas[name] = usageReplicas(currentReplicas[name], running[name], waiting[name], minReplicas, maxReplicas)
+		}
 	}
 
-	// Get all LoraAdapters
+	placements, decisions := placeAdapters(deploymentConfig.Algorithm, candidates, adapterNames, replicas, current)
+	if err := r.updateStatusWithPlacements(ctx, adapter, decisions); err != nil {
+		return nil, err
+	}
+
+	return placements, nil
+}
+
+// getPodLoads counts the adapters other LoraAdapters sharing the base model have loaded on each pod
+func (r *LoraAdapterReconciler) getPodLoads(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) (map[string]int, error) {
 	loraAdapters := &productionstackv1alpha1.LoraAdapterList{}
 	if err := r.List(ctx, loraAdapters); err != nil {
-		return nil
+		return nil, fmt.Errorf("failed to list LoraAdapters: %w", err)
 	}
 
-	var requests []reconcile.Request
-	for _, adapter := range loraAdapters.Items {
-		// If the pod's model matches the adapter's base model, add it to the requests
-		if adapter.Spec.BaseModel == modelName {
-			requests = append(requests, reconcile.Request{
-				NamespacedName: types.NamespacedName{
-					Name:      adapter.Name,
-					Namespace: adapter.Namespace,
-				},
-			})
+	loads := make(map[string]int)
+	for _, other := range loraAdapters.Items {
+		if other.Spec.BaseModel != adapter.Spec.BaseModel ||
+			(other.Namespace == adapter.Namespace && other.Name == adapter.Name) {
+			continue
+		}
+		for _, loaded := range other.Status.LoadedAdapters {
+			loads[loaded.PodAssignments.Namespace+"/"+loaded.PodAssignments.PodName]++
 		}
 	}
 
-	return requests
+	return loads, nil
 }
 
-// discoverAdapter discovers the adapter from its source location
-func (r *LoraAdapterReconciler) discoverAdapter(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace string) (string, error) {
-	source := adapter.Spec.AdapterSource
+// getPodVLLMRuntime returns the VLLMRuntime serving the pod, the referenced one or else the one
+// found by following the controllers of the pod, e.g. its ReplicaSet and Deployment. Returns nil
+// when the pod is not managed by a VLLMRuntime.
+func (r *LoraAdapterReconciler) getPodVLLMRuntime(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, pod *corev1.Pod) (*productionstackv1alpha1.VLLMRuntime, error) {
+	if adapter.Spec.VLLMRuntimeRef != nil {
+		return r.getVLLMRuntime(ctx, adapter)
+	}
 
-	// If path is already set, return it
-	if source.AdapterPath != "" {
-		return source.AdapterPath, nil
+	var owned client.Object = pod
+	for range runtimeOwnerDepth {
+		ref := metav1.GetControllerOf(owned)
+		if ref == nil {
+			return nil, nil
+		}
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return nil, nil
+		}
+		key := types.NamespacedName{Name: ref.Name, Namespace: pod.Namespace}
+
+		if gv.Group == productionstackv1alpha1.GroupVersion.Group && ref.Kind == "VLLMRuntime" {
+			vllmRuntime := &productionstackv1alpha1.VLLMRuntime{}
+			if err := r.Get(ctx, key, vllmRuntime); err != nil {
+				if errors.IsNotFound(err) {
+					return nil, nil
+				}
+				return nil, fmt.Errorf("failed to get VLLMRuntime %s: %w", ref.Name, err)
+			}
+			return vllmRuntime, nil
+		}
+
+		owner := &metav1.PartialObjectMetadata{}
+		owner.SetGroupVersionKind(gv.WithKind(ref.Kind))
+		if err := r.Get(ctx, key, owner); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to get %s %s: %w", ref.Kind, ref.Name, err)
+		}
+		owned = owner
 	}
+	return nil, nil
+}
 
-	// Handle different source types
-	switch source.Type {
-	case "local":
-		return "", fmt.Errorf("local adapter source requires AdapterPath to be set")
-	case "s3":
-		// TODO: Implement S3 discovery using credentials from CredentialsSecretRef
-		return "", fmt.Errorf("S3 adapter discovery not implemented yet")
-	case "http":
-		// TODO: Implement HTTP discovery
-		return "", fmt.Errorf("HTTP adapter discovery not implemented yet")
-	case "huggingface":
-		return r.downloadHuggingFaceAdapter(ctx, adapter, podName, namespace)
-	default:
-		return "", fmt.Errorf("unsupported adapter source type: %s", source.Type)
+// loraCapacity returns the number of adapters a pod of the VLLMRuntime holds without evicting
+// one, which vLLM bounds by --max-cpu-loras defaulting to --max-loras. Zero means unlimited.
+func loraCapacity(vllmRuntime *productionstackv1alpha1.VLLMRuntime) int {
+	if vllmRuntime == nil {
+		return 0
 	}
+	if vllmRuntime.Spec.VLLMConfig.MaxCPULoras > 0 {
+		return int(vllmRuntime.Spec.VLLMConfig.MaxCPULoras)
+	}
+	return int(vllmRuntime.Spec.VLLMConfig.MaxLoras)
 }
 
-// downloadHuggingFaceAdapter downloads a LoRA adapter from HuggingFace Hub
-func (r *LoraAdapterReconciler) downloadHuggingFaceAdapter(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, podName, namespace string) (string, error) {
-	logger := logf.Log.WithName("huggingface-download")
+// placementCandidate is a ready pod adapters can be placed on
+type placementCandidate struct {
+	pod corev1.Pod
+	// load is the number of adapters placed on the pod so far
+	load int
+	// capacity is the maximum number of adapters the pod can serve, zero when unlimited
+	capacity int
+	// kvCacheUsage is the fraction of the KV cache in use, only scraped for the usage algorithm
+	kvCacheUsage float64
+}
 
-	source := adapter.Spec.AdapterSource
+func (c *placementCandidate) full() bool {
+	return c.capacity > 0 && c.load >= c.capacity
+}
 
-	// Validate required fields
-	if source.Repository == nil || *source.Repository == "" {
-		return "", fmt.Errorf("repository is required for huggingface adapter source")
+// placeAdapters selects the requested number of pods for every adapter according to the
+// algorithm, skipping pods that have reached their capacity
+func placeAdapters(algorithm string, candidates []*placementCandidate, adapterNames []string, replicas map[string]int, current map[string]bool) ([]PodPlacement, []productionstackv1alpha1.AdapterPlacement) {
+	ordered := make([]*placementCandidate, len(candidates))
+	copy(ordered, candidates)
+
+	switch algorithm {
+	case "ordered":
+		// Fill the oldest pods first so placements stay put as the runtime scales out
+		sort.SliceStable(ordered, func(i, j int) bool {
+			a, b := ordered[i].pod, ordered[j].pod
+			if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+				return a.CreationTimestamp.Before(&b.CreationTimestamp)
+			}
+			return a.Name < b.Name
+		})
+	default:
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].pod.Name < ordered[j].pod.Name
+		})
 	}
 
-	// Download the adapter directly using command line tools
-	adapterPath := strings.ReplaceAll(source.AdapterName, "/", "-")
+	var placements []PodPlacement
+	var decisions []productionstackv1alpha1.AdapterPlacement
+	for _, name := range adapterNames {
+		if algorithm == "equalized" {
+			// Place on the least loaded pods, keeping the adapter where it is on ties
+			sort.SliceStable(ordered, func(i, j int) bool {
+				a, b := ordered[i], ordered[j]
+				if a.load != b.load {
+					return a.load < b.load
+				}
+				aCurrent := current[placementKey(a.pod.Namespace, a.pod.Name, name)]
+				bCurrent := current[placementKey(b.pod.Namespace, b.pod.Name, name)]
+				if aCurrent != bCurrent {
+					return aCurrent
+				}
+				return a.pod.Name < b.pod.Name
+			})
+		}
+		if algorithm == "usage" {
+			// Keep the adapter where it is loaded and add replicas on the pods with the most free KV cache
+			sort.SliceStable(ordered, func(i, j int) bool {
+				a, b := ordered[i], ordered[j]
+				aCurrent := current[placementKey(a.pod.Namespace, a.pod.Name, name)]
+				bCurrent := current[placementKey(b.pod.Namespace, b.pod.Name, name)]
+				if aCurrent != bCurrent {
+					return aCurrent
+				}
+				if a.kvCacheUsage != b.kvCacheUsage {
+					return a.kvCacheUsage < b.kvCacheUsage
+				}
+				if a.load != b.load {
+					return a.load < b.load
+				}
+				return a.pod.Name < b.pod.Name
+			})
+		}
 
-	// Get the HuggingFace token from the secret
-	secret := &corev1.Secret{}
-	if err := r.Get(ctx, types.NamespacedName{
-		Name:      source.CredentialsSecretRef.Name,
-		Namespace: adapter.Namespace,
-	}, secret); err != nil {
-		return "", fmt.Errorf("failed to get secret: %w", err)
+		decision := productionstackv1alpha1.AdapterPlacement{Name: name, DesiredReplicas: int32(replicas[name])}
+		for _, candidate := range ordered {
+			if len(decision.Pods) == replicas[name] {
+				break
+			}
+			if candidate.full() {
+				continue
+			}
+			candidate.load++
+			decision.Pods = append(decision.Pods, candidate.pod.Name)
+			placements = append(placements, PodPlacement{
+				PodName:     candidate.pod.Name,
+				Namespace:   candidate.pod.Namespace,
+				AdapterName: name,
+			})
+		}
+		if len(decision.Pods) < replicas[name] {
+			decision.Message = fmt.Sprintf("Placed on %d of %d requested pods, the remaining pods hold as many adapters as their VLLMRuntime keeps in CPU memory",
+				len(decision.Pods), replicas[name])
+		}
+		decisions = append(decisions, decision)
 	}
 
-	token, ok := secret.Data[source.CredentialsSecretRef.Key]
-	if !ok {
-		return "", fmt.Errorf("secret does not contain key %s", source.CredentialsSecretRef.Key)
-	}
+	return placements, decisions
+}
 
-	// Download using sidecar
-	endpoint, err := r.getPodEndpoint(ctx, podName, namespace, "/model/download", 30090)
-	if err != nil {
-		return "", fmt.Errorf("failed to get pod endpoint: %w", err)
-	}
+// usageReplicas sizes an adapter by the number of pods it has running or waiting requests on.
+// Pods with waiting requests ask for an extra replica, while scale down is limited to one
+// replica per reconciliation so a short lull does not unload a busy adapter everywhere.
+func usageReplicas(current, running, waiting, minReplicas, maxReplicas int) int {
+	desired := max(running+waiting, current-1)
+	return max(min(desired, maxReplicas), min(minReplicas, maxReplicas))
+}
 
-	payload := map[string]string{
-		"model_id":  *source.Repository,
-		"token":     string(token),
-		"local_dir": adapterPath,
-	}
+// podMetrics is the adapter related state scraped from the vLLM metrics endpoint of a pod
+type podMetrics struct {
+	// kvCacheUsage is the fraction of the KV cache in use, between 0 and 1
+	kvCacheUsage float64
+	// runningAdapters are the adapters with running requests
+	runningAdapters map[string]bool
+	// waitingAdapters are the adapters with waiting requests
+	waitingAdapters map[string]bool
+}
 
-	body, err := r.sendRequest(ctx, "POST", endpoint, payload, adapter)
+// getPodMetrics scrapes the vLLM metrics endpoint of a pod
+func (r *LoraAdapterReconciler) getPodMetrics(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, pod *corev1.Pod) (*podMetrics, error) {
+	port, err := r.getVLLMPort(ctx, adapter)
 	if err != nil {
-		return "", fmt.Errorf("failed to download adapter: %w", err)
+		return nil, err
 	}
-
-	// Update the adapter path in the spec
-	bodyMap := make(map[string]string)
-	if err := json.Unmarshal(body, &bodyMap); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response body: %w", err)
+	endpoint, err := podEndpoint(pod, "/metrics", port...)
+	if err != nil {
+		return nil, err
 	}
-	adapter.Spec.AdapterSource.AdapterPath = bodyMap["path"]
-	if err := r.Update(ctx, adapter); err != nil {
-		return "", fmt.Errorf("failed to update adapter path: %w", err)
+
+	body, err := r.sendRequest(ctx, "GET", endpoint, nil, adapter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics: %w", err)
 	}
 
-	logger.Info("Successfully downloaded HuggingFace adapter", "adapter", source.AdapterName, "path", adapter.Spec.AdapterSource.AdapterPath)
-	return adapter.Spec.AdapterSource.AdapterPath, nil
+	return parsePodMetrics(bytes.NewReader(body))
 }
 
-// GetOptimalPlacement determines the optimal pod placement for an adapter
-// TODO: Implement optimal placement logic, currently just returns all valid pods
-func (r *LoraAdapterReconciler) getOptimalPlacement(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) ([]PodPlacement, error) {
-	// Get all vLLM pods in the cluster
-	pods := &corev1.PodList{}
-	if err := r.List(ctx, pods, client.MatchingLabels{
-		"model": adapter.Spec.BaseModel,
-	}); err != nil {
-		return nil, fmt.Errorf("failed to list vLLM pods: %w", err)
-	}
-
-	// Filter for pods that are ready
-	var validPods []corev1.Pod
-	for _, pod := range pods.Items {
-		// Check if pod is ready by looking at pod conditions
-		for _, condition := range pod.Status.Conditions {
-			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
-				validPods = append(validPods, pod)
-				break
-			}
-		}
+// parsePodMetrics extracts the KV cache usage and the adapters with running or waiting requests
+// from vLLM metrics in the Prometheus text format
+func parsePodMetrics(in io.Reader) (*podMetrics, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
 	}
 
-	// If no pods are ready yet, return empty placement list instead of error
-	// This allows the controller to continue processing and wait for pods to become ready
-	if len(validPods) == 0 {
-		return []PodPlacement{}, nil
+	metrics := &podMetrics{
+		runningAdapters: make(map[string]bool),
+		waitingAdapters: make(map[string]bool),
 	}
 
-	// Sort validPods by their name
-	sort.Slice(validPods, func(i, j int) bool {
-		return validPods[i].Name < validPods[j].Name
-	})
-
-	// Determine number of pods to use
-	numPods := len(validPods)
-	if adapter.Spec.LoraAdapterDeploymentConfig.Replicas != nil {
-		replicas := int(*adapter.Spec.LoraAdapterDeploymentConfig.Replicas)
-		if replicas >= 0 {
-			numPods = min(replicas, len(validPods))
+	// Older vLLM versions report the KV cache usage as gpu_cache_usage_perc
+	for _, name := range []string{"vllm:kv_cache_usage_perc", "vllm:gpu_cache_usage_perc"} {
+		family, ok := families[name]
+		if !ok {
+			continue
 		}
+		for _, metric := range family.GetMetric() {
+			metrics.kvCacheUsage = math.Max(metrics.kvCacheUsage, metric.GetGauge().GetValue())
+		}
+		break
 	}
 
-	// Create placements
-	var placements []PodPlacement
-	for i := 0; i < numPods; i++ {
-		placements = append(placements, PodPlacement{
-			PodName:   validPods[i].Name,
-			Namespace: validPods[i].Namespace,
-		})
+	// lora_requests_info keeps one series per observed set of adapters, valued with the time it
+	// was observed, so only the most recent series describes the current requests
+	if family, ok := families["vllm:lora_requests_info"]; ok {
+		latest := -1.0
+		for _, metric := range family.GetMetric() {
+			if metric.GetGauge().GetValue() <= latest {
+				continue
+			}
+			latest = metric.GetGauge().GetValue()
+			clear(metrics.runningAdapters)
+			clear(metrics.waitingAdapters)
+			for _, label := range metric.GetLabel() {
+				var adapters map[string]bool
+				switch label.GetName() {
+				case "running_lora_adapters":
+					adapters = metrics.runningAdapters
+				case "waiting_lora_adapters":
+					adapters = metrics.waitingAdapters
+				default:
+					continue
+				}
+				for _, name := range strings.Split(label.GetValue(), ",") {
+					if name = strings.TrimSpace(name); name != "" {
+						adapters[name] = true
+					}
+				}
+			}
+		}
 	}
 
-	return placements, nil
+	return metrics, nil
 }
 
 // PodPlacement represents a pod where an adapter should be placed
 type PodPlacement struct {
 	PodName   string
 	Namespace string
+	// AdapterName is the name the adapter is served under on the pod
+	AdapterName string
+}
+
+// placementKey identifies an adapter on a specific pod
+func placementKey(namespace, podName, adapterName string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, podName, adapterName)
 }
 
 // getPodEndpoint gets the HTTP endpoint for a pod
@@ -426,21 +1521,26 @@ func (r *LoraAdapterReconciler) getPodEndpoint(ctx context.Context, podName, nam
 		return "", fmt.Errorf("failed to get pod: %w", err)
 	}
 
+	return podEndpoint(pod, path, port...)
+}
+
+// podEndpoint builds the HTTP endpoint for a path on a pod, using the vLLM container port
+// unless a port is provided. The vLLM container port is named container-port, or http in
+// pods created for a VLLMRuntime.
+func podEndpoint(pod *corev1.Pod, path string, port ...int) (string, error) {
 	// Get pod IP
 	if pod.Status.PodIP == "" {
-		return "", fmt.Errorf("pod %s has no IP address", podName)
+		return "", fmt.Errorf("pod %s has no IP address", pod.Name)
 	}
 
 	podPort := 0
 	// Get container port
 	if len(port) == 0 { // if no port is provided, use the default port
 		podPort = 8000 // default port
-		for _, container := range pod.Spec.Containers {
-			for _, containerPort := range container.Ports {
-				if containerPort.Name == "container-port" {
-					podPort = int(containerPort.ContainerPort)
-					break
-				}
+		for _, portName := range []string{"container-port", "http"} {
+			if namedPort := findContainerPort(pod, portName); namedPort != 0 {
+				podPort = namedPort
+				break
 			}
 		}
 	} else {
@@ -451,6 +1551,18 @@ func (r *LoraAdapterReconciler) getPodEndpoint(ctx context.Context, podName, nam
 	return fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, podPort, path), nil
 }
 
+// findContainerPort returns the number of the named container port of a pod, or zero if there is none
+func findContainerPort(pod *corev1.Pod, name string) int {
+	for _, container := range pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == name {
+				return int(containerPort.ContainerPort)
+			}
+		}
+	}
+	return 0
+}
+
 // sendRequest sends an HTTP request to the specified endpoint
 func (r *LoraAdapterReconciler) sendRequest(ctx context.Context, method, endpoint string, payload interface{}, adapter *productionstackv1alpha1.LoraAdapter) ([]byte, error) {
 	var bodyReader io.Reader
@@ -549,14 +1661,18 @@ func (r *LoraAdapterReconciler) getVLLMApiKey(ctx context.Context, adapter *prod
 }
 
 // loadAdapter loads a LoRA adapter on a specific pod
-func (r *LoraAdapterReconciler) loadAdapter(ctx context.Context, podName, namespace, adapterPath string, adapter *productionstackv1alpha1.LoraAdapter) error {
-	endpoint, err := r.getPodEndpoint(ctx, podName, namespace, "/v1/load_lora_adapter")
+func (r *LoraAdapterReconciler) loadAdapter(ctx context.Context, podName, namespace, loraName, adapterPath string, adapter *productionstackv1alpha1.LoraAdapter) error {
+	port, err := r.getVLLMPort(ctx, adapter)
+	if err != nil {
+		return err
+	}
+	endpoint, err := r.getPodEndpoint(ctx, podName, namespace, "/v1/load_lora_adapter", port...)
 	if err != nil {
 		return err
 	}
 
 	payload := map[string]string{
-		"lora_name": adapter.Spec.AdapterSource.AdapterName,
+		"lora_name": loraName,
 		"lora_path": adapterPath,
 	}
 
@@ -570,14 +1686,18 @@ func (r *LoraAdapterReconciler) loadAdapter(ctx context.Context, podName, namesp
 }
 
 // unloadAdapter unloads a LoRA adapter from a specific pod
-func (r *LoraAdapterReconciler) unloadAdapter(ctx context.Context, podName, namespace, adapterPath string, adapter *productionstackv1alpha1.LoraAdapter) error {
-	endpoint, err := r.getPodEndpoint(ctx, podName, namespace, "/v1/unload_lora_adapter")
+func (r *LoraAdapterReconciler) unloadAdapter(ctx context.Context, podName, namespace, loraName, adapterPath string, adapter *productionstackv1alpha1.LoraAdapter) error {
+	port, err := r.getVLLMPort(ctx, adapter)
+	if err != nil {
+		return err
+	}
+	endpoint, err := r.getPodEndpoint(ctx, podName, namespace, "/v1/unload_lora_adapter", port...)
 	if err != nil {
 		return err
 	}
 
 	payload := map[string]string{
-		"lora_name": adapter.Spec.AdapterSource.AdapterName,
+		"lora_name": loraName,
 	}
 
 	_, err = r.sendRequest(ctx, "POST", endpoint, payload, adapter)
@@ -612,15 +1732,19 @@ func (r *LoraAdapterReconciler) triggerPodEvent(ctx context.Context, podName, na
 // getAdapterRegistrations gets the current adapter registrations from all pods
 func (r *LoraAdapterReconciler) getAdapterRegistrations(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter) ([]productionstackv1alpha1.LoadedAdapter, error) {
 	// Get all vLLM pods
-	pods := &corev1.PodList{}
-	if err := r.List(ctx, pods, client.HasLabels{"model"}); err != nil {
-		return nil, fmt.Errorf("failed to list vLLM pods: %w", err)
+	pods, err := r.listVLLMPods(ctx, adapter)
+	if err != nil {
+		return nil, err
+	}
+	port, err := r.getVLLMPort(ctx, adapter)
+	if err != nil {
+		return nil, err
 	}
 	logger := logf.FromContext(ctx)
-	logger.Info("Found vLLM pods", "pods", len(pods.Items))
+	logger.Info("Found vLLM pods", "pods", len(pods))
 
 	var registrations []productionstackv1alpha1.LoadedAdapter
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
 		// Check if pod is ready by looking at pod conditions
 		podReady := false
 		for _, condition := range pod.Status.Conditions {
@@ -635,7 +1759,7 @@ func (r *LoraAdapterReconciler) getAdapterRegistrations(ctx context.Context, ada
 		}
 
 		// Get pod endpoint
-		endpoint, err := r.getPodEndpoint(ctx, pod.Name, pod.Namespace, "/v1/models")
+		endpoint, err := podEndpoint(&pod, "/v1/models", port...)
 		if err != nil {
 			logger.Error(err, "Failed to get pod endpoint", "pod", pod.Name, "namespace", pod.Namespace)
 			continue // Skip pods we can't reach
@@ -673,7 +1797,7 @@ func (r *LoraAdapterReconciler) getAdapterRegistrations(ctx context.Context, ada
 			if model.Parent == nil {
 				continue
 			}
-			if model.ID != adapter.Spec.AdapterSource.AdapterName {
+			if !servesAdapter(adapter, model.ID) {
 				continue
 			}
 
@@ -710,14 +1834,29 @@ func (r *LoraAdapterReconciler) updateStatusWithRegistrations(ctx context.Contex
 		adapter.Status.LoadedAdapters = registrations
 		adapter.Status.ObservedGeneration = adapter.Generation
 
-		// Clear any waiting conditions since we now have registrations
-		var updatedConditions []productionstackv1alpha1.Condition
-		for _, condition := range adapter.Status.Conditions {
-			if condition.Type != "WaitingForPods" {
-				updatedConditions = append(updatedConditions, condition)
-			}
+		if len(registrations) > 0 {
+			adapter.Status.Phase = phaseLoaded
+			meta.SetStatusCondition(&adapter.Status.Conditions, metav1.Condition{
+				Type:               conditionTypeAvailable,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: adapter.Generation,
+				Reason:             "AdaptersLoaded",
+				Message:            fmt.Sprintf("%d adapter registrations loaded", len(registrations)),
+			})
+		} else if !meta.IsStatusConditionFalse(adapter.Status.Conditions, conditionTypeAvailable) {
+			// Keep a more specific reason, such as waiting for pods, set by an earlier reconcile
+			adapter.Status.Phase = phasePending
+			meta.SetStatusCondition(&adapter.Status.Conditions, metav1.Condition{
+				Type:               conditionTypeAvailable,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: adapter.Generation,
+				Reason:             "NoAdaptersLoaded",
+				Message:            "The adapter is not loaded on any pod",
+			})
 		}
-		adapter.Status.Conditions = updatedConditions
+
+		// Clear any waiting conditions since we now have registrations
+		meta.RemoveStatusCondition(&adapter.Status.Conditions, conditionTypeWaitingForPods)
 
 		// Try to update the status
 		updateErr = r.Status().Update(ctx, adapter)
@@ -750,45 +1889,31 @@ func (r *LoraAdapterReconciler) updateStatusWithWaitingState(ctx context.Context
 			return fmt.Errorf("failed to get latest LoraAdapter: %w", err)
 		}
 
-		// Check if we already have the waiting condition with the same message
-		hasWaitingCondition := false
-		for _, condition := range adapter.Status.Conditions {
-			if condition.Type == "WaitingForPods" && condition.Status == "True" && condition.Message == message {
-				hasWaitingCondition = true
-				break
-			}
-		}
-
-		// Only update if we don't already have this condition
-		if hasWaitingCondition {
-			return nil // No update needed
-		}
-
 		// Update status to reflect waiting state
-		adapter.Status.LoadedAdapters = []productionstackv1alpha1.LoadedAdapter{}
-		adapter.Status.ObservedGeneration = adapter.Generation
-
-		// Add a condition to indicate waiting state
-		waitingCondition := productionstackv1alpha1.Condition{
-			Type:               "WaitingForPods",
-			Status:             "True",
-			LastTransitionTime: metav1.Now(),
+		waitingCondition := metav1.Condition{
+			Type:               conditionTypeWaitingForPods,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: adapter.Generation,
 			Reason:             "NoReadyPods",
 			Message:            message,
 		}
-
-		// Update or add the condition
-		found := false
-		for i, condition := range adapter.Status.Conditions {
-			if condition.Type == "WaitingForPods" {
-				adapter.Status.Conditions[i] = waitingCondition
-				found = true
-				break
-			}
+		available := metav1.Condition{
+			Type:               conditionTypeAvailable,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: adapter.Generation,
+			Reason:             "NoReadyPods",
+			Message:            message,
 		}
-		if !found {
-			adapter.Status.Conditions = append(adapter.Status.Conditions, waitingCondition)
+		changed := meta.SetStatusCondition(&adapter.Status.Conditions, waitingCondition)
+		changed = meta.SetStatusCondition(&adapter.Status.Conditions, available) || changed
+
+		// Only update if the conditions changed
+		if !changed && adapter.Status.Phase == phasePending && len(adapter.Status.LoadedAdapters) == 0 {
+			return nil // No update needed
 		}
+		adapter.Status.LoadedAdapters = []productionstackv1alpha1.LoadedAdapter{}
+		adapter.Status.ObservedGeneration = adapter.Generation
+		adapter.Status.Phase = phasePending
 
 		// Try to update the status
 		updateErr = r.Status().Update(ctx, adapter)
@@ -819,13 +1944,12 @@ func (r *LoraAdapterReconciler) compareStates(currentRegistrations []productions
 	// Build map of desired placements for efficient lookup
 	desiredMap := make(map[string]bool)
 	for _, placement := range desiredPlacements {
-		key := fmt.Sprintf("%s/%s", placement.Namespace, placement.PodName)
-		desiredMap[key] = true
+		desiredMap[placementKey(placement.Namespace, placement.PodName, placement.AdapterName)] = true
 	}
 
 	// Check if each current registration matches a desired placement
 	for _, reg := range currentRegistrations {
-		key := fmt.Sprintf("%s/%s", reg.PodAssignments.Namespace, reg.PodAssignments.PodName)
+		key := placementKey(reg.PodAssignments.Namespace, reg.PodAssignments.PodName, reg.Name)
 		if !desiredMap[key] {
 			return true, nil
 		}
@@ -844,38 +1968,60 @@ func (r *LoraAdapterReconciler) reconcileToDesiredState(ctx context.Context, ada
 	desiredMap := make(map[string]bool)
 
 	for _, reg := range currentRegistrations {
-		if reg.Name != adapter.Spec.AdapterSource.AdapterName {
-			continue
-		}
-		key := fmt.Sprintf("%s/%s", reg.PodAssignments.Namespace, reg.PodAssignments.PodName)
-		currentMap[key] = reg
+		currentMap[placementKey(reg.PodAssignments.Namespace, reg.PodAssignments.PodName, reg.Name)] = reg
 	}
 
 	for _, placement := range desiredPlacements {
-		key := fmt.Sprintf("%s/%s", placement.Namespace, placement.PodName)
-		desiredMap[key] = true
+		desiredMap[placementKey(placement.Namespace, placement.PodName, placement.AdapterName)] = true
 	}
 
+	// Pods serving an adapter downloaded from a previous source load the new download instead
+	outdated := downloadOutdated(adapter)
+
 	// Load adapters on missing pods
 	for _, placement := range desiredPlacements {
-		key := fmt.Sprintf("%s/%s", placement.Namespace, placement.PodName)
-		if _, exists := currentMap[key]; !exists {
-			logger.Info("Loading adapter on pod", "pod", placement.PodName, "namespace", placement.Namespace)
-			adapterPath, err := r.discoverAdapter(ctx, adapter, placement.PodName, placement.Namespace)
-			if err != nil {
-				return fmt.Errorf("failed to discover adapter: %w", err)
-			}
-			if err := r.loadAdapter(ctx, placement.PodName, placement.Namespace, adapterPath, adapter); err != nil {
-				return fmt.Errorf("failed to load adapter on pod %s: %w", placement.PodName, err)
+		key := placementKey(placement.Namespace, placement.PodName, placement.AdapterName)
+		reg, exists := currentMap[key]
+		if exists && !outdated {
+			continue
+		}
+
+		var adapterPath string
+		var err error
+		if adapter.Spec.AdapterSource.Pattern != "" {
+			adapterPath, err = r.discoverPatternAdapter(ctx, adapter, placement.AdapterName, placement.PodName, placement.Namespace)
+		} else {
+			adapterPath, err = r.discoverAdapter(ctx, adapter, placement.PodName, placement.Namespace)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to discover adapter: %w", err)
+		}
+
+		if exists {
+			// vLLM rejects loading an adapter name it already serves
+			logger.Info("Reloading adapter downloaded from a previous source", "adapter", reg.Name, "pod", placement.PodName, "namespace", placement.Namespace)
+			if err := r.unloadAdapter(ctx, placement.PodName, placement.Namespace, reg.Name, reg.Path, adapter); err != nil {
+				return fmt.Errorf("failed to unload adapter from pod %s: %w", placement.PodName, err)
 			}
+		} else {
+			logger.Info("Loading adapter on pod", "adapter", placement.AdapterName, "pod", placement.PodName, "namespace", placement.Namespace)
+		}
+		if err := r.loadAdapter(ctx, placement.PodName, placement.Namespace, placement.AdapterName, adapterPath, adapter); err != nil {
+			return fmt.Errorf("failed to load adapter on pod %s: %w", placement.PodName, err)
+		}
+	}
+
+	if adapter.Spec.AdapterSource.Pattern == "" {
+		if err := r.updateStatusWithDownload(ctx, adapter, adapter.Status.Download); err != nil {
+			return fmt.Errorf("failed to record adapter download: %w", err)
 		}
 	}
 
 	// Unload adapters from pods that shouldn't have them
 	for key, reg := range currentMap {
 		if !desiredMap[key] {
-			logger.Info("Unloading adapter from pod", "pod", reg.PodAssignments.PodName, "namespace", reg.PodAssignments.Namespace)
-			if err := r.unloadAdapter(ctx, reg.PodAssignments.PodName, reg.PodAssignments.Namespace, reg.Path, adapter); err != nil {
+			logger.Info("Unloading adapter from pod", "adapter", reg.Name, "pod", reg.PodAssignments.PodName, "namespace", reg.PodAssignments.Namespace)
+			if err := r.unloadAdapter(ctx, reg.PodAssignments.PodName, reg.PodAssignments.Namespace, reg.Name, reg.Path, adapter); err != nil {
 				return fmt.Errorf("failed to unload adapter from pod %s: %w", reg.PodAssignments.PodName, err)
 			}
 		}
@@ -896,21 +2042,21 @@ func (r *LoraAdapterReconciler) handleDeletion(ctx context.Context, adapter *pro
 		return fmt.Errorf("failed to get current registrations: %w", err)
 	}
 
-	// Unload adapter from all pods where it's currently loaded
+	// Unload the adapters from all pods where they're currently loaded
 	for _, reg := range currentRegistrations {
-		if reg.Name == adapter.Spec.AdapterSource.AdapterName {
-			logger.Info("Unloading adapter from pod",
-				"pod", reg.PodAssignments.PodName,
-				"namespace", reg.PodAssignments.Namespace)
-
-			if err := r.unloadAdapter(ctx,
-				reg.PodAssignments.PodName,
-				reg.PodAssignments.Namespace,
-				reg.Path,
-				adapter); err != nil {
-				return fmt.Errorf("failed to unload adapter from pod %s: %w",
-					reg.PodAssignments.PodName, err)
-			}
+		logger.Info("Unloading adapter from pod",
+			"adapter", reg.Name,
+			"pod", reg.PodAssignments.PodName,
+			"namespace", reg.PodAssignments.Namespace)
+
+		if err := r.unloadAdapter(ctx,
+			reg.PodAssignments.PodName,
+			reg.PodAssignments.Namespace,
+			reg.Name,
+			reg.Path,
+			adapter); err != nil {
+			return fmt.Errorf("failed to unload adapter from pod %s: %w",
+				reg.PodAssignments.PodName, err)
 		}
 	}
 
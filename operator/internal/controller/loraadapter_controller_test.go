@@ -18,16 +18,29 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	productionstackv1alpha1 "production-stack/api/v1alpha1"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -275,6 +288,823 @@ var _ = Describe("LoraAdapterReconciler", func() {
 	})
 })
 
+var _ = Describe("S3 adapter source", func() {
+	It("Should parse s3 repositories into bucket and prefix", func() {
+		bucket, prefix, err := parseS3Repository("s3://adapters/customers/acme/")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bucket).To(Equal("adapters"))
+		Expect(prefix).To(Equal("customers/acme"))
+
+		bucket, prefix, err = parseS3Repository("adapters")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bucket).To(Equal("adapters"))
+		Expect(prefix).To(BeEmpty())
+
+		_, _, err = parseS3Repository("s3:///acme")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should download the adapter through the sidecar using the credentials secret", func() {
+		ctx := context.Background()
+		namespace := "default"
+
+		By("Starting a fake downloader sidecar")
+		var received map[string]string
+		sidecar := startFakeSidecar(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.URL.Path).To(Equal("/s3/download"))
+			Expect(json.NewDecoder(req.Body).Decode(&received)).To(Succeed())
+			Expect(json.NewEncoder(w).Encode(map[string]string{
+				"path": "/data/lora-adapters/" + received["local_dir"],
+			})).To(Succeed())
+		})
+		defer sidecar.Close()
+
+		By("Creating the credentials secret")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "minio-credentials", Namespace: namespace},
+			StringData: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "minioadmin",
+				"AWS_SECRET_ACCESS_KEY": "minioadmin-secret",
+			},
+		}
+		Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, secret)).Should(Succeed()) }()
+
+		By("Creating a pod reachable on localhost")
+		pod := createLocalhostPod(ctx, "s3-test-pod", namespace)
+		defer func() { Expect(k8sClient.Delete(ctx, pod)).Should(Succeed()) }()
+
+		By("Creating an s3 LoraAdapter")
+		adapter := &productionstackv1alpha1.LoraAdapter{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3-adapter", Namespace: namespace},
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				BaseModel: "llama-3-1-8b",
+				AdapterSource: productionstackv1alpha1.AdapterSource{
+					Type:                 "s3",
+					AdapterName:          "acme/support",
+					Repository:           stringPtr("s3://adapters/customers/acme"),
+					Endpoint:             "http://minio.minio.svc:9000",
+					Region:               "us-east-1",
+					CredentialsSecretRef: &productionstackv1alpha1.SecretRef{Name: "minio-credentials"},
+				},
+				LoraAdapterDeploymentConfig: productionstackv1alpha1.LoraAdapterDeploymentConfig{
+					Algorithm: "default",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, adapter)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, adapter)).Should(Succeed()) }()
+
+		s3Reconciler := &LoraAdapterReconciler{
+			Client:      k8sClient,
+			Scheme:      k8sClient.Scheme(),
+			SidecarPort: sidecarPortOf(sidecar),
+		}
+		path, err := s3Reconciler.discoverAdapter(ctx, adapter, pod.Name, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal("/data/lora-adapters/acme-support"))
+
+		By("Verifying the sidecar received the bucket, endpoint and credentials")
+		Expect(received).To(HaveKeyWithValue("bucket", "adapters"))
+		Expect(received).To(HaveKeyWithValue("prefix", "customers/acme"))
+		Expect(received).To(HaveKeyWithValue("endpoint_url", "http://minio.minio.svc:9000"))
+		Expect(received).To(HaveKeyWithValue("region", "us-east-1"))
+		Expect(received).To(HaveKeyWithValue("access_key_id", "minioadmin"))
+		Expect(received).To(HaveKeyWithValue("secret_access_key", "minioadmin-secret"))
+		Expect(received).ToNot(HaveKey("session_token"))
+
+		By("Verifying the download was recorded with its source")
+		Expect(adapter.Status.Download).To(Equal(&productionstackv1alpha1.AdapterDownload{
+			Repository: "s3://adapters/customers/acme",
+			Path:       "/data/lora-adapters/acme-support",
+		}))
+	})
+})
+
+var _ = Describe("HTTP adapter source", func() {
+	It("Should report download progress and record the path once the sidecar finishes", func() {
+		ctx := context.Background()
+		namespace := "default"
+
+		By("Starting a fake downloader sidecar that completes on the second poll")
+		var received map[string]string
+		polls := 0
+		sidecar := startFakeSidecar(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.URL.Path).To(Equal("/http/download"))
+			Expect(json.NewDecoder(req.Body).Decode(&received)).To(Succeed())
+			polls++
+			response := map[string]interface{}{
+				"status":           "downloading",
+				"path":             "/data/lora-adapters/" + received["local_dir"],
+				"downloaded_bytes": 50,
+				"total_bytes":      200,
+			}
+			if polls > 1 {
+				response["status"] = "completed"
+				response["downloaded_bytes"] = 200
+			}
+			Expect(json.NewEncoder(w).Encode(response)).To(Succeed())
+		})
+		defer sidecar.Close()
+
+		By("Creating the bearer token secret")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "artifact-token", Namespace: namespace},
+			StringData: map[string]string{"token": "s3cr3t"},
+		}
+		Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, secret)).Should(Succeed()) }()
+
+		pod := createLocalhostPod(ctx, "http-test-pod", namespace)
+		defer func() { Expect(k8sClient.Delete(ctx, pod)).Should(Succeed()) }()
+
+		checksum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+		adapter := &productionstackv1alpha1.LoraAdapter{
+			ObjectMeta: metav1.ObjectMeta{Name: "http-adapter", Namespace: namespace},
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				BaseModel: "llama-3-1-8b",
+				AdapterSource: productionstackv1alpha1.AdapterSource{
+					Type:                 "http",
+					AdapterName:          "sql-lora",
+					Repository:           stringPtr("https://artifacts.example.com/adapters/sql-lora.tar.gz"),
+					SHA256:               checksum,
+					CredentialsSecretRef: &productionstackv1alpha1.SecretRef{Name: "artifact-token", Key: "token"},
+				},
+				LoraAdapterDeploymentConfig: productionstackv1alpha1.LoraAdapterDeploymentConfig{
+					Algorithm: "default",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, adapter)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, adapter)).Should(Succeed()) }()
+
+		httpReconciler := &LoraAdapterReconciler{
+			Client:      k8sClient,
+			Scheme:      k8sClient.Scheme(),
+			SidecarPort: sidecarPortOf(sidecar),
+		}
+
+		By("Polling while the download is in progress")
+		_, err := httpReconciler.discoverAdapter(ctx, adapter, pod.Name, namespace)
+		var inProgress *downloadInProgressError
+		Expect(errors.As(err, &inProgress)).To(BeTrue())
+		Expect(received).To(HaveKeyWithValue("url", "https://artifacts.example.com/adapters/sql-lora.tar.gz"))
+		Expect(received).To(HaveKeyWithValue("sha256", checksum))
+		Expect(received).To(HaveKeyWithValue("token", "s3cr3t"))
+
+		condition := getAdapterCondition(ctx, adapter, conditionTypeAdapterDownloaded)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("Downloading"))
+		Expect(condition.Message).To(ContainSubstring("downloaded 50 of 200 bytes (25%)"))
+
+		By("Polling again once the download has completed")
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: namespace}, adapter)).To(Succeed())
+		path, err := httpReconciler.discoverAdapter(ctx, adapter, pod.Name, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal("/data/lora-adapters/sql-lora"))
+
+		condition = getAdapterCondition(ctx, adapter, conditionTypeAdapterDownloaded)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("Downloaded"))
+	})
+
+	It("Should download and reload the adapter once its checksum changes", func() {
+		ctx := context.Background()
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(productionstackv1alpha1.AddToScheme(s)).To(Succeed())
+
+		By("Serving the sidecar and vLLM from one fake server")
+		var requests []string
+		var received map[string]string
+		server := startFakeSidecar(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			requests = append(requests, req.URL.Path)
+			Expect(json.NewDecoder(req.Body).Decode(&received)).To(Succeed())
+			if req.URL.Path == "/http/download" {
+				Expect(json.NewEncoder(w).Encode(map[string]string{
+					"status": "completed",
+					"path":   "/data/lora-adapters/" + received["local_dir"],
+				})).To(Succeed())
+			}
+		})
+		defer server.Close()
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "http-reload-pod", Namespace: "default"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name:  "vllm",
+				Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: int32(sidecarPortOf(server))}},
+			}}},
+			Status: corev1.PodStatus{PodIP: "127.0.0.1"},
+		}
+		repository := "https://artifacts.example.com/adapters/sql-lora.tar.gz"
+		checksum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+		adapter := &productionstackv1alpha1.LoraAdapter{
+			ObjectMeta: metav1.ObjectMeta{Name: "http-reload-adapter", Namespace: "default"},
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				BaseModel: "llama-3-1-8b",
+				AdapterSource: productionstackv1alpha1.AdapterSource{
+					Type:        "http",
+					AdapterName: "sql-lora",
+					Repository:  &repository,
+					SHA256:      checksum,
+				},
+			},
+			Status: productionstackv1alpha1.LoraAdapterStatus{
+				Download: &productionstackv1alpha1.AdapterDownload{
+					Repository: repository,
+					SHA256:     strings.Repeat("0", 64),
+					Path:       "/data/lora-adapters/sql-lora",
+				},
+			},
+		}
+		reloadReconciler := &LoraAdapterReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(pod, adapter).
+				WithStatusSubresource(adapter).Build(),
+			Scheme:      s,
+			SidecarPort: sidecarPortOf(server),
+		}
+		loaded := []productionstackv1alpha1.LoadedAdapter{{
+			Name:           "sql-lora",
+			Path:           "/data/lora-adapters/sql-lora",
+			PodAssignments: productionstackv1alpha1.PodAssignment{PodName: pod.Name, Namespace: pod.Namespace},
+		}}
+		placements := []PodPlacement{{PodName: pod.Name, Namespace: pod.Namespace, AdapterName: "sql-lora"}}
+
+		By("Downloading the new tarball before replacing the loaded adapter")
+		Expect(downloadOutdated(adapter)).To(BeTrue())
+		Expect(reloadReconciler.reconcileToDesiredState(ctx, adapter, loaded, placements)).To(Succeed())
+		Expect(requests).To(Equal([]string{"/http/download", "/v1/unload_lora_adapter", "/v1/load_lora_adapter"}))
+
+		var latest productionstackv1alpha1.LoraAdapter
+		Expect(reloadReconciler.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: adapter.Namespace}, &latest)).To(Succeed())
+		Expect(latest.Status.Download).To(Equal(&productionstackv1alpha1.AdapterDownload{
+			Repository: repository,
+			SHA256:     checksum,
+			Path:       "/data/lora-adapters/sql-lora",
+		}))
+
+		By("Leaving the adapter alone once the download matches its source")
+		requests = nil
+		Expect(downloadOutdated(&latest)).To(BeFalse())
+		Expect(reloadReconciler.reconcileToDesiredState(ctx, &latest, loaded, placements)).To(Succeed())
+		Expect(requests).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Pattern adapter discovery", func() {
+	It("Should select adapters matching a glob or regular expression up to MaxAdapters", func() {
+		candidates := []string{"customer-c", "customer-a", "internal", "customer-b"}
+
+		names, err := selectAdapters(productionstackv1alpha1.AdapterSource{Pattern: "customer-*"}, candidates)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(Equal([]string{"customer-a", "customer-b", "customer-c"}))
+
+		names, err = selectAdapters(productionstackv1alpha1.AdapterSource{Pattern: "regex:customer-[ab]", MaxAdapters: 1}, candidates)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(Equal([]string{"customer-a"}))
+
+		// Regular expressions must match the whole name
+		names, err = selectAdapters(productionstackv1alpha1.AdapterSource{Pattern: "regex:custom"}, candidates)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(BeEmpty())
+
+		_, err = selectAdapters(productionstackv1alpha1.AdapterSource{Pattern: "regex:("}, candidates)
+		Expect(err).To(HaveOccurred())
+		_, err = selectAdapters(productionstackv1alpha1.AdapterSource{Pattern: "[a-"}, candidates)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should list s3 adapters through the sidecar and report the matches", func() {
+		ctx := context.Background()
+		namespace := "default"
+
+		By("Starting a fake downloader sidecar")
+		var received map[string]string
+		sidecar := startFakeSidecar(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.URL.Path).To(Equal("/adapters/list"))
+			Expect(json.NewDecoder(req.Body).Decode(&received)).To(Succeed())
+			Expect(json.NewEncoder(w).Encode(map[string][]string{
+				"adapters": {"customer-a", "customer-b", "customer-c", "shared"},
+			})).To(Succeed())
+		})
+		defer sidecar.Close()
+
+		pod := createLocalhostPod(ctx, "pattern-test-pod", namespace)
+		defer func() { Expect(k8sClient.Delete(ctx, pod)).Should(Succeed()) }()
+
+		adapter := &productionstackv1alpha1.LoraAdapter{
+			ObjectMeta: metav1.ObjectMeta{Name: "pattern-adapter", Namespace: namespace},
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				BaseModel: "llama-3-1-8b",
+				AdapterSource: productionstackv1alpha1.AdapterSource{
+					Type:        "s3",
+					AdapterName: "customers",
+					Repository:  stringPtr("s3://lora-adapters/customers"),
+					Pattern:     "customer-*",
+					MaxAdapters: 2,
+				},
+				LoraAdapterDeploymentConfig: productionstackv1alpha1.LoraAdapterDeploymentConfig{
+					Algorithm: "default",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, adapter)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, adapter)).Should(Succeed()) }()
+
+		patternReconciler := &LoraAdapterReconciler{
+			Client:      k8sClient,
+			Scheme:      k8sClient.Scheme(),
+			SidecarPort: sidecarPortOf(sidecar),
+		}
+
+		names, err := patternReconciler.resolveAdapterNames(ctx, adapter, pod.Name, namespace)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names).To(Equal([]string{"customer-a", "customer-b"}))
+		Expect(received).To(HaveKeyWithValue("source", "s3"))
+		Expect(received).To(HaveKeyWithValue("bucket", "lora-adapters"))
+		Expect(received).To(HaveKeyWithValue("prefix", "customers"))
+
+		condition := getAdapterCondition(ctx, adapter, conditionTypeAdaptersDiscovered)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Message).To(Equal(`Selected 2 of 4 adapters matching "customer-*"`))
+
+		By("Verifying the selection was recorded as owned by the adapter")
+		var updatedAdapter productionstackv1alpha1.LoraAdapter
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: namespace}, &updatedAdapter)).To(Succeed())
+		Expect(updatedAdapter.Status.SelectedAdapters).To(Equal([]string{"customer-a", "customer-b"}))
+	})
+
+	It("Should only serve the adapters a pattern selected or still has loaded", func() {
+		adapter := &productionstackv1alpha1.LoraAdapter{
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				AdapterSource: productionstackv1alpha1.AdapterSource{
+					AdapterName: "customers",
+					Pattern:     "customer-*",
+				},
+			},
+			Status: productionstackv1alpha1.LoraAdapterStatus{
+				SelectedAdapters: []string{"customer-a"},
+				LoadedAdapters:   []productionstackv1alpha1.LoadedAdapter{{Name: "customer-b"}},
+			},
+		}
+		Expect(servesAdapter(adapter, "customer-a")).To(BeTrue())
+		Expect(servesAdapter(adapter, "customer-b")).To(BeTrue())
+		// Matching the pattern is not enough, customer-c may belong to another LoraAdapter
+		Expect(servesAdapter(adapter, "customer-c")).To(BeFalse())
+
+		adapter.Spec.AdapterSource.Pattern = ""
+		Expect(servesAdapter(adapter, "customers")).To(BeTrue())
+		Expect(servesAdapter(adapter, "customer-a")).To(BeFalse())
+	})
+})
+
+var _ = Describe("Adapter placement", func() {
+	// newCandidates builds ready pods named pod-0..pod-n, created in reverse name order
+	newCandidates := func(loads, capacities []int) []*placementCandidate {
+		created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		var candidates []*placementCandidate
+		for i := range loads {
+			candidates = append(candidates, &placementCandidate{
+				pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+					Name:              fmt.Sprintf("pod-%d", i),
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(created.Add(-time.Duration(i) * time.Minute)),
+				}},
+				load:     loads[i],
+				capacity: capacities[i],
+			})
+		}
+		return candidates
+	}
+	replicasOf := func(replicas int, names ...string) map[string]int {
+		replicasByName := make(map[string]int)
+		for _, name := range names {
+			replicasByName[name] = replicas
+		}
+		return replicasByName
+	}
+	podsOf := func(decisions []productionstackv1alpha1.AdapterPlacement) map[string][]string {
+		pods := make(map[string][]string)
+		for _, decision := range decisions {
+			pods[decision.Name] = decision.Pods
+		}
+		return pods
+	}
+
+	It("Should place adapters on the first pods by name with the default algorithm", func() {
+		placements, decisions := placeAdapters("default", newCandidates([]int{0, 0, 0}, []int{0, 0, 0}),
+			[]string{"a", "b"}, replicasOf(2, "a", "b"), nil)
+		Expect(placements).To(HaveLen(4))
+		Expect(podsOf(decisions)).To(Equal(map[string][]string{
+			"a": {"pod-0", "pod-1"},
+			"b": {"pod-0", "pod-1"},
+		}))
+	})
+
+	It("Should fill pods in creation order up to their capacity with the ordered algorithm", func() {
+		_, decisions := placeAdapters("ordered", newCandidates([]int{0, 0, 0}, []int{2, 2, 2}),
+			[]string{"a", "b", "c", "d"}, replicasOf(1, "a", "b", "c", "d"), nil)
+		Expect(podsOf(decisions)).To(Equal(map[string][]string{
+			"a": {"pod-2"},
+			"b": {"pod-2"},
+			"c": {"pod-1"},
+			"d": {"pod-1"},
+		}))
+	})
+
+	It("Should balance adapters across pods including other LoraAdapters with the equalized algorithm", func() {
+		_, decisions := placeAdapters("equalized", newCandidates([]int{2, 0, 1}, []int{0, 0, 0}),
+			[]string{"a", "b", "c", "d"}, replicasOf(1, "a", "b", "c", "d"), nil)
+		Expect(podsOf(decisions)).To(Equal(map[string][]string{
+			"a": {"pod-1"},
+			"b": {"pod-1"},
+			"c": {"pod-2"},
+			"d": {"pod-0"},
+		}))
+	})
+
+	It("Should keep an adapter on its current pod when loads are equal", func() {
+		current := map[string]bool{placementKey("default", "pod-2", "a"): true}
+		_, decisions := placeAdapters("equalized", newCandidates([]int{0, 0, 0}, []int{0, 0, 0}),
+			[]string{"a"}, replicasOf(1, "a"), current)
+		Expect(podsOf(decisions)).To(Equal(map[string][]string{"a": {"pod-2"}}))
+	})
+
+	It("Should report adapters that cannot be placed on every requested pod", func() {
+		placements, decisions := placeAdapters("default", newCandidates([]int{1, 0}, []int{1, 1}),
+			[]string{"a", "b"}, replicasOf(2, "a", "b"), nil)
+		Expect(placements).To(ConsistOf(PodPlacement{PodName: "pod-1", Namespace: "default", AdapterName: "a"}))
+		Expect(decisions[0].Message).To(ContainSubstring("Placed on 1 of 2 requested pods"))
+		Expect(decisions[1].Pods).To(BeEmpty())
+		Expect(decisions[1].Message).To(ContainSubstring("Placed on 0 of 2 requested pods"))
+	})
+})
+
+var _ = Describe("Usage adapter placement", func() {
+	const metricsTemplate = `# HELP vllm:kv_cache_usage_perc KV-cache usage. 1 means 100 percent usage.
+# TYPE vllm:kv_cache_usage_perc gauge
+vllm:kv_cache_usage_perc{engine="0",model_name="llama"} %s
+# HELP vllm:lora_requests_info Running stats on lora requests.
+# TYPE vllm:lora_requests_info gauge
+vllm:lora_requests_info{max_lora="4",running_lora_adapters="",waiting_lora_adapters=""} 1.7e+09
+vllm:lora_requests_info{max_lora="4",running_lora_adapters="%s",waiting_lora_adapters="%s"} 1.8e+09
+`
+
+	// startFakeMetricsServer serves vLLM metrics and returns a pod pointing at it
+	startFakeMetricsServer := func(name, kvCacheUsage, running, waiting string) (*httptest.Server, *corev1.Pod) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.URL.Path).To(Equal("/metrics"))
+			_, err := fmt.Fprintf(w, metricsTemplate, kvCacheUsage, running, waiting)
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		addr := server.Listener.Addr().(*net.TCPAddr)
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "vllm",
+					Ports: []corev1.ContainerPort{{Name: "container-port", ContainerPort: int32(addr.Port)}},
+				}},
+			},
+			Status: corev1.PodStatus{PodIP: addr.IP.String()},
+		}
+		return server, pod
+	}
+
+	It("Should parse the most recent adapter requests and the KV cache usage", func() {
+		metrics, err := parsePodMetrics(strings.NewReader(fmt.Sprintf(metricsTemplate, "0.25", "hot,warm", "hot")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(metrics.kvCacheUsage).To(Equal(0.25))
+		Expect(metrics.runningAdapters).To(Equal(map[string]bool{"hot": true, "warm": true}))
+		Expect(metrics.waitingAdapters).To(Equal(map[string]bool{"hot": true}))
+	})
+
+	It("Should size adapters by the pods they are busy on", func() {
+		// Waiting requests ask for more replicas, bounded by the maximum
+		Expect(usageReplicas(2, 2, 1, 1, 4)).To(Equal(3))
+		Expect(usageReplicas(3, 3, 3, 1, 4)).To(Equal(4))
+		// Idle adapters shrink one replica at a time down to the minimum
+		Expect(usageReplicas(3, 0, 0, 1, 4)).To(Equal(2))
+		Expect(usageReplicas(1, 0, 0, 1, 4)).To(Equal(1))
+		Expect(usageReplicas(0, 0, 0, 0, 4)).To(Equal(0))
+		// A minimum above the number of pods is capped
+		Expect(usageReplicas(0, 0, 0, 3, 2)).To(Equal(2))
+	})
+
+	It("Should scale hot adapters onto the pods with the most free KV cache", func() {
+		ctx := context.Background()
+		usageReconciler := &LoraAdapterReconciler{}
+		adapter := &productionstackv1alpha1.LoraAdapter{}
+
+		busyServer, busyPod := startFakeMetricsServer("pod-0", "0.9", "hot", "hot")
+		defer busyServer.Close()
+		freeServer, freePod := startFakeMetricsServer("pod-1", "0.1", "", "")
+		defer freeServer.Close()
+		idleServer, idlePod := startFakeMetricsServer("pod-2", "0.5", "", "")
+		defer idleServer.Close()
+
+		running := make(map[string]int)
+		waiting := make(map[string]int)
+		var candidates []*placementCandidate
+		for _, pod := range []*corev1.Pod{busyPod, freePod, idlePod} {
+			metrics, err := usageReconciler.getPodMetrics(ctx, adapter, pod)
+			Expect(err).ToNot(HaveOccurred())
+			for name := range metrics.runningAdapters {
+				running[name]++
+			}
+			for name := range metrics.waitingAdapters {
+				waiting[name]++
+			}
+			candidates = append(candidates, &placementCandidate{pod: *pod, kvCacheUsage: metrics.kvCacheUsage})
+		}
+
+		// hot is loaded on pod-0 only, cold is loaded on pod-0 and pod-2 without any requests
+		current := map[string]bool{
+			placementKey("default", "pod-0", "hot"):  true,
+			placementKey("default", "pod-0", "cold"): true,
+			placementKey("default", "pod-2", "cold"): true,
+		}
+		replicas := map[string]int{
+			"hot":  usageReplicas(1, running["hot"], waiting["hot"], 1, 3),
+			"cold": usageReplicas(2, running["cold"], waiting["cold"], 1, 3),
+		}
+		_, decisions := placeAdapters("usage", candidates, []string{"hot", "cold"}, replicas, current)
+
+		Expect(decisions).To(HaveLen(2))
+		Expect(decisions[0].Name).To(Equal("hot"))
+		Expect(decisions[0].DesiredReplicas).To(Equal(int32(2)))
+		Expect(decisions[0].Pods).To(Equal([]string{"pod-0", "pod-1"}))
+		Expect(decisions[1].Name).To(Equal("cold"))
+		Expect(decisions[1].DesiredReplicas).To(Equal(int32(1)))
+		Expect(decisions[1].Pods).To(Equal([]string{"pod-2"}))
+	})
+})
+
+var _ = Describe("VLLMRuntime pod discovery", func() {
+	It("Should resolve the vLLM port from named container ports", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "runtime-pod", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "vllm",
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 8123}},
+				}},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+		}
+
+		endpoint, err := podEndpoint(pod, "/v1/models")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(endpoint).To(Equal("http://10.0.0.1:8123/v1/models"))
+
+		pod.Spec.Containers[0].Ports = append(pod.Spec.Containers[0].Ports,
+			corev1.ContainerPort{Name: "container-port", ContainerPort: 9000})
+		endpoint, err = podEndpoint(pod, "/v1/models")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(endpoint).To(Equal("http://10.0.0.1:9000/v1/models"))
+
+		endpoint, err = podEndpoint(pod, "/metrics", 8000)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(endpoint).To(Equal("http://10.0.0.1:8000/metrics"))
+	})
+
+	It("Should select the pods and port of the referenced VLLMRuntime", func() {
+		ctx := context.Background()
+		namespace := "default"
+
+		vllmRuntime := &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "lora-runtime",
+				Namespace: namespace,
+				Labels:    map[string]string{"team": "nlp"},
+			},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				Model:      productionstackv1alpha1.ModelSpec{ModelURL: "meta-llama/Llama-3.1-8B-Instruct"},
+				VLLMConfig: productionstackv1alpha1.VLLMConfig{Port: 8123},
+				DeploymentConfig: productionstackv1alpha1.DeploymentConfig{
+					Image: productionstackv1alpha1.ImageSpec{Registry: "docker.io", Name: "vllm/vllm-openai:latest"},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, vllmRuntime)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, vllmRuntime)).Should(Succeed()) }()
+
+		By("Creating a pod of the runtime and an unrelated pod with the model label")
+		runtimePod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "lora-runtime-pod",
+				Namespace: namespace,
+				Labels:    labelsForVLLMRuntime(vllmRuntime),
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "vllm", Image: "test-image"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, runtimePod)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, runtimePod)).Should(Succeed()) }()
+		otherPod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "model-label-pod",
+				Namespace: namespace,
+				Labels:    map[string]string{"model": "llama-3-1-8b"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "vllm", Image: "test-image"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, otherPod)).Should(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, otherPod)).Should(Succeed()) }()
+
+		adapter := &productionstackv1alpha1.LoraAdapter{
+			ObjectMeta: metav1.ObjectMeta{Name: "runtime-adapter", Namespace: namespace},
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				BaseModel:      "llama-3-1-8b",
+				VLLMRuntimeRef: &productionstackv1alpha1.VLLMRuntimeReference{Name: vllmRuntime.Name},
+				AdapterSource: productionstackv1alpha1.AdapterSource{
+					Type:        "local",
+					AdapterName: "sql-lora",
+					AdapterPath: "/data/lora-adapters/sql-lora",
+				},
+			},
+		}
+
+		runtimeReconciler := &LoraAdapterReconciler{
+			Client: k8sClient,
+			Scheme: k8sClient.Scheme(),
+		}
+
+		pods, err := runtimeReconciler.listVLLMPods(ctx, adapter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pods).To(HaveLen(1))
+		Expect(pods[0].Name).To(Equal(runtimePod.Name))
+
+		port, err := runtimeReconciler.getVLLMPort(ctx, adapter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(port).To(Equal([]int{8123}))
+
+		By("Selecting pods by label instead")
+		adapter.Spec.VLLMRuntimeRef = nil
+		adapter.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "nlp"}}
+		pods, err = runtimeReconciler.listVLLMPods(ctx, adapter)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pods).To(HaveLen(1))
+		Expect(pods[0].Name).To(Equal(runtimePod.Name))
+	})
+
+	It("Should find the VLLMRuntime of a selected pod through its controllers", func() {
+		ctx := context.Background()
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(productionstackv1alpha1.AddToScheme(s)).To(Succeed())
+
+		// The selected label is shared with a pod of no runtime, so the runtime is found through the
+		// controllers of the pod
+		vllmRuntime := &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "lora-runtime", Namespace: "default", UID: "runtime-uid",
+				Labels: map[string]string{"team": "chat"}},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				VLLMConfig: productionstackv1alpha1.VLLMConfig{MaxLoras: 2, MaxCPULoras: 6},
+			},
+		}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "lora-runtime", Namespace: "default", UID: "deployment-uid"},
+		}
+		Expect(controllerutil.SetControllerReference(vllmRuntime, deployment, s)).To(Succeed())
+		replicaSet := &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "lora-runtime-7d9f", Namespace: "default", UID: "replicaset-uid"},
+		}
+		Expect(controllerutil.SetControllerReference(deployment, replicaSet, s)).To(Succeed())
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "lora-runtime-7d9f-abcde", Namespace: "default",
+				Labels: labelsForVLLMRuntime(vllmRuntime)},
+		}
+		Expect(controllerutil.SetControllerReference(replicaSet, pod, s)).To(Succeed())
+		standalonePod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "standalone", Namespace: "default",
+				Labels: map[string]string{"team": "chat"}},
+		}
+
+		ownerReconciler := &LoraAdapterReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).
+				WithObjects(vllmRuntime, deployment, replicaSet, pod, standalonePod).Build(),
+			Scheme: s,
+		}
+		adapter := &productionstackv1alpha1.LoraAdapter{
+			ObjectMeta: metav1.ObjectMeta{Name: "selector-adapter", Namespace: "default"},
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "chat"}},
+			},
+		}
+
+		found, err := ownerReconciler.getPodVLLMRuntime(ctx, adapter, pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).ToNot(BeNil())
+		Expect(found.Name).To(Equal(vllmRuntime.Name))
+		// vLLM keeps --max-cpu-loras adapters loaded, not the --max-loras it batches together
+		Expect(loraCapacity(found)).To(Equal(6))
+
+		found, err = ownerReconciler.getPodVLLMRuntime(ctx, adapter, standalonePod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeNil())
+		Expect(loraCapacity(found)).To(Equal(0))
+
+		vllmRuntime.Spec.VLLMConfig.MaxCPULoras = 0
+		Expect(loraCapacity(vllmRuntime)).To(Equal(2))
+	})
+
+	It("Should map pod and VLLMRuntime changes to the adapters targeting them", func() {
+		ctx := context.Background()
+		s := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+		Expect(productionstackv1alpha1.AddToScheme(s)).To(Succeed())
+
+		vllmRuntime := &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "chat-runtime", Namespace: "default",
+				Labels: map[string]string{"team": "nlp"}},
+		}
+		newAdapter := func(name string, mutate func(*productionstackv1alpha1.LoraAdapterSpec)) *productionstackv1alpha1.LoraAdapter {
+			adapter := &productionstackv1alpha1.LoraAdapter{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       productionstackv1alpha1.LoraAdapterSpec{BaseModel: "llama-3-1-8b"},
+			}
+			mutate(&adapter.Spec)
+			return adapter
+		}
+		byRef := newAdapter("by-ref", func(spec *productionstackv1alpha1.LoraAdapterSpec) {
+			spec.VLLMRuntimeRef = &productionstackv1alpha1.VLLMRuntimeReference{Name: vllmRuntime.Name}
+		})
+		bySelector := newAdapter("by-selector", func(spec *productionstackv1alpha1.LoraAdapterSpec) {
+			spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"team": "nlp"}}
+		})
+		byModel := newAdapter("by-model", func(*productionstackv1alpha1.LoraAdapterSpec) {})
+
+		mapReconciler := &LoraAdapterReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).
+				WithObjects(vllmRuntime, byRef, bySelector, byModel).Build(),
+			Scheme: s,
+		}
+		namesOf := func(requests []reconcile.Request) []string {
+			var names []string
+			for _, request := range requests {
+				names = append(names, request.Name)
+			}
+			return names
+		}
+
+		runtimePod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "chat-runtime-0", Namespace: "default",
+			Labels: labelsForVLLMRuntime(vllmRuntime)}}
+		Expect(namesOf(mapReconciler.findLoraAdaptersForPod(ctx, runtimePod))).To(
+			ConsistOf("by-ref", "by-selector"))
+
+		modelPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "llama-0", Namespace: "other",
+			Labels: map[string]string{"model": "llama-3-1-8b", "team": "nlp"}}}
+		Expect(namesOf(mapReconciler.findLoraAdaptersForPod(ctx, modelPod))).To(ConsistOf("by-model"))
+
+		Expect(namesOf(mapReconciler.findLoraAdaptersForRuntime(ctx, vllmRuntime))).To(
+			ConsistOf("by-ref", "by-selector"))
+	})
+})
+
+// startFakeSidecar serves handler as the downloader sidecar on a free port on localhost
+func startFakeSidecar(handler http.HandlerFunc) *httptest.Server {
+	return httptest.NewServer(handler)
+}
+
+// sidecarPortOf returns the port a fake sidecar listens on
+func sidecarPortOf(sidecar *httptest.Server) int {
+	return sidecar.Listener.Addr().(*net.TCPAddr).Port
+}
+
+// createLocalhostPod creates a pod whose IP points at localhost so requests reach fake servers
+func createLocalhostPod(ctx context.Context, name, namespace string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "vllm", Image: "test-image"}},
+		},
+	}
+	Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+	pod.Status.PodIP = "127.0.0.1"
+	Expect(k8sClient.Status().Update(ctx, pod)).Should(Succeed())
+	return pod
+}
+
+// getAdapterCondition returns the condition of the given type from the latest adapter status
+func getAdapterCondition(ctx context.Context, adapter *productionstackv1alpha1.LoraAdapter, conditionType string) *metav1.Condition {
+	var latest productionstackv1alpha1.LoraAdapter
+	Expect(k8sClient.Get(ctx, types.NamespacedName{Name: adapter.Name, Namespace: adapter.Namespace}, &latest)).To(Succeed())
+	return meta.FindStatusCondition(latest.Status.Conditions, conditionType)
+}
+
 // Helper function to create string pointer
 func stringPtr(s string) *string {
 	return &s
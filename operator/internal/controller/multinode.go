@@ -0,0 +1,485 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// defaultRayPort is the port of the Ray head when the spec does not set one
+	defaultRayPort = 6379
+
+	// leaderScript starts the Ray head, waits until every worker of the group joined it and then
+	// runs vLLM with the arguments passed to the script
+	leaderScript = `set -e
+/opt/venv/bin/ray start --head --port="${RAY_PORT}" --node-ip-address="${VLLM_HOST_IP}"
+until [ "$(/opt/venv/bin/python3 -c 'import ray; ray.init(address="auto", logging_level="ERROR"); print(sum(n["Alive"] for n in ray.nodes()))' 2>/dev/null)" -ge "${GROUP_SIZE}" ] 2>/dev/null; do
+  echo "Waiting for ${GROUP_SIZE} Ray nodes to join the group"
+  sleep 5
+done
+exec /opt/venv/bin/vllm serve "$@"`
+
+	// workerScript joins the Ray head of the leader of the group the worker belongs to. Workers are
+	// assigned to groups in order of their StatefulSet ordinal.
+	workerScript = `set -e
+GROUP=$(( ${HOSTNAME##*-} / (GROUP_SIZE - 1) ))
+LEADER="${LEADER_NAME}-${GROUP}.${LEADER_NAME}"
+until getent hosts "${LEADER}" > /dev/null; do
+  echo "Waiting for the leader ${LEADER}"
+  sleep 2
+done
+exec /opt/venv/bin/ray start --address="${LEADER}:${RAY_PORT}" --node-ip-address="${VLLM_HOST_IP}" --block`
+)
+
+// leaderNameForVLLMRuntime returns the name of the leader StatefulSet of a VLLMRuntime and of the
+// headless Service its pods are addressed through
+func leaderNameForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) string {
+	return vllmRuntime.Name + "-leader"
+}
+
+// workerNameForVLLMRuntime returns the name of the worker StatefulSet of a VLLMRuntime
+func workerNameForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) string {
+	return vllmRuntime.Name + "-worker"
+}
+
+// workerLabelsForVLLMRuntime returns the labels of the worker pods of a VLLMRuntime. They leave out
+// the labels of the VLLMRuntime so that routers and Services selecting the serving pods do not
+// select the workers, which do not serve requests.
+func workerLabelsForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) map[string]string {
+	return map[string]string{"app": workerNameForVLLMRuntime(vllmRuntime)}
+}
+
+// groupSize returns the number of pods in a leader/worker group
+func groupSize(vllmRuntime *productionstackv1alpha1.VLLMRuntime) int32 {
+	size := vllmRuntime.Spec.MultiNode.Size
+	if size == 0 {
+		size = vllmRuntime.Spec.VLLMConfig.PipelineParallelSize
+	}
+	return max(size, 2)
+}
+
+// rayPort returns the port of the Ray head of a VLLMRuntime
+func rayPort(vllmRuntime *productionstackv1alpha1.VLLMRuntime) int32 {
+	if vllmRuntime.Spec.MultiNode.RayPort != 0 {
+		return vllmRuntime.Spec.MultiNode.RayPort
+	}
+	return defaultRayPort
+}
+
+// groupEnv returns the environment the leader and worker scripts read
+func groupEnv(vllmRuntime *productionstackv1alpha1.VLLMRuntime) []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name: "VLLM_HOST_IP",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+			},
+		},
+		{Name: "GROUP_SIZE", Value: fmt.Sprintf("%d", groupSize(vllmRuntime))},
+		{Name: "LEADER_NAME", Value: leaderNameForVLLMRuntime(vllmRuntime)},
+		{Name: "RAY_PORT", Value: fmt.Sprintf("%d", rayPort(vllmRuntime))},
+	}
+}
+
+// leaderStatefulSetForVLLMRuntime returns the StatefulSet of the group leaders of a VLLMRuntime,
+// one per replica, or an error when the spec holds values that cannot be parsed
+func (r *VLLMRuntimeReconciler) leaderStatefulSetForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (*appsv1.StatefulSet, error) {
+	template, err := r.podTemplateForVLLMRuntime(vllmRuntime, kvRoleBoth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run vLLM through the leader script, which passes the arguments on to vllm serve
+	container := &template.Spec.Containers[0]
+	container.Command = []string{"/bin/bash", "-c", leaderScript, "vllm"}
+	container.Args = append(container.Args, "--distributed-executor-backend", "ray")
+	container.Env = append(container.Env, groupEnv(vllmRuntime)...)
+	container.Ports = append(container.Ports, corev1.ContainerPort{
+		Name:          "ray",
+		ContainerPort: rayPort(vllmRuntime),
+	})
+
+	replicas := desiredReplicas(vllmRuntime)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      leaderNameForVLLMRuntime(vllmRuntime),
+			Namespace: vllmRuntime.Namespace,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:            &replicas,
+			ServiceName:         leaderNameForVLLMRuntime(vllmRuntime),
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabelsForVLLMRuntime(vllmRuntime),
+			},
+			Template: template,
+		},
+	}
+
+	setSpecHash(sts, sts.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(vllmRuntime, sts, r.Scheme)
+	return sts, nil
+}
+
+// workerStatefulSetForVLLMRuntime returns the StatefulSet of the workers of all groups of a
+// VLLMRuntime, or an error when the spec holds values that cannot be parsed
+func (r *VLLMRuntimeReconciler) workerStatefulSetForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (*appsv1.StatefulSet, error) {
+	template, err := r.podTemplateForVLLMRuntime(vllmRuntime, kvRoleBoth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Workers only host a Ray node with the GPUs of the vLLM container. They serve no requests, so
+	// the probes, ports and sidecar of the leader are left out.
+	labels := workerLabelsForVLLMRuntime(vllmRuntime)
+	container := template.Spec.Containers[0]
+	container.Name = "ray-worker"
+	container.Command = []string{"/bin/bash", "-c", workerScript}
+	container.Args = nil
+	container.Env = append(container.Env, groupEnv(vllmRuntime)...)
+	container.Ports = nil
+	container.ReadinessProbe = nil
+	container.StartupProbe = nil
+	container.LivenessProbe = nil
+	template.Labels = labels
+	template.Spec.Containers = []corev1.Container{container}
+
+	replicas := desiredReplicas(vllmRuntime) * (groupSize(vllmRuntime) - 1)
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workerNameForVLLMRuntime(vllmRuntime),
+			Namespace: vllmRuntime.Namespace,
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:            &replicas,
+			ServiceName:         leaderNameForVLLMRuntime(vllmRuntime),
+			PodManagementPolicy: appsv1.ParallelPodManagement,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: template,
+		},
+	}
+
+	setSpecHash(sts, sts.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(vllmRuntime, sts, r.Scheme)
+	return sts, nil
+}
+
+// leaderServiceForVLLMRuntime returns the headless Service that gives every group leader of a
+// VLLMRuntime a DNS name its workers join
+func (r *VLLMRuntimeReconciler) leaderServiceForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) *corev1.Service {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      leaderNameForVLLMRuntime(vllmRuntime),
+			Namespace: vllmRuntime.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  labelsForVLLMRuntime(vllmRuntime),
+			// Leaders only become ready once their workers joined, so they must be resolvable before
+			PublishNotReadyAddresses: true,
+			Ports: []corev1.ServicePort{
+				{
+					Name:     "ray",
+					Port:     rayPort(vllmRuntime),
+					Protocol: corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+
+	setSpecHash(svc, svc.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(vllmRuntime, svc, r.Scheme)
+	return svc
+}
+
+// reconcileMultiNode serves a VLLMRuntime by leader/worker groups of StatefulSet pods, replacing
+// the Deployment of single-node mode
+func (r *VLLMRuntimeReconciler) reconcileMultiNode(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	// Remove the Deployment left from single-node mode
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}}
+	if err := deleteOwnedObject(ctx, r.Client, vllmRuntime, dep); err != nil {
+		log.Error(err, "Failed to delete Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
+		return ctrl.Result{}, err
+	}
+
+	// Check if the leader service already exists, if not create a new one
+	svc := r.leaderServiceForVLLMRuntime(vllmRuntime)
+	foundService := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, foundService)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new Service", "Service.Namespace", svc.Namespace, "Service.Name", svc.Name)
+		if err := r.Create(ctx, svc); err != nil {
+			log.Error(err, "Failed to create new Service", "Service.Namespace", svc.Namespace, "Service.Name", svc.Name)
+			return ctrl.Result{}, err
+		}
+		// Service created successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	} else if err != nil {
+		log.Error(err, "Failed to get Service")
+		return ctrl.Result{}, err
+	}
+
+	// Update the leader service if its desired state changed
+	if specHashChanged(foundService, svc) {
+		log.Info("Updating Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+		copyDesiredMetadata(foundService, svc)
+		// Keep the cluster IPs allocated to the live Service, they cannot be changed
+		svc.Spec.ClusterIP = foundService.Spec.ClusterIP
+		svc.Spec.ClusterIPs = foundService.Spec.ClusterIPs
+		foundService.Spec = svc.Spec
+		if err := r.Update(ctx, foundService); err != nil {
+			log.Error(err, "Failed to update Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+			return ctrl.Result{}, err
+		}
+		// Service updated successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Build the desired StatefulSets, reporting values that cannot be parsed on the VLLMRuntime
+	leader, err := r.leaderStatefulSetForVLLMRuntime(vllmRuntime)
+	if err != nil {
+		return r.handleInvalidSpec(ctx, vllmRuntime, err)
+	}
+	worker, err := r.workerStatefulSetForVLLMRuntime(vllmRuntime)
+	if err != nil {
+		return r.handleInvalidSpec(ctx, vllmRuntime, err)
+	}
+
+	found := make([]*appsv1.StatefulSet, 0, 2)
+	for _, sts := range []*appsv1.StatefulSet{leader, worker} {
+		// Check if the StatefulSet already exists, if not create a new one
+		foundSts := &appsv1.StatefulSet{}
+		err := r.Get(ctx, types.NamespacedName{Name: sts.Name, Namespace: sts.Namespace}, foundSts)
+		if err != nil && errors.IsNotFound(err) {
+			log.Info("Creating a new StatefulSet", "StatefulSet.Namespace", sts.Namespace, "StatefulSet.Name", sts.Name)
+			if err := r.Create(ctx, sts); err != nil {
+				log.Error(err, "Failed to create new StatefulSet", "StatefulSet.Namespace", sts.Namespace, "StatefulSet.Name", sts.Name)
+				return ctrl.Result{}, err
+			}
+			// StatefulSet created successfully - return and requeue
+			return ctrl.Result{Requeue: true}, nil
+		} else if err != nil {
+			log.Error(err, "Failed to get StatefulSet")
+			return ctrl.Result{}, err
+		}
+
+		// Update the StatefulSet if its desired state changed
+		if specHashChanged(foundSts, sts) {
+			log.Info("Updating StatefulSet", "StatefulSet.Namespace", foundSts.Namespace, "StatefulSet.Name", foundSts.Name)
+			copyDesiredMetadata(foundSts, sts)
+			sts.Spec.Selector = keepSelector(foundSts.Spec.Selector, &sts.Spec.Template)
+			foundSts.Spec = sts.Spec
+			if err := r.Update(ctx, foundSts); err != nil {
+				log.Error(err, "Failed to update StatefulSet", "StatefulSet.Namespace", foundSts.Namespace, "StatefulSet.Name", foundSts.Name)
+				return ctrl.Result{}, err
+			}
+			// StatefulSet updated successfully - return and requeue
+			return ctrl.Result{Requeue: true}, nil
+		}
+		found = append(found, foundSts)
+	}
+
+	// Update the status
+	if err := r.updateMultiNodeStatus(ctx, vllmRuntime, found[0], found[1]); err != nil {
+		log.Error(err, "Failed to update VLLMRuntime status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: resyncPeriod(vllmRuntime)}, nil
+}
+
+// deleteMultiNodeObjects removes the StatefulSets and headless Service left from multi-node mode
+func (r *VLLMRuntimeReconciler) deleteMultiNodeObjects(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime) error {
+	objects := []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: leaderNameForVLLMRuntime(vllmRuntime), Namespace: vllmRuntime.Namespace}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: workerNameForVLLMRuntime(vllmRuntime), Namespace: vllmRuntime.Namespace}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: leaderNameForVLLMRuntime(vllmRuntime), Namespace: vllmRuntime.Namespace}},
+	}
+	for _, obj := range objects {
+		if err := deleteOwnedObject(ctx, r.Client, vllmRuntime, obj); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// podOrdinal returns the StatefulSet ordinal of a pod named after the StatefulSet setName
+func podOrdinal(pod *corev1.Pod, setName string) (int32, bool) {
+	suffix, ok := strings.CutPrefix(pod.Name, setName+"-")
+	if !ok {
+		return 0, false
+	}
+	ordinal, err := strconv.ParseInt(suffix, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(ordinal), true
+}
+
+// readyGroups counts the groups whose leader and workers are all ready, along with the ready
+// workers. Workers belong to the group of their ordinal divided by the workers per group.
+func readyGroups(leaders, workers []corev1.Pod, leaderName, workerName string, groups, size int32) (int32, int32) {
+	workersPerGroup := size - 1
+	readyWorkersByGroup := make(map[int32]int32)
+	readyWorkers := int32(0)
+	for i := range workers {
+		ordinal, ok := podOrdinal(&workers[i], workerName)
+		if !ok || !isPodReady(&workers[i]) || ordinal >= groups*workersPerGroup {
+			continue
+		}
+		readyWorkersByGroup[ordinal/workersPerGroup]++
+		readyWorkers++
+	}
+
+	ready := int32(0)
+	for i := range leaders {
+		ordinal, ok := podOrdinal(&leaders[i], leaderName)
+		if !ok || !isPodReady(&leaders[i]) || ordinal >= groups {
+			continue
+		}
+		if readyWorkersByGroup[ordinal] == workersPerGroup {
+			ready++
+		}
+	}
+	return ready, readyWorkers
+}
+
+// groupConditions returns the Available, Progressing and Degraded conditions of a resource whose
+// pods are managed by the leader and worker StatefulSets of its groups
+func groupConditions(generation int64, groups, ready int32, sets ...*appsv1.StatefulSet) []metav1.Condition {
+	groupMessage := fmt.Sprintf("%d of %d groups ready", ready, groups)
+
+	available := metav1.Condition{
+		Type:               conditionTypeAvailable,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             "AllGroupsReady",
+		Message:            groupMessage,
+	}
+	if ready < groups {
+		available.Status = metav1.ConditionFalse
+		available.Reason = "GroupsNotReady"
+	}
+
+	progressing := metav1.Condition{
+		Type:               conditionTypeProgressing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             "RolloutComplete",
+		Message:            groupMessage,
+	}
+	for _, sts := range sets {
+		desired := int32(1)
+		if sts.Spec.Replicas != nil {
+			desired = *sts.Spec.Replicas
+		}
+		// The rollout is done once the StatefulSet controller saw the latest template and every
+		// pod runs it and is ready
+		status := sts.Status
+		if status.ObservedGeneration < sts.Generation || status.UpdatedReplicas != desired ||
+			status.Replicas != desired || status.ReadyReplicas != desired {
+			progressing.Status = metav1.ConditionTrue
+			progressing.Reason = "RollingOut"
+			progressing.Message = fmt.Sprintf("%d of %d pods of %s updated, %s", status.UpdatedReplicas, desired, sts.Name, groupMessage)
+			break
+		}
+	}
+
+	return []metav1.Condition{available, progressing, degradedCondition(generation, nil)}
+}
+
+// updateMultiNodeStatus updates the status of a VLLMRuntime served by leader/worker groups
+func (r *VLLMRuntimeReconciler) updateMultiNodeStatus(ctx context.Context, vr *productionstackv1alpha1.VLLMRuntime, leader, worker *appsv1.StatefulSet) error {
+	leaders := &corev1.PodList{}
+	if err := r.List(ctx, leaders, client.InNamespace(vr.Namespace), client.MatchingLabels(leader.Spec.Selector.MatchLabels)); err != nil {
+		return fmt.Errorf("failed to list leader pods: %w", err)
+	}
+	workers := &corev1.PodList{}
+	if err := r.List(ctx, workers, client.InNamespace(vr.Namespace), client.MatchingLabels(worker.Spec.Selector.MatchLabels)); err != nil {
+		return fmt.Errorf("failed to list worker pods: %w", err)
+	}
+	groups := *leader.Spec.Replicas
+	ready, readyWorkers := readyGroups(leaders.Items, workers.Items, leader.Name, worker.Name, groups, groupSize(vr))
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Get the latest version of the VLLMRuntime
+		latestVR := &productionstackv1alpha1.VLLMRuntime{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vr.Name, Namespace: vr.Namespace}, latestVR); err != nil {
+			return err
+		}
+
+		// Update the status fields. The replicas of a runtime are its groups.
+		latestVR.Status.LastUpdated = metav1.Now()
+		latestVR.Status.ObservedGeneration = latestVR.Generation
+		latestVR.Status.Replicas = leader.Status.Replicas
+		latestVR.Status.ReadyReplicas = ready
+		latestVR.Status.AvailableReplicas = ready
+		latestVR.Status.UpdatedReplicas = leader.Status.UpdatedReplicas
+		latestVR.Status.Topology = nil
+		latestVR.Status.MultiNode = &productionstackv1alpha1.MultiNodeStatus{
+			Groups:       groups,
+			ReadyGroups:  ready,
+			ReadyWorkers: readyWorkers,
+		}
+		conditions := groupConditions(latestVR.Generation, groups, ready, leader, worker)
+		for _, condition := range conditions {
+			meta.SetStatusCondition(&latestVR.Status.Conditions, condition)
+		}
+		clearScaleToZeroStatus(latestVR)
+
+		// Update model status based on group readiness
+		switch {
+		case isScaledToZero(latestVR) && groups == 0:
+			latestVR.Status.ModelStatus = statusScaledToZero
+		case ready == groups:
+			latestVR.Status.ModelStatus = "Ready"
+		case conditions[1].Status == metav1.ConditionTrue:
+			latestVR.Status.ModelStatus = "Updating"
+		default:
+			latestVR.Status.ModelStatus = "NotReady"
+		}
+
+		return r.Status().Update(ctx, latestVR)
+	})
+}
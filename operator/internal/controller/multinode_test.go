@@ -0,0 +1,143 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRuntime multi-node groups", func() {
+	newRuntime := func() *productionstackv1alpha1.VLLMRuntime {
+		return &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default", Labels: map[string]string{"model": "llama-70b"}},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				Model: productionstackv1alpha1.ModelSpec{ModelURL: "meta-llama/Llama-3.1-70B"},
+				VLLMConfig: productionstackv1alpha1.VLLMConfig{
+					Port: 8000, TensorParallelSize: 8, PipelineParallelSize: 2,
+				},
+				DeploymentConfig: productionstackv1alpha1.DeploymentConfig{
+					Replicas: 2,
+					Resources: productionstackv1alpha1.ResourceRequirements{
+						CPU: "32", Memory: "512Gi", GPU: "8",
+					},
+					SidecarConfig: productionstackv1alpha1.SidecarConfig{Enabled: true, Name: "sidecar"},
+				},
+				MultiNode: productionstackv1alpha1.MultiNodeConfig{Enabled: true, Size: 2, RayPort: 6379},
+			},
+		}
+	}
+	pod := func(name string, ready bool) corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+
+	It("builds a leader per replica that runs vLLM on the Ray head of its group", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		leader, err := r.leaderStatefulSetForVLLMRuntime(newRuntime())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leader.Name).To(Equal("llama-leader"))
+		Expect(*leader.Spec.Replicas).To(Equal(int32(2)))
+		Expect(leader.Spec.ServiceName).To(Equal("llama-leader"))
+		Expect(leader.Spec.Template.Labels).To(HaveKeyWithValue("model", "llama-70b"))
+		Expect(leader.Spec.Template.Spec.Containers).To(HaveLen(2))
+
+		container := leader.Spec.Template.Spec.Containers[0]
+		Expect(container.Command).To(Equal([]string{"/bin/bash", "-c", leaderScript, "vllm"}))
+		Expect(container.Args[0]).To(Equal("meta-llama/Llama-3.1-70B"))
+		Expect(container.Args).To(ContainElements("--pipeline-parallel-size", "2", "--distributed-executor-backend", "ray"))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "GROUP_SIZE", Value: "2"}))
+		Expect(container.ReadinessProbe).NotTo(BeNil())
+	})
+
+	It("builds the workers of all groups without the serving labels, probes and sidecar", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.MultiNode.Size = 3
+		worker, err := r.workerStatefulSetForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(worker.Name).To(Equal("llama-worker"))
+		Expect(*worker.Spec.Replicas).To(Equal(int32(4)))
+		Expect(worker.Spec.Template.Labels).To(Equal(map[string]string{"app": "llama-worker"}))
+		Expect(worker.Spec.Template.Spec.Containers).To(HaveLen(1))
+
+		container := worker.Spec.Template.Spec.Containers[0]
+		Expect(container.Command).To(Equal([]string{"/bin/bash", "-c", workerScript}))
+		Expect(container.Args).To(BeEmpty())
+		Expect(container.ReadinessProbe).To(BeNil())
+		Expect(container.Resources.Limits).To(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+		Expect(container.Env).To(ContainElement(corev1.EnvVar{Name: "LEADER_NAME", Value: "llama-leader"}))
+	})
+
+	It("publishes the leaders before they are ready", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		svc := r.leaderServiceForVLLMRuntime(newRuntime())
+		Expect(svc.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+		Expect(svc.Spec.PublishNotReadyAddresses).To(BeTrue())
+		Expect(svc.Spec.Ports[0].Port).To(Equal(int32(6379)))
+	})
+
+	It("only counts groups whose leader and workers are all ready", func() {
+		leaders := []corev1.Pod{pod("llama-leader-0", true), pod("llama-leader-1", true), pod("llama-leader-2", false)}
+		workers := []corev1.Pod{
+			pod("llama-worker-0", true), pod("llama-worker-1", true),
+			pod("llama-worker-2", true), pod("llama-worker-3", false),
+			pod("llama-worker-4", true), pod("llama-worker-5", true),
+		}
+		ready, readyWorkers := readyGroups(leaders, workers, "llama-leader", "llama-worker", 3, 3)
+		Expect(ready).To(Equal(int32(1)))
+		Expect(readyWorkers).To(Equal(int32(5)))
+	})
+
+	It("reports the rollout of the StatefulSets and the ready groups", func() {
+		sts := func(name string, replicas, ready int32) *appsv1.StatefulSet {
+			return &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+				Status: appsv1.StatefulSetStatus{
+					Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: ready,
+				},
+			}
+		}
+
+		conditions := groupConditions(1, 2, 2, sts("llama-leader", 2, 2), sts("llama-worker", 2, 2))
+		Expect(conditions[0].Status).To(Equal(metav1.ConditionTrue))
+		Expect(conditions[1].Status).To(Equal(metav1.ConditionFalse))
+
+		conditions = groupConditions(1, 2, 1, sts("llama-leader", 2, 2), sts("llama-worker", 2, 1))
+		Expect(conditions[0].Status).To(Equal(metav1.ConditionFalse))
+		Expect(conditions[0].Message).To(Equal(fmt.Sprintf("%d of %d groups ready", 1, 2)))
+		Expect(conditions[1].Status).To(Equal(metav1.ConditionTrue))
+		Expect(conditions[1].Message).To(ContainSubstring("llama-worker"))
+	})
+})
@@ -0,0 +1,573 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// prewarmModeInitContainer downloads the model in an init container of every vLLM pod
+	prewarmModeInitContainer = "InitContainer"
+
+	// downloadContainerName is the name of the container downloading the model
+	downloadContainerName = "model-download"
+	// waitContainerName is the name of the init container holding the vLLM pods until the
+	// download Job completed
+	waitContainerName = "wait-for-model"
+
+	// downloadProgressPort is the port the downloader reports its progress on
+	downloadProgressPort int32 = 8090
+	// downloadCompleteMarker is the file the downloader writes next to the model once all files
+	// are in place
+	downloadCompleteMarker = ".prewarm-complete"
+	// downloadSyncPeriod is the interval the progress of a running download is checked at
+	downloadSyncPeriod = 10 * time.Second
+
+	// Phases of the model download
+	downloadPhasePending     = "Pending"
+	downloadPhaseDownloading = "Downloading"
+	downloadPhaseCompleted   = "Completed"
+	downloadPhaseFailed      = "Failed"
+)
+
+// downloadScript downloads the files of a model revision one by one into a directory and serves
+// the downloaded and total bytes in the Prometheus text format meanwhile. It exits early when the
+// completion marker exists and writes it, holding the resolved commit hash, once done.
+const downloadScript = `import http.server
+import os
+import sys
+import threading
+
+from huggingface_hub import HfApi, hf_hub_download
+
+repo, revision, local_dir, marker, port = sys.argv[1:6]
+if os.path.exists(marker):
+    print(f"{repo}@{revision} is already in {local_dir}", flush=True)
+    sys.exit(0)
+
+info = HfApi().model_info(repo, revision=revision, files_metadata=True)
+files = [(f.rfilename, f.size or 0) for f in info.siblings]
+progress = {"downloaded": 0, "total": sum(size for _, size in files)}
+
+
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        body = (
+            "# TYPE model_download_downloaded_bytes gauge\n"
+            f"model_download_downloaded_bytes {progress['downloaded']}\n"
+            "# TYPE model_download_total_bytes gauge\n"
+            f"model_download_total_bytes {progress['total']}\n"
+        ).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain; version=0.0.4")
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+server = http.server.ThreadingHTTPServer(("", int(port)), Handler)
+threading.Thread(target=server.serve_forever, daemon=True).start()
+
+for name, size in files:
+    hf_hub_download(repo, name, revision=info.sha, local_dir=local_dir)
+    progress["downloaded"] += size
+    print(f"Downloaded {name} ({progress['downloaded']}/{progress['total']} bytes)", flush=True)
+
+with open(marker, "w") as f:
+    f.write(info.sha)
+`
+
+// waitScript blocks until the completion marker passed as first argument exists
+const waitScript = `until [ -f "$1" ]; do
+  echo "Waiting for the model download to complete"
+  sleep 10
+done`
+
+// downloadProgress is the progress scraped from a running downloader
+type downloadProgress struct {
+	// downloadedBytes is the size of the files downloaded so far
+	downloadedBytes int64
+	// totalBytes is the size of all files of the model
+	totalBytes int64
+}
+
+// parseDownloadProgress extracts the downloaded and total bytes from the progress reported by the
+// downloader in the Prometheus text format
+func parseDownloadProgress(in io.Reader) (*downloadProgress, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	progress := &downloadProgress{}
+	if family, ok := families["model_download_downloaded_bytes"]; ok {
+		for _, metric := range family.GetMetric() {
+			progress.downloadedBytes += int64(metric.GetGauge().GetValue())
+		}
+	}
+	if family, ok := families["model_download_total_bytes"]; ok {
+		for _, metric := range family.GetMetric() {
+			progress.totalBytes += int64(metric.GetGauge().GetValue())
+		}
+	}
+	return progress, nil
+}
+
+// isPrewarmed reports whether the model of a runtime is downloaded into its storage volume ahead
+// of serving
+func isPrewarmed(vllmRuntime *productionstackv1alpha1.VLLMRuntime) bool {
+	return vllmRuntime.Spec.Model.Prewarm.Enabled && vllmRuntime.Spec.StorageConfig.Enabled
+}
+
+// modelRevision returns the revision of the model to serve, main unless pinned
+func modelRevision(vllmRuntime *productionstackv1alpha1.VLLMRuntime) string {
+	if vllmRuntime.Spec.Model.Revision != "" {
+		return vllmRuntime.Spec.Model.Revision
+	}
+	return "main"
+}
+
+// localModelPath returns the path the model of a runtime is downloaded to in the storage volume.
+// Every revision gets its own directory, so pods of a rollout to a new revision never load a
+// partially downloaded model.
+func localModelPath(vllmRuntime *productionstackv1alpha1.VLLMRuntime) string {
+	_, mountPath := storageVolume(vllmRuntime)
+	return path.Join(mountPath, "models", vllmRuntime.Spec.Model.ModelURL, modelRevision(vllmRuntime))
+}
+
+// hasServedModelName reports whether the extra args of vllm serve name the served model
+func hasServedModelName(args []string) bool {
+	for _, arg := range args {
+		if arg == "--served-model-name" || strings.HasPrefix(arg, "--served-model-name=") {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadNameForVLLMRuntime returns the name of the Job downloading the model of a runtime
+func downloadNameForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) string {
+	return vllmRuntime.Name + "-model-download"
+}
+
+// downloadImage returns the image of the downloader, the vLLM image unless set
+func downloadImage(vllmRuntime *productionstackv1alpha1.VLLMRuntime) productionstackv1alpha1.ImageSpec {
+	if image := vllmRuntime.Spec.Model.Prewarm.Image; image != nil {
+		return *image
+	}
+	return vllmRuntime.Spec.DeploymentConfig.Image
+}
+
+// modelVolumeMounts returns the mount of the storage volume of a runtime in the containers
+// downloading or waiting for the model
+func modelVolumeMounts(vllmRuntime *productionstackv1alpha1.VLLMRuntime) []corev1.VolumeMount {
+	volumeName, mountPath := storageVolume(vllmRuntime)
+	return []corev1.VolumeMount{{Name: volumeName, MountPath: mountPath}}
+}
+
+// downloadContainerForVLLMRuntime returns the container downloading the model of a runtime into
+// its storage volume, or an error when its resources cannot be parsed
+func downloadContainerForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (corev1.Container, error) {
+	prewarm := vllmRuntime.Spec.Model.Prewarm
+	resources, err := buildResourceRequirements("spec.model.prewarm.resources", prewarm.Resources)
+	if err != nil {
+		return corev1.Container{}, err
+	}
+
+	image := downloadImage(vllmRuntime)
+	imagePullPolicy := corev1.PullIfNotPresent
+	if image.PullPolicy != "" {
+		imagePullPolicy = corev1.PullPolicy(image.PullPolicy)
+	}
+
+	var env []corev1.EnvVar
+	if vllmRuntime.Spec.Model.HFTokenSecret.Name != "" {
+		env = append(env, corev1.EnvVar{
+			Name: "HF_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: vllmRuntime.Spec.Model.HFTokenSecret,
+					Key:                  vllmRuntime.Spec.Model.HFTokenName,
+				},
+			},
+		})
+	}
+
+	localPath := localModelPath(vllmRuntime)
+	return corev1.Container{
+		Name:            downloadContainerName,
+		Image:           image.Registry + "/" + image.Name,
+		ImagePullPolicy: imagePullPolicy,
+		Command:         []string{"python3", "-c", downloadScript},
+		Args: []string{
+			vllmRuntime.Spec.Model.ModelURL,
+			modelRevision(vllmRuntime),
+			localPath,
+			path.Join(localPath, downloadCompleteMarker),
+			fmt.Sprintf("%d", downloadProgressPort),
+		},
+		Env: env,
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "progress",
+				ContainerPort: downloadProgressPort,
+			},
+		},
+		Resources:    resources,
+		VolumeMounts: modelVolumeMounts(vllmRuntime),
+	}, nil
+}
+
+// modelInitContainers returns the init containers of the vLLM pods of a pre-warmed runtime: the
+// downloader itself, or a container waiting for the download Job
+func modelInitContainers(vllmRuntime *productionstackv1alpha1.VLLMRuntime) ([]corev1.Container, error) {
+	if vllmRuntime.Spec.Model.Prewarm.Mode == prewarmModeInitContainer {
+		container, err := downloadContainerForVLLMRuntime(vllmRuntime)
+		if err != nil {
+			return nil, err
+		}
+		return []corev1.Container{container}, nil
+	}
+
+	image := downloadImage(vllmRuntime)
+	imagePullPolicy := corev1.PullIfNotPresent
+	if image.PullPolicy != "" {
+		imagePullPolicy = corev1.PullPolicy(image.PullPolicy)
+	}
+	return []corev1.Container{
+		{
+			Name:            waitContainerName,
+			Image:           image.Registry + "/" + image.Name,
+			ImagePullPolicy: imagePullPolicy,
+			Command:         []string{"/bin/sh", "-c", waitScript, waitContainerName},
+			Args:            []string{path.Join(localModelPath(vllmRuntime), downloadCompleteMarker)},
+			VolumeMounts:    modelVolumeMounts(vllmRuntime),
+		},
+	}, nil
+}
+
+// jobForVLLMRuntime returns the Job downloading the model of a runtime into its storage volume, or
+// an error when the spec holds values that cannot be parsed
+func (r *VLLMRuntimeReconciler) jobForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (*batchv1.Job, error) {
+	container, err := downloadContainerForVLLMRuntime(vllmRuntime)
+	if err != nil {
+		return nil, err
+	}
+
+	var imagePullSecrets []corev1.LocalObjectReference
+	if image := downloadImage(vllmRuntime); image.PullSecretName != "" {
+		imagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReference{Name: image.PullSecretName})
+	}
+
+	volumeName, _ := storageVolume(vllmRuntime)
+	backoffLimit := vllmRuntime.Spec.Model.Prewarm.BackoffLimit
+	name := downloadNameForVLLMRuntime(vllmRuntime)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: vllmRuntime.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": name},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					ImagePullSecrets: imagePullSecrets,
+					Volumes: []corev1.Volume{
+						{
+							Name: volumeName,
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: vllmRuntime.Name,
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{container},
+				},
+			},
+		},
+	}
+	// Land on the nodes of the vLLM pods, which a ReadWriteOnce volume is bound to
+	applyScheduling(&job.Spec.Template.Spec, vllmRuntime.Spec.Scheduling)
+
+	setSpecHash(job, job.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(vllmRuntime, job, r.Scheme)
+	return job, nil
+}
+
+// reconcileModelDownload runs the download Job of a pre-warmed runtime in Job mode and reports the
+// progress of the download. It returns a result to end the reconcile with, or nil to carry on.
+func (r *VLLMRuntimeReconciler) reconcileModelDownload(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime) (*ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: downloadNameForVLLMRuntime(vllmRuntime), Namespace: vllmRuntime.Namespace}}
+	if !isPrewarmed(vllmRuntime) || vllmRuntime.Spec.Model.Prewarm.Mode == prewarmModeInitContainer {
+		// Remove the Job left from Job mode
+		if err := deleteOwnedObject(ctx, r.Client, vllmRuntime, job); err != nil {
+			log.Error(err, "Failed to delete Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+			return &ctrl.Result{}, err
+		}
+	}
+	if !isPrewarmed(vllmRuntime) {
+		if err := r.updateModelDownloadStatus(ctx, vllmRuntime, nil); err != nil {
+			log.Error(err, "Failed to update VLLMRuntime model download status")
+			return &ctrl.Result{}, err
+		}
+		return nil, nil
+	}
+
+	var status *productionstackv1alpha1.ModelDownloadStatus
+	if vllmRuntime.Spec.Model.Prewarm.Mode == prewarmModeInitContainer {
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(vllmRuntime.Namespace),
+			client.MatchingLabels(servingLabelsForVLLMRuntime(vllmRuntime))); err != nil {
+			log.Error(err, "Failed to list pods")
+			return &ctrl.Result{}, err
+		}
+		status = r.podsDownloadStatus(ctx, vllmRuntime, pods.Items)
+	} else {
+		// Build the desired Job, reporting values that cannot be parsed on the VLLMRuntime
+		desired, err := r.jobForVLLMRuntime(vllmRuntime)
+		if err != nil {
+			result, err := r.handleInvalidSpec(ctx, vllmRuntime, err)
+			return &result, err
+		}
+
+		// Check if the Job already exists, if not create a new one
+		err = r.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, job)
+		if err != nil && errors.IsNotFound(err) {
+			log.Info("Creating a new Job", "Job.Namespace", desired.Namespace, "Job.Name", desired.Name)
+			if err := r.Create(ctx, desired); err != nil {
+				log.Error(err, "Failed to create new Job", "Job.Namespace", desired.Namespace, "Job.Name", desired.Name)
+				return &ctrl.Result{}, err
+			}
+			// Job created successfully - return and requeue
+			return &ctrl.Result{Requeue: true}, nil
+		} else if err != nil {
+			log.Error(err, "Failed to get Job")
+			return &ctrl.Result{}, err
+		}
+
+		// The template of a Job cannot change, so a Job for a different model, revision or
+		// downloader is replaced
+		if specHashChanged(job, desired) {
+			log.Info("Replacing Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+			if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Failed to delete Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+				return &ctrl.Result{}, err
+			}
+			// Job deleted successfully - return and requeue to create it again
+			return &ctrl.Result{Requeue: true}, nil
+		}
+
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(job.Namespace),
+			client.MatchingLabels(desired.Spec.Template.Labels)); err != nil {
+			log.Error(err, "Failed to list pods")
+			return &ctrl.Result{}, err
+		}
+		status = r.jobDownloadStatus(ctx, vllmRuntime, job, pods.Items)
+	}
+
+	if err := r.updateModelDownloadStatus(ctx, vllmRuntime, status); err != nil {
+		log.Error(err, "Failed to update VLLMRuntime model download status")
+		return &ctrl.Result{}, err
+	}
+	return nil, nil
+}
+
+// newDownloadStatus returns the status of a download in phase, keeping the progress last reported
+// for the same model revision
+func newDownloadStatus(vllmRuntime *productionstackv1alpha1.VLLMRuntime, phase, message string) *productionstackv1alpha1.ModelDownloadStatus {
+	status := &productionstackv1alpha1.ModelDownloadStatus{
+		Phase:     phase,
+		Revision:  modelRevision(vllmRuntime),
+		LocalPath: localModelPath(vllmRuntime),
+		Message:   message,
+	}
+	if previous := vllmRuntime.Status.ModelDownload; previous != nil &&
+		previous.Revision == status.Revision && previous.LocalPath == status.LocalPath {
+		status.DownloadedBytes = previous.DownloadedBytes
+		status.TotalBytes = previous.TotalBytes
+		status.Progress = previous.Progress
+	}
+	if phase == downloadPhaseCompleted && status.TotalBytes > 0 {
+		status.DownloadedBytes = status.TotalBytes
+		status.Progress = "100%"
+	}
+	return status
+}
+
+// setDownloadProgress records the progress scraped from a downloader in a download status
+func setDownloadProgress(status *productionstackv1alpha1.ModelDownloadStatus, progress *downloadProgress) {
+	status.DownloadedBytes = progress.downloadedBytes
+	status.TotalBytes = progress.totalBytes
+	if progress.totalBytes > 0 {
+		status.Progress = fmt.Sprintf("%d%%", progress.downloadedBytes*100/progress.totalBytes)
+	}
+}
+
+// scrapeDownloadProgress records the progress of the downloader running in pod in a download
+// status. A downloader that cannot be scraped yet, e.g. while it lists the files of the model,
+// leaves the status as is.
+func (r *VLLMRuntimeReconciler) scrapeDownloadProgress(ctx context.Context, pod *corev1.Pod, status *productionstackv1alpha1.ModelDownloadStatus) {
+	var progress *downloadProgress
+	err := r.scrapePodMetrics(ctx, pod, downloadProgressPort, func(in io.Reader) (err error) {
+		progress, err = parseDownloadProgress(in)
+		return err
+	})
+	if err == nil {
+		setDownloadProgress(status, progress)
+	}
+}
+
+// downloadContainerStatus returns the status of the downloader container in pod, or nil when the
+// pod has none
+func downloadContainerStatus(pod *corev1.Pod) *corev1.ContainerStatus {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for i := range statuses {
+			if statuses[i].Name == downloadContainerName {
+				return &statuses[i]
+			}
+		}
+	}
+	return nil
+}
+
+// downloadsTo reports whether the downloader of pod downloads the model into localPath
+func downloadsTo(pod *corev1.Pod, localPath string) bool {
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == downloadContainerName {
+			return len(container.Args) > 2 && container.Args[2] == localPath
+		}
+	}
+	return false
+}
+
+// jobDownloadStatus returns the status of the download run by job, scraping the progress of its
+// running pod
+func (r *VLLMRuntimeReconciler) jobDownloadStatus(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime, job *batchv1.Job, pods []corev1.Pod) *productionstackv1alpha1.ModelDownloadStatus {
+	if job.Status.Succeeded > 0 {
+		return newDownloadStatus(vllmRuntime, downloadPhaseCompleted, "The model is in the storage volume")
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return newDownloadStatus(vllmRuntime, downloadPhaseFailed, fmt.Sprintf("Job %s failed: %s", job.Name, condition.Message))
+		}
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if container := downloadContainerStatus(pod); container != nil && container.State.Running != nil {
+			status := newDownloadStatus(vllmRuntime, downloadPhaseDownloading, fmt.Sprintf("Job %s is downloading the model", job.Name))
+			r.scrapeDownloadProgress(ctx, pod, status)
+			return status
+		}
+	}
+	return newDownloadStatus(vllmRuntime, downloadPhasePending, fmt.Sprintf("Waiting for Job %s to start", job.Name))
+}
+
+// podsDownloadStatus returns the status of the download run by the init containers of the vLLM
+// pods, scraping the progress of a running one. The model is downloaded once any of them
+// succeeded, since they share the storage volume.
+func (r *VLLMRuntimeReconciler) podsDownloadStatus(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime, pods []corev1.Pod) *productionstackv1alpha1.ModelDownloadStatus {
+	localPath := localModelPath(vllmRuntime)
+	var running *corev1.Pod
+	var failure string
+	for i := range pods {
+		pod := &pods[i]
+		container := downloadContainerStatus(pod)
+		// Pods from before a change of the model or revision downloaded a different one
+		if container == nil || !downloadsTo(pod, localPath) {
+			continue
+		}
+		if terminated := container.State.Terminated; terminated != nil && terminated.ExitCode == 0 {
+			return newDownloadStatus(vllmRuntime, downloadPhaseCompleted, "The model is in the storage volume")
+		}
+		if container.State.Running != nil && running == nil {
+			running = pod
+		}
+		if terminated := container.LastTerminationState.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			failure = fmt.Sprintf("The download in pod %s failed with exit code %d", pod.Name, terminated.ExitCode)
+		}
+	}
+
+	switch {
+	case running != nil:
+		status := newDownloadStatus(vllmRuntime, downloadPhaseDownloading, fmt.Sprintf("Pod %s is downloading the model", running.Name))
+		r.scrapeDownloadProgress(ctx, running, status)
+		return status
+	case failure != "":
+		return newDownloadStatus(vllmRuntime, downloadPhaseFailed, failure)
+	default:
+		return newDownloadStatus(vllmRuntime, downloadPhasePending, "Waiting for the vLLM pods to start")
+	}
+}
+
+// isDownloading reports whether the model of a runtime is still being downloaded
+func isDownloading(vllmRuntime *productionstackv1alpha1.VLLMRuntime) bool {
+	status := vllmRuntime.Status.ModelDownload
+	return status != nil && (status.Phase == downloadPhasePending || status.Phase == downloadPhaseDownloading)
+}
+
+// updateModelDownloadStatus records the status of the model download of a runtime, nil when
+// pre-warming is disabled
+func (r *VLLMRuntimeReconciler) updateModelDownloadStatus(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime, status *productionstackv1alpha1.ModelDownloadStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestVR := &productionstackv1alpha1.VLLMRuntime{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}, latestVR); err != nil {
+			return err
+		}
+
+		vllmRuntime.Status.ModelDownload = status
+		if reflect.DeepEqual(latestVR.Status.ModelDownload, status) {
+			return nil // No update needed
+		}
+
+		latestVR.Status.ModelDownload = status
+		return r.Status().Update(ctx, latestVR)
+	})
+}
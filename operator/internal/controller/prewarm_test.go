@@ -0,0 +1,198 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRuntime model pre-warming", func() {
+	const localPath = "/models-cache/models/meta-llama/Llama-3.1-8B/0e9e39f"
+
+	newRuntime := func() *productionstackv1alpha1.VLLMRuntime {
+		return &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				Model: productionstackv1alpha1.ModelSpec{
+					ModelURL:      "meta-llama/Llama-3.1-8B",
+					Revision:      "0e9e39f",
+					HFTokenSecret: corev1.LocalObjectReference{Name: "hf-token"},
+					HFTokenName:   "token",
+					Prewarm:       productionstackv1alpha1.PrewarmConfig{Enabled: true, Mode: "Job", BackoffLimit: 6},
+				},
+				VLLMConfig: productionstackv1alpha1.VLLMConfig{Port: 8000},
+				StorageConfig: productionstackv1alpha1.StorageConfig{
+					Enabled: true, Size: "100Gi", MountPath: "/models-cache",
+				},
+				DeploymentConfig: productionstackv1alpha1.DeploymentConfig{
+					Replicas: 1,
+					Image:    productionstackv1alpha1.ImageSpec{Registry: "docker.io", Name: "lmcache/vllm-openai:latest"},
+				},
+			},
+		}
+	}
+	downloadPod := func(name, path string, state corev1.ContainerState) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: downloadContainerName, Args: []string{"repo", "rev", path}}},
+			},
+			Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{{Name: downloadContainerName, State: state}},
+			},
+		}
+	}
+
+	It("serves the model from the storage volume under its model URL once the Job downloaded it", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		dep, err := r.deploymentForVLLMRuntime(newRuntime())
+		Expect(err).NotTo(HaveOccurred())
+
+		container := dep.Spec.Template.Spec.Containers[0]
+		Expect(container.Args[0]).To(Equal(localPath))
+		Expect(strings.Join(container.Args, " ")).To(ContainSubstring("--served-model-name meta-llama/Llama-3.1-8B"))
+		Expect(container.Args).NotTo(ContainElement("--revision"))
+
+		initContainers := dep.Spec.Template.Spec.InitContainers
+		Expect(initContainers).To(HaveLen(1))
+		Expect(initContainers[0].Name).To(Equal(waitContainerName))
+		Expect(initContainers[0].Args).To(Equal([]string{localPath + "/" + downloadCompleteMarker}))
+		Expect(initContainers[0].VolumeMounts[0].MountPath).To(Equal("/models-cache"))
+	})
+
+	It("downloads the model in every pod in init container mode", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.Model.Prewarm.Mode = prewarmModeInitContainer
+		vr.Spec.VLLMConfig.ExtraArgs = []string{"--served-model-name", "llama"}
+		dep, err := r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(strings.Count(strings.Join(dep.Spec.Template.Spec.Containers[0].Args, " "), "--served-model-name")).To(Equal(1))
+		initContainers := dep.Spec.Template.Spec.InitContainers
+		Expect(initContainers).To(HaveLen(1))
+		Expect(initContainers[0].Name).To(Equal(downloadContainerName))
+		Expect(initContainers[0].Args[:3]).To(Equal([]string{"meta-llama/Llama-3.1-8B", "0e9e39f", localPath}))
+		Expect(initContainers[0].Env[0].ValueFrom.SecretKeyRef.Name).To(Equal("hf-token"))
+	})
+
+	It("pins the revision vLLM downloads at startup without pre-warming", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.Model.Prewarm.Enabled = false
+		dep, err := r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(dep.Spec.Template.Spec.Containers[0].Args[0]).To(Equal("meta-llama/Llama-3.1-8B"))
+		Expect(strings.Join(dep.Spec.Template.Spec.Containers[0].Args, " ")).To(ContainSubstring("--revision 0e9e39f"))
+		Expect(dep.Spec.Template.Spec.InitContainers).To(BeEmpty())
+	})
+
+	It("builds a Job that downloads the model into the storage volume and replaces it for a new revision", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.Model.Prewarm.Image = &productionstackv1alpha1.ImageSpec{
+			Registry: "ghcr.io", Name: "acme/hf-downloader:1.0", PullSecretName: "ghcr",
+		}
+		job, err := r.jobForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Name).To(Equal("llama-model-download"))
+		Expect(*job.Spec.BackoffLimit).To(Equal(int32(6)))
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		Expect(podSpec.ImagePullSecrets).To(Equal([]corev1.LocalObjectReference{{Name: "ghcr"}}))
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("llama"))
+		Expect(podSpec.Containers[0].Image).To(Equal("ghcr.io/acme/hf-downloader:1.0"))
+		Expect(podSpec.Containers[0].Ports[0].ContainerPort).To(Equal(downloadProgressPort))
+
+		vr.Spec.Model.Revision = "main"
+		replaced, err := r.jobForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(specHashChanged(job, replaced)).To(BeTrue())
+	})
+
+	It("parses the progress reported by the downloader", func() {
+		progress, err := parseDownloadProgress(strings.NewReader(`# TYPE model_download_downloaded_bytes gauge
+model_download_downloaded_bytes 4.2e+09
+# TYPE model_download_total_bytes gauge
+model_download_total_bytes 1.6e+10
+`))
+		Expect(err).NotTo(HaveOccurred())
+
+		status := &productionstackv1alpha1.ModelDownloadStatus{}
+		setDownloadProgress(status, progress)
+		Expect(status.DownloadedBytes).To(Equal(int64(4200000000)))
+		Expect(status.TotalBytes).To(Equal(int64(16000000000)))
+		Expect(status.Progress).To(Equal("26%"))
+	})
+
+	It("reports the download of the Job", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Status.ModelDownload = &productionstackv1alpha1.ModelDownloadStatus{
+			Revision: "0e9e39f", LocalPath: localPath, DownloadedBytes: 10, TotalBytes: 40, Progress: "25%",
+		}
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "llama-model-download"}}
+
+		status := r.jobDownloadStatus(context.Background(), vr, job, nil)
+		Expect(status.Phase).To(Equal(downloadPhasePending))
+		Expect(status.Progress).To(Equal("25%"))
+
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+		status = r.jobDownloadStatus(context.Background(), vr, job, nil)
+		Expect(status.Phase).To(Equal(downloadPhaseFailed))
+		Expect(status.Message).To(ContainSubstring("BackoffLimitExceeded"))
+
+		job.Status.Succeeded = 1
+		status = r.jobDownloadStatus(context.Background(), vr, job, nil)
+		Expect(status.Phase).To(Equal(downloadPhaseCompleted))
+		Expect(status.DownloadedBytes).To(Equal(int64(40)))
+		Expect(status.Progress).To(Equal("100%"))
+	})
+
+	It("reports the download of the init containers, ignoring pods of a previous revision", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.Model.Prewarm.Mode = prewarmModeInitContainer
+		succeeded := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}
+		waiting := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}
+
+		pods := []corev1.Pod{downloadPod("llama-old", "/models-cache/models/meta-llama/Llama-3.1-8B/main", succeeded)}
+		Expect(r.podsDownloadStatus(context.Background(), vr, pods).Phase).To(Equal(downloadPhasePending))
+
+		failing := downloadPod("llama-new", localPath, waiting)
+		failing.Status.InitContainerStatuses[0].LastTerminationState.Terminated = &corev1.ContainerStateTerminated{ExitCode: 1}
+		pods = append(pods, failing)
+		status := r.podsDownloadStatus(context.Background(), vr, pods)
+		Expect(status.Phase).To(Equal(downloadPhaseFailed))
+		Expect(status.Message).To(ContainSubstring("llama-new"))
+
+		pods = append(pods, downloadPod("llama-done", localPath, succeeded))
+		Expect(r.podsDownloadStatus(context.Background(), vr, pods).Phase).To(Equal(downloadPhaseCompleted))
+	})
+})
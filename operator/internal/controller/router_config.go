@@ -0,0 +1,284 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	servingv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// dynamicConfigKey is the key of the dynamic config in its ConfigMap
+	dynamicConfigKey = "dynamic_config.json"
+	// dynamicConfigVolumeName is the name of the volume the dynamic config is mounted from
+	dynamicConfigVolumeName = "dynamic-config"
+	// dynamicConfigMountPath is the directory the dynamic config is mounted at in the router container
+	dynamicConfigMountPath = "/etc/vllm-router"
+
+	// defaultModelLabel is the model label the router gives endpoints without one
+	defaultModelLabel = "default"
+	// defaultVLLMPort is the port vLLM serves on when a VLLMRuntime does not set one, which is
+	// also the port the k8s service discovery of the router defaults to
+	defaultVLLMPort = 8000
+)
+
+// routerDynamicConfig is the dynamic config file of the router, which it reloads when it changes.
+// The keys are the fields of its DynamicRouterConfig, any other key is rejected by the router.
+type routerDynamicConfig struct {
+	ServiceDiscovery  string `json:"service_discovery"`
+	RoutingLogic      string `json:"routing_logic"`
+	SessionKey        string `json:"session_key,omitempty"`
+	K8sPort           int32  `json:"k8s_port,omitempty"`
+	K8sNamespace      string `json:"k8s_namespace,omitempty"`
+	K8sLabelSelector  string `json:"k8s_label_selector,omitempty"`
+	StaticBackends    string `json:"static_backends,omitempty"`
+	StaticModels      string `json:"static_models,omitempty"`
+	StaticModelLabels string `json:"static_model_labels,omitempty"`
+}
+
+// routerBackend is a model served by a vLLM pod, one entry of the static backends of the router
+type routerBackend struct {
+	url        string
+	model      string
+	modelLabel string
+}
+
+// usesDynamicConfig reports whether the router reads its service discovery and routing from the
+// dynamic config
+func usesDynamicConfig(router *servingv1alpha1.VLLMRouter) bool {
+	return router.Spec.DynamicConfig.Enabled
+}
+
+// usesRuntimeBackends reports whether the static backends of the router are taken from the
+// VLLMRuntimes instead of its spec
+func usesRuntimeBackends(router *servingv1alpha1.VLLMRouter) bool {
+	return usesDynamicConfig(router) && router.Spec.ServiceDiscovery == "static" && router.Spec.StaticBackends == ""
+}
+
+// configMapNameForVLLMRouter returns the name of the ConfigMap holding the dynamic config of a router
+func configMapNameForVLLMRouter(router *servingv1alpha1.VLLMRouter) string {
+	return router.Name + "-dynamic-config"
+}
+
+// k8sPortForRuntimes returns the port the k8s service discovery of a router reaches the pods of
+// the runtimes it routes to on. The router uses one port for every pod, the one of the first
+// runtime.
+func k8sPortForRuntimes(runtimes []servingv1alpha1.VLLMRuntime) int32 {
+	if len(runtimes) == 0 || runtimes[0].Spec.VLLMConfig.Port == 0 {
+		return defaultVLLMPort
+	}
+	return runtimes[0].Spec.VLLMConfig.Port
+}
+
+// dynamicConfigForVLLMRouter returns the dynamic config of a router routing to the given runtimes,
+// with the given backends for static service discovery when its spec does not list any. Every
+// setting the router reconfigures from it has to be set, as a missing one is reset to its zero
+// value instead of the value of the router args.
+func dynamicConfigForVLLMRouter(router *servingv1alpha1.VLLMRouter, runtimes []servingv1alpha1.VLLMRuntime, backends []routerBackend) routerDynamicConfig {
+	spec := router.Spec
+	config := routerDynamicConfig{
+		ServiceDiscovery: spec.ServiceDiscovery,
+		RoutingLogic:     spec.RoutingLogic,
+		SessionKey:       spec.SessionKey,
+	}
+
+	switch {
+	case spec.ServiceDiscovery == "k8s":
+		config.K8sPort = k8sPortForRuntimes(runtimes)
+		config.K8sNamespace = router.Namespace
+		config.K8sLabelSelector = spec.K8sLabelSelector
+	case usesRuntimeBackends(router):
+		var urls, models, modelLabels []string
+		labelled := false
+		for _, backend := range backends {
+			urls = append(urls, backend.url)
+			models = append(models, backend.model)
+			modelLabel := backend.modelLabel
+			if modelLabel == "" {
+				modelLabel = defaultModelLabel
+			} else {
+				labelled = true
+			}
+			modelLabels = append(modelLabels, modelLabel)
+		}
+		config.StaticBackends = strings.Join(urls, ",")
+		config.StaticModels = strings.Join(models, ",")
+		if labelled {
+			config.StaticModelLabels = strings.Join(modelLabels, ",")
+		}
+	default:
+		config.StaticBackends = spec.StaticBackends
+		config.StaticModels = spec.StaticModels
+	}
+	return config
+}
+
+// configMapForVLLMRouter returns the ConfigMap holding the dynamic config of a router
+func (r *VLLMRouterReconciler) configMapForVLLMRouter(router *servingv1alpha1.VLLMRouter, runtimes []servingv1alpha1.VLLMRuntime, backends []routerBackend) (*corev1.ConfigMap, error) {
+	data, err := json.MarshalIndent(dynamicConfigForVLLMRouter(router, runtimes, backends), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapNameForVLLMRouter(router),
+			Namespace: router.Namespace,
+			Labels:    labelsForVLLMRouter(router),
+		},
+		Data: map[string]string{dynamicConfigKey: string(data)},
+	}
+
+	setSpecHash(cm, cm.Data)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(router, cm, r.Scheme)
+	return cm, nil
+}
+
+// runtimeBackends returns a backend for every model and loaded LoRA adapter of the ready pods of
+// the VLLMRuntimes in the namespace of a router whose pods match its label selector. The backends
+// address the pods directly, as the adapters are only loaded on some of them.
+func (r *VLLMRouterReconciler) runtimeBackends(ctx context.Context, router *servingv1alpha1.VLLMRouter) ([]routerBackend, error) {
+	selector, err := labels.Parse(router.Spec.K8sLabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec.k8sLabelSelector %q: %w", router.Spec.K8sLabelSelector, err)
+	}
+
+	runtimes := &servingv1alpha1.VLLMRuntimeList{}
+	if err := r.List(ctx, runtimes, client.InNamespace(router.Namespace)); err != nil {
+		return nil, err
+	}
+	adapters := &servingv1alpha1.LoraAdapterList{}
+	if err := r.List(ctx, adapters, client.InNamespace(router.Namespace)); err != nil {
+		return nil, err
+	}
+	adaptersByPod := map[string][]string{}
+	for _, adapter := range adapters.Items {
+		for _, loaded := range adapter.Status.LoadedAdapters {
+			if loaded.Status == phaseLoaded {
+				adaptersByPod[loaded.PodAssignments.PodName] = append(adaptersByPod[loaded.PodAssignments.PodName], loaded.Name)
+			}
+		}
+	}
+
+	var backends []routerBackend
+	for i := range runtimes.Items {
+		vr := &runtimes.Items[i]
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(vr.Namespace), client.MatchingLabels(servingLabelsForVLLMRuntime(vr))); err != nil {
+			return nil, err
+		}
+		for j := range pods.Items {
+			pod := &pods.Items[j]
+			if !selector.Matches(labels.Set(pod.Labels)) || !isPodReady(pod) || pod.Status.PodIP == "" {
+				continue
+			}
+			url := fmt.Sprintf("http://%s:%d", pod.Status.PodIP, vr.Spec.VLLMConfig.Port)
+			for _, model := range append(servedModelNames(vr), adaptersByPod[pod.Name]...) {
+				backends = append(backends, routerBackend{url: url, model: model, modelLabel: pod.Labels[modelLabel]})
+			}
+		}
+	}
+
+	// Keep the config stable across reconciles
+	sort.Slice(backends, func(i, j int) bool {
+		if backends[i].url != backends[j].url {
+			return backends[i].url < backends[j].url
+		}
+		return backends[i].model < backends[j].model
+	})
+	return backends, nil
+}
+
+// reconcileDynamicConfig writes the dynamic config of a router routing to the given runtimes into
+// its ConfigMap, which the router reloads without restarting, or deletes the ConfigMap once the
+// dynamic config is disabled. It reports whether an object was written.
+func (r *VLLMRouterReconciler) reconcileDynamicConfig(ctx context.Context, router *servingv1alpha1.VLLMRouter, runtimes []servingv1alpha1.VLLMRuntime) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if !usesDynamicConfig(router) {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: configMapNameForVLLMRouter(router), Namespace: router.Namespace}}
+		return false, deleteOwnedObject(ctx, r.Client, router, cm)
+	}
+
+	var backends []routerBackend
+	if usesRuntimeBackends(router) {
+		var err error
+		if backends, err = r.runtimeBackends(ctx, router); err != nil {
+			return false, err
+		}
+	}
+	cm, err := r.configMapForVLLMRouter(router, runtimes, backends)
+	if err != nil {
+		return false, err
+	}
+
+	// Check if the ConfigMap already exists, if not create a new one
+	found := &corev1.ConfigMap{}
+	err = r.Get(ctx, types.NamespacedName{Name: cm.Name, Namespace: cm.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ConfigMap", "ConfigMap.Namespace", cm.Namespace, "ConfigMap.Name", cm.Name)
+		return true, r.Create(ctx, cm)
+	} else if err != nil {
+		return false, err
+	}
+
+	// Update the ConfigMap in place if the dynamic config changed
+	if specHashChanged(found, cm) {
+		log.Info("Updating ConfigMap", "ConfigMap.Namespace", found.Namespace, "ConfigMap.Name", found.Name)
+		copyDesiredMetadata(found, cm)
+		found.Data = cm.Data
+		return true, r.Update(ctx, found)
+	}
+	return false, nil
+}
+
+// routersForAdapter maps a LoraAdapter to the routers in its namespace that take their backends
+// from the pods of the VLLMRuntimes, so their dynamic config follows the loaded adapters
+func (r *VLLMRouterReconciler) routersForAdapter(ctx context.Context, obj client.Object) []reconcile.Request {
+	routers := &servingv1alpha1.VLLMRouterList{}
+	if err := r.List(ctx, routers, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list VLLMRouters", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range routers.Items {
+		if usesRuntimeBackends(&routers.Items[i]) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: routers.Items[i].Name, Namespace: routers.Items[i].Namespace},
+			})
+		}
+	}
+	return requests
+}
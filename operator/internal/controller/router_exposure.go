@@ -0,0 +1,495 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	servingv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// conditionTypeExposed reports whether the Ingress, HTTPRoute or load balancer exposing a
+	// router outside the cluster has an address the router is reachable at
+	conditionTypeExposed = "Exposed"
+
+	// routerServicePort is the port the Service of a router serves it on
+	routerServicePort = 80
+
+	// httpRouteRecheckInterval is how often the status of an HTTPRoute that is not exposed yet is
+	// checked again. HTTPRoutes and Gateways are not watched, as the Gateway API CRDs are optional.
+	httpRouteRecheckInterval = 30 * time.Second
+)
+
+var (
+	// httpRouteGVK and gatewayGVK are the Gateway API kinds a router is exposed through. They are
+	// handled as unstructured objects, so the operator runs in clusters without the Gateway API.
+	httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+	gatewayGVK   = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+)
+
+// annotatedSpec is the desired state of an owned object that carries annotations from the spec
+type annotatedSpec struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Spec        any               `json:"spec"`
+}
+
+// serviceTypeForVLLMRouter returns the type of the Service of a router
+func serviceTypeForVLLMRouter(router *servingv1alpha1.VLLMRouter) corev1.ServiceType {
+	if serviceType := router.Spec.Exposure.Service.Type; serviceType != "" {
+		return serviceType
+	}
+	return corev1.ServiceTypeClusterIP
+}
+
+// exposurePath returns the path prefix an Ingress or HTTPRoute serves a router under
+func exposurePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// exposureURL returns the URL of a router served on host under the given path prefix
+func exposureURL(scheme, host, path string) string {
+	return fmt.Sprintf("%s://%s%s", scheme, host, strings.TrimSuffix(exposurePath(path), "/"))
+}
+
+// keepNodePorts keeps the node ports allocated to the ports of the live Service found on the
+// desired Service, so that updating the Service does not move them
+func keepNodePorts(found, desired *corev1.Service) {
+	if desired.Spec.Type == corev1.ServiceTypeClusterIP {
+		return
+	}
+	for i := range desired.Spec.Ports {
+		for _, port := range found.Spec.Ports {
+			if port.Name == desired.Spec.Ports[i].Name {
+				desired.Spec.Ports[i].NodePort = port.NodePort
+			}
+		}
+	}
+}
+
+// ingressForVLLMRouter returns the Ingress exposing a router
+func (r *VLLMRouterReconciler) ingressForVLLMRouter(router *servingv1alpha1.VLLMRouter) *networkingv1.Ingress {
+	config := router.Spec.Exposure.Ingress
+	pathType := networkingv1.PathTypePrefix
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      router.Name,
+			Namespace: router.Namespace,
+			Labels:    labelsForVLLMRouter(router),
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: config.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     exposurePath(config.Path),
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: router.Name,
+											Port: networkingv1.ServiceBackendPort{Number: routerServicePort},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if config.ClassName != "" {
+		className := config.ClassName
+		ingress.Spec.IngressClassName = &className
+	}
+	if config.TLSSecretName != "" {
+		tls := networkingv1.IngressTLS{SecretName: config.TLSSecretName}
+		if config.Host != "" {
+			tls.Hosts = []string{config.Host}
+		}
+		ingress.Spec.TLS = []networkingv1.IngressTLS{tls}
+	}
+
+	setDesiredAnnotations(ingress, config.Annotations)
+	setSpecHash(ingress, annotatedSpec{Annotations: config.Annotations, Spec: ingress.Spec})
+
+	// Set the owner reference
+	ctrl.SetControllerReference(router, ingress, r.Scheme)
+	return ingress
+}
+
+// httpRouteForVLLMRouter returns the Gateway API HTTPRoute exposing a router
+func (r *VLLMRouterReconciler) httpRouteForVLLMRouter(router *servingv1alpha1.VLLMRouter) *unstructured.Unstructured {
+	config := router.Spec.Exposure.HTTPRoute
+
+	parentRef := map[string]any{
+		"group": gatewayGVK.Group,
+		"kind":  gatewayGVK.Kind,
+		"name":  config.GatewayName,
+	}
+	if config.GatewayNamespace != "" {
+		parentRef["namespace"] = config.GatewayNamespace
+	}
+	if config.SectionName != "" {
+		parentRef["sectionName"] = config.SectionName
+	}
+	spec := map[string]any{
+		"parentRefs": []any{parentRef},
+		"rules": []any{
+			map[string]any{
+				"matches": []any{
+					map[string]any{
+						"path": map[string]any{"type": "PathPrefix", "value": exposurePath(config.Path)},
+					},
+				},
+				"backendRefs": []any{
+					map[string]any{"name": router.Name, "port": int64(routerServicePort)},
+				},
+			},
+		},
+	}
+	if len(config.Hostnames) > 0 {
+		hostnames := make([]any, 0, len(config.Hostnames))
+		for _, hostname := range config.Hostnames {
+			hostnames = append(hostnames, hostname)
+		}
+		spec["hostnames"] = hostnames
+	}
+
+	route := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	route.SetGroupVersionKind(httpRouteGVK)
+	route.SetName(router.Name)
+	route.SetNamespace(router.Namespace)
+	route.SetLabels(labelsForVLLMRouter(router))
+
+	setSpecHash(route, spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(router, route, r.Scheme)
+	return route
+}
+
+// reconcileExposure keeps the Ingress and HTTPRoute of a router in line with its spec, deleting
+// them once disabled. It reports whether an object was written.
+func (r *VLLMRouterReconciler) reconcileExposure(ctx context.Context, router *servingv1alpha1.VLLMRouter) (bool, error) {
+	if written, err := r.reconcileIngress(ctx, router); err != nil || written {
+		return written, err
+	}
+	return r.reconcileHTTPRoute(ctx, router)
+}
+
+// reconcileIngress creates or updates the Ingress of a router, or deletes it once disabled
+func (r *VLLMRouterReconciler) reconcileIngress(ctx context.Context, router *servingv1alpha1.VLLMRouter) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if !router.Spec.Exposure.Ingress.Enabled {
+		ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: router.Name, Namespace: router.Namespace}}
+		return false, deleteOwnedObject(ctx, r.Client, router, ingress)
+	}
+
+	// Check if the Ingress already exists, if not create a new one
+	ingress := r.ingressForVLLMRouter(router)
+	found := &networkingv1.Ingress{}
+	err := r.Get(ctx, types.NamespacedName{Name: ingress.Name, Namespace: ingress.Namespace}, found)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new Ingress", "Ingress.Namespace", ingress.Namespace, "Ingress.Name", ingress.Name)
+		return true, r.Create(ctx, ingress)
+	} else if err != nil {
+		return false, err
+	}
+
+	// Update the Ingress if its desired state changed
+	if specHashChanged(found, ingress) {
+		log.Info("Updating Ingress", "Ingress.Namespace", found.Namespace, "Ingress.Name", found.Name)
+		copyDesiredAnnotations(found, ingress)
+		copyDesiredMetadata(found, ingress)
+		found.Spec = ingress.Spec
+		return true, r.Update(ctx, found)
+	}
+	return false, nil
+}
+
+// reconcileHTTPRoute creates or updates the HTTPRoute of a router, or deletes it once disabled.
+// Without the Gateway API CRDs there is nothing to reconcile, the status of the router reports it.
+func (r *VLLMRouterReconciler) reconcileHTTPRoute(ctx context.Context, router *servingv1alpha1.VLLMRouter) (bool, error) {
+	log := log.FromContext(ctx)
+
+	if !router.Spec.Exposure.HTTPRoute.Enabled {
+		route := &unstructured.Unstructured{}
+		route.SetGroupVersionKind(httpRouteGVK)
+		route.SetName(router.Name)
+		route.SetNamespace(router.Namespace)
+		if err := deleteOwnedObject(ctx, r.Client, router, route); err != nil && !meta.IsNoMatchError(err) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	// Check if the HTTPRoute already exists, if not create a new one
+	route := r.httpRouteForVLLMRouter(router)
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(httpRouteGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: route.GetName(), Namespace: route.GetNamespace()}, found)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	} else if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new HTTPRoute", "HTTPRoute.Namespace", route.GetNamespace(), "HTTPRoute.Name", route.GetName())
+		return true, r.Create(ctx, route)
+	} else if err != nil {
+		return false, err
+	}
+
+	// Update the HTTPRoute if its desired state changed
+	if specHashChanged(found, route) {
+		log.Info("Updating HTTPRoute", "HTTPRoute.Namespace", found.GetNamespace(), "HTTPRoute.Name", found.GetName())
+		copyDesiredMetadata(found, route)
+		found.Object["spec"] = route.Object["spec"]
+		return true, r.Update(ctx, found)
+	}
+	return false, nil
+}
+
+// exposedCondition returns the Exposed condition of a router
+func exposedCondition(router *servingv1alpha1.VLLMRouter, exposed bool, reason, message string) *metav1.Condition {
+	condition := &metav1.Condition{
+		Type:               conditionTypeExposed,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: router.Generation,
+		Reason:             reason,
+		Message:            message,
+	}
+	if exposed {
+		condition.Status = metav1.ConditionTrue
+	}
+	return condition
+}
+
+// routerExposure returns the URL a router is reachable at and its Exposed condition. A router
+// that is only exposed inside the cluster has no Exposed condition and is reachable at its Service.
+func (r *VLLMRouterReconciler) routerExposure(ctx context.Context, router *servingv1alpha1.VLLMRouter) (string, *metav1.Condition, error) {
+	switch {
+	case router.Spec.Exposure.Ingress.Enabled:
+		return r.ingressExposure(ctx, router)
+	case router.Spec.Exposure.HTTPRoute.Enabled:
+		return r.httpRouteExposure(ctx, router)
+	case serviceTypeForVLLMRouter(router) == corev1.ServiceTypeLoadBalancer:
+		return r.loadBalancerExposure(ctx, router)
+	}
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local", router.Name, router.Namespace), nil, nil
+}
+
+// loadBalancerExposure returns the URL of a router at the load balancer of its Service
+func (r *VLLMRouterReconciler) loadBalancerExposure(ctx context.Context, router *servingv1alpha1.VLLMRouter) (string, *metav1.Condition, error) {
+	svc := &corev1.Service{}
+	if err := r.Get(ctx, types.NamespacedName{Name: router.Name, Namespace: router.Namespace}, svc); err != nil {
+		return "", nil, err
+	}
+	for _, lb := range svc.Status.LoadBalancer.Ingress {
+		host := lb.Hostname
+		if host == "" {
+			host = lb.IP
+		}
+		if host != "" {
+			return exposureURL("http", host, ""), exposedCondition(router, true, "Ready",
+				fmt.Sprintf("Service %s is exposed by its load balancer", svc.Name)), nil
+		}
+	}
+	return "", exposedCondition(router, false, "Pending",
+		fmt.Sprintf("The load balancer of Service %s has no address yet", svc.Name)), nil
+}
+
+// ingressExposure returns the URL of a router at the host of its Ingress, or at the address of
+// the load balancer of an Ingress without a host
+func (r *VLLMRouterReconciler) ingressExposure(ctx context.Context, router *servingv1alpha1.VLLMRouter) (string, *metav1.Condition, error) {
+	config := router.Spec.Exposure.Ingress
+	ingress := &networkingv1.Ingress{}
+	if err := r.Get(ctx, types.NamespacedName{Name: router.Name, Namespace: router.Namespace}, ingress); err != nil {
+		return "", nil, err
+	}
+
+	host := config.Host
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if host != "" {
+			break
+		}
+		host = lb.Hostname
+		if host == "" {
+			host = lb.IP
+		}
+	}
+	if host == "" {
+		return "", exposedCondition(router, false, "Pending",
+			fmt.Sprintf("Ingress %s has no address yet", ingress.Name)), nil
+	}
+
+	scheme := "http"
+	if config.TLSSecretName != "" {
+		scheme = "https"
+	}
+	return exposureURL(scheme, host, config.Path), exposedCondition(router, true, "Ready",
+		fmt.Sprintf("Ingress %s exposes the router", ingress.Name)), nil
+}
+
+// httpRouteExposure returns the URL of a router at the first hostname of its HTTPRoute, or else at
+// the hostname or address of the Gateway listener it attaches to, once the Gateway accepted it
+func (r *VLLMRouterReconciler) httpRouteExposure(ctx context.Context, router *servingv1alpha1.VLLMRouter) (string, *metav1.Condition, error) {
+	config := router.Spec.Exposure.HTTPRoute
+	gatewayNamespace := config.GatewayNamespace
+	if gatewayNamespace == "" {
+		gatewayNamespace = router.Namespace
+	}
+	gatewayName := gatewayNamespace + "/" + config.GatewayName
+
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	err := r.Get(ctx, types.NamespacedName{Name: router.Name, Namespace: router.Namespace}, route)
+	if meta.IsNoMatchError(err) {
+		return "", exposedCondition(router, false, "GatewayAPINotInstalled",
+			"The Gateway API CRDs are not installed, the HTTPRoute cannot be created"), nil
+	} else if err != nil {
+		return "", nil, err
+	}
+	if !httpRouteAccepted(route) {
+		return "", exposedCondition(router, false, "NotAccepted",
+			fmt.Sprintf("HTTPRoute %s is not accepted by Gateway %s yet", route.GetName(), gatewayName)), nil
+	}
+
+	gateway := &unstructured.Unstructured{}
+	gateway.SetGroupVersionKind(gatewayGVK)
+	err = r.Get(ctx, types.NamespacedName{Name: config.GatewayName, Namespace: gatewayNamespace}, gateway)
+	if errors.IsNotFound(err) {
+		return "", exposedCondition(router, false, "Pending",
+			fmt.Sprintf("Gateway %s does not exist", gatewayName)), nil
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	// Take the scheme, port and hostname from the listener the route attaches to
+	scheme, port, host := "http", int64(0), ""
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	for _, item := range listeners {
+		listener, ok := item.(map[string]any)
+		if !ok || (config.SectionName != "" && listener["name"] != config.SectionName) {
+			continue
+		}
+		if listener["protocol"] == "HTTPS" {
+			scheme = "https"
+		}
+		port, _, _ = unstructured.NestedInt64(listener, "port")
+		host, _, _ = unstructured.NestedString(listener, "hostname")
+		break
+	}
+	if strings.HasPrefix(host, "*") {
+		host = ""
+	}
+	if len(config.Hostnames) > 0 {
+		host = config.Hostnames[0]
+	}
+	if host == "" {
+		addresses, _, _ := unstructured.NestedSlice(gateway.Object, "status", "addresses")
+		for _, item := range addresses {
+			if address, ok := item.(map[string]any); ok {
+				host, _ = address["value"].(string)
+				break
+			}
+		}
+	}
+	if host == "" {
+		return "", exposedCondition(router, false, "Pending",
+			fmt.Sprintf("Gateway %s has no address yet", gatewayName)), nil
+	}
+	if port != 0 && !(scheme == "http" && port == 80) && !(scheme == "https" && port == 443) {
+		host = fmt.Sprintf("%s:%d", host, port)
+	}
+	return exposureURL(scheme, host, config.Path), exposedCondition(router, true, "Ready",
+		fmt.Sprintf("HTTPRoute %s is accepted by Gateway %s", route.GetName(), gatewayName)), nil
+}
+
+// httpRouteAccepted reports whether a Gateway accepted an HTTPRoute
+func httpRouteAccepted(route *unstructured.Unstructured) bool {
+	parents, _, _ := unstructured.NestedSlice(route.Object, "status", "parents")
+	for _, item := range parents {
+		parent, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		conditions, _, _ := unstructured.NestedSlice(parent, "conditions")
+		for _, item := range conditions {
+			if condition, ok := item.(map[string]any); ok && condition["type"] == "Accepted" && condition["status"] == "True" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// updateExposureStatus records the URL a router is reachable at and whether it is exposed
+// outside the cluster. It reports whether the router is exposed as configured.
+func (r *VLLMRouterReconciler) updateExposureStatus(ctx context.Context, router *servingv1alpha1.VLLMRouter) (bool, error) {
+	url, condition, err := r.routerExposure(ctx, router)
+	if err != nil {
+		return false, err
+	}
+
+	exposed := condition == nil || condition.Status == metav1.ConditionTrue
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestRouter := &servingv1alpha1.VLLMRouter{}
+		if err := r.Get(ctx, types.NamespacedName{Name: router.Name, Namespace: router.Namespace}, latestRouter); err != nil {
+			return err
+		}
+
+		previous := latestRouter.Status.DeepCopy()
+		latestRouter.Status.URL = url
+		if condition != nil {
+			meta.SetStatusCondition(&latestRouter.Status.Conditions, *condition)
+		} else {
+			meta.RemoveStatusCondition(&latestRouter.Status.Conditions, conditionTypeExposed)
+		}
+
+		router.Status.URL = latestRouter.Status.URL
+		router.Status.Conditions = latestRouter.Status.Conditions
+		if reflect.DeepEqual(previous, &latestRouter.Status) {
+			return nil // No update needed
+		}
+
+		return r.Status().Update(ctx, latestRouter)
+	})
+	return exposed, err
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	servingv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// legacyRoleName and legacyRoleBindingName name the RBAC objects all routers of a namespace
+	// shared before they got their own
+	legacyRoleName        = "pod-viewer-role"
+	legacyRoleBindingName = "pod-viewer-binding"
+)
+
+// serviceAccountNameForVLLMRouter returns the ServiceAccount of the router pods, the one named in
+// the spec or one named after the router
+func serviceAccountNameForVLLMRouter(router *servingv1alpha1.VLLMRouter) string {
+	if router.Spec.ServiceAccountName != "" {
+		return router.Spec.ServiceAccountName
+	}
+	return router.Name
+}
+
+// rbacNameForVLLMRouter returns the name of the Role and RoleBinding of a router
+func rbacNameForVLLMRouter(router *servingv1alpha1.VLLMRouter) string {
+	return router.Name + "-router"
+}
+
+// routerPolicyRules returns the permissions the service discovery of a router needs. k8s service
+// discovery watches the pods of the backends, static service discovery needs none.
+func routerPolicyRules(router *servingv1alpha1.VLLMRouter) []rbacv1.PolicyRule {
+	if router.Spec.ServiceDiscovery != "k8s" {
+		return nil
+	}
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"pods"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+	}
+}
+
+// reconcileRBAC creates the ServiceAccount of a router when it does not exist and keeps its Role
+// and RoleBinding in line with its service discovery. A router without permissions to grant gets
+// neither. It reports whether an object was written.
+func (r *VLLMRouterReconciler) reconcileRBAC(ctx context.Context, router *servingv1alpha1.VLLMRouter) (bool, error) {
+	log := log.FromContext(ctx)
+
+	// Remove the RBAC objects shared by the routers of the namespace before, once they are owned
+	// by this router
+	for _, obj := range []client.Object{
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: legacyRoleBindingName, Namespace: router.Namespace}},
+		&rbacv1.Role{ObjectMeta: metav1.ObjectMeta{Name: legacyRoleName, Namespace: router.Namespace}},
+	} {
+		if err := deleteOwnedObject(ctx, r.Client, router, obj); err != nil {
+			return false, err
+		}
+	}
+
+	// Remove the generated ServiceAccount once the spec names another one
+	if serviceAccountNameForVLLMRouter(router) != router.Name {
+		sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: router.Name, Namespace: router.Namespace}}
+		if err := deleteOwnedObject(ctx, r.Client, router, sa); err != nil {
+			return false, err
+		}
+	}
+
+	// Create the ServiceAccount if it doesn't exist. A ServiceAccount named in the spec that exists
+	// already is left alone.
+	sa := r.serviceAccountForVLLMRouter(router)
+	err := r.Get(ctx, types.NamespacedName{Name: sa.Name, Namespace: sa.Namespace}, &corev1.ServiceAccount{})
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new ServiceAccount", "ServiceAccount.Namespace", sa.Namespace, "ServiceAccount.Name", sa.Name)
+		return true, r.Create(ctx, sa)
+	} else if err != nil {
+		return false, err
+	}
+
+	role := r.roleForVLLMRouter(router)
+	roleBinding := r.roleBindingForVLLMRouter(router)
+	if len(role.Rules) == 0 {
+		for _, obj := range []client.Object{roleBinding, role} {
+			if err := deleteOwnedObject(ctx, r.Client, router, obj); err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	// Check if the Role already exists, if not create a new one
+	foundRole := &rbacv1.Role{}
+	err = r.Get(ctx, types.NamespacedName{Name: role.Name, Namespace: role.Namespace}, foundRole)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new Role", "Role.Namespace", role.Namespace, "Role.Name", role.Name)
+		return true, r.Create(ctx, role)
+	} else if err != nil {
+		return false, err
+	}
+
+	// Update the Role if its rules changed or were edited
+	if specHashChanged(foundRole, role) || !reflect.DeepEqual(foundRole.Rules, role.Rules) {
+		log.Info("Updating Role", "Role.Namespace", foundRole.Namespace, "Role.Name", foundRole.Name)
+		copyDesiredMetadata(foundRole, role)
+		foundRole.Rules = role.Rules
+		return true, r.Update(ctx, foundRole)
+	}
+
+	// Check if the RoleBinding already exists, if not create a new one
+	foundBinding := &rbacv1.RoleBinding{}
+	err = r.Get(ctx, types.NamespacedName{Name: roleBinding.Name, Namespace: roleBinding.Namespace}, foundBinding)
+	if err != nil && errors.IsNotFound(err) {
+		log.Info("Creating a new RoleBinding", "RoleBinding.Namespace", roleBinding.Namespace, "RoleBinding.Name", roleBinding.Name)
+		return true, r.Create(ctx, roleBinding)
+	} else if err != nil {
+		return false, err
+	}
+
+	// The role of a binding cannot be changed, so a binding to another role is replaced
+	if foundBinding.RoleRef != roleBinding.RoleRef {
+		log.Info("Replacing RoleBinding", "RoleBinding.Namespace", foundBinding.Namespace, "RoleBinding.Name", foundBinding.Name)
+		return true, r.Delete(ctx, foundBinding)
+	}
+
+	// Update the RoleBinding if its subjects changed or were edited
+	if specHashChanged(foundBinding, roleBinding) || !reflect.DeepEqual(foundBinding.Subjects, roleBinding.Subjects) {
+		log.Info("Updating RoleBinding", "RoleBinding.Namespace", foundBinding.Namespace, "RoleBinding.Name", foundBinding.Name)
+		copyDesiredMetadata(foundBinding, roleBinding)
+		foundBinding.Subjects = roleBinding.Subjects
+		return true, r.Update(ctx, foundBinding)
+	}
+	return false, nil
+}
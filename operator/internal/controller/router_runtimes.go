@@ -0,0 +1,261 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	servingv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// conditionTypeRuntimesReady reports whether every VLLMRuntime a router routes to exists and is
+	// available
+	conditionTypeRuntimesReady = "RuntimesReady"
+
+	// noRuntimesLabelSelector selects no pods, for a router whose runtime selection matches no
+	// VLLMRuntime. An empty selector would select every pod in the namespace.
+	noRuntimesLabelSelector = "production-stack.vllm.ai/no-runtimes"
+)
+
+// usesRuntimeSelection reports whether a router selects its backends by VLLMRuntime instead of a
+// pod label selector or static backends
+func usesRuntimeSelection(router *servingv1alpha1.VLLMRouter) bool {
+	return len(router.Spec.RuntimeRefs) > 0 || router.Spec.RuntimeSelector != nil
+}
+
+// routesToRuntimes reports whether the backends of a router are VLLMRuntimes, rather than static
+// backends listed in its spec
+func routesToRuntimes(router *servingv1alpha1.VLLMRouter) bool {
+	return usesRuntimeSelection(router) || router.Spec.ServiceDiscovery == "k8s" || usesRuntimeBackends(router)
+}
+
+// routerSelectsRuntime reports whether a router routes to a VLLMRuntime. Without runtime selection
+// the pods of the runtime have to match the k8s label selector of the router.
+func routerSelectsRuntime(router *servingv1alpha1.VLLMRouter, vllmRuntime *servingv1alpha1.VLLMRuntime) (bool, error) {
+	if !usesRuntimeSelection(router) {
+		if !routesToRuntimes(router) {
+			return false, nil
+		}
+		selector, err := labels.Parse(router.Spec.K8sLabelSelector)
+		if err != nil {
+			return false, fmt.Errorf("invalid spec.k8sLabelSelector %q: %w", router.Spec.K8sLabelSelector, err)
+		}
+		return selector.Matches(labels.Set(podLabelsForVLLMRuntime(vllmRuntime, labelsForVLLMRuntime(vllmRuntime)))), nil
+	}
+
+	for _, ref := range router.Spec.RuntimeRefs {
+		if ref.Name == vllmRuntime.Name {
+			return true, nil
+		}
+	}
+	if router.Spec.RuntimeSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(router.Spec.RuntimeSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid spec.runtimeSelector: %w", err)
+	}
+	return selector.Matches(labels.Set(vllmRuntime.Labels)), nil
+}
+
+// selectRuntimes returns the VLLMRuntimes a router routes to, sorted by name, and the names of
+// the referenced runtimes that do not exist
+func (r *VLLMRouterReconciler) selectRuntimes(ctx context.Context, router *servingv1alpha1.VLLMRouter) ([]servingv1alpha1.VLLMRuntime, []string, error) {
+	runtimes := &servingv1alpha1.VLLMRuntimeList{}
+	if err := r.List(ctx, runtimes, client.InNamespace(router.Namespace)); err != nil {
+		return nil, nil, err
+	}
+
+	var selected []servingv1alpha1.VLLMRuntime
+	found := map[string]bool{}
+	for i := range runtimes.Items {
+		vr := &runtimes.Items[i]
+		found[vr.Name] = true
+		selects, err := routerSelectsRuntime(router, vr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if selects {
+			selected = append(selected, *vr)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+
+	var missing []string
+	for _, ref := range router.Spec.RuntimeRefs {
+		if !found[ref.Name] {
+			missing = append(missing, ref.Name)
+		}
+	}
+	return selected, missing, nil
+}
+
+// routerForRuntimes returns a copy of a router that selects its backends by VLLMRuntime, with the
+// label selector or static backends derived from the runtimes, so the rest of the reconcile builds
+// the objects of the router from them. The static backends are the Services of the runtimes,
+// unless the dynamic config takes them from their pods.
+func routerForRuntimes(router *servingv1alpha1.VLLMRouter, runtimes []servingv1alpha1.VLLMRuntime) *servingv1alpha1.VLLMRouter {
+	derived := router.DeepCopy()
+	if derived.Spec.ServiceDiscovery == "static" && !usesDynamicConfig(derived) {
+		var backends, models []string
+		for _, vr := range runtimes {
+			for _, model := range servedModelNames(&vr) {
+				backends = append(backends, fmt.Sprintf("http://%s.%s.svc.cluster.local", vr.Name, vr.Namespace))
+				models = append(models, model)
+			}
+		}
+		derived.Spec.StaticBackends = strings.Join(backends, ",")
+		derived.Spec.StaticModels = strings.Join(models, ",")
+		return derived
+	}
+
+	// The pods of a runtime carry its name in the runtime label, which its labels cannot override
+	if len(runtimes) == 0 {
+		derived.Spec.K8sLabelSelector = noRuntimesLabelSelector
+		return derived
+	}
+	names := make([]string, 0, len(runtimes))
+	for _, vr := range runtimes {
+		names = append(names, vr.Name)
+	}
+	derived.Spec.K8sLabelSelector = fmt.Sprintf("%s in (%s)", runtimeLabel, strings.Join(names, ","))
+	return derived
+}
+
+// runtimesCondition returns the RuntimesReady condition of a router for the runtimes it routes to
+// and the referenced runtimes that do not exist
+func runtimesCondition(generation int64, runtimes []servingv1alpha1.RouterRuntimeStatus, missing []string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               conditionTypeRuntimesReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+
+	var notReady []string
+	for _, status := range runtimes {
+		if !status.Ready {
+			notReady = append(notReady, status.Name)
+		}
+	}
+	switch {
+	case len(missing) > 0:
+		condition.Reason = "NotFound"
+		condition.Message = fmt.Sprintf("VLLMRuntimes %s do not exist", strings.Join(missing, ", "))
+	case len(runtimes) == 0:
+		condition.Reason = "NoRuntimes"
+		condition.Message = "No VLLMRuntime is selected"
+	case len(notReady) > 0:
+		condition.Reason = "NotReady"
+		condition.Message = fmt.Sprintf("VLLMRuntimes %s are not available", strings.Join(notReady, ", "))
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Ready"
+		condition.Message = fmt.Sprintf("%d VLLMRuntimes are available", len(runtimes))
+	}
+	return condition
+}
+
+// updateRuntimeStatus records the runtimes a router routes to, with their models and readiness
+func (r *VLLMRouterReconciler) updateRuntimeStatus(ctx context.Context, router *servingv1alpha1.VLLMRouter, runtimes []servingv1alpha1.VLLMRuntime, missing []string) error {
+	var statuses []servingv1alpha1.RouterRuntimeStatus
+	var active int32
+	for i := range runtimes {
+		ready := meta.IsStatusConditionTrue(runtimes[i].Status.Conditions, conditionTypeAvailable)
+		if ready {
+			active++
+		}
+		statuses = append(statuses, servingv1alpha1.RouterRuntimeStatus{
+			Name:   runtimes[i].Name,
+			Models: servedModelNames(&runtimes[i]),
+			Ready:  ready,
+		})
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestRouter := &servingv1alpha1.VLLMRouter{}
+		if err := r.Get(ctx, types.NamespacedName{Name: router.Name, Namespace: router.Namespace}, latestRouter); err != nil {
+			return err
+		}
+
+		previous := latestRouter.Status.DeepCopy()
+		latestRouter.Status.ActiveRuntimes = active
+		latestRouter.Status.Runtimes = statuses
+		// Static backends are not tied to runtimes, so there is no readiness to report for them
+		if routesToRuntimes(router) {
+			meta.SetStatusCondition(&latestRouter.Status.Conditions, runtimesCondition(latestRouter.Generation, statuses, missing))
+		} else {
+			meta.RemoveStatusCondition(&latestRouter.Status.Conditions, conditionTypeRuntimesReady)
+		}
+
+		router.Status.ActiveRuntimes = latestRouter.Status.ActiveRuntimes
+		router.Status.Runtimes = latestRouter.Status.Runtimes
+		router.Status.Conditions = latestRouter.Status.Conditions
+		if reflect.DeepEqual(previous, &latestRouter.Status) {
+			return nil // No update needed
+		}
+
+		return r.Status().Update(ctx, latestRouter)
+	})
+}
+
+// routersForRuntime maps a VLLMRuntime to the routers in its namespace that route to it or did so
+// before, so their backends and status follow it, and to those taking their dynamic config from
+// the pods of the runtimes
+func (r *VLLMRouterReconciler) routersForRuntime(ctx context.Context, obj client.Object) []reconcile.Request {
+	vllmRuntime, ok := obj.(*servingv1alpha1.VLLMRuntime)
+	if !ok {
+		return nil
+	}
+	routers := &servingv1alpha1.VLLMRouterList{}
+	if err := r.List(ctx, routers, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list VLLMRouters for VLLMRuntime", "VLLMRuntime.Name", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range routers.Items {
+		router := &routers.Items[i]
+		selects, err := routerSelectsRuntime(router, vllmRuntime)
+		if err != nil {
+			// Reconciling the router reports the invalid selector
+			selects = true
+		}
+		for _, status := range router.Status.Runtimes {
+			selects = selects || status.Name == vllmRuntime.Name
+		}
+		if selects || usesRuntimeBackends(router) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: router.Name, Namespace: router.Namespace},
+			})
+		}
+	}
+	return requests
+}
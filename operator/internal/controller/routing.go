@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	servingv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	routingLogicKVAware              = "kvaware"
+	routingLogicDisaggregatedPrefill = "disaggregated_prefill"
+
+	// lmCacheControllerPortName names the port the LMCache controller of a kvaware router
+	// listens on, in its pod and Service
+	lmCacheControllerPortName = "lmcache-ctrl"
+
+	// defaultLMCacheControllerPort is the port the LMCache controller of a kvaware router listens
+	// on when the spec does not set one
+	defaultLMCacheControllerPort = 9000
+)
+
+// routingArgs returns the router args for the parameters of its routing logic. The parameters of
+// the other routing modes are ignored.
+func routingArgs(router *servingv1alpha1.VLLMRouter) []string {
+	var args []string
+	switch router.Spec.RoutingLogic {
+	case routingLogicKVAware:
+		if threshold := router.Spec.KVAware.Threshold; threshold > 0 {
+			args = append(args, "--kv-aware-threshold", fmt.Sprintf("%d", threshold))
+		}
+		args = append(args, "--lmcache-controller-port", fmt.Sprintf("%d", lmCacheControllerPort(router)))
+	case routingLogicDisaggregatedPrefill:
+		// The labels are part of the dynamic config when the router reads it
+		if usesDynamicConfig(router) {
+			break
+		}
+		// Missing labels are rejected by the validating webhook
+		config := router.Spec.DisaggregatedPrefill
+		if len(config.PrefillModelLabels) > 0 {
+			args = append(args, "--prefill-model-labels", strings.Join(config.PrefillModelLabels, ","))
+		}
+		if len(config.DecodeModelLabels) > 0 {
+			args = append(args, "--decode-model-labels", strings.Join(config.DecodeModelLabels, ","))
+		}
+	}
+	return args
+}
+
+// lmCacheControllerPort returns the port the LMCache controller of a kvaware router listens on
+func lmCacheControllerPort(router *servingv1alpha1.VLLMRouter) int32 {
+	if port := router.Spec.KVAware.LMCacheControllerPort; port > 0 {
+		return port
+	}
+	return defaultLMCacheControllerPort
+}
+
+// routerContainerPorts returns the ports of the router container, which include the port of the
+// LMCache controller the runtimes register with under kvaware routing
+func routerContainerPorts(router *servingv1alpha1.VLLMRouter) []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{
+		{
+			Name:          "http",
+			ContainerPort: router.Spec.Port,
+		},
+	}
+	if router.Spec.RoutingLogic == routingLogicKVAware {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          lmCacheControllerPortName,
+			ContainerPort: lmCacheControllerPort(router),
+		})
+	}
+	return ports
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// conditionTypeScaledToZero reports whether the Deployment of a runtime was scaled to zero
+	// because its model received no requests
+	conditionTypeScaledToZero = "ScaledToZero"
+
+	// statusScaledToZero is the model status shown while the runtime is scaled to zero
+	statusScaledToZero = "ScaledToZero"
+)
+
+// routerMetrics is the request related state scraped from the metrics endpoint of a router pod for
+// the models of a runtime
+type routerMetrics struct {
+	// incomingRequests is the total number of requests the router received, a counter
+	incomingRequests float64
+	// heldRequests is the number of requests the router holds while the model scales up
+	heldRequests float64
+}
+
+// parseRouterMetrics extracts the requests received and held for any of models from router
+// metrics in the Prometheus text format
+func parseRouterMetrics(in io.Reader, models []string) (*routerMetrics, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	served := make(map[string]bool, len(models))
+	for _, model := range models {
+		served[model] = true
+	}
+
+	metrics := &routerMetrics{}
+	if family, ok := families["vllm:num_incoming_requests_total"]; ok {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "model" && served[label.GetValue()] {
+					metrics.incomingRequests += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	if family, ok := families["vllm:num_cold_start_requests"]; ok {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "model" && served[label.GetValue()] {
+					metrics.heldRequests += metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+// servedModelNames returns the model names a runtime serves requests for: the names passed with
+// --served-model-name in the extra args, otherwise the model URL
+func servedModelNames(vllmRuntime *productionstackv1alpha1.VLLMRuntime) []string {
+	args := vllmRuntime.Spec.VLLMConfig.ExtraArgs
+	for i, arg := range args {
+		if name, ok := strings.CutPrefix(arg, "--served-model-name="); ok {
+			return []string{name}
+		}
+		if arg != "--served-model-name" {
+			continue
+		}
+		var names []string
+		for _, name := range args[i+1:] {
+			if strings.HasPrefix(name, "-") {
+				break
+			}
+			names = append(names, name)
+		}
+		if len(names) > 0 {
+			return names
+		}
+	}
+	return []string{vllmRuntime.Spec.Model.ModelURL}
+}
+
+// isScaledToZero reports whether scaling to zero is enabled for a runtime and its Deployment was
+// scaled to zero
+func isScaledToZero(vllmRuntime *productionstackv1alpha1.VLLMRuntime) bool {
+	return vllmRuntime.Spec.ScaleToZero.Enabled &&
+		meta.IsStatusConditionTrue(vllmRuntime.Status.Conditions, conditionTypeScaledToZero)
+}
+
+// clearScaleToZeroStatus drops the scale to zero state of a runtime once scaling to zero is
+// disabled
+func clearScaleToZeroStatus(vllmRuntime *productionstackv1alpha1.VLLMRuntime) {
+	if vllmRuntime.Spec.ScaleToZero.Enabled {
+		return
+	}
+	vllmRuntime.Status.LastRequestTime = nil
+	meta.RemoveStatusCondition(&vllmRuntime.Status.Conditions, conditionTypeScaledToZero)
+}
+
+// idleTracker keeps the request counters of the engine and router pods of every runtime between
+// checks, so that requests finished or received in between are noticed. The zero value is ready
+// for use.
+type idleTracker struct {
+	mu       sync.Mutex
+	counters map[types.NamespacedName]map[types.UID]float64
+}
+
+// observe records the request counters of the pods scraped for a runtime and reports whether the
+// counter of any pod also scraped at the previous check changed. Pods seen for the first time
+// only set the baseline, and pods no longer scraped are dropped.
+func (t *idleTracker) observe(key types.NamespacedName, counters map[types.UID]float64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.counters == nil {
+		t.counters = make(map[types.NamespacedName]map[types.UID]float64)
+	}
+
+	changed := false
+	for uid, value := range counters {
+		if previous, ok := t.counters[key][uid]; ok && previous != value {
+			changed = true
+		}
+	}
+	t.counters[key] = counters
+	return changed
+}
+
+// forget drops all state kept for a runtime
+func (t *idleTracker) forget(key types.NamespacedName) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.counters, key)
+}
+
+// observeRequests scrapes the engine metrics of the ready pods of a runtime and the request metrics
+// of the ready router pods in its namespace, and reports whether requests for its model were
+// processed, received or held since the previous check
+func (r *VLLMRuntimeReconciler) observeRequests(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime) (bool, error) {
+	key := types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}
+	counters := make(map[types.UID]float64)
+	active := false
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(vllmRuntime.Namespace),
+		client.MatchingLabels(servingLabelsForVLLMRuntime(vllmRuntime))); err != nil {
+		return false, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !isPodReady(pod) {
+			continue
+		}
+		metrics, err := r.scrapeEngineMetrics(ctx, pod, vllmRuntime.Spec.VLLMConfig.Port)
+		if err != nil {
+			// A pod that cannot be scraped gives no evidence of requests either way
+			continue
+		}
+		if metrics.runningRequests+metrics.waitingRequests > 0 {
+			active = true
+		}
+		counters[pod.UID] = metrics.finishedRequests
+	}
+
+	routers := &productionstackv1alpha1.VLLMRouterList{}
+	if err := r.List(ctx, routers, client.InNamespace(vllmRuntime.Namespace)); err != nil {
+		return false, fmt.Errorf("failed to list routers: %w", err)
+	}
+	models := servedModelNames(vllmRuntime)
+	for i := range routers.Items {
+		router := &routers.Items[i]
+		routerPods := &corev1.PodList{}
+		if err := r.List(ctx, routerPods, client.InNamespace(router.Namespace),
+			client.MatchingLabels(labelsForVLLMRouter(router))); err != nil {
+			return false, fmt.Errorf("failed to list pods of router %s: %w", router.Name, err)
+		}
+		for j := range routerPods.Items {
+			pod := &routerPods.Items[j]
+			if !isPodReady(pod) {
+				continue
+			}
+			var metrics *routerMetrics
+			err := r.scrapePodMetrics(ctx, pod, router.Spec.Port, func(in io.Reader) (err error) {
+				metrics, err = parseRouterMetrics(in, models)
+				return err
+			})
+			if err != nil {
+				continue
+			}
+			if metrics.heldRequests > 0 {
+				active = true
+			}
+			counters[pod.UID] = metrics.incomingRequests
+		}
+	}
+
+	if r.idle.observe(key, counters) {
+		active = true
+	}
+	return active, nil
+}
+
+// scaleToZeroStatus returns the last request time and ScaledToZero condition of a runtime after
+// a check that did or did not observe requests at now
+func scaleToZeroStatus(vllmRuntime *productionstackv1alpha1.VLLMRuntime, active bool, now metav1.Time) (*metav1.Time, metav1.Condition) {
+	idleMinutes := vllmRuntime.Spec.ScaleToZero.IdleMinutes
+	lastRequest := vllmRuntime.Status.LastRequestTime
+	if active || lastRequest == nil {
+		lastRequest = &now
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionTypeScaledToZero,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: vllmRuntime.Generation,
+		Reason:             "RequestsReceived",
+		Message:            fmt.Sprintf("Requests were observed within the last %d minutes", idleMinutes),
+	}
+	scaledToZero := meta.IsStatusConditionTrue(vllmRuntime.Status.Conditions, conditionTypeScaledToZero)
+	idle := now.Sub(lastRequest.Time) >= time.Duration(idleMinutes)*time.Minute
+	switch {
+	case scaledToZero && active:
+		condition.Reason = "Activated"
+		condition.Message = "Requests arrived for the model, scaling up from zero"
+	case scaledToZero || idle:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Idle"
+		condition.Message = fmt.Sprintf("No requests were observed for %d minutes", idleMinutes)
+	}
+	return lastRequest, condition
+}
+
+// updateScaleToZeroStatus records whether requests were observed for a runtime and scales it to
+// zero or back up accordingly, so the deployment built afterwards uses it
+func (r *VLLMRuntimeReconciler) updateScaleToZeroStatus(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime, active bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestVR := &productionstackv1alpha1.VLLMRuntime{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}, latestVR); err != nil {
+			return err
+		}
+
+		previous := latestVR.Status.DeepCopy()
+		lastRequest, condition := scaleToZeroStatus(latestVR, active, metav1.Now())
+		latestVR.Status.LastRequestTime = lastRequest
+		meta.SetStatusCondition(&latestVR.Status.Conditions, condition)
+		// Start over from the configured replicas when scaling back up
+		if isScaledToZero(latestVR) {
+			latestVR.Status.Autoscaling = nil
+		}
+
+		vllmRuntime.Status.LastRequestTime = latestVR.Status.LastRequestTime
+		vllmRuntime.Status.Conditions = latestVR.Status.Conditions
+		vllmRuntime.Status.Autoscaling = latestVR.Status.Autoscaling
+		if reflect.DeepEqual(previous, &latestVR.Status) {
+			return nil // No update needed
+		}
+
+		return r.Status().Update(ctx, latestVR)
+	})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRuntime scale to zero", func() {
+	const routerMetrics = `# HELP vllm:num_incoming_requests_total Total valid incoming requests to router.
+# TYPE vllm:num_incoming_requests_total counter
+vllm:num_incoming_requests_total{model="llama"} 12.0
+vllm:num_incoming_requests_total{model="mistral"} 30.0
+# HELP vllm:num_cold_start_requests Number of requests held while their model scales up from zero
+# TYPE vllm:num_cold_start_requests gauge
+vllm:num_cold_start_requests{model="llama"} 2.0
+vllm:num_cold_start_requests{model="mistral"} 0.0
+`
+	key := types.NamespacedName{Name: "llama", Namespace: "default"}
+
+	var vr *productionstackv1alpha1.VLLMRuntime
+
+	BeforeEach(func() {
+		vr = &productionstackv1alpha1.VLLMRuntime{}
+		vr.Spec.Model.ModelURL = "meta-llama/Llama-3.1-8B"
+		vr.Spec.DeploymentConfig.Replicas = 2
+		vr.Spec.ScaleToZero = productionstackv1alpha1.ScaleToZeroConfig{Enabled: true, IdleMinutes: 10}
+	})
+
+	It("Should parse the requests received and held for the served models", func() {
+		metrics, err := parseRouterMetrics(strings.NewReader(routerMetrics), []string{"llama"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.incomingRequests).To(Equal(12.0))
+		Expect(metrics.heldRequests).To(Equal(2.0))
+
+		metrics, err = parseRouterMetrics(strings.NewReader(routerMetrics), []string{"gemma"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.incomingRequests).To(BeZero())
+	})
+
+	It("Should match the served model names or the model URL", func() {
+		Expect(servedModelNames(vr)).To(Equal([]string{"meta-llama/Llama-3.1-8B"}))
+		vr.Spec.VLLMConfig.ExtraArgs = []string{"--served-model-name", "llama", "llama-8b", "--seed", "1"}
+		Expect(servedModelNames(vr)).To(Equal([]string{"llama", "llama-8b"}))
+		vr.Spec.VLLMConfig.ExtraArgs = []string{"--served-model-name=llama"}
+		Expect(servedModelNames(vr)).To(Equal([]string{"llama"}))
+	})
+
+	It("Should only count changed counters of pods seen before as requests", func() {
+		var tracker idleTracker
+		Expect(tracker.observe(key, map[types.UID]float64{"engine-0": 5, "router-0": 12})).To(BeFalse())
+		Expect(tracker.observe(key, map[types.UID]float64{"engine-0": 5, "router-0": 12, "engine-1": 3})).To(BeFalse())
+		Expect(tracker.observe(key, map[types.UID]float64{"router-0": 13})).To(BeTrue())
+		// A scaled down engine does not count as a request
+		Expect(tracker.observe(key, map[types.UID]float64{"router-0": 13})).To(BeFalse())
+		tracker.forget(key)
+		Expect(tracker.observe(key, map[types.UID]float64{"router-0": 20})).To(BeFalse())
+	})
+
+	It("Should scale to zero after the idle time and back up on the next request", func() {
+		start := metav1.NewTime(time.Now())
+
+		lastRequest, condition := scaleToZeroStatus(vr, false, start)
+		Expect(lastRequest.Time).To(Equal(start.Time))
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		vr.Status.LastRequestTime = lastRequest
+		meta.SetStatusCondition(&vr.Status.Conditions, condition)
+		Expect(desiredReplicas(vr)).To(Equal(int32(2)))
+
+		// Still within the idle time
+		_, condition = scaleToZeroStatus(vr, false, metav1.NewTime(start.Add(9*time.Minute)))
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+
+		lastRequest, condition = scaleToZeroStatus(vr, false, metav1.NewTime(start.Add(10*time.Minute)))
+		Expect(lastRequest.Time).To(Equal(start.Time))
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal("Idle"))
+		meta.SetStatusCondition(&vr.Status.Conditions, condition)
+		Expect(isScaledToZero(vr)).To(BeTrue())
+		Expect(desiredReplicas(vr)).To(BeZero())
+
+		now := metav1.NewTime(start.Add(30 * time.Minute))
+		lastRequest, condition = scaleToZeroStatus(vr, true, now)
+		Expect(lastRequest.Time).To(Equal(now.Time))
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal("Activated"))
+		meta.SetStatusCondition(&vr.Status.Conditions, condition)
+		Expect(desiredReplicas(vr)).To(Equal(int32(2)))
+	})
+
+	It("Should ignore the ScaledToZero condition once scaling to zero is disabled", func() {
+		meta.SetStatusCondition(&vr.Status.Conditions, metav1.Condition{
+			Type: conditionTypeScaledToZero, Status: metav1.ConditionTrue, Reason: "Idle",
+		})
+		Expect(desiredReplicas(vr)).To(BeZero())
+		vr.Spec.ScaleToZero.Enabled = false
+		Expect(desiredReplicas(vr)).To(Equal(int32(2)))
+	})
+})
@@ -19,13 +19,14 @@ package controller
 import (
 	"context"
 	"fmt"
-	"reflect"
+	"maps"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -33,6 +34,7 @@ import (
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	servingv1alpha1 "production-stack/api/v1alpha1"
@@ -48,6 +50,8 @@ type VLLMRouterReconciler struct {
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmrouters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmrouters/finalizers,verbs=update
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmruntimes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=loraadapters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
@@ -56,6 +60,8 @@ type VLLMRouterReconciler struct {
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=create;delete;get;list;patch;update;watch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -77,59 +83,31 @@ func (r *VLLMRouterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// Create ServiceAccount if it doesn't exist
-	sa := &corev1.ServiceAccount{}
-	err = r.Get(ctx, types.NamespacedName{Name: router.Spec.ServiceAccountName, Namespace: router.Namespace}, sa)
-	if err != nil && errors.IsNotFound(err) {
-		sa = r.serviceAccountForVLLMRouter(router)
-		log.Info("Creating a new ServiceAccount", "ServiceAccount.Namespace", sa.Namespace, "ServiceAccount.Name", sa.Name)
-		err = r.Create(ctx, sa)
-		if err != nil {
-			log.Error(err, "Failed to create new ServiceAccount", "ServiceAccount.Namespace", sa.Namespace, "ServiceAccount.Name", sa.Name)
-			return ctrl.Result{}, err
-		}
-		// ServiceAccount created successfully - return and requeue
-		return ctrl.Result{Requeue: true}, nil
-	} else if err != nil {
-		log.Error(err, "Failed to get ServiceAccount")
+	// Record the runtimes the router routes to, and derive its backends from them when it selects
+	// them by VLLMRuntime
+	runtimes, missing, err := r.selectRuntimes(ctx, router)
+	if err != nil {
+		log.Error(err, "Failed to select VLLMRuntimes")
 		return ctrl.Result{}, err
 	}
-
-	// Create Role if it doesn't exist
-	role := &rbacv1.Role{}
-	err = r.Get(ctx, types.NamespacedName{Name: "pod-viewer-role", Namespace: router.Namespace}, role)
-	if err != nil && errors.IsNotFound(err) {
-		role = r.roleForVLLMRouter(router)
-		log.Info("Creating a new Role", "Role.Namespace", role.Namespace, "Role.Name", role.Name)
-		err = r.Create(ctx, role)
-		if err != nil {
-			log.Error(err, "Failed to create new Role", "Role.Namespace", role.Namespace, "Role.Name", role.Name)
-			return ctrl.Result{}, err
-		}
-		// Role created successfully - return and requeue
-		return ctrl.Result{Requeue: true}, nil
-	} else if err != nil {
-		log.Error(err, "Failed to get Role")
+	if err := r.updateRuntimeStatus(ctx, router, runtimes, missing); err != nil {
+		log.Error(err, "Failed to update VLLMRouter status")
 		return ctrl.Result{}, err
 	}
+	if usesRuntimeSelection(router) {
+		router = routerForRuntimes(router, runtimes)
+	}
 
-	// Create RoleBinding if it doesn't exist
-	roleBinding := &rbacv1.RoleBinding{}
-	err = r.Get(ctx, types.NamespacedName{Name: "pod-viewer-binding", Namespace: router.Namespace}, roleBinding)
-	if err != nil && errors.IsNotFound(err) {
-		roleBinding = r.roleBindingForVLLMRouter(router)
-		log.Info("Creating a new RoleBinding", "RoleBinding.Namespace", roleBinding.Namespace, "RoleBinding.Name", roleBinding.Name)
-		err = r.Create(ctx, roleBinding)
-		if err != nil {
-			log.Error(err, "Failed to create new RoleBinding", "RoleBinding.Namespace", roleBinding.Namespace, "RoleBinding.Name", roleBinding.Name)
-			return ctrl.Result{}, err
-		}
-		// RoleBinding created successfully - return and requeue
-		return ctrl.Result{Requeue: true}, nil
-	} else if err != nil {
-		log.Error(err, "Failed to get RoleBinding")
+	// Reconcile the ServiceAccount of the router pods and the RBAC of its service discovery
+	written, err := r.reconcileRBAC(ctx, router)
+	if err != nil {
+		log.Error(err, "Failed to reconcile the RBAC of the router")
 		return ctrl.Result{}, err
 	}
+	if written {
+		// RBAC written successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
 
 	// Check if the service already exists, if not create a new one
 	foundService := &corev1.Service{}
@@ -150,12 +128,70 @@ func (r *VLLMRouterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Update the service if its desired state changed
+	if svc := r.serviceForVLLMRouter(router); specHashChanged(foundService, svc) {
+		log.Info("Updating Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+		copyDesiredAnnotations(foundService, svc)
+		copyDesiredMetadata(foundService, svc)
+		// Keep the cluster IPs and node ports allocated to the live Service, the cluster IPs cannot
+		// be changed
+		svc.Spec.ClusterIP = foundService.Spec.ClusterIP
+		svc.Spec.ClusterIPs = foundService.Spec.ClusterIPs
+		keepNodePorts(foundService, svc)
+		foundService.Spec = svc.Spec
+		err = r.Update(ctx, foundService)
+		if err != nil {
+			log.Error(err, "Failed to update Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
+			return ctrl.Result{}, err
+		}
+		// Service updated successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Expose the router through its Ingress or HTTPRoute
+	written, err = r.reconcileExposure(ctx, router)
+	if err != nil {
+		log.Error(err, "Failed to reconcile the exposure of the router")
+		return ctrl.Result{}, err
+	}
+	if written {
+		// Ingress or HTTPRoute written successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// Write the dynamic config before the pods reading it are created
+	written, err = r.reconcileDynamicConfig(ctx, router, runtimes)
+	if err != nil {
+		log.Error(err, "Failed to reconcile the dynamic config ConfigMap")
+		return ctrl.Result{}, err
+	}
+	if written {
+		// ConfigMap written successfully - return and requeue
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	// The router cannot start without static backends, wait for a runtime to be selected
+	if router.Spec.ServiceDiscovery == "static" && router.Spec.StaticBackends == "" && !usesRuntimeBackends(router) {
+		log.Info("Waiting for a VLLMRuntime to route to", "VLLMRouter.Name", router.Name)
+		return ctrl.Result{}, nil
+	}
+
+	// Build the desired deployment, reporting values that cannot be parsed on the VLLMRouter
+	dep, err := r.deploymentForVLLMRouter(router)
+	if err != nil {
+		log.Error(err, "Invalid VLLMRouter spec")
+		if err := r.reportInvalidSpec(ctx, router, err); err != nil {
+			log.Error(err, "Failed to update VLLMRouter status")
+			return ctrl.Result{}, err
+		}
+		// Wait for the spec to be fixed, which triggers a new reconcile
+		return ctrl.Result{}, nil
+	}
+
 	// Check if the deployment already exists, if not create a new one
 	found := &appsv1.Deployment{}
 	err = r.Get(ctx, types.NamespacedName{Name: router.Name, Namespace: router.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
-		// Define a new deployment
-		dep := r.deploymentForVLLMRouter(router)
 		log.Info("Creating a new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
 		err = r.Create(ctx, dep)
 		if err != nil {
@@ -169,13 +205,13 @@ func (r *VLLMRouterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// Update the deployment if needed
-	if r.deploymentNeedsUpdate(found, router) {
+	// Update the deployment if its desired state changed
+	if specHashChanged(found, dep) {
 		log.Info("Updating Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
-		// Create new deployment spec
-		newDep := r.deploymentForVLLMRouter(router)
-
-		err = r.Update(ctx, newDep)
+		copyDesiredMetadata(found, dep)
+		dep.Spec.Selector = keepSelector(found.Spec.Selector, &dep.Spec.Template)
+		found.Spec = dep.Spec
+		err = r.Update(ctx, found)
 		if err != nil {
 			log.Error(err, "Failed to update Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
 			return ctrl.Result{}, err
@@ -184,21 +220,42 @@ func (r *VLLMRouterReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	// Report the URL the router is reachable at
+	exposed, err := r.updateExposureStatus(ctx, router)
+	if err != nil {
+		log.Error(err, "Failed to update VLLMRouter status")
+		return ctrl.Result{}, err
+	}
+
 	// Update the status
 	if err := r.updateStatus(ctx, router, found); err != nil {
 		log.Error(err, "Failed to update VLLMRouter status")
 		return ctrl.Result{}, err
 	}
 
+	// HTTPRoutes are not watched, check again until the route is exposed
+	if !exposed && router.Spec.Exposure.HTTPRoute.Enabled {
+		return ctrl.Result{RequeueAfter: httpRouteRecheckInterval}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
-// deploymentForVLLMRouter returns a VLLMRouter Deployment object
-func (r *VLLMRouterReconciler) deploymentForVLLMRouter(router *servingv1alpha1.VLLMRouter) *appsv1.Deployment {
-	labels := map[string]string{"app": router.Name}
-	for k, v := range router.Labels {
-		labels[k] = v
+// labelsForVLLMRouter returns the labels of the objects created for a VLLMRouter, the labels of
+// the VLLMRouter and its name as app label. The app label is set last, so the labels of the
+// VLLMRouter cannot override it.
+func labelsForVLLMRouter(router *servingv1alpha1.VLLMRouter) map[string]string {
+	labels := maps.Clone(router.Labels)
+	if labels == nil {
+		labels = map[string]string{}
 	}
+	labels["app"] = router.Name
+	return labels
+}
+
+// deploymentForVLLMRouter returns a VLLMRouter Deployment object, or an error when the spec
+// holds values that cannot be parsed
+func (r *VLLMRouterReconciler) deploymentForVLLMRouter(router *servingv1alpha1.VLLMRouter) (*appsv1.Deployment, error) {
+	labels := labelsForVLLMRouter(router)
 
 	// Add user-defined environment variables
 	env := []corev1.EnvVar{}
@@ -225,19 +282,9 @@ func (r *VLLMRouterReconciler) deploymentForVLLMRouter(router *servingv1alpha1.V
 	}
 
 	// Build resource requirements
-	resources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{},
-		Limits:   corev1.ResourceList{},
-	}
-
-	if router.Spec.Resources.CPU != "" {
-		resources.Requests[corev1.ResourceCPU] = resource.MustParse(router.Spec.Resources.CPU)
-		resources.Limits[corev1.ResourceCPU] = resource.MustParse(router.Spec.Resources.CPU)
-	}
-
-	if router.Spec.Resources.Memory != "" {
-		resources.Requests[corev1.ResourceMemory] = resource.MustParse(router.Spec.Resources.Memory)
-		resources.Limits[corev1.ResourceMemory] = resource.MustParse(router.Spec.Resources.Memory)
+	resources, err := buildResourceRequirements("spec.resources", router.Spec.Resources)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get the image from Image spec or use default
@@ -261,43 +308,54 @@ func (r *VLLMRouterReconciler) deploymentForVLLMRouter(router *servingv1alpha1.V
 	args := []string{
 		"--host", "0.0.0.0",
 		"--port", fmt.Sprintf("%d", router.Spec.Port),
-		"--service-discovery", router.Spec.ServiceDiscovery,
-	}
-
-	// Add service discovery specific args
-	if router.Spec.ServiceDiscovery == "k8s" {
-		args = append(args,
-			"--k8s-namespace", router.Namespace,
-			"--k8s-label-selector", router.Spec.K8sLabelSelector,
-		)
-	} else if router.Spec.ServiceDiscovery == "static" {
-		if router.Spec.StaticBackends == "" || router.Spec.StaticModels == "" {
-			// This should be handled by validation webhook
-			panic("static service discovery requires both staticBackends and staticModels")
-		}
-		args = append(args,
-			"--static-backends", router.Spec.StaticBackends,
-			"--static-models", router.Spec.StaticModels,
-		)
 	}
 
-	// Add optional args
-	if router.Spec.RoutingLogic != "" {
-		args = append(args, "--routing-logic", router.Spec.RoutingLogic)
-	}
-	if router.Spec.SessionKey != "" {
-		args = append(args, "--session-key", router.Spec.SessionKey)
+	if usesDynamicConfig(router) {
+		// Service discovery and routing are read from the dynamic config, so changing them does
+		// not roll out the Deployment
+		args = append(args, "--dynamic-config-json", dynamicConfigMountPath+"/"+dynamicConfigKey)
+	} else {
+		args = append(args, "--service-discovery", router.Spec.ServiceDiscovery)
+
+		// Add service discovery specific args
+		if router.Spec.ServiceDiscovery == "k8s" {
+			args = append(args,
+				"--k8s-namespace", router.Namespace,
+				"--k8s-label-selector", router.Spec.K8sLabelSelector,
+			)
+		} else if router.Spec.ServiceDiscovery == "static" {
+			// Missing backends or models are rejected by the validating webhook
+			args = append(args,
+				"--static-backends", router.Spec.StaticBackends,
+				"--static-models", router.Spec.StaticModels,
+			)
+		}
+
+		// Add optional args
+		if router.Spec.RoutingLogic != "" {
+			args = append(args, "--routing-logic", router.Spec.RoutingLogic)
+		}
+		if router.Spec.SessionKey != "" {
+			args = append(args, "--session-key", router.Spec.SessionKey)
+		}
 	}
+	args = append(args, routingArgs(router)...)
 	if router.Spec.EngineScrapeInterval != 0 {
 		args = append(args, "--engine-stats-interval", fmt.Sprintf("%d", router.Spec.EngineScrapeInterval))
 	}
 	if router.Spec.RequestStatsWindow != 0 {
 		args = append(args, "--request-stats-window", fmt.Sprintf("%d", router.Spec.RequestStatsWindow))
 	}
+	if router.Spec.ColdStartTimeoutSeconds > 0 {
+		args = append(args, "--cold-start-timeout", fmt.Sprintf("%d", router.Spec.ColdStartTimeoutSeconds))
+	}
 	if router.Spec.ExtraArgs != nil {
 		args = append(args, router.Spec.ExtraArgs...)
 	}
 
+	// Only the liveness probe is set by default
+	readinessProbe, livenessProbe, startupProbe := routerProbes(router)
+
 	dep := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      router.Name,
@@ -305,15 +363,17 @@ func (r *VLLMRouterReconciler) deploymentForVLLMRouter(router *servingv1alpha1.V
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: &router.Spec.Replicas,
+			// Select on the app label alone, so changing the labels of the router does not change
+			// the selector, which cannot change
 			Selector: &metav1.LabelSelector{
-				MatchLabels: labels,
+				MatchLabels: map[string]string{"app": router.Name},
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
-					ServiceAccountName: router.Spec.ServiceAccountName,
+					ServiceAccountName: serviceAccountNameForVLLMRouter(router),
 					ImagePullSecrets:   imagePullSecrets,
 					Containers: []corev1.Container{
 						{
@@ -322,24 +382,11 @@ func (r *VLLMRouterReconciler) deploymentForVLLMRouter(router *servingv1alpha1.V
 							ImagePullPolicy: imagePullPolicy,
 							Args:            args,
 							Env:             env,
-							Ports: []corev1.ContainerPort{
-								{
-									Name:          "http",
-									ContainerPort: router.Spec.Port,
-								},
-							},
-							Resources: resources,
-							LivenessProbe: &corev1.Probe{
-								InitialDelaySeconds: 30,
-								PeriodSeconds:       5,
-								FailureThreshold:    3,
-								ProbeHandler: corev1.ProbeHandler{
-									HTTPGet: &corev1.HTTPGetAction{
-										Path: "/health",
-										Port: intstr.FromInt32(router.Spec.Port),
-									},
-								},
-							},
+							Ports:           routerContainerPorts(router),
+							Resources:       resources,
+							ReadinessProbe:  readinessProbe,
+							LivenessProbe:   livenessProbe,
+							StartupProbe:    startupProbe,
 						},
 					},
 				},
@@ -347,44 +394,74 @@ func (r *VLLMRouterReconciler) deploymentForVLLMRouter(router *servingv1alpha1.V
 		},
 	}
 
-	// Add node affinity if specified
-	if router.Spec.NodeSelectorTerms != nil {
-		dep.Spec.Template.Spec.Affinity = &corev1.Affinity{
-			NodeAffinity: &corev1.NodeAffinity{
-				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
-					NodeSelectorTerms: router.Spec.NodeSelectorTerms,
+	// Mount the dynamic config, which the kubelet keeps in sync with its ConfigMap
+	podSpec := &dep.Spec.Template.Spec
+	if usesDynamicConfig(router) {
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: dynamicConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMapNameForVLLMRouter(router)},
 				},
 			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      dynamicConfigVolumeName,
+			MountPath: dynamicConfigMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	// Add the scheduling constraints, and node affinity if specified and not set by them
+	applyScheduling(podSpec, router.Spec.Scheduling)
+	if router.Spec.NodeSelectorTerms != nil {
+		if podSpec.Affinity == nil {
+			podSpec.Affinity = &corev1.Affinity{}
+		}
+		if podSpec.Affinity.NodeAffinity == nil {
+			podSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+		}
+		if podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+			podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{
+				NodeSelectorTerms: router.Spec.NodeSelectorTerms,
+			}
 		}
 	}
 
+	setSpecHash(dep, dep.Spec)
+
 	// Set the owner reference
 	ctrl.SetControllerReference(router, dep, r.Scheme)
-	return dep
+	return dep, nil
 }
 
-// deploymentNeedsUpdate checks if the deployment needs to be updated
-func (r *VLLMRouterReconciler) deploymentNeedsUpdate(dep *appsv1.Deployment, router *servingv1alpha1.VLLMRouter) bool {
-	// Compare replicas
-	if *dep.Spec.Replicas != router.Spec.Replicas {
-		return true
+// routerProbes returns the readiness, liveness and startup probes of the router container, the
+// defaults with the overrides of the probe config. Readiness and startup probes check /health too
+// once configured.
+func routerProbes(router *servingv1alpha1.VLLMRouter) (*corev1.Probe, *corev1.Probe, *corev1.Probe) {
+	port := router.Spec.Port
+	health := corev1.ProbeHandler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path: "/health",
+			Port: intstr.FromInt32(port),
+		},
 	}
-	// Generate the expected deployment
-	expectedDep := r.deploymentForVLLMRouter(router)
 
-	// Compare image
-	if expectedDep.Spec.Template.Spec.Containers[0].Image != dep.Spec.Template.Spec.Containers[0].Image {
-		return true
+	liveness := corev1.Probe{
+		ProbeHandler:        health,
+		InitialDelaySeconds: 30,
+		PeriodSeconds:       5,
+		FailureThreshold:    3,
 	}
-
-	// Compare resources
-	expectedResources := expectedDep.Spec.Template.Spec.Containers[0].Resources
-	actualResources := dep.Spec.Template.Spec.Containers[0].Resources
-	if !reflect.DeepEqual(expectedResources, actualResources) {
-		return true
+	readiness := corev1.Probe{
+		ProbeHandler:  health,
+		PeriodSeconds: 5,
 	}
 
-	return false
+	probes := router.Spec.Probes
+	return buildProbe(probes.Readiness, readiness, false, port),
+		buildProbe(probes.Liveness, liveness, true, port),
+		buildProbe(probes.Startup, readiness, false, port)
 }
 
 // updateStatus updates the status of the VLLMRouter
@@ -398,6 +475,14 @@ func (r *VLLMRouterReconciler) updateStatus(ctx context.Context, router *serving
 
 		// Update the status fields
 		latestRouter.Status.LastUpdated = metav1.Now()
+		latestRouter.Status.ObservedGeneration = latestRouter.Generation
+		latestRouter.Status.Replicas = dep.Status.Replicas
+		latestRouter.Status.ReadyReplicas = dep.Status.ReadyReplicas
+		latestRouter.Status.AvailableReplicas = dep.Status.AvailableReplicas
+		latestRouter.Status.UpdatedReplicas = dep.Status.UpdatedReplicas
+		for _, condition := range deploymentConditions(latestRouter.Generation, dep) {
+			meta.SetStatusCondition(&latestRouter.Status.Conditions, condition)
+		}
 
 		// Update VLLMRouter status based on deployment status
 		if dep.Status.AvailableReplicas == *dep.Spec.Replicas && dep.Status.UnavailableReplicas == 0 {
@@ -414,12 +499,26 @@ func (r *VLLMRouterReconciler) updateStatus(ctx context.Context, router *serving
 	})
 }
 
+// reportInvalidSpec records a spec that cannot be turned into a Deployment as a Degraded condition
+func (r *VLLMRouterReconciler) reportInvalidSpec(ctx context.Context, router *servingv1alpha1.VLLMRouter, specErr error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestRouter := &servingv1alpha1.VLLMRouter{}
+		if err := r.Get(ctx, types.NamespacedName{Name: router.Name, Namespace: router.Namespace}, latestRouter); err != nil {
+			return err
+		}
+
+		latestRouter.Status.LastUpdated = metav1.Now()
+		latestRouter.Status.Status = statusInvalidSpec
+		latestRouter.Status.ObservedGeneration = latestRouter.Generation
+		meta.SetStatusCondition(&latestRouter.Status.Conditions, degradedCondition(latestRouter.Generation, specErr))
+
+		return r.Status().Update(ctx, latestRouter)
+	})
+}
+
 // serviceForVLLMRouter returns a VLLMRouter Service object
 func (r *VLLMRouterReconciler) serviceForVLLMRouter(router *servingv1alpha1.VLLMRouter) *corev1.Service {
-	labels := map[string]string{"app": router.Name}
-	for k, v := range router.Labels {
-		labels[k] = v
-	}
+	labels := labelsForVLLMRouter(router)
 
 	svc := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -427,12 +526,12 @@ func (r *VLLMRouterReconciler) serviceForVLLMRouter(router *servingv1alpha1.VLLM
 			Namespace: router.Namespace,
 		},
 		Spec: corev1.ServiceSpec{
-			Type:     corev1.ServiceTypeClusterIP,
+			Type:     serviceTypeForVLLMRouter(router),
 			Selector: labels,
 			Ports: []corev1.ServicePort{
 				{
 					Name:       "http",
-					Port:       80,
+					Port:       routerServicePort,
 					TargetPort: intstr.FromInt32(router.Spec.Port),
 					Protocol:   corev1.ProtocolTCP,
 				},
@@ -440,74 +539,83 @@ func (r *VLLMRouterReconciler) serviceForVLLMRouter(router *servingv1alpha1.VLLM
 		},
 	}
 
+	// Let the runtimes reach the LMCache controller of a kvaware router
+	if router.Spec.RoutingLogic == routingLogicKVAware {
+		port := lmCacheControllerPort(router)
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       lmCacheControllerPortName,
+			Port:       port,
+			TargetPort: intstr.FromInt32(port),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+
+	annotations := router.Spec.Exposure.Service.Annotations
+	setDesiredAnnotations(svc, annotations)
+	setSpecHash(svc, annotatedSpec{Annotations: annotations, Spec: svc.Spec})
+
 	// Set the owner reference
 	ctrl.SetControllerReference(router, svc, r.Scheme)
 	return svc
 }
 
-// serviceAccountForVLLMRouter returns a ServiceAccount object
+// serviceAccountForVLLMRouter returns the ServiceAccount of the router pods
 func (r *VLLMRouterReconciler) serviceAccountForVLLMRouter(router *servingv1alpha1.VLLMRouter) *corev1.ServiceAccount {
 	sa := &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      router.Spec.ServiceAccountName,
+			Name:      serviceAccountNameForVLLMRouter(router),
 			Namespace: router.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       "production-stack",
-				"app.kubernetes.io/managed-by": "kustomize",
-			},
+			Labels:    labelsForVLLMRouter(router),
 		},
 	}
 	ctrl.SetControllerReference(router, sa, r.Scheme)
 	return sa
 }
 
-// roleForVLLMRouter returns a Role object
+// roleForVLLMRouter returns the Role granting the router what its service discovery needs
 func (r *VLLMRouterReconciler) roleForVLLMRouter(router *servingv1alpha1.VLLMRouter) *rbacv1.Role {
 	role := &rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-viewer-role",
+			Name:      rbacNameForVLLMRouter(router),
 			Namespace: router.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       "production-stack",
-				"app.kubernetes.io/managed-by": "kustomize",
-			},
-		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{""},
-				Resources: []string{"pods"},
-				Verbs:     []string{"get", "list", "watch"},
-			},
+			Labels:    labelsForVLLMRouter(router),
 		},
+		Rules: routerPolicyRules(router),
 	}
+
+	setSpecHash(role, role.Rules)
+
 	ctrl.SetControllerReference(router, role, r.Scheme)
 	return role
 }
 
-// roleBindingForVLLMRouter returns a RoleBinding object
+// roleBindingForVLLMRouter returns the RoleBinding granting the Role of a router to its ServiceAccount
 func (r *VLLMRouterReconciler) roleBindingForVLLMRouter(router *servingv1alpha1.VLLMRouter) *rbacv1.RoleBinding {
 	roleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "pod-viewer-binding",
+			Name:      rbacNameForVLLMRouter(router),
 			Namespace: router.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       "production-stack",
-				"app.kubernetes.io/managed-by": "kustomize",
-			},
+			Labels:    labelsForVLLMRouter(router),
 		},
 		Subjects: []rbacv1.Subject{
 			{
 				Kind:      "ServiceAccount",
-				Name:      router.Spec.ServiceAccountName,
+				Name:      serviceAccountNameForVLLMRouter(router),
 				Namespace: router.Namespace,
 			},
 		},
 		RoleRef: rbacv1.RoleRef{
 			Kind:     "Role",
-			Name:     "pod-viewer-role",
+			Name:     rbacNameForVLLMRouter(router),
 			APIGroup: "rbac.authorization.k8s.io",
 		},
 	}
+
+	setSpecHash(roleBinding, struct {
+		Subjects []rbacv1.Subject `json:"subjects"`
+		RoleRef  rbacv1.RoleRef   `json:"roleRef"`
+	}{roleBinding.Subjects, roleBinding.RoleRef})
+
 	ctrl.SetControllerReference(router, roleBinding, r.Scheme)
 	return roleBinding
 }
@@ -518,5 +626,12 @@ func (r *VLLMRouterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&servingv1alpha1.VLLMRouter{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Watches(&servingv1alpha1.VLLMRuntime{}, handler.EnqueueRequestsFromMapFunc(r.routersForRuntime)).
+		Watches(&servingv1alpha1.LoraAdapter{}, handler.EnqueueRequestsFromMapFunc(r.routersForAdapter)).
 		Complete(r)
 }
@@ -18,11 +18,18 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -82,3 +89,380 @@ var _ = Describe("VLLMRouter Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("VLLMRouter builders", func() {
+	It("returns an error instead of panicking on quantities that cannot be parsed", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				Port:      80,
+				Resources: productionstackv1alpha1.ResourceRequirements{CPU: "1", Memory: "two gigs"},
+			},
+		}
+		_, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).To(MatchError(ContainSubstring("spec.resources.memory")))
+
+		obj.Spec.Resources.Memory = "2Gi"
+		dep, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).To(Equal("2Gi"))
+	})
+
+	It("keeps the node selector terms unless the scheduling affinity requires nodes itself", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		terms := []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{{
+			Key: "kubernetes.io/os", Operator: corev1.NodeSelectorOpIn, Values: []string{"linux"},
+		}}}}
+		antiAffinity := &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+				Weight:          100,
+				PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: "kubernetes.io/hostname"},
+			}},
+		}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				Port:              80,
+				NodeSelectorTerms: terms,
+				Scheduling: productionstackv1alpha1.SchedulingConfig{
+					Affinity:    &corev1.Affinity{PodAntiAffinity: antiAffinity},
+					Tolerations: []corev1.Toleration{{Key: "dedicated", Value: "router", Effect: corev1.TaintEffectNoSchedule}},
+				},
+			},
+		}
+		dep, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		affinity := dep.Spec.Template.Spec.Affinity
+		Expect(affinity.PodAntiAffinity).To(Equal(antiAffinity))
+		Expect(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(Equal(terms))
+		Expect(dep.Spec.Template.Spec.Tolerations).To(HaveLen(1))
+		// The spec itself is left untouched
+		Expect(obj.Spec.Scheduling.Affinity.NodeAffinity).To(BeNil())
+	})
+
+	It("passes the prefill and decode model labels of disaggregated prefill routing", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				Port:             80,
+				ServiceDiscovery: "k8s",
+				RoutingLogic:     "disaggregated_prefill",
+				DisaggregatedPrefill: productionstackv1alpha1.DisaggregatedPrefillRoutingConfig{
+					PrefillModelLabels: []string{"llama-prefill"},
+					DecodeModelLabels:  []string{"llama-decode", "mistral-decode"},
+				},
+			},
+		}
+		dep, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		args := dep.Spec.Template.Spec.Containers[0].Args
+		Expect(args).To(ContainElements("--routing-logic", "disaggregated_prefill"))
+		Expect(args).To(ContainElements("--prefill-model-labels", "llama-prefill"))
+		Expect(args).To(ContainElements("--decode-model-labels", "llama-decode,mistral-decode"))
+	})
+
+	It("serves the LMCache controller of kvaware routing and ignores the parameters of other modes", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				Port:             80,
+				ServiceDiscovery: "k8s",
+				RoutingLogic:     "kvaware",
+				KVAware:          productionstackv1alpha1.KVAwareRoutingConfig{Threshold: 500},
+				DisaggregatedPrefill: productionstackv1alpha1.DisaggregatedPrefillRoutingConfig{
+					PrefillModelLabels: []string{"llama-prefill"},
+				},
+			},
+		}
+		dep, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		container := dep.Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(ContainElements("--kv-aware-threshold", "500"))
+		Expect(container.Args).To(ContainElements("--lmcache-controller-port", "9000"))
+		Expect(container.Args).NotTo(ContainElement("--prefill-model-labels"))
+		Expect(container.Ports).To(ContainElement(corev1.ContainerPort{Name: "lmcache-ctrl", ContainerPort: 9000}))
+
+		svc := r.serviceForVLLMRouter(obj)
+		Expect(svc.Spec.Ports).To(HaveLen(2))
+		Expect(svc.Spec.Ports[1].Port).To(Equal(int32(9000)))
+
+		obj.Spec.RoutingLogic = "roundrobin"
+		dep, err = r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[0].Args).NotTo(ContainElement("--kv-aware-threshold"))
+		Expect(dep.Spec.Template.Spec.Containers[0].Ports).To(HaveLen(1))
+	})
+
+	It("moves service discovery and routing into the dynamic config", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				Port:             80,
+				ServiceDiscovery: "k8s",
+				K8sLabelSelector: "app=llama",
+				RoutingLogic:     "session",
+				SessionKey:       "x-user-id",
+				DynamicConfig:    productionstackv1alpha1.RouterDynamicConfig{Enabled: true},
+			},
+		}
+		dep, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		podSpec := dep.Spec.Template.Spec
+		Expect(podSpec.Containers[0].Args).To(ContainElements("--dynamic-config-json", "/etc/vllm-router/dynamic_config.json"))
+		Expect(podSpec.Containers[0].Args).NotTo(ContainElement("--routing-logic"))
+		Expect(podSpec.Containers[0].VolumeMounts[0].MountPath).To(Equal("/etc/vllm-router"))
+		Expect(podSpec.Volumes[0].ConfigMap.Name).To(Equal("router-dynamic-config"))
+
+		cm, err := r.configMapForVLLMRouter(obj, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cm.Data["dynamic_config.json"]).To(MatchJSON(`{
+			"service_discovery": "k8s", "routing_logic": "session", "session_key": "x-user-id",
+			"k8s_port": 8000, "k8s_namespace": "default", "k8s_label_selector": "app=llama"
+		}`))
+
+		// Routing changes only change the ConfigMap
+		changed := obj.DeepCopy()
+		changed.Spec.RoutingLogic = "roundrobin"
+		changedDep, err := r.deploymentForVLLMRouter(changed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(specHashChanged(dep, changedDep)).To(BeFalse())
+		changedCM, err := r.configMapForVLLMRouter(changed, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(specHashChanged(cm, changedCM)).To(BeTrue())
+	})
+
+	It("renders a dynamic config the router loads into its DynamicRouterConfig", func() {
+		// The router builds a DynamicRouterConfig from the keys of the file and reconfigures
+		// everything it holds, so every key has to be one of its fields and the k8s service
+		// discovery needs its port
+		source, err := os.ReadFile(filepath.Join("..", "..", "..", "src", "vllm_router", "dynamic_config.py"))
+		Expect(err).NotTo(HaveOccurred())
+		class := regexp.MustCompile(`(?s)class DynamicRouterConfig:(.*?)@staticmethod`).FindSubmatch(source)
+		Expect(class).NotTo(BeNil())
+		fields := map[string]bool{}
+		for _, match := range regexp.MustCompile(`(?m)^    (\w+): `).FindAllSubmatch(class[1], -1) {
+			fields[string(match[1])] = true
+		}
+		Expect(fields).To(HaveKey("k8s_port"))
+
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				ServiceDiscovery: "k8s",
+				RoutingLogic:     "roundrobin",
+				DynamicConfig:    productionstackv1alpha1.RouterDynamicConfig{Enabled: true},
+			},
+		}
+		llama := productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default"},
+			Spec:       productionstackv1alpha1.VLLMRuntimeSpec{VLLMConfig: productionstackv1alpha1.VLLMConfig{Port: 8080}},
+		}
+		for _, config := range []routerDynamicConfig{
+			dynamicConfigForVLLMRouter(obj, []productionstackv1alpha1.VLLMRuntime{llama}, nil),
+			dynamicConfigForVLLMRouter(&productionstackv1alpha1.VLLMRouter{Spec: productionstackv1alpha1.VLLMRouterSpec{
+				ServiceDiscovery: "static",
+				RoutingLogic:     "session",
+				SessionKey:       "x-user-id",
+				DynamicConfig:    productionstackv1alpha1.RouterDynamicConfig{Enabled: true},
+			}}, nil, []routerBackend{{url: "http://10.0.0.1:8000", model: "llama", modelLabel: "llama"}}),
+		} {
+			data, err := json.Marshal(config)
+			Expect(err).NotTo(HaveOccurred())
+			rendered := map[string]any{}
+			Expect(json.Unmarshal(data, &rendered)).To(Succeed())
+			for key := range rendered {
+				Expect(fields).To(HaveKey(key))
+			}
+		}
+		Expect(dynamicConfigForVLLMRouter(obj, []productionstackv1alpha1.VLLMRuntime{llama}, nil).K8sPort).To(Equal(int32(8080)))
+		Expect(dynamicConfigForVLLMRouter(obj, nil, nil).K8sPort).To(Equal(int32(8000)))
+	})
+
+	It("renders the pods and adapters of the runtimes as static backends of the dynamic config", func() {
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				ServiceDiscovery: "static",
+				RoutingLogic:     "roundrobin",
+				DynamicConfig:    productionstackv1alpha1.RouterDynamicConfig{Enabled: true},
+			},
+		}
+		backends := []routerBackend{
+			{url: "http://10.0.0.1:8000", model: "llama"},
+			{url: "http://10.0.0.1:8000", model: "llama-sql-lora"},
+			{url: "http://10.0.0.2:8000", model: "mistral", modelLabel: "mistral-decode"},
+		}
+		config := dynamicConfigForVLLMRouter(obj, nil, backends)
+		Expect(config.StaticBackends).To(Equal("http://10.0.0.1:8000,http://10.0.0.1:8000,http://10.0.0.2:8000"))
+		Expect(config.StaticModels).To(Equal("llama,llama-sql-lora,mistral"))
+		Expect(config.StaticModelLabels).To(Equal("default,default,mistral-decode"))
+
+		obj.Spec.StaticBackends = "http://llama:80"
+		obj.Spec.StaticModels = "llama"
+		config = dynamicConfigForVLLMRouter(obj, nil, nil)
+		Expect(config.StaticBackends).To(Equal("http://llama:80"))
+		Expect(config.StaticModelLabels).To(BeEmpty())
+	})
+
+	It("derives the backends of the router from the runtimes it selects", func() {
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRouterSpec{
+				ServiceDiscovery: "k8s",
+				RuntimeRefs:      []corev1.LocalObjectReference{{Name: "llama"}},
+				RuntimeSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"team": "search"}},
+			},
+		}
+		llama := productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default"},
+			Spec:       productionstackv1alpha1.VLLMRuntimeSpec{Model: productionstackv1alpha1.ModelSpec{ModelURL: "meta-llama/Llama-3.1-8B"}},
+		}
+		mistral := productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "mistral", Namespace: "default", Labels: map[string]string{"team": "search"}},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{VLLMConfig: productionstackv1alpha1.VLLMConfig{
+				ExtraArgs: []string{"--served-model-name", "mistral"},
+			}},
+		}
+		other := productionstackv1alpha1.VLLMRuntime{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"}}
+		for vr, selected := range map[*productionstackv1alpha1.VLLMRuntime]bool{&llama: true, &mistral: true, &other: false} {
+			Expect(routerSelectsRuntime(obj, vr)).To(Equal(selected))
+		}
+
+		runtimes := []productionstackv1alpha1.VLLMRuntime{llama, mistral}
+		Expect(routerForRuntimes(obj, runtimes).Spec.K8sLabelSelector).To(Equal("production-stack.vllm.ai/runtime in (llama,mistral)"))
+		// The pods are selected by the runtime label, whatever labels the runtimes carry
+		selector, err := labels.Parse(routerForRuntimes(obj, runtimes).Spec.K8sLabelSelector)
+		Expect(err).NotTo(HaveOccurred())
+		runtimeReconciler := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		for vr, selected := range map[*productionstackv1alpha1.VLLMRuntime]bool{&llama: true, &mistral: true, &other: false} {
+			dep, err := runtimeReconciler.deploymentForVLLMRuntime(vr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(selector.Matches(labels.Set(dep.Spec.Template.Labels))).To(Equal(selected))
+		}
+		Expect(routerForRuntimes(obj, nil).Spec.K8sLabelSelector).To(Equal(noRuntimesLabelSelector))
+		Expect(obj.Spec.K8sLabelSelector).To(BeEmpty())
+
+		obj.Spec.ServiceDiscovery = "static"
+		derived := routerForRuntimes(obj, runtimes)
+		Expect(derived.Spec.StaticBackends).To(Equal("http://llama.default.svc.cluster.local,http://mistral.default.svc.cluster.local"))
+		Expect(derived.Spec.StaticModels).To(Equal("meta-llama/Llama-3.1-8B,mistral"))
+	})
+
+	It("reports whether the runtimes of the router exist and are ready", func() {
+		statuses := []productionstackv1alpha1.RouterRuntimeStatus{{Name: "llama", Ready: true}, {Name: "mistral"}}
+		condition := runtimesCondition(3, statuses, []string{"qwen"})
+		Expect(condition.Reason).To(Equal("NotFound"))
+		Expect(condition.Message).To(ContainSubstring("qwen"))
+
+		condition = runtimesCondition(3, statuses, nil)
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(Equal("VLLMRuntimes mistral are not available"))
+
+		Expect(runtimesCondition(3, nil, nil).Reason).To(Equal("NoRuntimes"))
+		condition = runtimesCondition(3, statuses[:1], nil)
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+	})
+
+	It("names the RBAC of a router after it and grants only what its service discovery needs", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec:       productionstackv1alpha1.VLLMRouterSpec{Port: 80, ServiceDiscovery: "k8s"},
+		}
+		dep, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.ServiceAccountName).To(Equal("router"))
+		Expect(r.serviceAccountForVLLMRouter(obj).Name).To(Equal("router"))
+
+		role := r.roleForVLLMRouter(obj)
+		Expect(role.Name).To(Equal("router-router"))
+		Expect(role.Rules).To(HaveLen(1))
+		Expect(role.Rules[0].Resources).To(Equal([]string{"pods"}))
+		Expect(role.Rules[0].Verbs).To(Equal([]string{"get", "list", "watch"}))
+
+		obj.Spec.ServiceAccountName = "shared-sa"
+		roleBinding := r.roleBindingForVLLMRouter(obj)
+		Expect(roleBinding.RoleRef.Name).To(Equal("router-router"))
+		Expect(roleBinding.Subjects[0].Name).To(Equal("shared-sa"))
+
+		obj.Spec.ServiceDiscovery = "static"
+		Expect(r.roleForVLLMRouter(obj).Rules).To(BeEmpty())
+		Expect(specHashChanged(role, r.roleForVLLMRouter(obj))).To(BeTrue())
+	})
+
+	It("exposes the router through its Service, an Ingress or an HTTPRoute", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec:       productionstackv1alpha1.VLLMRouterSpec{Port: 8000},
+		}
+		obj.Spec.Exposure.Service = productionstackv1alpha1.RouterServiceConfig{
+			Type:        corev1.ServiceTypeLoadBalancer,
+			Annotations: map[string]string{"lb": "internal"},
+		}
+		svc := r.serviceForVLLMRouter(obj)
+		Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+		Expect(svc.Annotations).To(HaveKeyWithValue("lb", "internal"))
+
+		// Annotations dropped from the spec are removed, those of others are kept
+		found := svc.DeepCopy()
+		found.Annotations["other"] = "kept"
+		obj.Spec.Exposure.Service.Annotations = nil
+		desired := r.serviceForVLLMRouter(obj)
+		Expect(specHashChanged(found, desired)).To(BeTrue())
+		copyDesiredAnnotations(found, desired)
+		Expect(found.Annotations).NotTo(HaveKey("lb"))
+		Expect(found.Annotations).To(HaveKeyWithValue("other", "kept"))
+
+		obj.Spec.Exposure.Ingress = productionstackv1alpha1.RouterIngressConfig{
+			Host:          "router.example.com",
+			TLSSecretName: "router-tls",
+		}
+		ingress := r.ingressForVLLMRouter(obj)
+		Expect(ingress.Spec.Rules[0].Host).To(Equal("router.example.com"))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Path).To(Equal("/"))
+		Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).To(Equal("router"))
+		Expect(ingress.Spec.TLS[0].Hosts).To(Equal([]string{"router.example.com"}))
+
+		obj.Spec.Exposure.HTTPRoute = productionstackv1alpha1.RouterHTTPRouteConfig{
+			GatewayName:      "gateway",
+			GatewayNamespace: "infra",
+			Hostnames:        []string{"router.example.com"},
+			Path:             "/v1",
+		}
+		route := r.httpRouteForVLLMRouter(obj)
+		Expect(route.GroupVersionKind()).To(Equal(httpRouteGVK))
+		Expect(route.Object["spec"]).To(HaveKeyWithValue("hostnames", []any{"router.example.com"}))
+		Expect(route.Object["spec"]).To(HaveKeyWithValue("parentRefs", []any{map[string]any{
+			"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": "gateway", "namespace": "infra",
+		}}))
+		Expect(exposureURL("https", "router.example.com", "/v1/")).To(Equal("https://router.example.com/v1"))
+	})
+
+	It("only probes the liveness of the router unless more probes are configured", func() {
+		r := &VLLMRouterReconciler{Scheme: scheme.Scheme}
+		obj := &productionstackv1alpha1.VLLMRouter{
+			ObjectMeta: metav1.ObjectMeta{Name: "router", Namespace: "default"},
+			Spec:       productionstackv1alpha1.VLLMRouterSpec{Port: 80},
+		}
+		dep, err := r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		container := dep.Spec.Template.Spec.Containers[0]
+		Expect(container.LivenessProbe.HTTPGet.Path).To(Equal("/health"))
+		Expect(container.ReadinessProbe).To(BeNil())
+		Expect(container.StartupProbe).To(BeNil())
+
+		period := int32(2)
+		obj.Spec.Probes.Readiness = productionstackv1alpha1.ProbeConfig{PeriodSeconds: &period}
+		dep, err = r.deploymentForVLLMRouter(obj)
+		Expect(err).NotTo(HaveOccurred())
+		readiness := dep.Spec.Template.Spec.Containers[0].ReadinessProbe
+		Expect(readiness.HTTPGet.Path).To(Equal("/health"))
+		Expect(readiness.PeriodSeconds).To(Equal(int32(2)))
+	})
+})
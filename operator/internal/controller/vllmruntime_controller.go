@@ -19,11 +19,16 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/http"
 	"reflect"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -32,6 +37,7 @@ import (
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	productionstackv1alpha1 "production-stack/api/v1alpha1"
@@ -41,16 +47,30 @@ import (
 type VLLMRuntimeReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// HTTPClient scrapes the metrics of pods, a client shared by all reconcilers when nil
+	HTTPClient *http.Client
+
+	// scaler keeps the state of the autoscaling loop between reconciles
+	scaler autoscaler
+
+	// idle keeps the request counters used to scale to zero between reconciles
+	idle idleTracker
 }
 
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmruntimes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmruntimes/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmruntimes/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=vllmrouters,verbs=get;list;watch
+// +kubebuilder:rbac:groups=production-stack.vllm.ai,resources=cacheservers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -91,13 +111,15 @@ func (r *VLLMRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// Update the service if needed
-	if r.serviceNeedsUpdate(foundService, vllmRuntime) {
+	// Update the service if its desired state changed
+	if svc := r.serviceForVLLMRuntime(vllmRuntime); specHashChanged(foundService, svc) {
 		log.Info("Updating Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
-		// Create new service spec
-		newSvc := r.serviceForVLLMRuntime(vllmRuntime)
-
-		err = r.Update(ctx, newSvc)
+		copyDesiredMetadata(foundService, svc)
+		// Keep the cluster IPs allocated to the live Service, they cannot be changed
+		svc.Spec.ClusterIP = foundService.Spec.ClusterIP
+		svc.Spec.ClusterIPs = foundService.Spec.ClusterIPs
+		foundService.Spec = svc.Spec
+		err = r.Update(ctx, foundService)
 		if err != nil {
 			log.Error(err, "Failed to update Service", "Service.Namespace", foundService.Namespace, "Service.Name", foundService.Name)
 			return ctrl.Result{}, err
@@ -108,12 +130,16 @@ func (r *VLLMRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// Handle PVC if storage is enabled
 	if vllmRuntime.Spec.StorageConfig.Enabled {
+		// Build the desired PVC, reporting a size that cannot be parsed on the VLLMRuntime
+		pvc, err := r.pvcForVLLMRuntime(vllmRuntime)
+		if err != nil {
+			return r.handleInvalidSpec(ctx, vllmRuntime, err)
+		}
+
 		// Check if the PVC already exists, if not create a new one
 		foundPVC := &corev1.PersistentVolumeClaim{}
 		err = r.Get(ctx, types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}, foundPVC)
 		if err != nil && errors.IsNotFound(err) {
-			// Define a new PVC
-			pvc := r.pvcForVLLMRuntime(vllmRuntime)
 			log.Info("Creating a new PVC", "PVC.Namespace", pvc.Namespace, "PVC.Name", pvc.Name)
 			err = r.Create(ctx, pvc)
 			if err != nil {
@@ -127,13 +153,18 @@ func (r *VLLMRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			return ctrl.Result{}, err
 		}
 
-		// Update the PVC if needed
-		if r.pvcNeedsUpdate(foundPVC, vllmRuntime) {
-			log.Info("Updating PVC", "PVC.Namespace", foundPVC.Namespace, "PVC.Name", foundPVC.Name)
-			// Create new PVC spec
-			newPVC := r.pvcForVLLMRuntime(vllmRuntime)
+		// Only the requested size of a PVC can grow after creation, any other change is reported
+		// instead of being rejected by the API server on every reconcile
+		if err := pvcChangeError(foundPVC, pvc); err != nil {
+			return r.handleInvalidSpec(ctx, vllmRuntime, err)
+		}
 
-			err = r.Update(ctx, newPVC)
+		// Update the PVC if its labels or requested size changed
+		if specHashChanged(foundPVC, pvc) {
+			log.Info("Updating PVC", "PVC.Namespace", foundPVC.Namespace, "PVC.Name", foundPVC.Name)
+			copyDesiredMetadata(foundPVC, pvc)
+			foundPVC.Spec.Resources = pvc.Spec.Resources
+			err = r.Update(ctx, foundPVC)
 			if err != nil {
 				log.Error(err, "Failed to update PVC", "PVC.Namespace", foundPVC.Namespace, "PVC.Name", foundPVC.Name)
 				return ctrl.Result{}, err
@@ -143,12 +174,86 @@ func (r *VLLMRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
+	// Report the readiness of the CacheServer the runtime takes its remote cache from
+	if err := r.reconcileCacheServerRef(ctx, vllmRuntime); err != nil {
+		log.Error(err, "Failed to update VLLMRuntime cache server status")
+		return ctrl.Result{}, err
+	}
+
+	// Download the model into the storage volume ahead of serving when pre-warming is enabled
+	if result, err := r.reconcileModelDownload(ctx, vllmRuntime); result != nil {
+		return *result, err
+	}
+
+	// Scale the deployment to zero while the model receives no requests, and back up once it does
+	scaleToZero := vllmRuntime.Spec.ScaleToZero
+	if scaleToZero.Enabled {
+		active, err := r.observeRequests(ctx, vllmRuntime)
+		if err != nil {
+			log.Error(err, "Failed to observe requests for VLLMRuntime")
+			return ctrl.Result{}, err
+		}
+		if err := r.updateScaleToZeroStatus(ctx, vllmRuntime, active); err != nil {
+			log.Error(err, "Failed to update VLLMRuntime scale to zero status")
+			return ctrl.Result{}, err
+		}
+	} else {
+		r.idle.forget(req.NamespacedName)
+	}
+
+	// Size the deployment from the engine metrics when autoscaling is enabled. A runtime scaled to
+	// zero has no engines to scrape.
+	autoscaling := vllmRuntime.Spec.Autoscaling
+	if autoscaling.Enabled && !isScaledToZero(vllmRuntime) {
+		target, err := parseAutoscalingTarget(autoscaling)
+		if err != nil {
+			return r.handleInvalidSpec(ctx, vllmRuntime, err)
+		}
+		replicas, value, err := r.autoscale(ctx, vllmRuntime, target)
+		if err != nil {
+			log.Error(err, "Failed to autoscale VLLMRuntime")
+			return ctrl.Result{}, err
+		}
+		if err := r.updateAutoscalingStatus(ctx, vllmRuntime, replicas, value); err != nil {
+			log.Error(err, "Failed to update VLLMRuntime autoscaling status")
+			return ctrl.Result{}, err
+		}
+	} else {
+		r.scaler.forget(req.NamespacedName)
+	}
+
+	// Serve every replica by a leader/worker group in multi-node mode
+	if vllmRuntime.Spec.MultiNode.Enabled {
+		return r.reconcileMultiNode(ctx, vllmRuntime)
+	}
+
+	// Remove the groups left from multi-node mode
+	if err := r.deleteMultiNodeObjects(ctx, vllmRuntime); err != nil {
+		log.Error(err, "Failed to delete multi-node objects")
+		return ctrl.Result{}, err
+	}
+
+	// Serve prefill and decode by separate Deployments in disaggregated mode
+	if vllmRuntime.Spec.Topology.Disaggregated {
+		return r.reconcileDisaggregated(ctx, vllmRuntime)
+	}
+
+	// Remove the prefill and decode Deployments left from disaggregated mode
+	if err := r.deleteDisaggregatedObjects(ctx, vllmRuntime); err != nil {
+		log.Error(err, "Failed to delete disaggregated objects")
+		return ctrl.Result{}, err
+	}
+
+	// Build the desired deployment, reporting values that cannot be parsed on the VLLMRuntime
+	dep, err := r.deploymentForVLLMRuntime(vllmRuntime)
+	if err != nil {
+		return r.handleInvalidSpec(ctx, vllmRuntime, err)
+	}
+
 	// Check if the deployment already exists, if not create a new one
 	found := &appsv1.Deployment{}
 	err = r.Get(ctx, types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}, found)
 	if err != nil && errors.IsNotFound(err) {
-		// Define a new deployment
-		dep := r.deploymentForVLLMRuntime(vllmRuntime)
 		log.Info("Creating a new Deployment", "Deployment.Namespace", dep.Namespace, "Deployment.Name", dep.Name)
 		err = r.Create(ctx, dep)
 		if err != nil {
@@ -162,13 +267,13 @@ func (r *VLLMRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	// Update the deployment if needed
-	if r.deploymentNeedsUpdate(ctx, found, vllmRuntime) {
+	// Update the deployment if its desired state changed
+	if specHashChanged(found, dep) {
 		log.Info("Updating Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
-		// Create new deployment spec
-		newDep := r.deploymentForVLLMRuntime(vllmRuntime)
-
-		err = r.Update(ctx, newDep)
+		copyDesiredMetadata(found, dep)
+		dep.Spec.Selector = keepSelector(found.Spec.Selector, &dep.Spec.Template)
+		found.Spec = dep.Spec
+		err = r.Update(ctx, found)
 		if err != nil {
 			log.Error(err, "Failed to update Deployment", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
 			return ctrl.Result{}, err
@@ -183,70 +288,180 @@ func (r *VLLMRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: resyncPeriod(vllmRuntime)}, nil
 }
 
-// deploymentForVLLMRuntime returns a VLLMRuntime Deployment object
-func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) *appsv1.Deployment {
-	labels := map[string]string{"app": vllmRuntime.Name}
-	for k, v := range vllmRuntime.Labels {
-		labels[k] = v
+// resyncPeriod returns the interval the metrics of a runtime are scraped again after, the shortest
+// sync period of autoscaling, scaling to zero and a running model download, or zero when none applies
+func resyncPeriod(vllmRuntime *productionstackv1alpha1.VLLMRuntime) time.Duration {
+	var period time.Duration
+	if autoscaling := vllmRuntime.Spec.Autoscaling; autoscaling.Enabled {
+		period = time.Duration(max(autoscaling.SyncPeriodSeconds, 1)) * time.Second
+	}
+	if scaleToZero := vllmRuntime.Spec.ScaleToZero; scaleToZero.Enabled {
+		scaleToZeroPeriod := time.Duration(max(scaleToZero.SyncPeriodSeconds, 1)) * time.Second
+		if period == 0 || scaleToZeroPeriod < period {
+			period = scaleToZeroPeriod
+		}
+	}
+	if isDownloading(vllmRuntime) && (period == 0 || downloadSyncPeriod < period) {
+		period = downloadSyncPeriod
 	}
+	return period
+}
 
-	// Define probes
-	readinessProbe := &corev1.Probe{
-		ProbeHandler: corev1.ProbeHandler{
-			HTTPGet: &corev1.HTTPGetAction{
-				Path:   "/health",
-				Port:   intstr.FromInt(int(vllmRuntime.Spec.VLLMConfig.Port)),
-				Scheme: corev1.URISchemeHTTP,
-			},
-		},
-		InitialDelaySeconds: 10,
-		PeriodSeconds:       20,
-		TimeoutSeconds:      5,
-		SuccessThreshold:    1,
-		FailureThreshold:    10,
+// handleInvalidSpec reports a spec that cannot be turned into Kubernetes objects on the VLLMRuntime
+// and stops the reconcile until the spec changes
+func (r *VLLMRuntimeReconciler) handleInvalidSpec(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime, specErr error) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	log.Error(specErr, "Invalid VLLMRuntime spec")
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestVR := &productionstackv1alpha1.VLLMRuntime{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}, latestVR); err != nil {
+			return err
+		}
+
+		latestVR.Status.LastUpdated = metav1.Now()
+		latestVR.Status.ModelStatus = statusInvalidSpec
+		latestVR.Status.ObservedGeneration = latestVR.Generation
+		meta.SetStatusCondition(&latestVR.Status.Conditions, degradedCondition(latestVR.Generation, specErr))
+
+		return r.Status().Update(ctx, latestVR)
+	})
+	if err != nil {
+		log.Error(err, "Failed to update VLLMRuntime status")
+		return ctrl.Result{}, err
 	}
 
-	livenessProbe := &corev1.Probe{
-		ProbeHandler: corev1.ProbeHandler{
-			HTTPGet: &corev1.HTTPGetAction{
-				Path:   "/health",
-				Port:   intstr.FromInt(int(vllmRuntime.Spec.VLLMConfig.Port)),
-				Scheme: corev1.URISchemeHTTP,
-			},
-		},
-		InitialDelaySeconds: 10,
-		PeriodSeconds:       20,
-		TimeoutSeconds:      3,
-		SuccessThreshold:    1,
-		FailureThreshold:    10,
+	// Wait for the spec to be fixed, which triggers a new reconcile
+	return ctrl.Result{}, nil
+}
+
+// updateAutoscalingStatus records the replica count chosen by the autoscaler and the metric value
+// it was computed from, so the deployment built afterwards uses it
+func (r *VLLMRuntimeReconciler) updateAutoscalingStatus(ctx context.Context, vllmRuntime *productionstackv1alpha1.VLLMRuntime, replicas int32, value string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestVR := &productionstackv1alpha1.VLLMRuntime{}
+		if err := r.Get(ctx, types.NamespacedName{Name: vllmRuntime.Name, Namespace: vllmRuntime.Namespace}, latestVR); err != nil {
+			return err
+		}
+
+		previous := latestVR.Status.Autoscaling
+		status := &productionstackv1alpha1.AutoscalingStatus{DesiredReplicas: replicas, CurrentValue: value}
+		if previous != nil {
+			status.LastScaleTime = previous.LastScaleTime
+			// Keep the last known value while no pod reports metrics
+			if value == "" {
+				status.CurrentValue = previous.CurrentValue
+			}
+		}
+		if desiredReplicas(latestVR) != replicas {
+			now := metav1.Now()
+			status.LastScaleTime = &now
+		}
+		vllmRuntime.Status.Autoscaling = status
+		if reflect.DeepEqual(previous, status) {
+			return nil // No update needed
+		}
+
+		latestVR.Status.Autoscaling = status
+		return r.Status().Update(ctx, latestVR)
+	})
+}
+
+// labelsForVLLMRuntime returns the labels of the objects created for a VLLMRuntime, the labels of
+// the VLLMRuntime and its name as app label. The app label is set last, so the labels of the
+// VLLMRuntime cannot override it.
+func labelsForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) map[string]string {
+	labels := maps.Clone(vllmRuntime.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels["app"] = vllmRuntime.Name
+	return labels
+}
+
+// selectorLabelsForVLLMRuntime returns the labels the Deployments and StatefulSets of a
+// VLLMRuntime select its pods by. The operator sets them alone, so changing the labels of the
+// VLLMRuntime does not change the selectors, which cannot change.
+func selectorLabelsForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) map[string]string {
+	return map[string]string{"app": vllmRuntime.Name, runtimeLabel: vllmRuntime.Name}
+}
+
+// podLabelsForVLLMRuntime returns the labels of the pods serving a VLLMRuntime, the given labels
+// and the runtime label. The runtime label is set last, so the labels of the VLLMRuntime cannot
+// override it.
+func podLabelsForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime, labels map[string]string) map[string]string {
+	labels = maps.Clone(labels)
+	labels[runtimeLabel] = vllmRuntime.Name
+	return labels
+}
+
+// deploymentForVLLMRuntime returns a VLLMRuntime Deployment object, or an error when the spec
+// holds values that cannot be parsed
+func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (*appsv1.Deployment, error) {
+	template, err := r.podTemplateForVLLMRuntime(vllmRuntime, kvRoleBoth)
+	if err != nil {
+		return nil, err
 	}
 
-	startupProbe := &corev1.Probe{
-		ProbeHandler: corev1.ProbeHandler{
-			HTTPGet: &corev1.HTTPGetAction{
-				Path:   "/health",
-				Port:   intstr.FromInt(int(vllmRuntime.Spec.VLLMConfig.Port)),
-				Scheme: corev1.URISchemeHTTP,
+	replicas := desiredReplicas(vllmRuntime)
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vllmRuntime.Name,
+			Namespace: vllmRuntime.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.DeploymentStrategyType(vllmRuntime.Spec.DeploymentConfig.DeployStrategy),
 			},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: selectorLabelsForVLLMRuntime(vllmRuntime),
+			},
+			Template: template,
 		},
-		InitialDelaySeconds: 120,
-		PeriodSeconds:       20,
-		TimeoutSeconds:      3,
-		FailureThreshold:    100,
 	}
 
-	// Build command line arguments
+	setSpecHash(dep, dep.Spec)
+
+	// Set the owner reference
+	ctrl.SetControllerReference(vllmRuntime, dep, r.Scheme)
+	return dep, nil
+}
+
+// podTemplateForVLLMRuntime returns the template of the pods serving a VLLMRuntime, or an error
+// when the spec holds values that cannot be parsed. The vLLM container comes first. kvRole is the
+// role of the pods in the LMCache KV transfer.
+func (r *VLLMRuntimeReconciler) podTemplateForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime, kvRole string) (corev1.PodTemplateSpec, error) {
+	labels := labelsForVLLMRuntime(vllmRuntime)
+
+	// Define probes
+	readinessProbe, livenessProbe, startupProbe := vllmProbes(vllmRuntime)
+
+	// Build command line arguments. A pre-warmed model is loaded from the storage volume under the
+	// name of the model URL, otherwise the requested revision is downloaded at startup.
+	model := vllmRuntime.Spec.Model.ModelURL
+	if isPrewarmed(vllmRuntime) {
+		model = localModelPath(vllmRuntime)
+	}
 	args := []string{
-		vllmRuntime.Spec.Model.ModelURL,
+		model,
 		"--host",
 		"0.0.0.0",
 		"--port",
 		fmt.Sprintf("%d", vllmRuntime.Spec.VLLMConfig.Port),
 	}
 
+	if isPrewarmed(vllmRuntime) {
+		if !hasServedModelName(vllmRuntime.Spec.VLLMConfig.ExtraArgs) {
+			args = append(args, "--served-model-name", vllmRuntime.Spec.Model.ModelURL)
+		}
+	} else if vllmRuntime.Spec.Model.Revision != "" {
+		args = append(args, "--revision", vllmRuntime.Spec.Model.Revision)
+	}
+
 	if vllmRuntime.Spec.Model.EnableLoRA {
 		args = append(args, "--enable-lora")
 	}
@@ -283,6 +498,10 @@ func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *production
 		args = append(args, "--tensor-parallel-size", fmt.Sprintf("%d", vllmRuntime.Spec.VLLMConfig.TensorParallelSize))
 	}
 
+	if vllmRuntime.Spec.VLLMConfig.PipelineParallelSize > 0 {
+		args = append(args, "--pipeline-parallel-size", fmt.Sprintf("%d", vllmRuntime.Spec.VLLMConfig.PipelineParallelSize))
+	}
+
 	if vllmRuntime.Spec.Model.MaxNumSeqs > 0 {
 		args = append(args, "--max-num-seqs", fmt.Sprintf("%d", vllmRuntime.Spec.Model.MaxNumSeqs))
 	}
@@ -295,6 +514,10 @@ func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *production
 		args = append(args, "--max_loras", fmt.Sprintf("%d", vllmRuntime.Spec.VLLMConfig.MaxLoras))
 	}
 
+	if vllmRuntime.Spec.VLLMConfig.MaxCPULoras > 0 {
+		args = append(args, "--max-cpu-loras", fmt.Sprintf("%d", vllmRuntime.Spec.VLLMConfig.MaxCPULoras))
+	}
+
 	if vllmRuntime.Spec.VLLMConfig.ExtraArgs != nil {
 		args = append(args, vllmRuntime.Spec.VLLMConfig.ExtraArgs...)
 	}
@@ -324,68 +547,16 @@ func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *production
 
 	// LM Cache configuration
 	if vllmRuntime.Spec.LMCacheConfig.Enabled {
-		env = append(env,
-			corev1.EnvVar{
-				Name:  "LMCACHE_LOG_LEVEL",
-				Value: "DEBUG",
-			},
-			corev1.EnvVar{
-				Name:  "LMCACHE_USE_EXPERIMENTAL",
-				Value: "True",
-			},
-			corev1.EnvVar{
-				Name:  "VLLM_RPC_TIMEOUT",
-				Value: "1000000",
-			},
-		)
+		env = append(env, lmCacheEnv(vllmRuntime)...)
 
 		// Add KV transfer config based on V1 flag
 		var lmcache_config string
 		if vllmRuntime.Spec.VLLMConfig.V1 {
-			lmcache_config = `{"kv_connector":"LMCacheConnectorV1","kv_role":"kv_both"}`
+			lmcache_config = fmt.Sprintf(`{"kv_connector":"LMCacheConnectorV1","kv_role":"%s"}`, kvRole)
 		} else {
-			lmcache_config = `{"kv_connector":"LMCacheConnector","kv_role":"kv_both"}`
+			lmcache_config = fmt.Sprintf(`{"kv_connector":"LMCacheConnector","kv_role":"%s"}`, kvRole)
 		}
 		args = append(args, "--kv-transfer-config", lmcache_config)
-
-		if vllmRuntime.Spec.LMCacheConfig.CPUOffloadingBufferSize != "" {
-			env = append(env,
-				corev1.EnvVar{
-					Name:  "LMCACHE_LOCAL_CPU",
-					Value: "True",
-				},
-				corev1.EnvVar{
-					Name:  "LMCACHE_MAX_LOCAL_CPU_SIZE",
-					Value: vllmRuntime.Spec.LMCacheConfig.CPUOffloadingBufferSize,
-				},
-			)
-		}
-
-		if vllmRuntime.Spec.LMCacheConfig.DiskOffloadingBufferSize != "" {
-			env = append(env,
-				corev1.EnvVar{
-					Name:  "LMCACHE_LOCAL_DISK",
-					Value: "True",
-				},
-				corev1.EnvVar{
-					Name:  "LMCACHE_MAX_LOCAL_DISK_SIZE",
-					Value: vllmRuntime.Spec.LMCacheConfig.DiskOffloadingBufferSize,
-				},
-			)
-		}
-
-		if vllmRuntime.Spec.LMCacheConfig.RemoteURL != "" {
-			env = append(env,
-				corev1.EnvVar{
-					Name:  "LMCACHE_REMOTE_URL",
-					Value: vllmRuntime.Spec.LMCacheConfig.RemoteURL,
-				},
-				corev1.EnvVar{
-					Name:  "LMCACHE_REMOTE_SERDE",
-					Value: vllmRuntime.Spec.LMCacheConfig.RemoteSerde,
-				},
-			)
-		}
 	}
 
 	// Add user-defined environment variables
@@ -399,26 +570,9 @@ func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *production
 	}
 
 	// Build resource requirements
-	resources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{},
-		Limits:   corev1.ResourceList{},
-	}
-
-	if vllmRuntime.Spec.DeploymentConfig.Resources.CPU != "" {
-		resources.Requests[corev1.ResourceCPU] = resource.MustParse(vllmRuntime.Spec.DeploymentConfig.Resources.CPU)
-		resources.Limits[corev1.ResourceCPU] = resource.MustParse(vllmRuntime.Spec.DeploymentConfig.Resources.CPU)
-	}
-
-	if vllmRuntime.Spec.DeploymentConfig.Resources.Memory != "" {
-		resources.Requests[corev1.ResourceMemory] = resource.MustParse(vllmRuntime.Spec.DeploymentConfig.Resources.Memory)
-		resources.Limits[corev1.ResourceMemory] = resource.MustParse(vllmRuntime.Spec.DeploymentConfig.Resources.Memory)
-	}
-
-	if vllmRuntime.Spec.DeploymentConfig.Resources.GPU != "" {
-		// Parse GPU resource as a decimal value
-		gpuResource := resource.MustParse(vllmRuntime.Spec.DeploymentConfig.Resources.GPU)
-		resources.Requests["nvidia.com/gpu"] = gpuResource
-		resources.Limits["nvidia.com/gpu"] = gpuResource
+	resources, err := vllmResourceRequirements("spec.deploymentConfig.resources", vllmRuntime.Spec.DeploymentConfig.Resources)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, err
 	}
 
 	// Get the image from Image spec or use default
@@ -455,16 +609,7 @@ func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *production
 	var volumeMounts []corev1.VolumeMount
 
 	if vllmRuntime.Spec.StorageConfig.Enabled {
-		volumeName := "pvc-storage"
-		if vllmRuntime.Spec.StorageConfig.VolumeName != "" {
-			volumeName = vllmRuntime.Spec.StorageConfig.VolumeName
-		}
-
-		mountPath := "/data"
-		if vllmRuntime.Spec.StorageConfig.MountPath != "" {
-			mountPath = vllmRuntime.Spec.StorageConfig.MountPath
-		}
-
+		volumeName, mountPath := storageVolume(vllmRuntime)
 		volumes = append(volumes, corev1.Volume{
 			Name: volumeName,
 			VolumeSource: corev1.VolumeSource{
@@ -480,6 +625,19 @@ func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *production
 		})
 	}
 
+	ports := []corev1.ContainerPort{
+		{
+			Name:          "http",
+			ContainerPort: vllmRuntime.Spec.VLLMConfig.Port,
+		},
+	}
+	if vllmRuntime.Spec.LMCacheConfig.Enabled && vllmRuntime.Spec.LMCacheConfig.P2P.Enabled {
+		ports = append(ports, corev1.ContainerPort{
+			Name:          "lmcache-p2p",
+			ContainerPort: lmCacheP2PPort(vllmRuntime),
+		})
+	}
+
 	containers := []corev1.Container{
 		{
 			Name:            "vllm",
@@ -488,57 +646,122 @@ func (r *VLLMRuntimeReconciler) deploymentForVLLMRuntime(vllmRuntime *production
 			Command:         []string{"/opt/venv/bin/vllm", "serve"},
 			Args:            args,
 			Env:             env,
-			Ports: []corev1.ContainerPort{
-				{
-					Name:          "http",
-					ContainerPort: vllmRuntime.Spec.VLLMConfig.Port,
-				},
-			},
-			Resources:      resources,
-			VolumeMounts:   volumeMounts,
-			ReadinessProbe: readinessProbe,
-			StartupProbe:   startupProbe,
-			LivenessProbe:  livenessProbe,
+			Ports:           ports,
+			Resources:       resources,
+			VolumeMounts:    volumeMounts,
+			ReadinessProbe:  readinessProbe,
+			StartupProbe:    startupProbe,
+			LivenessProbe:   livenessProbe,
 		},
 	}
 
 	if vllmRuntime.Spec.DeploymentConfig.SidecarConfig.Enabled {
-		containers = append(containers, r.buildSidecarContainer(vllmRuntime))
+		sidecar, err := r.buildSidecarContainer(vllmRuntime)
+		if err != nil {
+			return corev1.PodTemplateSpec{}, err
+		}
+		containers = append(containers, sidecar)
 	}
 
-	dep := &appsv1.Deployment{
+	// Hold the vLLM container until the model is in the storage volume
+	var initContainers []corev1.Container
+	if isPrewarmed(vllmRuntime) {
+		initContainers, err = modelInitContainers(vllmRuntime)
+		if err != nil {
+			return corev1.PodTemplateSpec{}, err
+		}
+	}
+
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      vllmRuntime.Name,
-			Namespace: vllmRuntime.Namespace,
+			Labels: podLabelsForVLLMRuntime(vllmRuntime, labels),
 		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: &vllmRuntime.Spec.DeploymentConfig.Replicas,
-			Strategy: appsv1.DeploymentStrategy{
-				Type: appsv1.DeploymentStrategyType(vllmRuntime.Spec.DeploymentConfig.DeployStrategy),
-			},
-			Selector: &metav1.LabelSelector{
-				MatchLabels: labels,
-			},
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: labels,
-				},
-				Spec: corev1.PodSpec{
-					ImagePullSecrets: imagePullSecrets,
-					Volumes:          volumes,
-					Containers:       containers,
-				},
-			},
+		Spec: corev1.PodSpec{
+			ImagePullSecrets: imagePullSecrets,
+			Volumes:          volumes,
+			InitContainers:   initContainers,
+			Containers:       containers,
 		},
 	}
+	applyScheduling(&template.Spec, vllmRuntime.Spec.Scheduling)
+	return template, nil
+}
 
-	// Set the owner reference
-	ctrl.SetControllerReference(vllmRuntime, dep, r.Scheme)
-	return dep
+// startupBudget returns the initial delay, period and failure threshold of the startup probe of
+// the vLLM container for a model size hint. Larger models take longer to load, and the container
+// is restarted once the budget is used up.
+func startupBudget(sizeHint string) (int32, int32, int32) {
+	switch sizeHint {
+	case "Small":
+		return 30, 10, 60
+	case "Medium":
+		return 60, 10, 120
+	case "Large":
+		return 120, 20, 150
+	case "XLarge":
+		return 180, 30, 200
+	default:
+		return 120, 20, 100
+	}
+}
+
+// vllmProbes returns the readiness, liveness and startup probes of the vLLM container of a runtime,
+// the defaults with the overrides of the probe config
+func vllmProbes(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (*corev1.Probe, *corev1.Probe, *corev1.Probe) {
+	port := vllmRuntime.Spec.VLLMConfig.Port
+	health := corev1.ProbeHandler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path:   "/health",
+			Port:   intstr.FromInt32(port),
+			Scheme: corev1.URISchemeHTTP,
+		},
+	}
+
+	readiness := corev1.Probe{
+		ProbeHandler:        health,
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       20,
+		TimeoutSeconds:      5,
+		SuccessThreshold:    1,
+		FailureThreshold:    10,
+	}
+	liveness := corev1.Probe{
+		ProbeHandler:        health,
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       20,
+		TimeoutSeconds:      3,
+		SuccessThreshold:    1,
+		FailureThreshold:    10,
+	}
+	initialDelay, period, failureThreshold := startupBudget(vllmRuntime.Spec.Model.SizeHint)
+	startup := corev1.Probe{
+		ProbeHandler:        health,
+		InitialDelaySeconds: initialDelay,
+		PeriodSeconds:       period,
+		TimeoutSeconds:      3,
+		FailureThreshold:    failureThreshold,
+	}
+
+	probes := vllmRuntime.Spec.Probes
+	return buildProbe(probes.Readiness, readiness, true, port),
+		buildProbe(probes.Liveness, liveness, true, port),
+		buildProbe(probes.Startup, startup, true, port)
+}
+
+// vllmResourceRequirements returns the requests and limits of a vLLM container for a resource
+// block, GPUs included. field is the spec path of the block, used in errors.
+func vllmResourceRequirements(field string, spec productionstackv1alpha1.ResourceRequirements) (corev1.ResourceRequirements, error) {
+	resources, err := buildResourceRequirements(field, spec)
+	if err != nil {
+		return resources, err
+	}
+
+	err = addGPUResources(&resources, field, spec, gpuResourceName(spec))
+	return resources, err
 }
 
 // buildSidecarContainer builds the sidecar container configuration
-func (r *VLLMRuntimeReconciler) buildSidecarContainer(vllmRuntime *productionstackv1alpha1.VLLMRuntime) corev1.Container {
+func (r *VLLMRuntimeReconciler) buildSidecarContainer(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (corev1.Container, error) {
 	sidecarConfig := vllmRuntime.Spec.DeploymentConfig.SidecarConfig
 
 	// Build sidecar volume mounts
@@ -581,34 +804,29 @@ func (r *VLLMRuntimeReconciler) buildSidecarContainer(vllmRuntime *productionsta
 	}
 
 	// Build sidecar resources
-	sidecarResources := corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{},
-		Limits:   corev1.ResourceList{},
+	sidecarResources, err := buildResourceRequirements("spec.deploymentConfig.sidecarConfig.resources", sidecarConfig.Resources)
+	if err != nil {
+		return corev1.Container{}, err
 	}
 
-	if sidecarConfig.Resources.CPU != "" {
-		sidecarResources.Requests[corev1.ResourceCPU] = resource.MustParse(sidecarConfig.Resources.CPU)
-		sidecarResources.Limits[corev1.ResourceCPU] = resource.MustParse(sidecarConfig.Resources.CPU)
-	} else {
+	if sidecarConfig.Resources.CPU == "" {
 		sidecarResources.Requests[corev1.ResourceCPU] = resource.MustParse("0.5")
 		sidecarResources.Limits[corev1.ResourceCPU] = resource.MustParse("0.5")
 	}
 
-	if sidecarConfig.Resources.Memory != "" {
-		sidecarResources.Requests[corev1.ResourceMemory] = resource.MustParse(sidecarConfig.Resources.Memory)
-		sidecarResources.Limits[corev1.ResourceMemory] = resource.MustParse(sidecarConfig.Resources.Memory)
-	} else {
+	if sidecarConfig.Resources.Memory == "" {
 		sidecarResources.Requests[corev1.ResourceMemory] = resource.MustParse("128Mi")
 		sidecarResources.Limits[corev1.ResourceMemory] = resource.MustParse("128Mi")
 	}
 
-	if sidecarConfig.Resources.GPU != "" {
-		gpuResource := resource.MustParse(sidecarConfig.Resources.GPU)
-		sidecarResources.Requests["nvidia.com/gpu"] = gpuResource
-		sidecarResources.Limits["nvidia.com/gpu"] = gpuResource
-	} else {
-		sidecarResources.Requests["nvidia.com/gpu"] = resource.MustParse("0")
-		sidecarResources.Limits["nvidia.com/gpu"] = resource.MustParse("0")
+	// The sidecar only requests GPUs when asked to, as the accelerator of the vLLM container unless
+	// it names its own
+	gpuName := gpuResourceName(vllmRuntime.Spec.DeploymentConfig.Resources)
+	if sidecarConfig.Resources.GPUResourceName != "" {
+		gpuName = gpuResourceName(sidecarConfig.Resources)
+	}
+	if err := addGPUResources(&sidecarResources, "spec.deploymentConfig.sidecarConfig.resources", sidecarConfig.Resources, gpuName); err != nil {
+		return corev1.Container{}, err
 	}
 
 	// Get sidecar image
@@ -632,92 +850,7 @@ func (r *VLLMRuntimeReconciler) buildSidecarContainer(vllmRuntime *productionsta
 		VolumeMounts:    sidecarVolumeMounts,
 	}
 
-	return sidecarContainer
-}
-
-// deploymentNeedsUpdate checks if the deployment needs to be updated
-func (r *VLLMRuntimeReconciler) deploymentNeedsUpdate(ctx context.Context, dep *appsv1.Deployment, vr *productionstackv1alpha1.VLLMRuntime) bool {
-
-	log := log.FromContext(ctx)
-	// Generate the expected deployment
-	expectedDep := r.deploymentForVLLMRuntime(vr)
-
-	// Compare replicas
-	if *dep.Spec.Replicas != vr.Spec.DeploymentConfig.Replicas {
-		return true
-	}
-
-	// Compare model URL
-	expectedModelURL := vr.Spec.Model.ModelURL
-	actualModelURL := ""
-	// For vllm serve, the model URL is the first argument after the command
-	if len(dep.Spec.Template.Spec.Containers[0].Args) > 0 {
-		actualModelURL = dep.Spec.Template.Spec.Containers[0].Args[0]
-	}
-	if expectedModelURL != actualModelURL {
-		log.Info("Model URL mismatch", "expected", expectedModelURL, "actual", actualModelURL)
-		return true
-	}
-
-	// Compare port
-	expectedPort := vr.Spec.VLLMConfig.Port
-	actualPort := dep.Spec.Template.Spec.Containers[0].Ports[0].ContainerPort
-	if expectedPort != actualPort {
-		log.Info("Port mismatch", "expected", expectedPort, "actual", actualPort)
-		return true
-	}
-
-	// Compare image
-	if expectedDep.Spec.Template.Spec.Containers[0].Image != dep.Spec.Template.Spec.Containers[0].Image {
-		log.Info("Image mismatch", "expected", expectedDep.Spec.Template.Spec.Containers[0].Image, "actual", dep.Spec.Template.Spec.Containers[0].Image)
-		return true
-	}
-
-	// Compare resources
-	expectedResources := expectedDep.Spec.Template.Spec.Containers[0].Resources
-	actualResources := dep.Spec.Template.Spec.Containers[0].Resources
-	if !reflect.DeepEqual(expectedResources, actualResources) {
-		log.Info("Resources mismatch", "expected", expectedResources, "actual", actualResources)
-		return true
-	}
-
-	// Compare LM Cache configuration
-	expectedLMCacheConfig := vr.Spec.LMCacheConfig
-	actualLMCacheConfig := dep.Spec.Template.Spec.Containers[0].Env
-
-	// Extract actual values from environment variables
-	actualEnabled := false
-	actualCPUOffloadingBufferSize := ""
-	actualDiskOffloadingBufferSize := ""
-	actualRemoteURL := ""
-	actualRemoteSerde := ""
-
-	for _, env := range actualLMCacheConfig {
-		switch env.Name {
-		case "LMCACHE_USE_EXPERIMENTAL":
-			actualEnabled = env.Value == "True"
-		case "LMCACHE_MAX_LOCAL_CPU_SIZE":
-			actualCPUOffloadingBufferSize = env.Value
-		case "LMCACHE_MAX_LOCAL_DISK_SIZE":
-			actualDiskOffloadingBufferSize = env.Value
-		case "LMCACHE_REMOTE_URL":
-			actualRemoteURL = env.Value
-		case "LMCACHE_REMOTE_SERDE":
-			actualRemoteSerde = env.Value
-		}
-	}
-
-	// Compare specific fields
-	if expectedLMCacheConfig.Enabled != actualEnabled ||
-		expectedLMCacheConfig.CPUOffloadingBufferSize != actualCPUOffloadingBufferSize ||
-		expectedLMCacheConfig.DiskOffloadingBufferSize != actualDiskOffloadingBufferSize ||
-		expectedLMCacheConfig.RemoteURL != actualRemoteURL ||
-		expectedLMCacheConfig.RemoteSerde != actualRemoteSerde {
-		log.Info("LM Cache configuration mismatch", "expected", expectedLMCacheConfig, "actual", actualLMCacheConfig)
-		return true
-	}
-
-	return false
+	return sidecarContainer, nil
 }
 
 // updateStatus updates the status of the VLLMRuntime
@@ -731,9 +864,23 @@ func (r *VLLMRuntimeReconciler) updateStatus(ctx context.Context, vr *production
 
 		// Update the status fields
 		latestVR.Status.LastUpdated = metav1.Now()
+		latestVR.Status.ObservedGeneration = latestVR.Generation
+		latestVR.Status.Replicas = dep.Status.Replicas
+		latestVR.Status.ReadyReplicas = dep.Status.ReadyReplicas
+		latestVR.Status.AvailableReplicas = dep.Status.AvailableReplicas
+		latestVR.Status.UpdatedReplicas = dep.Status.UpdatedReplicas
+		for _, condition := range deploymentConditions(latestVR.Generation, dep) {
+			meta.SetStatusCondition(&latestVR.Status.Conditions, condition)
+		}
+
+		latestVR.Status.MultiNode = nil
+		latestVR.Status.Topology = nil
+		clearScaleToZeroStatus(latestVR)
 
 		// Update model status based on deployment status
-		if dep.Status.AvailableReplicas == *dep.Spec.Replicas && dep.Status.UnavailableReplicas == 0 {
+		if isScaledToZero(latestVR) && *dep.Spec.Replicas == 0 {
+			latestVR.Status.ModelStatus = statusScaledToZero
+		} else if dep.Status.AvailableReplicas == *dep.Spec.Replicas && dep.Status.UnavailableReplicas == 0 {
 			latestVR.Status.ModelStatus = "Ready"
 		} else if dep.Status.UpdatedReplicas > 0 && dep.Status.AvailableReplicas != *dep.Spec.Replicas && dep.Status.UnavailableReplicas > 0 {
 			// If we have updated replicas but they're not yet available, mark as updating
@@ -748,11 +895,12 @@ func (r *VLLMRuntimeReconciler) updateStatus(ctx context.Context, vr *production
 	})
 }
 
-// serviceForVLLMRuntime returns a VLLMRuntime Service object
+// serviceForVLLMRuntime returns a VLLMRuntime Service object. In disaggregated mode it selects the
+// decode pods, which produce the responses.
 func (r *VLLMRuntimeReconciler) serviceForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) *corev1.Service {
-	labels := map[string]string{"app": vllmRuntime.Name}
-	for k, v := range vllmRuntime.Labels {
-		labels[k] = v
+	labels := labelsForVLLMRuntime(vllmRuntime)
+	if vllmRuntime.Spec.Topology.Disaggregated {
+		labels = roleLabelsForVLLMRuntime(vllmRuntime, roleDecode)
 	}
 
 	svc := &corev1.Service{
@@ -774,29 +922,31 @@ func (r *VLLMRuntimeReconciler) serviceForVLLMRuntime(vllmRuntime *productionsta
 		},
 	}
 
+	setSpecHash(svc, svc.Spec)
+
 	// Set the owner reference
 	ctrl.SetControllerReference(vllmRuntime, svc, r.Scheme)
 	return svc
 }
 
-// serviceNeedsUpdate checks if the service needs to be updated
-func (r *VLLMRuntimeReconciler) serviceNeedsUpdate(svc *corev1.Service, vr *productionstackv1alpha1.VLLMRuntime) bool {
-	// Compare target port
-	expectedTargetPort := int(vr.Spec.VLLMConfig.Port)
-	actualTargetPort := svc.Spec.Ports[0].TargetPort.IntValue()
-	if expectedTargetPort != actualTargetPort {
-		return true
+// storageVolume returns the name of the storage volume of a runtime in its pods and the path it is
+// mounted at in the vLLM container
+func storageVolume(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (string, string) {
+	volumeName := "pvc-storage"
+	if vllmRuntime.Spec.StorageConfig.VolumeName != "" {
+		volumeName = vllmRuntime.Spec.StorageConfig.VolumeName
 	}
 
-	return false
+	mountPath := "/data"
+	if vllmRuntime.Spec.StorageConfig.MountPath != "" {
+		mountPath = vllmRuntime.Spec.StorageConfig.MountPath
+	}
+	return volumeName, mountPath
 }
 
-// pvcForVLLMRuntime returns a VLLMRuntime PVC object
-func (r *VLLMRuntimeReconciler) pvcForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) *corev1.PersistentVolumeClaim {
-	labels := map[string]string{"app": vllmRuntime.Name}
-	for k, v := range vllmRuntime.Labels {
-		labels[k] = v
-	}
+// pvcForVLLMRuntime returns a VLLMRuntime PVC object, or an error when the storage size cannot be parsed
+func (r *VLLMRuntimeReconciler) pvcForVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (*corev1.PersistentVolumeClaim, error) {
+	labels := labelsForVLLMRuntime(vllmRuntime)
 
 	// Set default values if not specified
 	accessMode := corev1.ReadWriteOnce
@@ -811,9 +961,13 @@ func (r *VLLMRuntimeReconciler) pvcForVLLMRuntime(vllmRuntime *productionstackv1
 		}
 	}
 
-	size := "10Gi"
+	size := resource.MustParse("10Gi")
 	if vllmRuntime.Spec.StorageConfig.Size != "" {
-		size = vllmRuntime.Spec.StorageConfig.Size
+		var err error
+		size, err = parseQuantity("spec.storageConfig.size", vllmRuntime.Spec.StorageConfig.Size)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	pvc := &corev1.PersistentVolumeClaim{
@@ -826,7 +980,7 @@ func (r *VLLMRuntimeReconciler) pvcForVLLMRuntime(vllmRuntime *productionstackv1
 			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
 			Resources: corev1.VolumeResourceRequirements{
 				Requests: corev1.ResourceList{
-					corev1.ResourceStorage: resource.MustParse(size),
+					corev1.ResourceStorage: size,
 				},
 			},
 		},
@@ -837,24 +991,33 @@ func (r *VLLMRuntimeReconciler) pvcForVLLMRuntime(vllmRuntime *productionstackv1
 		pvc.Spec.StorageClassName = &vllmRuntime.Spec.StorageConfig.StorageClassName
 	}
 
+	// The requested size is the only part of the spec that can change after creation
+	setSpecHash(pvc, pvc.Spec.Resources)
+
 	// Set the owner reference
 	ctrl.SetControllerReference(vllmRuntime, pvc, r.Scheme)
-	return pvc
+	return pvc, nil
 }
 
-// pvcNeedsUpdate checks if the PVC needs to be updated
-func (r *VLLMRuntimeReconciler) pvcNeedsUpdate(pvc *corev1.PersistentVolumeClaim, vr *productionstackv1alpha1.VLLMRuntime) bool {
-	// Compare storage size
-	expectedSize := "10Gi"
-	if vr.Spec.StorageConfig.Size != "" {
-		expectedSize = vr.Spec.StorageConfig.Size
-	}
-	actualSize := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
-	if expectedSize != actualSize.String() {
-		return true
-	}
-
-	return false
+// pvcChangeError returns an error for a change to the storage config of a VLLMRuntime its live PVC
+// cannot take: the access mode and storage class cannot change after creation and the size cannot
+// shrink
+func pvcChangeError(found, desired *corev1.PersistentVolumeClaim) error {
+	if !reflect.DeepEqual(found.Spec.AccessModes, desired.Spec.AccessModes) {
+		return fmt.Errorf("spec.storageConfig.accessMode %v cannot change, the PVC was created with %v",
+			desired.Spec.AccessModes, found.Spec.AccessModes)
+	}
+	// A PVC created without a storage class is given the default one
+	if desired.Spec.StorageClassName != nil &&
+		(found.Spec.StorageClassName == nil || *found.Spec.StorageClassName != *desired.Spec.StorageClassName) {
+		return fmt.Errorf("spec.storageConfig.storageClassName %q cannot change after the PVC was created",
+			*desired.Spec.StorageClassName)
+	}
+	if desired.Spec.Resources.Requests.Storage().Cmp(*found.Spec.Resources.Requests.Storage()) < 0 {
+		return fmt.Errorf("spec.storageConfig.size %s cannot shrink the PVC from %s",
+			desired.Spec.Resources.Requests.Storage(), found.Spec.Resources.Requests.Storage())
+	}
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -862,7 +1025,10 @@ func (r *VLLMRuntimeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&productionstackv1alpha1.VLLMRuntime{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
 		Owns(&corev1.Service{}).
 		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&batchv1.Job{}).
+		Watches(&productionstackv1alpha1.CacheServer{}, handler.EnqueueRequestsFromMapFunc(r.runtimesForCacheServer)).
 		Complete(r)
 }
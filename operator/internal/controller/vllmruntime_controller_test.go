@@ -18,11 +18,20 @@ package controller
 
 import (
 	"context"
+	"reflect"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -81,4 +90,334 @@ var _ = Describe("VLLMRuntime Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When the spec holds values that cannot be parsed", func() {
+		const resourceName = "invalid-spec-runtime"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &productionstackv1alpha1.VLLMRuntime{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+					Model:      productionstackv1alpha1.ModelSpec{ModelURL: "facebook/opt-125m"},
+					VLLMConfig: productionstackv1alpha1.VLLMConfig{Port: 8000},
+					DeploymentConfig: productionstackv1alpha1.DeploymentConfig{
+						Replicas:  1,
+						Resources: productionstackv1alpha1.ResourceRequirements{CPU: "two"},
+						Image:     productionstackv1alpha1.ImageSpec{Registry: "docker.io", Name: "vllm/vllm-openai"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &productionstackv1alpha1.VLLMRuntime{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should report a Degraded condition instead of creating the Deployment", func() {
+			controllerReconciler := &VLLMRuntimeReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			// The first reconcile creates the Service, the second builds the Deployment
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			resource := &productionstackv1alpha1.VLLMRuntime{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.ModelStatus).To(Equal("InvalidSpec"))
+			condition := meta.FindStatusCondition(resource.Status.Conditions, "Degraded")
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("InvalidSpec"))
+			Expect(condition.Message).To(ContainSubstring("spec.deploymentConfig.resources.cpu"))
+
+			err := k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("VLLMRuntime builders", func() {
+	newRuntime := func() *productionstackv1alpha1.VLLMRuntime {
+		return &productionstackv1alpha1.VLLMRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "llama", Namespace: "default"},
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				Model:      productionstackv1alpha1.ModelSpec{ModelURL: "meta-llama/Llama-3.1-8B"},
+				VLLMConfig: productionstackv1alpha1.VLLMConfig{Port: 8000},
+				DeploymentConfig: productionstackv1alpha1.DeploymentConfig{
+					Replicas: 1,
+					Resources: productionstackv1alpha1.ResourceRequirements{
+						CPU: "4", Memory: "16Gi", GPU: "1",
+					},
+				},
+			},
+		}
+	}
+
+	It("builds the deployment from valid quantities", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		dep, err := r.deploymentForVLLMRuntime(newRuntime())
+		Expect(err).NotTo(HaveOccurred())
+		limits := dep.Spec.Template.Spec.Containers[0].Resources.Limits
+		Expect(limits.Cpu().String()).To(Equal("4"))
+		Expect(limits.Memory().String()).To(Equal("16Gi"))
+	})
+
+	It("requests the GPUs as the configured accelerator and none without GPUs", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.DeploymentConfig.Resources.GPUResourceName = "amd.com/gpu"
+		vr.Spec.DeploymentConfig.SidecarConfig = productionstackv1alpha1.SidecarConfig{Enabled: true, Name: "sidecar"}
+		dep, err := r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		containers := dep.Spec.Template.Spec.Containers
+		Expect(containers[0].Resources.Limits).To(HaveKey(corev1.ResourceName("amd.com/gpu")))
+		Expect(containers[0].Resources.Limits).NotTo(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+		// The sidecar requests no accelerator unless it sets GPUs
+		Expect(containers[1].Resources.Requests).To(HaveLen(2))
+		Expect(containers[1].Resources.Limits).To(HaveLen(2))
+
+		vr.Spec.DeploymentConfig.SidecarConfig.Resources.GPU = "1"
+		dep, err = r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[1].Resources.Limits).To(HaveKey(corev1.ResourceName("amd.com/gpu")))
+
+		vr = newRuntime()
+		vr.Spec.DeploymentConfig.Resources.GPU = ""
+		dep, err = r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[0].Resources.Limits).To(HaveLen(2))
+	})
+
+	It("returns an error naming the field for quantities that cannot be parsed", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		for field, mutate := range map[string]func(*productionstackv1alpha1.VLLMRuntime){
+			"spec.deploymentConfig.resources.memory": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.DeploymentConfig.Resources.Memory = "16 gigs"
+			},
+			"spec.deploymentConfig.resources.gpu": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.DeploymentConfig.Resources.GPU = "one"
+			},
+			"spec.deploymentConfig.sidecarConfig.resources.cpu": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.DeploymentConfig.SidecarConfig.Enabled = true
+				vr.Spec.DeploymentConfig.SidecarConfig.Resources.CPU = "half"
+			},
+		} {
+			vr := newRuntime()
+			mutate(vr)
+			_, err := r.deploymentForVLLMRuntime(vr)
+			Expect(err).To(MatchError(ContainSubstring(field)))
+		}
+	})
+
+	It("schedules the pods by the scheduling constraints of the spec", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.Scheduling = productionstackv1alpha1.SchedulingConfig{
+			NodeSelector: map[string]string{"gpu-pool": "h100"},
+			Tolerations:  []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}},
+			TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+				MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: corev1.ScheduleAnyway,
+			}},
+			PriorityClassName: "inference",
+			RuntimeClassName:  "nvidia",
+		}
+		dep, err := r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		podSpec := dep.Spec.Template.Spec
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"gpu-pool": "h100"}))
+		Expect(podSpec.Tolerations).To(Equal(vr.Spec.Scheduling.Tolerations))
+		Expect(podSpec.TopologySpreadConstraints).To(HaveLen(1))
+		Expect(podSpec.PriorityClassName).To(Equal("inference"))
+		Expect(*podSpec.RuntimeClassName).To(Equal("nvidia"))
+
+		dep, err = r.deploymentForVLLMRuntime(newRuntime())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.RuntimeClassName).To(BeNil())
+	})
+
+	It("sizes the startup budget by the size hint of the model and applies the probe config", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		dep, err := r.deploymentForVLLMRuntime(newRuntime())
+		Expect(err).NotTo(HaveOccurred())
+		startup := dep.Spec.Template.Spec.Containers[0].StartupProbe
+		Expect(startup.InitialDelaySeconds + startup.PeriodSeconds*startup.FailureThreshold).To(Equal(int32(2120)))
+
+		vr := newRuntime()
+		vr.Spec.Model.SizeHint = "Small"
+		failureThreshold := int32(3)
+		vr.Spec.Probes = productionstackv1alpha1.ProbesConfig{
+			Readiness: productionstackv1alpha1.ProbeConfig{Path: "/v1/models"},
+			Liveness:  productionstackv1alpha1.ProbeConfig{FailureThreshold: &failureThreshold},
+		}
+		dep, err = r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		container := dep.Spec.Template.Spec.Containers[0]
+		Expect(container.StartupProbe.InitialDelaySeconds).To(Equal(int32(30)))
+		Expect(container.StartupProbe.PeriodSeconds * container.StartupProbe.FailureThreshold).To(Equal(int32(600)))
+		Expect(container.ReadinessProbe.HTTPGet.Path).To(Equal("/v1/models"))
+		Expect(container.LivenessProbe.HTTPGet.Path).To(Equal("/health"))
+		Expect(container.LivenessProbe.FailureThreshold).To(Equal(int32(3)))
+
+		vr.Spec.Model.SizeHint = "XLarge"
+		vr.Spec.Probes.Startup.Disabled = true
+		dep, err = r.deploymentForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.Spec.Template.Spec.Containers[0].StartupProbe).To(BeNil())
+	})
+
+	It("returns an error for a storage size that cannot be parsed", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.StorageConfig = productionstackv1alpha1.StorageConfig{Enabled: true, Size: "lots"}
+		_, err := r.pvcForVLLMRuntime(vr)
+		Expect(err).To(MatchError(ContainSubstring("spec.storageConfig.size")))
+
+		vr.Spec.StorageConfig.Size = "20Gi"
+		pvc, err := r.pvcForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pvc.Annotations).To(HaveKey(specHashAnnotation))
+	})
+
+	It("updates the PVC only for a larger size and reports changes it cannot take", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		vr := newRuntime()
+		vr.Spec.StorageConfig = productionstackv1alpha1.StorageConfig{Enabled: true, Size: "20Gi", StorageClassName: "fast"}
+		found, err := r.pvcForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+
+		vr.Spec.StorageConfig.Size = "40Gi"
+		grown, err := r.pvcForVLLMRuntime(vr)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(specHashChanged(found, grown)).To(BeTrue())
+		Expect(pvcChangeError(found, grown)).To(Succeed())
+
+		for field, mutate := range map[string]func(*productionstackv1alpha1.StorageConfig){
+			"spec.storageConfig.size":             func(config *productionstackv1alpha1.StorageConfig) { config.Size = "10Gi" },
+			"spec.storageConfig.accessMode":       func(config *productionstackv1alpha1.StorageConfig) { config.AccessMode = "ReadWriteMany" },
+			"spec.storageConfig.storageClassName": func(config *productionstackv1alpha1.StorageConfig) { config.StorageClassName = "slow" },
+		} {
+			changed := vr.DeepCopy()
+			changed.Spec.StorageConfig.Size = "20Gi"
+			mutate(&changed.Spec.StorageConfig)
+			pvc, err := r.pvcForVLLMRuntime(changed)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pvcChangeError(found, pvc)).To(MatchError(ContainSubstring(field)))
+			if field != "spec.storageConfig.size" {
+				// The hash covers the size alone, so these changes are never recorded as applied
+				Expect(specHashChanged(found, pvc)).To(BeFalse(), field)
+			}
+		}
+	})
+
+	It("changes the spec hash of the deployment for any change to the spec", func() {
+		r := &VLLMRuntimeReconciler{Scheme: scheme.Scheme}
+		found, err := r.deploymentForVLLMRuntime(newRuntime())
+		Expect(err).NotTo(HaveOccurred())
+
+		same, err := r.deploymentForVLLMRuntime(newRuntime())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(specHashChanged(found, same)).To(BeFalse())
+
+		for name, mutate := range map[string]func(*productionstackv1alpha1.VLLMRuntime){
+			"extraArgs": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.VLLMConfig.ExtraArgs = []string{"--enforce-eager"}
+			},
+			"env": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.VLLMConfig.Env = []productionstackv1alpha1.EnvVar{{Name: "VLLM_LOGGING_LEVEL", Value: "DEBUG"}}
+			},
+			"maxModelLen": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.Model.MaxModelLen = 8192
+			},
+			"dtype": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.Model.DType = "bfloat16"
+			},
+			"tensorParallelSize": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.VLLMConfig.TensorParallelSize = 2
+			},
+			"sizeHint": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.Model.SizeHint = "Large"
+			},
+			"scheduling": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.Scheduling.Tolerations = []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}}
+			},
+			"sidecarConfig": func(vr *productionstackv1alpha1.VLLMRuntime) {
+				vr.Spec.DeploymentConfig.SidecarConfig = productionstackv1alpha1.SidecarConfig{
+					Enabled: true, Name: "sidecar", MountPath: "/data",
+					Image: productionstackv1alpha1.ImageSpec{Registry: "docker.io", Name: "lmcache/lmstack-sidecar:latest"},
+				}
+			},
+		} {
+			vr := newRuntime()
+			mutate(vr)
+			changed, err := r.deploymentForVLLMRuntime(vr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(specHashChanged(found, changed)).To(BeTrue(), name)
+		}
+	})
+
+	It("keeps the selector of the deployment when the labels of the runtime change", func() {
+		ctx := context.Background()
+		s := runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(productionstackv1alpha1.AddToScheme(s)).To(Succeed())
+
+		vr := newRuntime()
+		vr.Labels = map[string]string{"team": "search"}
+		// The API server rejects any change to the selector of a Deployment
+		c := fake.NewClientBuilder().WithScheme(s).WithObjects(vr).WithStatusSubresource(vr).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					if dep, ok := obj.(*appsv1.Deployment); ok {
+						live := &appsv1.Deployment{}
+						Expect(c.Get(ctx, client.ObjectKeyFromObject(dep), live)).To(Succeed())
+						if !reflect.DeepEqual(live.Spec.Selector, dep.Spec.Selector) {
+							return errors.NewInvalid(appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind(), dep.Name, nil)
+						}
+					}
+					return c.Update(ctx, obj, opts...)
+				},
+			}).Build()
+		r := &VLLMRuntimeReconciler{Client: c, Scheme: s}
+		reconcileUntilDone := func() {
+			for range 10 {
+				result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(vr)})
+				Expect(err).NotTo(HaveOccurred())
+				if !result.Requeue {
+					return
+				}
+			}
+		}
+		reconcileUntilDone()
+		dep := &appsv1.Deployment{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(vr), dep)).To(Succeed())
+		selector := dep.Spec.Selector.DeepCopy()
+		Expect(selector.MatchLabels).To(Equal(map[string]string{"app": "llama", runtimeLabel: "llama"}))
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(vr), vr)).To(Succeed())
+		vr.Labels = map[string]string{"team": "recommendations", "app": "recommender"}
+		Expect(c.Update(ctx, vr)).To(Succeed())
+		reconcileUntilDone()
+
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(vr), dep)).To(Succeed())
+		Expect(dep.Spec.Selector).To(Equal(selector))
+		Expect(dep.Spec.Template.Labels).To(HaveKeyWithValue("team", "recommendations"))
+		Expect(dep.Spec.Template.Labels).To(HaveKeyWithValue("app", "llama"))
+	})
 })
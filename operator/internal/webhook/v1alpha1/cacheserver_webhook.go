@@ -0,0 +1,202 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var cacheserverlog = logf.Log.WithName("cacheserver-resource")
+
+const (
+	defaultCacheServerImage = "lmcache/vllm-openai:2025-04-18"
+	defaultCacheServerPort  = 8000
+	defaultCacheServerMode  = "Deployment"
+
+	defaultCacheVolumeSize = "50Gi"
+	defaultCacheMountPath  = "/cache"
+)
+
+// SetupCacheServerWebhookWithManager registers the webhook for CacheServer in the manager.
+func SetupCacheServerWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&productionstackv1alpha1.CacheServer{}).
+		WithValidator(&CacheServerCustomValidator{}).
+		WithDefaulter(&CacheServerCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-production-stack-vllm-ai-v1alpha1-cacheserver,mutating=true,failurePolicy=fail,sideEffects=None,groups=production-stack.vllm.ai,resources=cacheservers,verbs=create;update,versions=v1alpha1,name=mcacheserver-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// CacheServerCustomDefaulter sets default values on the CacheServer resource
+// when it is created or updated.
+type CacheServerCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &CacheServerCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind CacheServer.
+func (d *CacheServerCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	cacheServer, ok := obj.(*productionstackv1alpha1.CacheServer)
+	if !ok {
+		return fmt.Errorf("expected a CacheServer object but got %T", obj)
+	}
+	cacheserverlog.V(1).Info("Defaulting for CacheServer", "name", cacheServer.GetName())
+
+	spec := &cacheServer.Spec
+	if spec.Replicas == 0 {
+		spec.Replicas = 1
+	}
+	if spec.Port == 0 {
+		spec.Port = defaultCacheServerPort
+	}
+	if spec.DeploymentStrategy == "" {
+		spec.DeploymentStrategy = "RollingUpdate"
+	}
+	if spec.Mode == "" {
+		spec.Mode = defaultCacheServerMode
+	}
+	defaultImage(&spec.Image, defaultCacheServerImage)
+
+	if persistence := &spec.Persistence; persistence.Enabled {
+		if persistence.Size == "" {
+			persistence.Size = defaultCacheVolumeSize
+		}
+		if persistence.MountPath == "" {
+			persistence.MountPath = defaultCacheMountPath
+		}
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-production-stack-vllm-ai-v1alpha1-cacheserver,mutating=false,failurePolicy=fail,sideEffects=None,groups=production-stack.vllm.ai,resources=cacheservers,verbs=create;update,versions=v1alpha1,name=vcacheserver-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// CacheServerCustomValidator validates the CacheServer resource when it is created or updated.
+type CacheServerCustomValidator struct{}
+
+var _ webhook.CustomValidator = &CacheServerCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type CacheServer.
+func (v *CacheServerCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cacheServer, ok := obj.(*productionstackv1alpha1.CacheServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a CacheServer object but got %T", obj)
+	}
+	cacheserverlog.V(1).Info("Validation for CacheServer upon creation", "name", cacheServer.GetName())
+
+	return nil, validateCacheServer(cacheServer)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type CacheServer.
+func (v *CacheServerCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	cacheServer, ok := newObj.(*productionstackv1alpha1.CacheServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a CacheServer object for the newObj but got %T", newObj)
+	}
+	oldCacheServer, ok := oldObj.(*productionstackv1alpha1.CacheServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a CacheServer object for the oldObj but got %T", oldObj)
+	}
+	cacheserverlog.V(1).Info("Validation for CacheServer upon update", "name", cacheServer.GetName())
+
+	if err := validateCacheServer(cacheServer); err != nil {
+		return nil, err
+	}
+	return nil, validateCacheServerUpdate(oldCacheServer, cacheServer)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type CacheServer.
+func (v *CacheServerCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateCacheServer checks the parts of a CacheServer spec the CRD schema cannot express
+func validateCacheServer(cacheServer *productionstackv1alpha1.CacheServer) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	allErrs = append(allErrs, validatePort(specPath.Child("port"), cacheServer.Spec.Port)...)
+	allErrs = append(allErrs, validateResources(specPath.Child("resources"), cacheServer.Spec.Resources)...)
+	allErrs = append(allErrs, validateScheduling(specPath.Child("scheduling"), cacheServer.Spec.Scheduling)...)
+	allErrs = append(allErrs, validateProbes(specPath.Child("probes"), cacheServer.Spec.Probes)...)
+	if cacheServer.Spec.Replicas < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("replicas"), cacheServer.Spec.Replicas, "must not be negative"))
+	}
+	allErrs = append(allErrs, validateCachePersistence(specPath, cacheServer.Spec)...)
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(productionstackv1alpha1.GroupVersion.WithKind("CacheServer").GroupKind(), cacheServer.Name, allErrs)
+}
+
+// validateCachePersistence checks that the volume of every replica is only requested in
+// StatefulSet mode, which gives every replica a claim of its own
+func validateCachePersistence(specPath *field.Path, spec productionstackv1alpha1.CacheServerSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	persistence := spec.Persistence
+	if !persistence.Enabled {
+		return nil
+	}
+
+	fldPath := specPath.Child("persistence")
+	if spec.Mode != "StatefulSet" {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("enabled"), persistence.Enabled,
+			"requires StatefulSet mode, which gives every replica a volume of its own"))
+	}
+	allErrs = append(allErrs, validateQuantity(fldPath.Child("size"), persistence.Size)...)
+	if persistence.MountPath != "" && !strings.HasPrefix(persistence.MountPath, "/") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("mountPath"), persistence.MountPath, "must be an absolute path"))
+	}
+	return allErrs
+}
+
+// validateCacheServerUpdate checks that the volumes of a StatefulSet are not changed, the claim
+// templates of a StatefulSet cannot change after creation
+func validateCacheServerUpdate(oldCacheServer, cacheServer *productionstackv1alpha1.CacheServer) error {
+	oldSpec, spec := oldCacheServer.Spec, cacheServer.Spec
+	if oldSpec.Mode != "StatefulSet" || spec.Mode != "StatefulSet" {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	fldPath := field.NewPath("spec", "persistence")
+	if oldSpec.Persistence.Enabled != spec.Persistence.Enabled ||
+		oldSpec.Persistence.Size != spec.Persistence.Size ||
+		oldSpec.Persistence.StorageClassName != spec.Persistence.StorageClassName {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			"the volumes of the shards cannot change in StatefulSet mode, switch to Deployment mode and back to recreate them"))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(productionstackv1alpha1.GroupVersion.WithKind("CacheServer").GroupKind(), cacheServer.Name, allErrs)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("CacheServer Webhook", func() {
+	var (
+		obj       *productionstackv1alpha1.CacheServer
+		validator CacheServerCustomValidator
+		defaulter CacheServerCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		obj = &productionstackv1alpha1.CacheServer{}
+		validator = CacheServerCustomValidator{}
+		defaulter = CacheServerCustomDefaulter{}
+	})
+
+	Context("When creating CacheServer under Defaulting Webhook", func() {
+		It("Should fill in the port, image, replicas and strategy defaults", func() {
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.Port).To(Equal(int32(8000)))
+			Expect(obj.Spec.Replicas).To(Equal(int32(1)))
+			Expect(obj.Spec.DeploymentStrategy).To(Equal("RollingUpdate"))
+			Expect(obj.Spec.Image.Name).To(Equal("lmcache/vllm-openai:2025-04-18"))
+			Expect(obj.Spec.Mode).To(Equal("Deployment"))
+		})
+
+		It("Should default the volume of every replica only when persistence is enabled", func() {
+			obj.Spec.Persistence.Enabled = true
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.Persistence.Size).To(Equal("50Gi"))
+			Expect(obj.Spec.Persistence.MountPath).To(Equal("/cache"))
+		})
+	})
+
+	Context("When creating or updating CacheServer under Validating Webhook", func() {
+		BeforeEach(func() {
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+		})
+
+		It("Should admit the defaulted cache server", func() {
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit persistent shards and deny persistence outside StatefulSet mode", func() {
+			obj.Spec.Persistence = productionstackv1alpha1.CacheServerPersistence{Enabled: true, Size: "100Gi", MountPath: "/cache"}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.persistence.enabled"))
+
+			obj.Spec.Mode = "StatefulSet"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny changing the volumes of the shards of a StatefulSet", func() {
+			obj.Spec.Mode = "StatefulSet"
+			obj.Spec.Persistence = productionstackv1alpha1.CacheServerPersistence{Enabled: true, Size: "100Gi", MountPath: "/cache"}
+			oldObj := obj.DeepCopy()
+			obj.Spec.Replicas = 4
+			Expect(validator.ValidateUpdate(context.Background(), oldObj, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Persistence.Size = "200Gi"
+			_, err := validator.ValidateUpdate(context.Background(), oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.persistence"))
+		})
+
+		It("Should deny quantities that cannot be parsed", func() {
+			obj.Spec.Resources.Memory = "4 gigabytes"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.resources.memory"))
+		})
+
+		It("Should deny tolerations and topology spread constraints the API server rejects", func() {
+			seconds := int64(60)
+			obj.Spec.Scheduling = productionstackv1alpha1.SchedulingConfig{
+				NodeSelector: map[string]string{"pool": "cpu"},
+				Tolerations: []corev1.Toleration{
+					{Key: "dedicated", Operator: corev1.TolerationOpExists, Value: "cache"},
+					{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule, TolerationSeconds: &seconds},
+				},
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{WhenUnsatisfiable: "Sometimes"}},
+				PriorityClassName:         "High_Priority",
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.scheduling.tolerations[0].value"))
+			Expect(err.Error()).To(ContainSubstring("spec.scheduling.tolerations[1].tolerationSeconds"))
+			Expect(err.Error()).To(ContainSubstring("spec.scheduling.topologySpreadConstraints[0].maxSkew"))
+			Expect(err.Error()).To(ContainSubstring("spec.scheduling.topologySpreadConstraints[0].topologyKey"))
+			Expect(err.Error()).To(ContainSubstring("spec.scheduling.topologySpreadConstraints[0].whenUnsatisfiable"))
+			Expect(err.Error()).To(ContainSubstring("spec.scheduling.priorityClassName"))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.scheduling.nodeSelector"))
+		})
+
+		It("Should deny probes with several checks or a liveness success threshold above 1", func() {
+			successThreshold := int32(2)
+			obj.Spec.Probes = productionstackv1alpha1.ProbesConfig{
+				Readiness: productionstackv1alpha1.ProbeConfig{SuccessThreshold: &successThreshold},
+				Liveness: productionstackv1alpha1.ProbeConfig{
+					Path: "healthz", Exec: []string{"true"}, SuccessThreshold: &successThreshold,
+				},
+				Startup: productionstackv1alpha1.ProbeConfig{GRPC: &productionstackv1alpha1.GRPCProbe{Port: 70000}},
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.probes.liveness: Forbidden"))
+			Expect(err.Error()).To(ContainSubstring("spec.probes.liveness.path"))
+			Expect(err.Error()).To(ContainSubstring("spec.probes.liveness.successThreshold"))
+			Expect(err.Error()).To(ContainSubstring("spec.probes.startup.grpc.port"))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.probes.readiness"))
+		})
+
+		It("Should deny ports out of range", func() {
+			obj.Spec.Port = 70000
+			_, err := validator.ValidateUpdate(context.Background(), obj.DeepCopy(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.port"))
+		})
+	})
+})
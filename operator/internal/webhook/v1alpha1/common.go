@@ -0,0 +1,201 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+const (
+	// defaultImageRegistry is the registry used when an image is not set
+	defaultImageRegistry = "docker.io"
+)
+
+// defaultImage fills an unset image with the given registry and name
+func defaultImage(image *productionstackv1alpha1.ImageSpec, name string) {
+	if image.Registry == "" && image.Name == "" {
+		image.Registry = defaultImageRegistry
+		image.Name = name
+	}
+	if image.PullPolicy == "" {
+		image.PullPolicy = "IfNotPresent"
+	}
+}
+
+// validateQuantity checks that value can be parsed as a resource quantity
+func validateQuantity(fldPath *field.Path, value string) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, value, err.Error())}
+	}
+	if q.Sign() < 0 {
+		return field.ErrorList{field.Invalid(fldPath, value, "must not be negative")}
+	}
+	return nil
+}
+
+// validateResources checks the CPU, memory and GPU quantities of a resource block
+func validateResources(fldPath *field.Path, resources productionstackv1alpha1.ResourceRequirements) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateQuantity(fldPath.Child("cpu"), resources.CPU)...)
+	allErrs = append(allErrs, validateQuantity(fldPath.Child("memory"), resources.Memory)...)
+	allErrs = append(allErrs, validateQuantity(fldPath.Child("gpu"), resources.GPU)...)
+	if name := resources.GPUResourceName; name != "" {
+		// Accelerators are extended resources, which always carry a domain prefix
+		for _, msg := range validation.IsQualifiedName(name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("gpuResourceName"), name, msg))
+		}
+		if !strings.Contains(name, "/") {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("gpuResourceName"), name,
+				"must be an extended resource name with a domain prefix, e.g. amd.com/gpu"))
+		}
+	}
+	return allErrs
+}
+
+// validatePort checks that a port is in the valid range
+func validatePort(fldPath *field.Path, port int32) field.ErrorList {
+	if port < 1 || port > 65535 {
+		return field.ErrorList{field.Invalid(fldPath, port, "must be between 1 and 65535")}
+	}
+	return nil
+}
+
+// validateScheduling checks the scheduling constraints the API server would otherwise only reject
+// when the Deployment creates its pods
+func validateScheduling(fldPath *field.Path, scheduling productionstackv1alpha1.SchedulingConfig) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for key, value := range scheduling.NodeSelector {
+		for _, msg := range validation.IsQualifiedName(key) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("nodeSelector"), key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("nodeSelector").Key(key), value, msg))
+		}
+	}
+
+	for i, toleration := range scheduling.Tolerations {
+		tolerationPath := fldPath.Child("tolerations").Index(i)
+		switch toleration.Operator {
+		case "", corev1.TolerationOpEqual:
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				allErrs = append(allErrs, field.Invalid(tolerationPath.Child("value"), toleration.Value,
+					"must be empty when the operator is Exists"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(tolerationPath.Child("operator"), toleration.Operator,
+				[]string{string(corev1.TolerationOpEqual), string(corev1.TolerationOpExists)}))
+		}
+		switch toleration.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			allErrs = append(allErrs, field.NotSupported(tolerationPath.Child("effect"), toleration.Effect,
+				[]string{string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute)}))
+		}
+		if toleration.TolerationSeconds != nil && toleration.Effect != corev1.TaintEffectNoExecute {
+			allErrs = append(allErrs, field.Invalid(tolerationPath.Child("tolerationSeconds"), *toleration.TolerationSeconds,
+				"only applies to the NoExecute effect"))
+		}
+	}
+
+	for i, constraint := range scheduling.TopologySpreadConstraints {
+		constraintPath := fldPath.Child("topologySpreadConstraints").Index(i)
+		if constraint.MaxSkew < 1 {
+			allErrs = append(allErrs, field.Invalid(constraintPath.Child("maxSkew"), constraint.MaxSkew, "must be at least 1"))
+		}
+		if constraint.TopologyKey == "" {
+			allErrs = append(allErrs, field.Required(constraintPath.Child("topologyKey"), "pods are spread across the domains of a node label"))
+		}
+		switch constraint.WhenUnsatisfiable {
+		case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+		default:
+			allErrs = append(allErrs, field.NotSupported(constraintPath.Child("whenUnsatisfiable"), constraint.WhenUnsatisfiable,
+				[]string{string(corev1.DoNotSchedule), string(corev1.ScheduleAnyway)}))
+		}
+	}
+
+	if name := scheduling.PriorityClassName; name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("priorityClassName"), name, msg))
+		}
+	}
+	if name := scheduling.RuntimeClassName; name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("runtimeClassName"), name, msg))
+		}
+	}
+
+	return allErrs
+}
+
+// validateProbes checks the probe overrides the API server would otherwise only reject once the
+// Deployment is written
+func validateProbes(fldPath *field.Path, probes productionstackv1alpha1.ProbesConfig) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for _, p := range []struct {
+		name  string
+		probe productionstackv1alpha1.ProbeConfig
+	}{
+		{"readiness", probes.Readiness},
+		{"liveness", probes.Liveness},
+		{"startup", probes.Startup},
+	} {
+		probePath := fldPath.Child(p.name)
+		probe := p.probe
+
+		handlers := 0
+		if probe.Path != "" {
+			handlers++
+			if !strings.HasPrefix(probe.Path, "/") {
+				allErrs = append(allErrs, field.Invalid(probePath.Child("path"), probe.Path, "must start with /"))
+			}
+		}
+		if len(probe.Exec) > 0 {
+			handlers++
+		}
+		if probe.GRPC != nil {
+			handlers++
+			if probe.GRPC.Port != 0 {
+				allErrs = append(allErrs, validatePort(probePath.Child("grpc", "port"), probe.GRPC.Port)...)
+			}
+		}
+		if handlers > 1 {
+			allErrs = append(allErrs, field.Forbidden(probePath, "at most one of path, exec and grpc may be set"))
+		}
+
+		// Kubernetes only accepts a single success for the probes that restart the container
+		if p.name != "readiness" && probe.SuccessThreshold != nil && *probe.SuccessThreshold != 1 {
+			allErrs = append(allErrs, field.Invalid(probePath.Child("successThreshold"), *probe.SuccessThreshold,
+				"must be 1 for liveness and startup probes"))
+		}
+	}
+
+	return allErrs
+}
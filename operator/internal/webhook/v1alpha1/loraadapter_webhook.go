@@ -0,0 +1,180 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var loraadapterlog = logf.Log.WithName("loraadapter-resource")
+
+// SetupLoraAdapterWebhookWithManager registers the webhook for LoraAdapter in the manager.
+func SetupLoraAdapterWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&productionstackv1alpha1.LoraAdapter{}).
+		WithValidator(&LoraAdapterCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-production-stack-vllm-ai-v1alpha1-loraadapter,mutating=false,failurePolicy=fail,sideEffects=None,groups=production-stack.vllm.ai,resources=loraadapters,verbs=create;update,versions=v1alpha1,name=vloraadapter-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// LoraAdapterCustomValidator validates the LoraAdapter resource when it is created or updated.
+type LoraAdapterCustomValidator struct{}
+
+var _ webhook.CustomValidator = &LoraAdapterCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type LoraAdapter.
+func (v *LoraAdapterCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	adapter, ok := obj.(*productionstackv1alpha1.LoraAdapter)
+	if !ok {
+		return nil, fmt.Errorf("expected a LoraAdapter object but got %T", obj)
+	}
+	loraadapterlog.V(1).Info("Validation for LoraAdapter upon creation", "name", adapter.GetName())
+
+	return nil, validateLoraAdapter(adapter)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type LoraAdapter.
+func (v *LoraAdapterCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	adapter, ok := newObj.(*productionstackv1alpha1.LoraAdapter)
+	if !ok {
+		return nil, fmt.Errorf("expected a LoraAdapter object for the newObj but got %T", newObj)
+	}
+	loraadapterlog.V(1).Info("Validation for LoraAdapter upon update", "name", adapter.GetName())
+
+	return nil, validateLoraAdapter(adapter)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type LoraAdapter.
+func (v *LoraAdapterCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateLoraAdapter checks the parts of a LoraAdapter spec the CRD schema cannot express
+func validateLoraAdapter(adapter *productionstackv1alpha1.LoraAdapter) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec := adapter.Spec
+
+	allErrs = append(allErrs, validateAdapterSource(specPath.Child("adapterSource"), spec.AdapterSource)...)
+
+	if spec.VLLMRuntimeRef != nil && spec.PodSelector != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("podSelector"), "may not be set together with vllmRuntimeRef"))
+	}
+	if spec.PodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.PodSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("podSelector"), spec.PodSelector, err.Error()))
+		}
+	}
+
+	deploymentConfig := spec.LoraAdapterDeploymentConfig
+	if deploymentConfig.Replicas != nil && deploymentConfig.MinReplicas != nil &&
+		*deploymentConfig.MinReplicas > *deploymentConfig.Replicas {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("loraAdapterDeploymentConfig", "minReplicas"),
+			*deploymentConfig.MinReplicas, "must not be greater than replicas"))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(productionstackv1alpha1.GroupVersion.WithKind("LoraAdapter").GroupKind(), adapter.Name, allErrs)
+}
+
+// validateAdapterSource checks that the fields of an adapter source fit its type
+func validateAdapterSource(fldPath *field.Path, source productionstackv1alpha1.AdapterSource) field.ErrorList {
+	var allErrs field.ErrorList
+	repository := ""
+	if source.Repository != nil {
+		repository = *source.Repository
+	}
+
+	switch source.Type {
+	case "local":
+		if source.AdapterPath == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("adapterPath"), "local adapter sources require a path"))
+		}
+	case "s3":
+		if !strings.HasPrefix(repository, "s3://") || strings.TrimPrefix(repository, "s3://") == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("repository"), repository,
+				"s3 adapter sources require a repository of the form s3://bucket/prefix"))
+		}
+	case "http":
+		u, err := url.Parse(repository)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("repository"), repository,
+				"http adapter sources require an http or https URL"))
+		}
+		if source.Pattern != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("pattern"), "pattern discovery is not supported for http adapter sources"))
+		}
+	case "huggingface":
+		if strings.Trim(repository, "/") == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("repository"), "huggingface adapter sources require a repository"))
+		}
+		if source.CredentialsSecretRef != nil && source.CredentialsSecretRef.Key == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("credentialsSecretRef", "key"),
+				"huggingface adapter sources read the token from this key of the secret"))
+		}
+	}
+
+	if source.SHA256 != "" && source.Type != "http" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("sha256"), "only supported for http adapter sources"))
+	}
+	if source.Endpoint != "" && source.Type != "s3" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("endpoint"), "only supported for s3 adapter sources"))
+	}
+	if source.Region != "" && source.Type != "s3" {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("region"), "only supported for s3 adapter sources"))
+	}
+
+	if source.Pattern != "" {
+		if err := validateAdapterPattern(source.Pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("pattern"), source.Pattern, err.Error()))
+		}
+	} else if source.MaxAdapters != 0 {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("maxAdapters"), "only supported together with pattern"))
+	}
+
+	return allErrs
+}
+
+// validateAdapterPattern checks that a glob or regex: pattern compiles
+func validateAdapterPattern(pattern string) error {
+	if expr, ok := strings.CutPrefix(pattern, "regex:"); ok {
+		_, err := regexp.Compile("^(?:" + expr + ")$")
+		return err
+	}
+	_, err := path.Match(pattern, "")
+	return err
+}
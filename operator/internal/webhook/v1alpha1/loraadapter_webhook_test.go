@@ -0,0 +1,133 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("LoraAdapter Webhook", func() {
+	var (
+		obj       *productionstackv1alpha1.LoraAdapter
+		validator LoraAdapterCustomValidator
+	)
+
+	repository := func(value string) *string { return &value }
+	int32Ptr := func(value int32) *int32 { return &value }
+
+	BeforeEach(func() {
+		obj = &productionstackv1alpha1.LoraAdapter{
+			Spec: productionstackv1alpha1.LoraAdapterSpec{
+				BaseModel: "llama3",
+				AdapterSource: productionstackv1alpha1.AdapterSource{
+					Type:        "local",
+					AdapterName: "sql-lora",
+					AdapterPath: "/data/lora/sql-lora",
+				},
+			},
+		}
+		validator = LoraAdapterCustomValidator{}
+	})
+
+	Context("When creating or updating LoraAdapter under Validating Webhook", func() {
+		It("Should admit a local adapter", func() {
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a local adapter without a path", func() {
+			obj.Spec.AdapterSource.AdapterPath = ""
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.adapterSource.adapterPath"))
+		})
+
+		It("Should deny an s3 repository without the s3 scheme", func() {
+			obj.Spec.AdapterSource.Type = "s3"
+			obj.Spec.AdapterSource.Repository = repository("bucket/adapters")
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.adapterSource.repository"))
+		})
+
+		It("Should deny a checksum or pattern on the wrong source type", func() {
+			obj.Spec.AdapterSource.SHA256 = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			obj.Spec.AdapterSource.Endpoint = "http://minio:9000"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.adapterSource.sha256"))
+			Expect(err.Error()).To(ContainSubstring("spec.adapterSource.endpoint"))
+
+			obj.Spec.AdapterSource = productionstackv1alpha1.AdapterSource{
+				Type:        "http",
+				AdapterName: "sql-lora",
+				Repository:  repository("https://example.com/sql-lora.tar.gz"),
+				Pattern:     "sql-*",
+			}
+			_, err = validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.adapterSource.pattern"))
+		})
+
+		It("Should deny a huggingface token secret without a key", func() {
+			obj.Spec.AdapterSource = productionstackv1alpha1.AdapterSource{
+				Type:                 "huggingface",
+				AdapterName:          "sql-lora",
+				Repository:           repository("yard1/llama-2-7b-sql-lora-test"),
+				CredentialsSecretRef: &productionstackv1alpha1.SecretRef{Name: "hf-token"},
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.adapterSource.credentialsSecretRef.key"))
+
+			obj.Spec.AdapterSource.CredentialsSecretRef.Key = "token"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny patterns that do not compile", func() {
+			obj.Spec.AdapterSource.Pattern = "regex:customer-("
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.adapterSource.pattern"))
+
+			obj.Spec.AdapterSource.Pattern = "customer-["
+			_, err = validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should deny a runtime reference together with a pod selector", func() {
+			obj.Spec.VLLMRuntimeRef = &productionstackv1alpha1.VLLMRuntimeReference{Name: "llama3"}
+			obj.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "llama3"}}
+			_, err := validator.ValidateUpdate(context.Background(), obj.DeepCopy(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.podSelector"))
+		})
+
+		It("Should deny more min replicas than replicas", func() {
+			obj.Spec.LoraAdapterDeploymentConfig.Replicas = int32Ptr(1)
+			obj.Spec.LoraAdapterDeploymentConfig.MinReplicas = int32Ptr(2)
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("minReplicas"))
+		})
+	})
+})
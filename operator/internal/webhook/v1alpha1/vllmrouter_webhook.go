@@ -0,0 +1,488 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var vllmrouterlog = logf.Log.WithName("vllmrouter-resource")
+
+const (
+	defaultRouterImage = "lmcache/lmstack-router"
+	defaultRouterPort  = 80
+
+	defaultKVAwareThreshold      = 2000
+	defaultLMCacheControllerPort = 9000
+)
+
+// SetupVLLMRouterWebhookWithManager registers the webhook for VLLMRouter in the manager.
+func SetupVLLMRouterWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&productionstackv1alpha1.VLLMRouter{}).
+		WithValidator(&VLLMRouterCustomValidator{Client: mgr.GetClient()}).
+		WithDefaulter(&VLLMRouterCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-production-stack-vllm-ai-v1alpha1-vllmrouter,mutating=true,failurePolicy=fail,sideEffects=None,groups=production-stack.vllm.ai,resources=vllmrouters,verbs=create;update,versions=v1alpha1,name=mvllmrouter-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VLLMRouterCustomDefaulter sets default values on the VLLMRouter resource
+// when it is created or updated.
+type VLLMRouterCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &VLLMRouterCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind VLLMRouter.
+func (d *VLLMRouterCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	router, ok := obj.(*productionstackv1alpha1.VLLMRouter)
+	if !ok {
+		return fmt.Errorf("expected a VLLMRouter object but got %T", obj)
+	}
+	vllmrouterlog.V(1).Info("Defaulting for VLLMRouter", "name", router.GetName())
+
+	spec := &router.Spec
+	if spec.Replicas == 0 {
+		spec.Replicas = 1
+	}
+	if spec.Port == 0 {
+		spec.Port = defaultRouterPort
+	}
+	if spec.ServiceDiscovery == "" {
+		spec.ServiceDiscovery = "k8s"
+	}
+	if spec.RoutingLogic == "" {
+		spec.RoutingLogic = "roundrobin"
+	}
+	if spec.RoutingLogic == "kvaware" {
+		if spec.KVAware.Threshold == 0 {
+			spec.KVAware.Threshold = defaultKVAwareThreshold
+		}
+		if spec.KVAware.LMCacheControllerPort == 0 {
+			spec.KVAware.LMCacheControllerPort = defaultLMCacheControllerPort
+		}
+	}
+	defaultImage(&spec.Image, defaultRouterImage)
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-production-stack-vllm-ai-v1alpha1-vllmrouter,mutating=false,failurePolicy=fail,sideEffects=None,groups=production-stack.vllm.ai,resources=vllmrouters,verbs=create;update,versions=v1alpha1,name=vvllmrouter-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VLLMRouterCustomValidator validates the VLLMRouter resource when it is created or updated.
+type VLLMRouterCustomValidator struct {
+	// Client looks up the runtimes a router routes to. Without it only the spec is validated.
+	Client client.Reader
+}
+
+var _ webhook.CustomValidator = &VLLMRouterCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type VLLMRouter.
+func (v *VLLMRouterCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	router, ok := obj.(*productionstackv1alpha1.VLLMRouter)
+	if !ok {
+		return nil, fmt.Errorf("expected a VLLMRouter object but got %T", obj)
+	}
+	vllmrouterlog.V(1).Info("Validation for VLLMRouter upon creation", "name", router.GetName())
+
+	return v.validate(ctx, router)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type VLLMRouter.
+func (v *VLLMRouterCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	router, ok := newObj.(*productionstackv1alpha1.VLLMRouter)
+	if !ok {
+		return nil, fmt.Errorf("expected a VLLMRouter object for the newObj but got %T", newObj)
+	}
+	vllmrouterlog.V(1).Info("Validation for VLLMRouter upon update", "name", router.GetName())
+
+	return v.validate(ctx, router)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type VLLMRouter.
+func (v *VLLMRouterCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the spec of a VLLMRouter and, for kvaware routing, the runtimes it routes to
+func (v *VLLMRouterCustomValidator) validate(ctx context.Context, router *productionstackv1alpha1.VLLMRouter) (admission.Warnings, error) {
+	warnings, err := validateVLLMRouter(router)
+	if err != nil || v.Client == nil || router.Spec.RoutingLogic != "kvaware" {
+		return warnings, err
+	}
+
+	runtimeWarnings, allErrs, err := validateKVAwareRuntimes(ctx, v.Client, router)
+	if err != nil {
+		return warnings, apierrors.NewInternalError(err)
+	}
+	warnings = append(warnings, runtimeWarnings...)
+	if len(allErrs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(productionstackv1alpha1.GroupVersion.WithKind("VLLMRouter").GroupKind(), router.Name, allErrs)
+}
+
+// validateVLLMRouter checks the parts of a VLLMRouter spec the CRD schema cannot express
+func validateVLLMRouter(router *productionstackv1alpha1.VLLMRouter) (admission.Warnings, error) {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec := router.Spec
+
+	allErrs = append(allErrs, validatePort(specPath.Child("port"), spec.Port)...)
+	allErrs = append(allErrs, validateResources(specPath.Child("resources"), spec.Resources)...)
+	allErrs = append(allErrs, validateScheduling(specPath.Child("scheduling"), spec.Scheduling)...)
+	allErrs = append(allErrs, validateProbes(specPath.Child("probes"), spec.Probes)...)
+	if name := spec.ServiceAccountName; name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("serviceAccountName"), name, msg))
+		}
+	}
+
+	// The static backends are derived from the VLLMRuntimes selected by reference, or taken by the
+	// dynamic config from those matching the label selector
+	runtimeSelection := len(spec.RuntimeRefs) > 0 || spec.RuntimeSelector != nil
+	runtimeBackends := runtimeSelection || (spec.DynamicConfig.Enabled && spec.StaticBackends == "" && spec.StaticModels == "")
+	if runtimeSelection {
+		allErrs = append(allErrs, validateRuntimeSelection(specPath, spec)...)
+	} else if spec.ServiceDiscovery == "k8s" || (spec.ServiceDiscovery == "static" && runtimeBackends) {
+		if spec.K8sLabelSelector != "" {
+			if _, err := labels.Parse(spec.K8sLabelSelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(specPath.Child("k8sLabelSelector"), spec.K8sLabelSelector, err.Error()))
+			}
+		}
+	}
+	if spec.ServiceDiscovery == "static" && !runtimeBackends {
+		allErrs = append(allErrs, validateStaticBackends(specPath, spec.StaticBackends, spec.StaticModels)...)
+	}
+
+	warnings, routingErrs := validateRouting(specPath, spec)
+	allErrs = append(allErrs, routingErrs...)
+	exposureWarnings, exposureErrs := validateExposure(specPath.Child("exposure"), spec.Exposure)
+	warnings = append(warnings, exposureWarnings...)
+	allErrs = append(allErrs, exposureErrs...)
+	if spec.ColdStartTimeoutSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("coldStartTimeoutSeconds"),
+			spec.ColdStartTimeoutSeconds, "must not be negative"))
+	} else if spec.ColdStartTimeoutSeconds > 0 && spec.ServiceDiscovery != "k8s" {
+		warnings = append(warnings, fmt.Sprintf("%s only holds requests with k8s service discovery, static backends are never scaled to zero",
+			specPath.Child("coldStartTimeoutSeconds")))
+	}
+
+	if len(allErrs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(productionstackv1alpha1.GroupVersion.WithKind("VLLMRouter").GroupKind(), router.Name, allErrs)
+}
+
+// validateRouting checks that the routing logic has the parameters it needs and can be reloaded
+// from the dynamic config, and warns about the parameters of other routing modes, which the router
+// ignores
+func validateRouting(specPath *field.Path, spec productionstackv1alpha1.VLLMRouterSpec) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+	kvAwarePath := specPath.Child("kvAware")
+	disaggregatedPath := specPath.Child("disaggregatedPrefill")
+
+	switch spec.RoutingLogic {
+	case "session":
+		if spec.SessionKey == "" {
+			allErrs = append(allErrs, field.Required(specPath.Child("sessionKey"), "session routing requires a session key"))
+		}
+	case "kvaware":
+		if port := spec.KVAware.LMCacheControllerPort; port != 0 {
+			allErrs = append(allErrs, validatePort(kvAwarePath.Child("lmcacheControllerPort"), port)...)
+			if port == spec.Port {
+				allErrs = append(allErrs, field.Duplicate(kvAwarePath.Child("lmcacheControllerPort"), port))
+			}
+		}
+	case "disaggregated_prefill":
+		if len(spec.DisaggregatedPrefill.PrefillModelLabels) == 0 {
+			allErrs = append(allErrs, field.Required(disaggregatedPath.Child("prefillModelLabels"),
+				"disaggregated prefill routing requires the model labels of the prefill backends"))
+		}
+		if len(spec.DisaggregatedPrefill.DecodeModelLabels) == 0 {
+			allErrs = append(allErrs, field.Required(disaggregatedPath.Child("decodeModelLabels"),
+				"disaggregated prefill routing requires the model labels of the decode backends"))
+		}
+	}
+
+	// The router rebuilds its routing logic from the dynamic config with the session key alone, so
+	// the parameters of these modes would be lost on the first reload
+	if spec.DynamicConfig.Enabled && (spec.RoutingLogic == "kvaware" || spec.RoutingLogic == "disaggregated_prefill") {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("dynamicConfig", "enabled"),
+			fmt.Sprintf("%s routing cannot be reloaded from the dynamic config", spec.RoutingLogic)))
+	}
+
+	if spec.RoutingLogic != "kvaware" && spec.KVAware != (productionstackv1alpha1.KVAwareRoutingConfig{}) {
+		warnings = append(warnings, fmt.Sprintf("%s is ignored with %s routing", kvAwarePath, spec.RoutingLogic))
+	}
+	if spec.RoutingLogic != "disaggregated_prefill" &&
+		(len(spec.DisaggregatedPrefill.PrefillModelLabels) > 0 || len(spec.DisaggregatedPrefill.DecodeModelLabels) > 0) {
+		warnings = append(warnings, fmt.Sprintf("%s is ignored with %s routing", disaggregatedPath, spec.RoutingLogic))
+	}
+	return warnings, allErrs
+}
+
+// validateExposure checks the Service annotations and the Ingress or HTTPRoute of a router, and
+// warns about the settings of a disabled Ingress or HTTPRoute, which are ignored
+func validateExposure(fldPath *field.Path, exposure productionstackv1alpha1.RouterExposure) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+	ingressPath := fldPath.Child("ingress")
+	httpRoutePath := fldPath.Child("httpRoute")
+
+	allErrs = append(allErrs, apivalidation.ValidateAnnotations(exposure.Service.Annotations, fldPath.Child("service", "annotations"))...)
+
+	ingress := exposure.Ingress
+	if ingress.Enabled {
+		if ingress.Host != "" {
+			allErrs = append(allErrs, validateHostname(ingressPath.Child("host"), ingress.Host)...)
+		}
+		allErrs = append(allErrs, validateExposurePath(ingressPath.Child("path"), ingress.Path)...)
+		for name, value := range map[string]string{"className": ingress.ClassName, "tlsSecretName": ingress.TLSSecretName} {
+			if value == "" {
+				continue
+			}
+			for _, msg := range validation.IsDNS1123Subdomain(value) {
+				allErrs = append(allErrs, field.Invalid(ingressPath.Child(name), value, msg))
+			}
+		}
+		allErrs = append(allErrs, apivalidation.ValidateAnnotations(ingress.Annotations, ingressPath.Child("annotations"))...)
+	} else if !reflect.DeepEqual(ingress, productionstackv1alpha1.RouterIngressConfig{Path: ingress.Path}) {
+		warnings = append(warnings, fmt.Sprintf("%s is ignored unless enabled", ingressPath))
+	}
+
+	httpRoute := exposure.HTTPRoute
+	if httpRoute.Enabled {
+		if ingress.Enabled {
+			allErrs = append(allErrs, field.Forbidden(httpRoutePath.Child("enabled"), "cannot be combined with ingress.enabled"))
+		}
+		if httpRoute.GatewayName == "" {
+			allErrs = append(allErrs, field.Required(httpRoutePath.Child("gatewayName"), "an HTTPRoute requires a Gateway to attach to"))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(httpRoute.GatewayName) {
+				allErrs = append(allErrs, field.Invalid(httpRoutePath.Child("gatewayName"), httpRoute.GatewayName, msg))
+			}
+		}
+		if httpRoute.GatewayNamespace != "" {
+			for _, msg := range validation.IsDNS1123Label(httpRoute.GatewayNamespace) {
+				allErrs = append(allErrs, field.Invalid(httpRoutePath.Child("gatewayNamespace"), httpRoute.GatewayNamespace, msg))
+			}
+		}
+		if httpRoute.SectionName != "" {
+			for _, msg := range validation.IsDNS1123Subdomain(httpRoute.SectionName) {
+				allErrs = append(allErrs, field.Invalid(httpRoutePath.Child("sectionName"), httpRoute.SectionName, msg))
+			}
+		}
+		seen := map[string]bool{}
+		for i, hostname := range httpRoute.Hostnames {
+			allErrs = append(allErrs, validateHostname(httpRoutePath.Child("hostnames").Index(i), hostname)...)
+			if seen[hostname] {
+				allErrs = append(allErrs, field.Duplicate(httpRoutePath.Child("hostnames").Index(i), hostname))
+			}
+			seen[hostname] = true
+		}
+		allErrs = append(allErrs, validateExposurePath(httpRoutePath.Child("path"), httpRoute.Path)...)
+	} else if !reflect.DeepEqual(httpRoute, productionstackv1alpha1.RouterHTTPRouteConfig{Path: httpRoute.Path}) {
+		warnings = append(warnings, fmt.Sprintf("%s is ignored unless enabled", httpRoutePath))
+	}
+	return warnings, allErrs
+}
+
+// validateHostname checks that a host the router is exposed on is a DNS name. Wildcard hosts are
+// not allowed, as the router is reported reachable at the host.
+func validateHostname(fldPath *field.Path, host string) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(host) {
+		allErrs = append(allErrs, field.Invalid(fldPath, host, msg))
+	}
+	return allErrs
+}
+
+// validateExposurePath checks the path prefix an Ingress or HTTPRoute serves the router under
+func validateExposurePath(fldPath *field.Path, path string) field.ErrorList {
+	if path != "" && !strings.HasPrefix(path, "/") {
+		return field.ErrorList{field.Invalid(fldPath, path, "must be an absolute path")}
+	}
+	return nil
+}
+
+// validateRuntimeSelection checks the references and selector of the VLLMRuntimes a router routes
+// to, which replace its label selector and static backends
+func validateRuntimeSelection(specPath *field.Path, spec productionstackv1alpha1.VLLMRouterSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	refsPath := specPath.Child("runtimeRefs")
+
+	seen := map[string]bool{}
+	for i, ref := range spec.RuntimeRefs {
+		namePath := refsPath.Index(i).Child("name")
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(namePath, "must name a VLLMRuntime"))
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, ref.Name, msg))
+		}
+		if seen[ref.Name] {
+			allErrs = append(allErrs, field.Duplicate(namePath, ref.Name))
+		}
+		seen[ref.Name] = true
+	}
+	if spec.RuntimeSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.RuntimeSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("runtimeSelector"), spec.RuntimeSelector, err.Error()))
+		}
+	}
+
+	for name, value := range map[string]string{
+		"k8sLabelSelector": spec.K8sLabelSelector,
+		"staticBackends":   spec.StaticBackends,
+		"staticModels":     spec.StaticModels,
+	} {
+		if value != "" {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child(name), "cannot be combined with runtimeRefs or runtimeSelector"))
+		}
+	}
+	return allErrs
+}
+
+// selectsRuntime reports whether a router routes to a VLLMRuntime, selected by reference or label,
+// or through pods matching its label selector
+func selectsRuntime(spec productionstackv1alpha1.VLLMRouterSpec, vr *productionstackv1alpha1.VLLMRuntime) (bool, error) {
+	if len(spec.RuntimeRefs) == 0 && spec.RuntimeSelector == nil {
+		selector, err := labels.Parse(spec.K8sLabelSelector)
+		if err != nil {
+			return false, err
+		}
+		// The pods of a runtime carry its labels and its name as app label
+		podLabels := labels.Set{"app": vr.Name}
+		for k, v := range vr.Labels {
+			podLabels[k] = v
+		}
+		return selector.Matches(podLabels), nil
+	}
+
+	for _, ref := range spec.RuntimeRefs {
+		if ref.Name == vr.Name {
+			return true, nil
+		}
+	}
+	if spec.RuntimeSelector == nil {
+		return false, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(spec.RuntimeSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(vr.Labels)), nil
+}
+
+// validateKVAwareRuntimes checks that the runtimes a kvaware router discovers have LMCache
+// enabled, since the router finds the KV cache of a prompt through LMCache. Runtimes that do not
+// register with the LMCache controller of the router are only warned about, as their KV cache is
+// then invisible to it.
+func validateKVAwareRuntimes(ctx context.Context, c client.Reader, router *productionstackv1alpha1.VLLMRouter) (admission.Warnings, field.ErrorList, error) {
+	spec := router.Spec
+	if spec.ServiceDiscovery != "k8s" && len(spec.RuntimeRefs) == 0 && spec.RuntimeSelector == nil {
+		return nil, nil, nil
+	}
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+
+	runtimes := &productionstackv1alpha1.VLLMRuntimeList{}
+	if err := c.List(ctx, runtimes, client.InNamespace(router.Namespace)); err != nil {
+		return nil, nil, err
+	}
+
+	selected := 0
+	for i := range runtimes.Items {
+		vr := &runtimes.Items[i]
+		// An invalid selector was rejected before
+		if selects, err := selectsRuntime(spec, vr); err != nil || !selects {
+			continue
+		}
+		selected++
+		if !vr.Spec.LMCacheConfig.Enabled {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "routingLogic"), router.Spec.RoutingLogic,
+				fmt.Sprintf("kvaware routing requires LMCache, but VLLMRuntime %s does not enable spec.lmCacheConfig", vr.Name)))
+		} else if vr.Spec.LMCacheConfig.ControllerURL == "" {
+			warnings = append(warnings, fmt.Sprintf(
+				"VLLMRuntime %s does not set spec.lmCacheConfig.controllerUrl, so the router cannot see its KV cache", vr.Name))
+		}
+	}
+	if selected == 0 {
+		warnings = append(warnings, "no VLLMRuntime is selected yet, kvaware routing requires runtimes with LMCache enabled")
+	}
+	return warnings, allErrs, nil
+}
+
+// validateStaticBackends checks that every static backend is a URL and has a matching model
+func validateStaticBackends(specPath *field.Path, staticBackends, staticModels string) field.ErrorList {
+	var allErrs field.ErrorList
+	backendsPath := specPath.Child("staticBackends")
+	modelsPath := specPath.Child("staticModels")
+
+	if staticBackends == "" {
+		allErrs = append(allErrs, field.Required(backendsPath, "static service discovery requires backends"))
+	}
+	if staticModels == "" {
+		allErrs = append(allErrs, field.Required(modelsPath, "static service discovery requires models"))
+	}
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+
+	backends := strings.Split(staticBackends, ",")
+	models := strings.Split(staticModels, ",")
+	if len(backends) != len(models) {
+		allErrs = append(allErrs, field.Invalid(modelsPath, staticModels,
+			fmt.Sprintf("got %d models for %d backends, each backend needs exactly one model", len(models), len(backends))))
+	}
+	for _, backend := range backends {
+		u, err := url.Parse(strings.TrimSpace(backend))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(backendsPath, backend, "must be an http or https URL"))
+		}
+	}
+	for _, model := range models {
+		if strings.TrimSpace(model) == "" {
+			allErrs = append(allErrs, field.Invalid(modelsPath, staticModels, "model names must not be empty"))
+			break
+		}
+	}
+	return allErrs
+}
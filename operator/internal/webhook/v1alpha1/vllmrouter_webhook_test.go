@@ -0,0 +1,271 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRouter Webhook", func() {
+	var (
+		obj       *productionstackv1alpha1.VLLMRouter
+		validator VLLMRouterCustomValidator
+		defaulter VLLMRouterCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		obj = &productionstackv1alpha1.VLLMRouter{}
+		validator = VLLMRouterCustomValidator{}
+		defaulter = VLLMRouterCustomDefaulter{}
+	})
+
+	Context("When creating VLLMRouter under Defaulting Webhook", func() {
+		It("Should fill in the port, image, replicas and discovery defaults", func() {
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.Port).To(Equal(int32(80)))
+			Expect(obj.Spec.Replicas).To(Equal(int32(1)))
+			Expect(obj.Spec.ServiceDiscovery).To(Equal("k8s"))
+			Expect(obj.Spec.RoutingLogic).To(Equal("roundrobin"))
+			Expect(obj.Spec.Image.Registry).To(Equal("docker.io"))
+			Expect(obj.Spec.Image.Name).To(Equal("lmcache/lmstack-router"))
+		})
+
+		It("Should keep values that are already set", func() {
+			obj.Spec.Port = 8080
+			obj.Spec.Image = productionstackv1alpha1.ImageSpec{Registry: "ghcr.io", Name: "my/router:v1"}
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.Port).To(Equal(int32(8080)))
+			Expect(obj.Spec.Image.Registry).To(Equal("ghcr.io"))
+			Expect(obj.Spec.Image.Name).To(Equal("my/router:v1"))
+		})
+	})
+
+	Context("When creating or updating VLLMRouter under Validating Webhook", func() {
+		BeforeEach(func() {
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+		})
+
+		It("Should admit the defaulted router", func() {
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny quantities that cannot be parsed", func() {
+			obj.Spec.Resources.CPU = "two"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.resources.cpu"))
+		})
+
+		It("Should deny static discovery without backends", func() {
+			obj.Spec.ServiceDiscovery = "static"
+			obj.Spec.StaticModels = "model-a"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.staticBackends"))
+		})
+
+		It("Should deny static backends and models of different lengths", func() {
+			obj.Spec.ServiceDiscovery = "static"
+			obj.Spec.StaticBackends = "http://a:8000,http://b:8000"
+			obj.Spec.StaticModels = "model-a"
+			_, err := validator.ValidateUpdate(context.Background(), obj.DeepCopy(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("got 1 models for 2 backends"))
+		})
+
+		It("Should admit matching static backends and models", func() {
+			obj.Spec.ServiceDiscovery = "static"
+			obj.Spec.StaticBackends = "http://a:8000,http://b:8000"
+			obj.Spec.StaticModels = "model-a,model-b"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit static discovery taking its backends from the runtimes through the dynamic config", func() {
+			obj.Spec.ServiceDiscovery = "static"
+			obj.Spec.DynamicConfig.Enabled = true
+			obj.Spec.K8sLabelSelector = "env=prod"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.K8sLabelSelector = "env in (prod"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.k8sLabelSelector"))
+		})
+
+		It("Should deny routing modes the router cannot reload from the dynamic config", func() {
+			obj.Spec.DynamicConfig.Enabled = true
+			obj.Spec.RoutingLogic = "kvaware"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.dynamicConfig.enabled"))
+
+			obj.Spec.RoutingLogic = "disaggregated_prefill"
+			obj.Spec.DisaggregatedPrefill.PrefillModelLabels = []string{"llama-prefill"}
+			obj.Spec.DisaggregatedPrefill.DecodeModelLabels = []string{"llama-decode"}
+			_, err = validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.dynamicConfig.enabled"))
+
+			obj.Spec.RoutingLogic = "prefixaware"
+			obj.Spec.DisaggregatedPrefill = productionstackv1alpha1.DisaggregatedPrefillRoutingConfig{}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny session routing without a session key", func() {
+			obj.Spec.RoutingLogic = "session"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.sessionKey"))
+		})
+
+		It("Should deny disaggregated prefill routing without prefill and decode model labels", func() {
+			obj.Spec.RoutingLogic = "disaggregated_prefill"
+			obj.Spec.DisaggregatedPrefill.PrefillModelLabels = []string{"llama-prefill"}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.disaggregatedPrefill.decodeModelLabels"))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.disaggregatedPrefill.prefillModelLabels"))
+		})
+
+		It("Should warn about the parameters of another routing mode", func() {
+			obj.Spec.DisaggregatedPrefill.DecodeModelLabels = []string{"llama-decode"}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ContainElement(ContainSubstring("spec.disaggregatedPrefill is ignored with roundrobin routing")))
+		})
+
+		It("Should deny a kvaware LMCache controller port that clashes with the router port", func() {
+			obj.Spec.RoutingLogic = "kvaware"
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.KVAware.Threshold).To(Equal(int32(2000)))
+			Expect(obj.Spec.KVAware.LMCacheControllerPort).To(Equal(int32(9000)))
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.KVAware.LMCacheControllerPort = obj.Spec.Port
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.kvAware.lmcacheControllerPort"))
+		})
+
+		It("Should deny kvaware routing to a runtime without LMCache", func() {
+			newRuntime := func(name string, lmCache bool) *productionstackv1alpha1.VLLMRuntime {
+				return &productionstackv1alpha1.VLLMRuntime{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"env": "prod"}},
+					Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+						LMCacheConfig: productionstackv1alpha1.LMCacheConfig{Enabled: lmCache, ControllerURL: "router:9000"},
+					},
+				}
+			}
+			s := runtime.NewScheme()
+			Expect(productionstackv1alpha1.AddToScheme(s)).To(Succeed())
+			validator.Client = fake.NewClientBuilder().WithScheme(s).
+				WithObjects(newRuntime("llama", true), newRuntime("mistral", false)).Build()
+			obj.Namespace = "default"
+			obj.Spec.RoutingLogic = "kvaware"
+			obj.Spec.K8sLabelSelector = "app=llama"
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.K8sLabelSelector = "env=prod"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VLLMRuntime mistral does not enable spec.lmCacheConfig"))
+
+			obj.Spec.K8sLabelSelector = "env=dev"
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ContainElement(ContainSubstring("no VLLMRuntime is selected")))
+
+			obj.Spec.K8sLabelSelector = ""
+			obj.Spec.RuntimeRefs = []corev1.LocalObjectReference{{Name: "mistral"}}
+			_, err = validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VLLMRuntime mistral does not enable spec.lmCacheConfig"))
+		})
+
+		It("Should admit runtime selection in place of a label selector or static backends", func() {
+			obj.Spec.ServiceDiscovery = "static"
+			obj.Spec.RuntimeRefs = []corev1.LocalObjectReference{{Name: "llama"}}
+			obj.Spec.RuntimeSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.RuntimeRefs = append(obj.Spec.RuntimeRefs, corev1.LocalObjectReference{Name: "llama"})
+			obj.Spec.RuntimeSelector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "tier", Operator: "Near"}}
+			obj.Spec.StaticBackends = "http://llama:80"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.runtimeRefs[1].name: Duplicate value"))
+			Expect(err.Error()).To(ContainSubstring("spec.runtimeSelector"))
+			Expect(err.Error()).To(ContainSubstring("spec.staticBackends: Forbidden"))
+		})
+
+		It("Should deny a service account name that is not a DNS subdomain", func() {
+			obj.Spec.ServiceAccountName = "Router_SA"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.serviceAccountName"))
+		})
+
+		It("Should deny exposing the router through both an Ingress and an HTTPRoute", func() {
+			obj.Spec.Exposure.Ingress = productionstackv1alpha1.RouterIngressConfig{Enabled: true, Host: "router.example.com", Path: "/"}
+			obj.Spec.Exposure.HTTPRoute = productionstackv1alpha1.RouterHTTPRouteConfig{Enabled: true, Path: "v1"}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.exposure.httpRoute.enabled: Forbidden"))
+			Expect(err.Error()).To(ContainSubstring("spec.exposure.httpRoute.gatewayName: Required value"))
+			Expect(err.Error()).To(ContainSubstring("spec.exposure.httpRoute.path"))
+
+			obj.Spec.Exposure.HTTPRoute = productionstackv1alpha1.RouterHTTPRouteConfig{GatewayName: "gateway", Path: "/"}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ContainElement("spec.exposure.httpRoute is ignored unless enabled"))
+		})
+
+		It("Should deny an Ingress host that is not a DNS name", func() {
+			obj.Spec.Exposure.Ingress = productionstackv1alpha1.RouterIngressConfig{Enabled: true, Host: "*.example.com"}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.exposure.ingress.host"))
+		})
+
+		It("Should deny a negative cold start timeout", func() {
+			obj.Spec.ColdStartTimeoutSeconds = -1
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.coldStartTimeoutSeconds"))
+		})
+
+		It("Should warn that a cold start timeout only holds requests with k8s discovery", func() {
+			obj.Spec.ColdStartTimeoutSeconds = 120
+			Expect(validator.ValidateCreate(context.Background(), obj)).To(BeEmpty())
+
+			obj.Spec.ServiceDiscovery = "static"
+			obj.Spec.RuntimeRefs = []corev1.LocalObjectReference{{Name: "llama"}}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ContainElement(ContainSubstring("spec.coldStartTimeoutSeconds")))
+		})
+	})
+})
@@ -0,0 +1,555 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var vllmruntimelog = logf.Log.WithName("vllmruntime-resource")
+
+const (
+	defaultRuntimeImage = "lmcache/vllm-openai:2025-05-27-v1"
+	defaultSidecarImage = "lmcache/lmstack-sidecar:latest"
+	defaultRuntimePort  = 8000
+	defaultRemoteSerde  = "naive"
+
+	defaultLMCacheLogLevel = "INFO"
+	defaultLMCacheP2PPort  = 8200
+
+	defaultAutoscalingMetric     = "queueLength"
+	defaultAutoscalingSyncPeriod = 15
+
+	defaultScaleToZeroIdleMinutes = 15
+	defaultScaleToZeroSyncPeriod  = 10
+
+	defaultRayPort = 6379
+
+	defaultPrewarmMode = "Job"
+)
+
+// SetupVLLMRuntimeWebhookWithManager registers the webhook for VLLMRuntime in the manager.
+func SetupVLLMRuntimeWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&productionstackv1alpha1.VLLMRuntime{}).
+		WithValidator(&VLLMRuntimeCustomValidator{}).
+		WithDefaulter(&VLLMRuntimeCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-production-stack-vllm-ai-v1alpha1-vllmruntime,mutating=true,failurePolicy=fail,sideEffects=None,groups=production-stack.vllm.ai,resources=vllmruntimes,verbs=create;update,versions=v1alpha1,name=mvllmruntime-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VLLMRuntimeCustomDefaulter sets default values on the VLLMRuntime resource
+// when it is created or updated.
+type VLLMRuntimeCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &VLLMRuntimeCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind VLLMRuntime.
+func (d *VLLMRuntimeCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	vllmRuntime, ok := obj.(*productionstackv1alpha1.VLLMRuntime)
+	if !ok {
+		return fmt.Errorf("expected a VLLMRuntime object but got %T", obj)
+	}
+	vllmruntimelog.V(1).Info("Defaulting for VLLMRuntime", "name", vllmRuntime.GetName())
+
+	spec := &vllmRuntime.Spec
+	if spec.VLLMConfig.Port == 0 {
+		spec.VLLMConfig.Port = defaultRuntimePort
+	}
+
+	deploymentConfig := &spec.DeploymentConfig
+	if deploymentConfig.Replicas == 0 {
+		deploymentConfig.Replicas = 1
+	}
+	if deploymentConfig.DeployStrategy == "" {
+		deploymentConfig.DeployStrategy = "RollingUpdate"
+	}
+	defaultImage(&deploymentConfig.Image, defaultRuntimeImage)
+
+	if sidecar := &deploymentConfig.SidecarConfig; sidecar.Enabled {
+		if sidecar.Name == "" {
+			sidecar.Name = "sidecar"
+		}
+		if sidecar.MountPath == "" {
+			sidecar.MountPath = "/data"
+		}
+		defaultImage(&sidecar.Image, defaultSidecarImage)
+	}
+
+	if prewarm := &spec.Model.Prewarm; prewarm.Enabled {
+		if prewarm.Mode == "" {
+			prewarm.Mode = defaultPrewarmMode
+		}
+		if prewarm.Image != nil {
+			defaultImage(prewarm.Image, defaultRuntimeImage)
+		}
+	}
+
+	if lmCache := &spec.LMCacheConfig; lmCache.Enabled {
+		if lmCache.LogLevel == "" {
+			lmCache.LogLevel = defaultLMCacheLogLevel
+		}
+		if lmCache.P2P.Enabled && lmCache.P2P.Port == 0 {
+			lmCache.P2P.Port = defaultLMCacheP2PPort
+		}
+	}
+	if (spec.LMCacheConfig.RemoteURL != "" || spec.LMCacheConfig.CacheServerRef != nil) && spec.LMCacheConfig.RemoteSerde == "" {
+		spec.LMCacheConfig.RemoteSerde = defaultRemoteSerde
+	}
+
+	if autoscaling := &spec.Autoscaling; autoscaling.Enabled {
+		if autoscaling.MinReplicas == 0 {
+			autoscaling.MinReplicas = 1
+		}
+		if autoscaling.Metric == "" {
+			autoscaling.Metric = defaultAutoscalingMetric
+		}
+		if autoscaling.SyncPeriodSeconds == 0 {
+			autoscaling.SyncPeriodSeconds = defaultAutoscalingSyncPeriod
+		}
+	}
+
+	if scaleToZero := &spec.ScaleToZero; scaleToZero.Enabled {
+		if scaleToZero.IdleMinutes == 0 {
+			scaleToZero.IdleMinutes = defaultScaleToZeroIdleMinutes
+		}
+		if scaleToZero.SyncPeriodSeconds == 0 {
+			scaleToZero.SyncPeriodSeconds = defaultScaleToZeroSyncPeriod
+		}
+	}
+
+	if multiNode := &spec.MultiNode; multiNode.Enabled {
+		// One node per pipeline stage
+		if multiNode.Size == 0 && spec.VLLMConfig.PipelineParallelSize > 1 {
+			multiNode.Size = spec.VLLMConfig.PipelineParallelSize
+		}
+		if multiNode.RayPort == 0 {
+			multiNode.RayPort = defaultRayPort
+		}
+	}
+
+	if topology := &spec.Topology; topology.Disaggregated {
+		for role, group := range map[string]*productionstackv1alpha1.ReplicaGroup{"prefill": &topology.Prefill, "decode": &topology.Decode} {
+			if group.Replicas == 0 {
+				group.Replicas = 1
+			}
+			if group.ModelLabel == "" {
+				group.ModelLabel = vllmRuntime.Name + "-" + role
+			}
+		}
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-production-stack-vllm-ai-v1alpha1-vllmruntime,mutating=false,failurePolicy=fail,sideEffects=None,groups=production-stack.vllm.ai,resources=vllmruntimes,verbs=create;update,versions=v1alpha1,name=vvllmruntime-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VLLMRuntimeCustomValidator validates the VLLMRuntime resource when it is created or updated.
+type VLLMRuntimeCustomValidator struct{}
+
+var _ webhook.CustomValidator = &VLLMRuntimeCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type VLLMRuntime.
+func (v *VLLMRuntimeCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	vllmRuntime, ok := obj.(*productionstackv1alpha1.VLLMRuntime)
+	if !ok {
+		return nil, fmt.Errorf("expected a VLLMRuntime object but got %T", obj)
+	}
+	vllmruntimelog.V(1).Info("Validation for VLLMRuntime upon creation", "name", vllmRuntime.GetName())
+
+	return validateVLLMRuntime(vllmRuntime)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type VLLMRuntime.
+func (v *VLLMRuntimeCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	vllmRuntime, ok := newObj.(*productionstackv1alpha1.VLLMRuntime)
+	if !ok {
+		return nil, fmt.Errorf("expected a VLLMRuntime object for the newObj but got %T", newObj)
+	}
+	vllmruntimelog.V(1).Info("Validation for VLLMRuntime upon update", "name", vllmRuntime.GetName())
+
+	return validateVLLMRuntime(vllmRuntime)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type VLLMRuntime.
+func (v *VLLMRuntimeCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateVLLMRuntime checks the parts of a VLLMRuntime spec the CRD schema cannot express
+func validateVLLMRuntime(vllmRuntime *productionstackv1alpha1.VLLMRuntime) (admission.Warnings, error) {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	spec := vllmRuntime.Spec
+
+	vllmConfigPath := specPath.Child("vllmConfig")
+	allErrs = append(allErrs, validatePort(vllmConfigPath.Child("port"), spec.VLLMConfig.Port)...)
+	if spec.VLLMConfig.GpuMemoryUtilization != "" {
+		utilization, err := strconv.ParseFloat(spec.VLLMConfig.GpuMemoryUtilization, 64)
+		if err != nil || utilization <= 0 || utilization > 1 {
+			allErrs = append(allErrs, field.Invalid(vllmConfigPath.Child("gpuMemoryUtilization"),
+				spec.VLLMConfig.GpuMemoryUtilization, "must be a number greater than 0 and at most 1"))
+		}
+	}
+	if spec.VLLMConfig.TensorParallelSize < 0 {
+		allErrs = append(allErrs, field.Invalid(vllmConfigPath.Child("tensorParallelSize"),
+			spec.VLLMConfig.TensorParallelSize, "must not be negative"))
+	}
+	if spec.VLLMConfig.PipelineParallelSize < 0 {
+		allErrs = append(allErrs, field.Invalid(vllmConfigPath.Child("pipelineParallelSize"),
+			spec.VLLMConfig.PipelineParallelSize, "must not be negative"))
+	}
+	if spec.VLLMConfig.MaxCPULoras > 0 && spec.VLLMConfig.MaxCPULoras < spec.VLLMConfig.MaxLoras {
+		allErrs = append(allErrs, field.Invalid(vllmConfigPath.Child("maxCPULoras"),
+			spec.VLLMConfig.MaxCPULoras, "must not be less than maxLoras"))
+	}
+
+	deploymentConfigPath := specPath.Child("deploymentConfig")
+	if spec.DeploymentConfig.Replicas < 0 {
+		allErrs = append(allErrs, field.Invalid(deploymentConfigPath.Child("replicas"),
+			spec.DeploymentConfig.Replicas, "must not be negative"))
+	}
+	allErrs = append(allErrs, validateResources(deploymentConfigPath.Child("resources"), spec.DeploymentConfig.Resources)...)
+	allErrs = append(allErrs, validateResources(deploymentConfigPath.Child("sidecarConfig", "resources"),
+		spec.DeploymentConfig.SidecarConfig.Resources)...)
+
+	if spec.StorageConfig.Enabled {
+		allErrs = append(allErrs, validateQuantity(specPath.Child("storageConfig", "size"), spec.StorageConfig.Size)...)
+	}
+	allErrs = append(allErrs, validateScheduling(specPath.Child("scheduling"), spec.Scheduling)...)
+	allErrs = append(allErrs, validateProbes(specPath.Child("probes"), spec.Probes)...)
+
+	warnings, lmCacheErrs := validateLMCacheConfig(specPath.Child("lmCacheConfig"), spec.LMCacheConfig)
+	allErrs = append(allErrs, lmCacheErrs...)
+
+	if spec.Model.Prewarm.Enabled {
+		prewarmWarnings, prewarmErrs := validatePrewarm(specPath.Child("model"), spec)
+		warnings = append(warnings, prewarmWarnings...)
+		allErrs = append(allErrs, prewarmErrs...)
+	}
+
+	if spec.Autoscaling.Enabled {
+		allErrs = append(allErrs, validateAutoscaling(specPath.Child("autoscaling"), spec.Autoscaling)...)
+	}
+	if spec.ScaleToZero.Enabled {
+		allErrs = append(allErrs, validateScaleToZero(specPath.Child("scaleToZero"), spec.ScaleToZero)...)
+	}
+	if spec.MultiNode.Enabled {
+		multiNodeWarnings, multiNodeErrs := validateMultiNode(specPath.Child("multiNode"), spec)
+		warnings = append(warnings, multiNodeWarnings...)
+		allErrs = append(allErrs, multiNodeErrs...)
+	}
+	if spec.Topology.Disaggregated {
+		topologyWarnings, topologyErrs := validateTopology(specPath.Child("topology"), spec)
+		warnings = append(warnings, topologyWarnings...)
+		allErrs = append(allErrs, topologyErrs...)
+	}
+
+	if len(allErrs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(productionstackv1alpha1.GroupVersion.WithKind("VLLMRuntime").GroupKind(), vllmRuntime.Name, allErrs)
+}
+
+// validateLMCacheConfig checks the LMCache buffer sizes, remote backend, blending, P2P and
+// controller settings
+func validateLMCacheConfig(fldPath *field.Path, config productionstackv1alpha1.LMCacheConfig) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+
+	allErrs = append(allErrs, validateQuantity(fldPath.Child("cpuOffloadingBufferSize"), config.CPUOffloadingBufferSize)...)
+	allErrs = append(allErrs, validateQuantity(fldPath.Child("diskOffloadingBufferSize"), config.DiskOffloadingBufferSize)...)
+
+	if config.ChunkSize < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("chunkSize"), config.ChunkSize, "must not be negative"))
+	}
+
+	if ref := config.CacheServerRef; ref != nil {
+		refPath := fldPath.Child("cacheServerRef", "name")
+		if ref.Name == "" {
+			allErrs = append(allErrs, field.Required(refPath, "must name a CacheServer"))
+		} else {
+			for _, msg := range validation.IsDNS1123Subdomain(ref.Name) {
+				allErrs = append(allErrs, field.Invalid(refPath, ref.Name, msg))
+			}
+		}
+		if config.RemoteURL != "" {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("remoteUrl"),
+				"cannot be combined with cacheServerRef, which resolves the remote cache URL"))
+		}
+		if !config.Enabled {
+			warnings = append(warnings, "spec.lmCacheConfig.cacheServerRef is ignored because LMCache is not enabled")
+		}
+	}
+
+	if config.RemoteSerde != "" && config.RemoteURL == "" && config.CacheServerRef == nil {
+		allErrs = append(allErrs, field.Required(fldPath.Child("remoteUrl"), "remoteSerde requires a remote cache URL or cacheServerRef"))
+	}
+	if config.RemoteSerde != "" && config.RemoteSerde != "naive" && config.RemoteSerde != "cachegen" {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("remoteSerde"), config.RemoteSerde, []string{"naive", "cachegen"}))
+	}
+	if config.RemoteURL != "" {
+		u, err := url.Parse(config.RemoteURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("remoteUrl"), config.RemoteURL,
+				"must be a URL with a scheme and host, e.g. lm://cacheserver:80"))
+		}
+		if !config.Enabled {
+			warnings = append(warnings, "spec.lmCacheConfig.remoteUrl is ignored because LMCache is not enabled")
+		}
+	}
+
+	if blending := config.Blending; blending.Enabled {
+		if blending.RecomputeRatio != "" {
+			ratio, err := strconv.ParseFloat(blending.RecomputeRatio, 64)
+			if err != nil || ratio < 0 || ratio > 1 {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("blending", "recomputeRatio"), blending.RecomputeRatio,
+					"must be a number between 0 and 1"))
+			}
+		}
+		if blending.MinTokens < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("blending", "minTokens"), blending.MinTokens, "must not be negative"))
+		}
+	}
+
+	if p2p := config.P2P; p2p.Enabled {
+		p2pPath := fldPath.Child("p2p")
+		if p2p.LookupURL == "" {
+			allErrs = append(allErrs, field.Required(p2pPath.Child("lookupUrl"), "P2P sharing requires a lookup server"))
+		} else {
+			allErrs = append(allErrs, validateHostPort(p2pPath.Child("lookupUrl"), p2p.LookupURL)...)
+		}
+		allErrs = append(allErrs, validatePort(p2pPath.Child("port"), p2p.Port)...)
+	}
+
+	if config.ControllerURL != "" {
+		allErrs = append(allErrs, validateHostPort(fldPath.Child("controllerUrl"), config.ControllerURL)...)
+	}
+
+	return warnings, allErrs
+}
+
+// validateHostPort checks that an address is a host and port, e.g. lmcache-controller:9000
+func validateHostPort(fldPath *field.Path, address string) field.ErrorList {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return field.ErrorList{field.Invalid(fldPath, address, "must be a host and port, e.g. lmcache-controller:9000")}
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return field.ErrorList{field.Invalid(fldPath, address, "must end in a port between 1 and 65535")}
+	}
+	return nil
+}
+
+// validateAutoscaling checks the replica bounds and the target of an enabled autoscaling config
+func validateAutoscaling(fldPath *field.Path, autoscaling productionstackv1alpha1.AutoscalingConfig) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if autoscaling.MinReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("minReplicas"), autoscaling.MinReplicas, "must be at least 1"))
+	}
+	if autoscaling.MaxReplicas == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("maxReplicas"), "autoscaling requires a maximum number of replicas"))
+	} else if autoscaling.MaxReplicas < autoscaling.MinReplicas {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxReplicas"), autoscaling.MaxReplicas,
+			"must not be less than minReplicas"))
+	}
+	if autoscaling.ScaleUpStabilizationSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("scaleUpStabilizationSeconds"),
+			autoscaling.ScaleUpStabilizationSeconds, "must not be negative"))
+	}
+	if autoscaling.ScaleDownStabilizationSeconds < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("scaleDownStabilizationSeconds"),
+			autoscaling.ScaleDownStabilizationSeconds, "must not be negative"))
+	}
+
+	switch autoscaling.Metric {
+	case "queueLength", "kvCacheUsage", "tokensPerSecond":
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("metric"), autoscaling.Metric,
+			[]string{"queueLength", "kvCacheUsage", "tokensPerSecond"}))
+	}
+
+	targetPath := fldPath.Child("target")
+	if autoscaling.Target == "" {
+		allErrs = append(allErrs, field.Required(targetPath, "autoscaling requires a target value"))
+		return allErrs
+	}
+	target, err := strconv.ParseFloat(autoscaling.Target, 64)
+	if err != nil || target <= 0 {
+		allErrs = append(allErrs, field.Invalid(targetPath, autoscaling.Target, "must be a positive number"))
+	} else if autoscaling.Metric == "kvCacheUsage" && target > 1 {
+		allErrs = append(allErrs, field.Invalid(targetPath, autoscaling.Target,
+			"KV cache usage is a fraction, the target must be at most 1"))
+	}
+
+	return allErrs
+}
+
+// validateScaleToZero checks the idle time and check interval of an enabled scale to zero config
+func validateScaleToZero(fldPath *field.Path, scaleToZero productionstackv1alpha1.ScaleToZeroConfig) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if scaleToZero.IdleMinutes < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("idleMinutes"), scaleToZero.IdleMinutes, "must be at least 1"))
+	}
+	if scaleToZero.SyncPeriodSeconds < 1 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("syncPeriodSeconds"), scaleToZero.SyncPeriodSeconds, "must be at least 1"))
+	}
+
+	return allErrs
+}
+
+// validatePrewarm checks that the model of a pre-warmed runtime can be downloaded from Hugging Face
+// into its storage volume
+func validatePrewarm(fldPath *field.Path, spec productionstackv1alpha1.VLLMRuntimeSpec) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+	prewarmPath := fldPath.Child("prewarm")
+	prewarm := spec.Model.Prewarm
+
+	if !spec.StorageConfig.Enabled {
+		allErrs = append(allErrs, field.Invalid(prewarmPath.Child("enabled"), prewarm.Enabled,
+			"the model is downloaded into the storage volume, spec.storageConfig.enabled must be set"))
+	}
+
+	// The model URL and revision become directories in the storage volume
+	if strings.Contains(spec.Model.ModelURL, "://") || slices.Contains(strings.Split(spec.Model.ModelURL, "/"), "..") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("modelURL"), spec.Model.ModelURL,
+			"must be a Hugging Face model ID to be pre-warmed"))
+	}
+	if strings.TrimSpace(spec.Model.Revision) != spec.Model.Revision || slices.Contains(strings.Split(spec.Model.Revision, "/"), "..") {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("revision"), spec.Model.Revision,
+			"must be a branch, tag or commit hash"))
+	}
+
+	if image := prewarm.Image; image != nil && (image.Registry == "" || image.Name == "") {
+		allErrs = append(allErrs, field.Required(prewarmPath.Child("image"), "registry and name must both be set"))
+	}
+	allErrs = append(allErrs, validateResources(prewarmPath.Child("resources"), prewarm.Resources)...)
+	if prewarm.BackoffLimit < 0 {
+		allErrs = append(allErrs, field.Invalid(prewarmPath.Child("backoffLimit"), prewarm.BackoffLimit, "must not be negative"))
+	}
+
+	if prewarm.Mode != "InitContainer" && spec.StorageConfig.AccessMode != "" && spec.StorageConfig.AccessMode != "ReadWriteMany" {
+		warnings = append(warnings, "spec.storageConfig.accessMode should be ReadWriteMany to pre-warm the model in a Job, the Job and the vLLM pods share the volume")
+	}
+
+	return warnings, allErrs
+}
+
+// validateMultiNode checks the group size and Ray port of an enabled multi-node config, and that
+// a group has the GPUs the parallelism needs
+func validateMultiNode(fldPath *field.Path, spec productionstackv1alpha1.VLLMRuntimeSpec) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+	multiNode := spec.MultiNode
+
+	if multiNode.Size < 2 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("size"), multiNode.Size,
+			"must be at least 2, set it or a pipelineParallelSize above 1"))
+	}
+	allErrs = append(allErrs, validatePort(fldPath.Child("rayPort"), multiNode.RayPort)...)
+	if multiNode.RayPort == spec.VLLMConfig.Port {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("rayPort"), multiNode.RayPort, "must differ from the vLLM port"))
+	}
+
+	// vLLM needs a GPU per tensor parallel rank of every pipeline stage
+	if gpus, err := strconv.Atoi(spec.DeploymentConfig.Resources.GPU); err == nil && multiNode.Size >= 2 {
+		needed := max(spec.VLLMConfig.TensorParallelSize, 1) * max(spec.VLLMConfig.PipelineParallelSize, 1)
+		if available := int32(gpus) * multiNode.Size; needed > available {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("size"), multiNode.Size,
+				fmt.Sprintf("a group of %d pods with %d GPUs each has %d GPUs, but tensorParallelSize times pipelineParallelSize needs %d",
+					multiNode.Size, gpus, available, needed)))
+		}
+	}
+
+	if spec.StorageConfig.Enabled && spec.StorageConfig.AccessMode != "" && spec.StorageConfig.AccessMode != "ReadWriteMany" {
+		warnings = append(warnings, "spec.storageConfig.accessMode should be ReadWriteMany in multi-node mode, the pods of a group share the volume across nodes")
+	}
+
+	return warnings, allErrs
+}
+
+// validateTopology checks the prefill and decode groups of a disaggregated topology, and that the
+// rest of the spec lets them hand the KV cache over through LMCache
+func validateTopology(fldPath *field.Path, spec productionstackv1alpha1.VLLMRuntimeSpec) (admission.Warnings, field.ErrorList) {
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+	topology := spec.Topology
+
+	groups := []struct {
+		path  *field.Path
+		group productionstackv1alpha1.ReplicaGroup
+	}{
+		{fldPath.Child("prefill"), topology.Prefill},
+		{fldPath.Child("decode"), topology.Decode},
+	}
+	for _, g := range groups {
+		if g.group.Replicas < 0 {
+			allErrs = append(allErrs, field.Invalid(g.path.Child("replicas"), g.group.Replicas, "must not be negative"))
+		}
+		allErrs = append(allErrs, validateResources(g.path.Child("resources"), g.group.Resources)...)
+		for _, msg := range validation.IsValidLabelValue(g.group.ModelLabel) {
+			allErrs = append(allErrs, field.Invalid(g.path.Child("modelLabel"), g.group.ModelLabel, msg))
+		}
+	}
+	if topology.Prefill.ModelLabel != "" && topology.Prefill.ModelLabel == topology.Decode.ModelLabel {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("decode", "modelLabel"), topology.Decode.ModelLabel,
+			"must differ from the prefill model label, the router tells the groups apart by it"))
+	}
+
+	if !spec.LMCacheConfig.Enabled {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "lmCacheConfig", "enabled"), spec.LMCacheConfig.Enabled,
+			"disaggregated prefill hands the KV cache over through LMCache, which must be enabled"))
+	} else if spec.LMCacheConfig.RemoteURL == "" && spec.LMCacheConfig.CacheServerRef == nil {
+		warnings = append(warnings, "neither spec.lmCacheConfig.remoteUrl nor cacheServerRef is set, the prefill and decode pods only share the KV cache through a remote LMCache backend")
+	}
+	if spec.MultiNode.Enabled {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("disaggregated"), topology.Disaggregated,
+			"cannot be combined with multi-node mode"))
+	}
+	if spec.Autoscaling.Enabled {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("disaggregated"), topology.Disaggregated,
+			"cannot be combined with autoscaling, the replicas of each group are set in the topology"))
+	}
+
+	if spec.StorageConfig.Enabled && spec.StorageConfig.AccessMode != "" && spec.StorageConfig.AccessMode != "ReadWriteMany" {
+		warnings = append(warnings, "spec.storageConfig.accessMode should be ReadWriteMany in disaggregated mode, the prefill and decode pods share the volume")
+	}
+
+	return warnings, allErrs
+}
@@ -0,0 +1,368 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	productionstackv1alpha1 "production-stack/api/v1alpha1"
+)
+
+var _ = Describe("VLLMRuntime Webhook", func() {
+	var (
+		obj       *productionstackv1alpha1.VLLMRuntime
+		validator VLLMRuntimeCustomValidator
+		defaulter VLLMRuntimeCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		obj = &productionstackv1alpha1.VLLMRuntime{
+			Spec: productionstackv1alpha1.VLLMRuntimeSpec{
+				Model: productionstackv1alpha1.ModelSpec{ModelURL: "meta-llama/Llama-3.1-8B"},
+			},
+		}
+		validator = VLLMRuntimeCustomValidator{}
+		defaulter = VLLMRuntimeCustomDefaulter{}
+	})
+
+	Context("When creating VLLMRuntime under Defaulting Webhook", func() {
+		It("Should fill in the port, image and replicas defaults", func() {
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.VLLMConfig.Port).To(Equal(int32(8000)))
+			Expect(obj.Spec.DeploymentConfig.Replicas).To(Equal(int32(1)))
+			Expect(obj.Spec.DeploymentConfig.DeployStrategy).To(Equal("RollingUpdate"))
+			Expect(obj.Spec.DeploymentConfig.Image.Registry).To(Equal("docker.io"))
+			Expect(obj.Spec.DeploymentConfig.Image.Name).To(Equal("lmcache/vllm-openai:2025-05-27-v1"))
+			Expect(obj.Spec.DeploymentConfig.SidecarConfig.Image.Name).To(BeEmpty())
+		})
+
+		It("Should default the sidecar only when it is enabled", func() {
+			obj.Spec.DeploymentConfig.SidecarConfig.Enabled = true
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			sidecar := obj.Spec.DeploymentConfig.SidecarConfig
+			Expect(sidecar.Name).To(Equal("sidecar"))
+			Expect(sidecar.MountPath).To(Equal("/data"))
+			Expect(sidecar.Image.Name).To(Equal("lmcache/lmstack-sidecar:latest"))
+		})
+
+		It("Should default the remote serde when a remote URL is set", func() {
+			obj.Spec.LMCacheConfig.RemoteURL = "lm://cacheserver:80"
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.LMCacheConfig.RemoteSerde).To(Equal("naive"))
+		})
+
+		It("Should default the LMCache log level, P2P port and the serde of a CacheServer reference", func() {
+			obj.Spec.LMCacheConfig = productionstackv1alpha1.LMCacheConfig{
+				Enabled:        true,
+				CacheServerRef: &corev1.LocalObjectReference{Name: "cacheserver"},
+				P2P:            productionstackv1alpha1.LMCacheP2PConfig{Enabled: true, LookupURL: "lmcache-lookup:8100"},
+			}
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.LMCacheConfig.LogLevel).To(Equal("INFO"))
+			Expect(obj.Spec.LMCacheConfig.P2P.Port).To(Equal(int32(8200)))
+			Expect(obj.Spec.LMCacheConfig.RemoteSerde).To(Equal("naive"))
+		})
+
+		It("Should default the autoscaling metric and bounds when autoscaling is enabled", func() {
+			obj.Spec.Autoscaling = productionstackv1alpha1.AutoscalingConfig{Enabled: true, MaxReplicas: 4, Target: "5"}
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.Autoscaling.MinReplicas).To(Equal(int32(1)))
+			Expect(obj.Spec.Autoscaling.Metric).To(Equal("queueLength"))
+			Expect(obj.Spec.Autoscaling.SyncPeriodSeconds).To(Equal(int32(15)))
+		})
+
+		It("Should default the idle time and check interval when scaling to zero is enabled", func() {
+			obj.Spec.ScaleToZero.Enabled = true
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.ScaleToZero.IdleMinutes).To(Equal(int32(15)))
+			Expect(obj.Spec.ScaleToZero.SyncPeriodSeconds).To(Equal(int32(10)))
+		})
+
+		It("Should default the group size to the pipeline parallel size in multi-node mode", func() {
+			obj.Spec.VLLMConfig.PipelineParallelSize = 4
+			obj.Spec.MultiNode.Enabled = true
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.MultiNode.Size).To(Equal(int32(4)))
+			Expect(obj.Spec.MultiNode.RayPort).To(Equal(int32(6379)))
+		})
+
+		It("Should default the replicas and model labels of the prefill and decode groups", func() {
+			obj.Name = "llama"
+			obj.Spec.Topology = productionstackv1alpha1.TopologyConfig{
+				Disaggregated: true,
+				Decode:        productionstackv1alpha1.ReplicaGroup{Replicas: 3, ModelLabel: "llama-decoder"},
+			}
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.Topology.Prefill.Replicas).To(Equal(int32(1)))
+			Expect(obj.Spec.Topology.Prefill.ModelLabel).To(Equal("llama-prefill"))
+			Expect(obj.Spec.Topology.Decode.Replicas).To(Equal(int32(3)))
+			Expect(obj.Spec.Topology.Decode.ModelLabel).To(Equal("llama-decoder"))
+		})
+
+		It("Should default the pre-warming mode and the pull policy of the downloader image", func() {
+			obj.Spec.Model.Prewarm = productionstackv1alpha1.PrewarmConfig{
+				Enabled: true,
+				Image:   &productionstackv1alpha1.ImageSpec{Registry: "ghcr.io", Name: "acme/hf-downloader:1.0"},
+			}
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+			Expect(obj.Spec.Model.Prewarm.Mode).To(Equal("Job"))
+			Expect(obj.Spec.Model.Prewarm.Image.Name).To(Equal("acme/hf-downloader:1.0"))
+			Expect(obj.Spec.Model.Prewarm.Image.PullPolicy).To(Equal("IfNotPresent"))
+		})
+	})
+
+	Context("When creating or updating VLLMRuntime under Validating Webhook", func() {
+		BeforeEach(func() {
+			Expect(defaulter.Default(context.Background(), obj)).To(Succeed())
+		})
+
+		It("Should admit the defaulted runtime", func() {
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny quantities that cannot be parsed", func() {
+			obj.Spec.DeploymentConfig.Resources.GPU = "one"
+			obj.Spec.DeploymentConfig.SidecarConfig.Resources.CPU = "-1"
+			obj.Spec.StorageConfig = productionstackv1alpha1.StorageConfig{Enabled: true, Size: "lots"}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.deploymentConfig.resources.gpu"))
+			Expect(err.Error()).To(ContainSubstring("spec.deploymentConfig.sidecarConfig.resources.cpu"))
+			Expect(err.Error()).To(ContainSubstring("spec.storageConfig.size"))
+		})
+
+		It("Should only admit GPU resource names of extended resources", func() {
+			obj.Spec.DeploymentConfig.Resources = productionstackv1alpha1.ResourceRequirements{
+				GPU: "1", GPUResourceName: "nvidia.com/mig-1g.10gb",
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+
+			obj.Spec.DeploymentConfig.Resources.GPUResourceName = "gpu"
+			obj.Spec.DeploymentConfig.SidecarConfig.Resources.GPUResourceName = "amd.com/gpu!"
+			_, err = validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.deploymentConfig.resources.gpuResourceName"))
+			Expect(err.Error()).To(ContainSubstring("spec.deploymentConfig.sidecarConfig.resources.gpuResourceName"))
+		})
+
+		It("Should deny a GPU memory utilization above 1", func() {
+			obj.Spec.VLLMConfig.GpuMemoryUtilization = "1.5"
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.vllmConfig.gpuMemoryUtilization"))
+		})
+
+		It("Should deny keeping fewer LoRAs in CPU memory than vLLM batches", func() {
+			obj.Spec.VLLMConfig.MaxLoras = 4
+			obj.Spec.VLLMConfig.MaxCPULoras = 2
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.vllmConfig.maxCPULoras"))
+
+			obj.Spec.VLLMConfig.MaxCPULoras = 8
+			Expect(validator.ValidateCreate(context.Background(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a remote serde without a remote URL", func() {
+			obj.Spec.LMCacheConfig = productionstackv1alpha1.LMCacheConfig{Enabled: true, RemoteSerde: "naive"}
+			_, err := validator.ValidateUpdate(context.Background(), obj.DeepCopy(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.remoteUrl"))
+		})
+
+		It("Should deny an unknown remote serde and invalid buffer sizes", func() {
+			obj.Spec.LMCacheConfig = productionstackv1alpha1.LMCacheConfig{
+				Enabled:                 true,
+				CPUOffloadingBufferSize: "lots",
+				RemoteURL:               "lm://cacheserver:80",
+				RemoteSerde:             "json",
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.remoteSerde"))
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.cpuOffloadingBufferSize"))
+		})
+
+		It("Should admit a CacheServer reference with blending, P2P sharing and a controller", func() {
+			obj.Spec.LMCacheConfig = productionstackv1alpha1.LMCacheConfig{
+				Enabled:        true,
+				CacheServerRef: &corev1.LocalObjectReference{Name: "cacheserver"},
+				RemoteSerde:    "naive",
+				ChunkSize:      256,
+				Blending:       productionstackv1alpha1.LMCacheBlendingConfig{Enabled: true, RecomputeRatio: "0.15", MinTokens: 256},
+				P2P:            productionstackv1alpha1.LMCacheP2PConfig{Enabled: true, LookupURL: "lmcache-lookup:8100", Port: 8200},
+				ControllerURL:  "lmcache-controller:9000",
+			}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny a CacheServer reference next to a remote URL and invalid blending, P2P or controller settings", func() {
+			obj.Spec.LMCacheConfig = productionstackv1alpha1.LMCacheConfig{
+				Enabled:        true,
+				CacheServerRef: &corev1.LocalObjectReference{Name: "Cache_Server"},
+				RemoteURL:      "lm://cacheserver:80",
+				Blending:       productionstackv1alpha1.LMCacheBlendingConfig{Enabled: true, RecomputeRatio: "1.5"},
+				P2P:            productionstackv1alpha1.LMCacheP2PConfig{Enabled: true, Port: 8200},
+				ControllerURL:  "lmcache-controller",
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.cacheServerRef.name"))
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.remoteUrl"))
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.blending.recomputeRatio"))
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.p2p.lookupUrl"))
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.controllerUrl"))
+		})
+
+		It("Should admit an autoscaling config within bounds", func() {
+			obj.Spec.Autoscaling = productionstackv1alpha1.AutoscalingConfig{
+				Enabled: true, MinReplicas: 1, MaxReplicas: 4, Metric: "kvCacheUsage", Target: "0.8",
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should deny autoscaling without a maximum or with a target that is not a positive number", func() {
+			obj.Spec.Autoscaling = productionstackv1alpha1.AutoscalingConfig{
+				Enabled: true, MinReplicas: 1, Metric: "queueLength", Target: "-3",
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.autoscaling.maxReplicas"))
+			Expect(err.Error()).To(ContainSubstring("spec.autoscaling.target"))
+		})
+
+		It("Should deny a maximum below the minimum and a KV cache usage target above 1", func() {
+			obj.Spec.Autoscaling = productionstackv1alpha1.AutoscalingConfig{
+				Enabled: true, MinReplicas: 3, MaxReplicas: 2, Metric: "kvCacheUsage", Target: "80",
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.autoscaling.maxReplicas"))
+			Expect(err.Error()).To(ContainSubstring("at most 1"))
+		})
+
+		It("Should deny scaling to zero with an idle time or check interval below 1", func() {
+			obj.Spec.ScaleToZero = productionstackv1alpha1.ScaleToZeroConfig{Enabled: true, IdleMinutes: -5}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.scaleToZero.idleMinutes"))
+			Expect(err.Error()).To(ContainSubstring("spec.scaleToZero.syncPeriodSeconds"))
+		})
+
+		It("Should admit a multi-node group with the GPUs the parallelism needs", func() {
+			obj.Spec.VLLMConfig.TensorParallelSize = 8
+			obj.Spec.VLLMConfig.PipelineParallelSize = 2
+			obj.Spec.DeploymentConfig.Resources.GPU = "8"
+			obj.Spec.MultiNode = productionstackv1alpha1.MultiNodeConfig{Enabled: true, Size: 2, RayPort: 6379}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny a multi-node group that is too small or lacks GPUs", func() {
+			obj.Spec.MultiNode = productionstackv1alpha1.MultiNodeConfig{Enabled: true, RayPort: 6379}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.multiNode.size"))
+
+			obj.Spec.VLLMConfig.TensorParallelSize = 8
+			obj.Spec.VLLMConfig.PipelineParallelSize = 2
+			obj.Spec.DeploymentConfig.Resources.GPU = "4"
+			obj.Spec.MultiNode.Size = 2
+			_, err = validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("needs 16"))
+		})
+
+		It("Should admit a disaggregated topology sharing the KV cache through a remote backend", func() {
+			obj.Spec.LMCacheConfig = productionstackv1alpha1.LMCacheConfig{
+				Enabled: true, RemoteURL: "lm://cacheserver:80", RemoteSerde: "naive",
+			}
+			obj.Spec.Topology = productionstackv1alpha1.TopologyConfig{
+				Disaggregated: true,
+				Prefill:       productionstackv1alpha1.ReplicaGroup{Replicas: 1, ModelLabel: "llama-prefill"},
+				Decode:        productionstackv1alpha1.ReplicaGroup{Replicas: 2, ModelLabel: "llama-decode"},
+			}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+		})
+
+		It("Should deny a disaggregated topology without LMCache or with the same model label twice", func() {
+			obj.Spec.Topology = productionstackv1alpha1.TopologyConfig{
+				Disaggregated: true,
+				Prefill:       productionstackv1alpha1.ReplicaGroup{Replicas: 1, ModelLabel: "llama"},
+				Decode:        productionstackv1alpha1.ReplicaGroup{Replicas: 1, ModelLabel: "llama", Resources: productionstackv1alpha1.ResourceRequirements{GPU: "one"}},
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.lmCacheConfig.enabled"))
+			Expect(err.Error()).To(ContainSubstring("spec.topology.decode.modelLabel"))
+			Expect(err.Error()).To(ContainSubstring("spec.topology.decode.resources.gpu"))
+		})
+
+		It("Should admit pre-warming a pinned revision into a shared volume", func() {
+			obj.Spec.Model.Revision = "0e9e39f249a16976918f6564b8830bc894c89659"
+			obj.Spec.Model.Prewarm = productionstackv1alpha1.PrewarmConfig{Enabled: true, Mode: "Job"}
+			obj.Spec.StorageConfig = productionstackv1alpha1.StorageConfig{Enabled: true, Size: "100Gi", AccessMode: "ReadWriteMany"}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(BeEmpty())
+
+			obj.Spec.StorageConfig.AccessMode = "ReadWriteOnce"
+			warnings, err = validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ContainElement(ContainSubstring("spec.storageConfig.accessMode")))
+		})
+
+		It("Should deny pre-warming without storage or a model that is not on Hugging Face", func() {
+			obj.Spec.Model.ModelURL = "s3://models/llama"
+			obj.Spec.Model.Revision = "../main"
+			obj.Spec.Model.Prewarm = productionstackv1alpha1.PrewarmConfig{
+				Enabled: true, Mode: "Job", BackoffLimit: -1,
+				Image: &productionstackv1alpha1.ImageSpec{Registry: "ghcr.io"},
+			}
+			_, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.model.prewarm.enabled"))
+			Expect(err.Error()).To(ContainSubstring("spec.model.modelURL"))
+			Expect(err.Error()).To(ContainSubstring("spec.model.revision"))
+			Expect(err.Error()).To(ContainSubstring("spec.model.prewarm.image"))
+			Expect(err.Error()).To(ContainSubstring("spec.model.prewarm.backoffLimit"))
+		})
+
+		It("Should warn when a remote URL is set without enabling LMCache", func() {
+			obj.Spec.LMCacheConfig = productionstackv1alpha1.LMCacheConfig{
+				RemoteURL:   "lm://cacheserver:80",
+				RemoteSerde: "naive",
+			}
+			warnings, err := validator.ValidateCreate(context.Background(), obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(HaveLen(1))
+		})
+	})
+})
@@ -1,11 +1,21 @@
+import hashlib
 import logging
 import os
-from typing import Optional
+import shutil
+import tarfile
+import tempfile
+import threading
+from html.parser import HTMLParser
+from typing import Dict, List, Optional
+from urllib.parse import urljoin, urlparse
 
+import boto3
 import fastapi
+import requests
 import uvicorn
+from botocore.config import Config
 from fastapi import HTTPException
-from huggingface_hub import snapshot_download
+from huggingface_hub import HfApi, snapshot_download
 from pydantic import BaseModel
 
 logger = logging.getLogger(__name__)
@@ -20,17 +30,71 @@ class DownloadRequest(BaseModel):
     token: Optional[str] = None
 
 
+class S3DownloadRequest(BaseModel):
+    bucket: str
+    local_dir: str
+    prefix: str = ""
+    endpoint_url: Optional[str] = None
+    region: Optional[str] = None
+    access_key_id: Optional[str] = None
+    secret_access_key: Optional[str] = None
+    session_token: Optional[str] = None
+
+
+class HTTPDownloadRequest(BaseModel):
+    url: str
+    local_dir: str
+    sha256: Optional[str] = None
+    token: Optional[str] = None
+    username: Optional[str] = None
+    password: Optional[str] = None
+
+
+class ListAdaptersRequest(BaseModel):
+    source: str
+    # local sources
+    path: Optional[str] = None
+    # s3 sources
+    bucket: Optional[str] = None
+    prefix: str = ""
+    endpoint_url: Optional[str] = None
+    region: Optional[str] = None
+    access_key_id: Optional[str] = None
+    secret_access_key: Optional[str] = None
+    session_token: Optional[str] = None
+    # huggingface sources
+    organization: Optional[str] = None
+    token: Optional[str] = None
+
+
+# HTTP downloads run in the background and are tracked by target directory, so the
+# operator can poll the same endpoint for progress instead of holding a request open.
+http_downloads: Dict[str, dict] = {}
+http_downloads_lock = threading.Lock()
+
+ARCHIVE_SUFFIXES = (".tar", ".tar.gz", ".tgz")
+
+
+def resolve_base_path(path: str, field: str) -> str:
+    """Resolves path against LORA_DOWNLOAD_BASE_DIR, refusing paths outside of it."""
+    download_base_dir = os.path.realpath(
+        os.environ.get("LORA_DOWNLOAD_BASE_DIR", "/data/lora-adapters")
+    )
+    resolved = os.path.realpath(os.path.join(download_base_dir, path))
+    if os.path.commonpath([download_base_dir, resolved]) != download_base_dir:
+        raise HTTPException(status_code=400, detail=f"Invalid '{field}' provided.")
+    return resolved
+
+
+def resolve_target_dir(local_dir: str) -> str:
+    return resolve_base_path(local_dir, "local_dir")
+
+
 @app.post("/model/download")
 async def download(request: DownloadRequest):
     try:
-        DOWNLOAD_BASE_DIR = os.path.abspath(
-            os.environ.get("LORA_DOWNLOAD_BASE_DIR", "/data/lora-adapters")
-        )
-
-        target_dir = os.path.abspath(os.path.join(DOWNLOAD_BASE_DIR, request.local_dir))
+        target_dir = resolve_target_dir(request.local_dir)
         model_id = request.model_id
-        if not target_dir.startswith(DOWNLOAD_BASE_DIR):
-            raise HTTPException(status_code=400, detail="Invalid 'local_dir' provided.")
 
         logger.info(f"Downloading {model_id} to {target_dir}")
         os.makedirs(target_dir, exist_ok=True)
@@ -44,5 +108,272 @@ async def download(request: DownloadRequest):
         raise HTTPException(status_code=500, detail=str(e))
 
 
+def s3_client(request):
+    # S3-compatible stores such as MinIO generally only support path-style addressing
+    return boto3.client(
+        "s3",
+        config=(
+            Config(s3={"addressing_style": "path"}) if request.endpoint_url else None
+        ),
+        endpoint_url=request.endpoint_url,
+        region_name=request.region,
+        aws_access_key_id=request.access_key_id,
+        aws_secret_access_key=request.secret_access_key,
+        aws_session_token=request.session_token,
+    )
+
+
+def s3_prefix(prefix: str) -> str:
+    prefix = prefix.strip("/")
+    return prefix + "/" if prefix else ""
+
+
+@app.post("/s3/download")
+async def download_s3(request: S3DownloadRequest):
+    try:
+        target_dir = resolve_target_dir(request.local_dir)
+        prefix = s3_prefix(request.prefix)
+        s3 = s3_client(request)
+
+        logger.info(f"Downloading s3://{request.bucket}/{prefix} to {target_dir}")
+        downloaded = 0
+        paginator = s3.get_paginator("list_objects_v2")
+        for page in paginator.paginate(Bucket=request.bucket, Prefix=prefix):
+            for obj in page.get("Contents", []):
+                key = obj["Key"]
+                relative_path = key[len(prefix) :]
+                if not relative_path or key.endswith("/"):
+                    continue
+                file_path = os.path.abspath(os.path.join(target_dir, relative_path))
+                if not file_path.startswith(target_dir + os.sep):
+                    raise HTTPException(
+                        status_code=400, detail=f"Invalid object key '{key}'."
+                    )
+                os.makedirs(os.path.dirname(file_path), exist_ok=True)
+                s3.download_file(request.bucket, key, file_path)
+                downloaded += 1
+
+        if downloaded == 0:
+            raise HTTPException(
+                status_code=404,
+                detail=f"No objects found under s3://{request.bucket}/{prefix}",
+            )
+
+        return {
+            "message": f"Successfully downloaded {downloaded} files from s3://{request.bucket}/{prefix} to {target_dir}",
+            "path": target_dir,
+        }
+    except HTTPException:
+        raise
+    except Exception as e:
+        logger.exception(f"Error: {e}")
+        raise HTTPException(status_code=500, detail=str(e))
+
+
+@app.post("/adapters/list")
+async def list_adapters(request: ListAdaptersRequest):
+    """Lists the candidate adapters of a source, the operator filters them by pattern."""
+    try:
+        if request.source == "local":
+            if not request.path:
+                raise HTTPException(status_code=400, detail="'path' is required.")
+            adapters = [
+                entry.name
+                for entry in os.scandir(resolve_base_path(request.path, "path"))
+                if entry.is_dir()
+            ]
+        elif request.source == "s3":
+            if not request.bucket:
+                raise HTTPException(status_code=400, detail="'bucket' is required.")
+            prefix = s3_prefix(request.prefix)
+            paginator = s3_client(request).get_paginator("list_objects_v2")
+            adapters = []
+            for page in paginator.paginate(
+                Bucket=request.bucket, Prefix=prefix, Delimiter="/"
+            ):
+                for common_prefix in page.get("CommonPrefixes", []):
+                    adapters.append(common_prefix["Prefix"][len(prefix) :].strip("/"))
+        elif request.source == "huggingface":
+            if not request.organization:
+                raise HTTPException(
+                    status_code=400, detail="'organization' is required."
+                )
+            models = HfApi().list_models(
+                author=request.organization, token=request.token
+            )
+            adapters = [model.id.split("/", 1)[-1] for model in models]
+        else:
+            raise HTTPException(
+                status_code=400, detail=f"Unsupported source '{request.source}'."
+            )
+
+        return {"adapters": sorted(adapters)}
+    except HTTPException:
+        raise
+    except Exception as e:
+        logger.exception(f"Error: {e}")
+        raise HTTPException(status_code=500, detail=str(e))
+
+
+class LinkParser(HTMLParser):
+    def __init__(self):
+        super().__init__()
+        self.links: List[str] = []
+
+    def handle_starttag(self, tag, attrs):
+        if tag != "a":
+            return
+        for name, value in attrs:
+            if name == "href" and value:
+                self.links.append(value)
+
+
+def index_links(base_url: str, html: str) -> List[str]:
+    """Returns the links of a directory index that point below base_url."""
+    parser = LinkParser()
+    parser.feed(html)
+    links = []
+    for link in parser.links:
+        absolute = urljoin(base_url, link.split("#")[0].split("?")[0])
+        if absolute.startswith(base_url) and absolute != base_url:
+            links.append(absolute)
+    return sorted(set(links))
+
+
+def fetch_to_file(session, url: str, file_path: str, state: dict, digest=None):
+    with session.get(url, stream=True, timeout=60) as response:
+        response.raise_for_status()
+        state["total_bytes"] += int(response.headers.get("Content-Length", 0))
+        with open(file_path, "wb") as f:
+            for chunk in response.iter_content(chunk_size=1 << 20):
+                f.write(chunk)
+                if digest is not None:
+                    digest.update(chunk)
+                state["downloaded_bytes"] += len(chunk)
+
+
+def fetch_index(
+    session, root_url: str, url: str, target_dir: str, state: dict, depth: int = 0
+):
+    if depth > 8:
+        raise ValueError(f"Directory index {url} is nested too deeply")
+    response = session.get(url, timeout=60)
+    response.raise_for_status()
+    for link in index_links(url, response.text):
+        if link.endswith("/"):
+            fetch_index(session, root_url, link, target_dir, state, depth + 1)
+            continue
+        relative_path = link[len(root_url) :]
+        file_path = os.path.abspath(os.path.join(target_dir, relative_path))
+        if not file_path.startswith(target_dir + os.sep):
+            raise ValueError(f"Invalid link '{link}' in directory index")
+        os.makedirs(os.path.dirname(file_path), exist_ok=True)
+        fetch_to_file(session, link, file_path, state)
+
+
+def run_http_download(request: HTTPDownloadRequest, target_dir: str, state: dict):
+    staging_dir = tempfile.mkdtemp(
+        prefix=".download-", dir=os.path.dirname(target_dir) or None
+    )
+    try:
+        session = requests.Session()
+        if request.token:
+            session.headers["Authorization"] = f"Bearer {request.token}"
+        elif request.username:
+            session.auth = (request.username, request.password or "")
+
+        url_path = urlparse(request.url).path
+        if url_path.endswith(ARCHIVE_SUFFIXES):
+            archive_path = os.path.join(staging_dir, ".archive")
+            digest = hashlib.sha256()
+            fetch_to_file(session, request.url, archive_path, state, digest)
+            if request.sha256 and digest.hexdigest() != request.sha256.lower():
+                raise ValueError(
+                    f"SHA256 mismatch: expected {request.sha256.lower()}, got {digest.hexdigest()}"
+                )
+            extract_dir = os.path.join(staging_dir, "adapter")
+            with tarfile.open(archive_path) as archive:
+                archive.extractall(extract_dir, filter="data")
+        else:
+            if request.sha256:
+                raise ValueError(
+                    "SHA256 verification is only supported for tarball downloads"
+                )
+            base_url = request.url if request.url.endswith("/") else request.url + "/"
+            extract_dir = os.path.join(staging_dir, "adapter")
+            os.makedirs(extract_dir)
+            fetch_index(
+                session, base_url, base_url, os.path.abspath(extract_dir), state
+            )
+
+        if not os.listdir(extract_dir):
+            raise ValueError(f"No files found at {request.url}")
+
+        with http_downloads_lock:
+            # A download restarted for another source owns target_dir now
+            if http_downloads.get(target_dir) is not state:
+                logger.info(f"Discarding superseded download of {request.url}")
+                return
+            shutil.rmtree(target_dir, ignore_errors=True)
+            os.replace(extract_dir, target_dir)
+        state["status"] = "completed"
+        state["message"] = f"Successfully downloaded {request.url} to {target_dir}"
+        logger.info(state["message"])
+    except Exception as e:
+        logger.exception(f"Error: {e}")
+        state["status"] = "failed"
+        state["message"] = str(e)
+    finally:
+        shutil.rmtree(staging_dir, ignore_errors=True)
+
+
+@app.post("/http/download")
+async def download_http(request: HTTPDownloadRequest):
+    target_dir = resolve_target_dir(request.local_dir)
+    os.makedirs(os.path.dirname(target_dir), exist_ok=True)
+
+    with http_downloads_lock:
+        state = http_downloads.get(target_dir)
+
+        # Restart when the source changed, even while its previous download is running
+        if state is not None:
+            if state["url"] != request.url or state["sha256"] != request.sha256:
+                state = None
+
+        if state is None:
+            logger.info(f"Downloading {request.url} to {target_dir}")
+            state = {
+                "status": "downloading",
+                "url": request.url,
+                "sha256": request.sha256,
+                "path": target_dir,
+                "message": "",
+                "downloaded_bytes": 0,
+                "total_bytes": 0,
+            }
+            http_downloads[target_dir] = state
+            threading.Thread(
+                target=run_http_download,
+                args=(request, target_dir, state),
+                daemon=True,
+            ).start()
+
+        if state["status"] == "failed":
+            # Report the failure once, the next request retries the download
+            del http_downloads[target_dir]
+            raise HTTPException(status_code=500, detail=state["message"])
+
+        return {
+            key: state[key]
+            for key in (
+                "status",
+                "path",
+                "message",
+                "downloaded_bytes",
+                "total_bytes",
+            )
+        }
+
+
 if __name__ == "__main__":
     uvicorn.run(app, host="0.0.0.0", port=8000)
@@ -0,0 +1,84 @@
+from typing import List
+
+import pytest
+
+from vllm_router.services.metrics_service import num_cold_start_requests
+from vllm_router.services.request_service import request as request_service
+
+pytest_plugins = ("pytest_asyncio",)
+
+
+class EndpointInfo:
+    def __init__(self, model_names: List[str], sleep: bool = False):
+        self.model_names = model_names
+        self.sleep = sleep
+
+
+class ServiceDiscovery:
+    """Reports no endpoint for the first polls, then the given endpoints."""
+
+    def __init__(self, model: str, endpoints: List[EndpointInfo], empty_polls: int):
+        self.model = model
+        self.endpoints = endpoints
+        self.empty_polls = empty_polls
+        self.held_requests = []
+
+    def get_endpoint_info(self) -> List[EndpointInfo]:
+        self.held_requests.append(held_requests(self.model))
+        if self.empty_polls > 0:
+            self.empty_polls -= 1
+            return []
+        return self.endpoints
+
+
+def held_requests(model: str) -> float:
+    return num_cold_start_requests.labels(model=model)._value.get()
+
+
+@pytest.fixture(autouse=True)
+def fast_polling(monkeypatch: pytest.MonkeyPatch):
+    monkeypatch.setattr(request_service, "COLD_START_POLL_INTERVAL", 0.01)
+
+
+@pytest.mark.asyncio
+async def test_wait_for_model_endpoints_returns_endpoints_once_the_model_scales_up():
+    endpoint = EndpointInfo(["scaled-up-model"])
+    service_discovery = ServiceDiscovery("scaled-up-model", [endpoint], empty_polls=2)
+
+    endpoints = await request_service.wait_for_model_endpoints(
+        service_discovery, "scaled-up-model", timeout=5
+    )
+
+    assert endpoints == [endpoint]
+    # The request counts as held while it waits, and no longer once it is routed
+    assert service_discovery.held_requests == [1, 1, 1]
+    assert held_requests("scaled-up-model") == 0
+
+
+@pytest.mark.asyncio
+async def test_wait_for_model_endpoints_ignores_other_models_and_sleeping_endpoints():
+    service_discovery = ServiceDiscovery(
+        "sleeping-model",
+        [EndpointInfo(["other-model"]), EndpointInfo(["sleeping-model"], sleep=True)],
+        empty_polls=0,
+    )
+
+    endpoints = await request_service.wait_for_model_endpoints(
+        service_discovery, "sleeping-model", timeout=0.1
+    )
+
+    assert endpoints == []
+    assert len(service_discovery.held_requests) > 1
+    assert held_requests("sleeping-model") == 0
+
+
+@pytest.mark.asyncio
+async def test_wait_for_model_endpoints_when_the_timeout_expires_returns_no_endpoints():
+    service_discovery = ServiceDiscovery("scaled-to-zero-model", [], empty_polls=0)
+
+    endpoints = await request_service.wait_for_model_endpoints(
+        service_discovery, "scaled-to-zero-model", timeout=0.05
+    )
+
+    assert endpoints == []
+    assert held_requests("scaled-to-zero-model") == 0
@@ -165,3 +165,44 @@ def test_validate_static_model_types_when_model_types_contains_only_supported_mo
     None
 ):
     parser.validate_static_model_types("chat,completion,rerank,score")
+
+
+def test_parse_args_when_cold_start_timeout_is_negative_raises_value_error(
+    monkeypatch: pytest.MonkeyPatch,
+) -> None:
+    monkeypatch.setattr(
+        sys,
+        "argv",
+        [
+            sys.argv[0],
+            "--service-discovery",
+            "k8s",
+            "--k8s-port",
+            "8000",
+            "--routing-logic",
+            "roundrobin",
+            "--cold-start-timeout",
+            "-1",
+        ],
+    )
+    with pytest.raises(ValueError):
+        parser.parse_args()
+
+
+def test_parse_args_when_cold_start_timeout_is_set_holds_requests_for_it(
+    monkeypatch: pytest.MonkeyPatch,
+) -> None:
+    argv = [
+        sys.argv[0],
+        "--service-discovery",
+        "k8s",
+        "--k8s-port",
+        "8000",
+        "--routing-logic",
+        "roundrobin",
+    ]
+    monkeypatch.setattr(sys, "argv", argv)
+    assert parser.parse_args().cold_start_timeout == 0
+
+    monkeypatch.setattr(sys, "argv", argv + ["--cold-start-timeout", "120"])
+    assert parser.parse_args().cold_start_timeout == 120
@@ -273,6 +273,7 @@ def initialize_all(app: FastAPI, args):
     app.state.request_stats_monitor = get_request_stats_monitor()
     app.state.router = get_routing_logic()
     app.state.request_rewriter = get_request_rewriter()
+    app.state.cold_start_timeout = args.cold_start_timeout
 
 
 app = FastAPI(lifespan=lifespan)
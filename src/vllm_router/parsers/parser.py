@@ -107,6 +107,8 @@ def validate_args(args):
         raise ValueError("Engine stats interval must be greater than 0.")
     if args.request_stats_window <= 0:
         raise ValueError("Request stats window must be greater than 0.")
+    if args.cold_start_timeout < 0:
+        raise ValueError("Cold start timeout must not be negative.")
     if not (0.0 <= args.sentry_traces_sample_rate <= 1.0):
         raise ValueError("Sentry traces sample rate must be between 0.0 and 1.0.")
     if not (0.0 <= args.sentry_profile_session_sample_rate <= 1.0):
@@ -194,6 +196,13 @@ def parse_args():
         default=0,
         help="Timeout in seconds for Kubernetes watcher streams (default: 0).",
     )
+    parser.add_argument(
+        "--cold-start-timeout",
+        type=int,
+        default=0,
+        help="Seconds to hold a request for a model that is scaled to zero while "
+        "it scales up, instead of failing it right away (default: 0, disabled).",
+    )
     parser.add_argument(
         "--backend-health-check-timeout-seconds",
         type=int,
@@ -38,6 +38,11 @@ num_incoming_requests_total = Counter(
     "Total valid incoming requests to router (including when no backends available).",
     ["model"],
 )
+num_cold_start_requests = Gauge(
+    "vllm:num_cold_start_requests",
+    "Number of requests held while their model scales up from zero",
+    ["model"],
+)
 
 # New metrics per dashboard update
 healthy_pods_total = Gauge(
@@ -13,6 +13,7 @@
 # limitations under the License.
 
 # --- Request Processing & Routing ---
+import asyncio
 import json
 import os
 import time
@@ -47,10 +48,51 @@ try:
 except ImportError:
     semantic_cache_available = False
 
-from vllm_router.services.metrics_service import num_incoming_requests_total
+from vllm_router.services.metrics_service import (
+    num_cold_start_requests,
+    num_incoming_requests_total,
+)
 
 logger = init_logger(__name__)
 
+# Interval in seconds at which service discovery is checked for a model that is scaling up
+COLD_START_POLL_INTERVAL = 0.5
+
+
+async def wait_for_model_endpoints(service_discovery, model: str, timeout: float):
+    """
+    Hold a request for a model that is scaled to zero until service discovery
+    reports an endpoint serving it again.
+
+    The number of held requests is exported per model, so that an autoscaler
+    can scale the model up while requests wait for it.
+
+    Args:
+        service_discovery: The service discovery module.
+        model (str): The requested model.
+        timeout (float): Seconds to wait before giving up.
+
+    Returns:
+        List[EndpointInfo]: The endpoints serving the model, empty when none
+        came up within the timeout.
+    """
+    logger.info(f"Holding request for model {model} while it scales up from zero")
+    num_cold_start_requests.labels(model=model).inc()
+    try:
+        deadline = time.time() + timeout
+        while time.time() < deadline:
+            await asyncio.sleep(COLD_START_POLL_INTERVAL)
+            endpoints = [
+                ep
+                for ep in service_discovery.get_endpoint_info()
+                if model in ep.model_names and not ep.sleep
+            ]
+            if endpoints:
+                return endpoints
+        return []
+    finally:
+        num_cold_start_requests.labels(model=model).dec()
+
 
 # TODO: (Brian) check if request is json beforehand
 async def process_request(
@@ -243,6 +285,18 @@ async def route_general_request(
     # Track all valid incoming requests
     num_incoming_requests_total.labels(model=requested_model).inc()
 
+    # Hold requests for a model that is scaled to zero while it scales up
+    cold_start_timeout = getattr(request.app.state, "cold_start_timeout", 0)
+    if (
+        not endpoints
+        and model_ever_existed
+        and not request_endpoint
+        and cold_start_timeout > 0
+    ):
+        endpoints = await wait_for_model_endpoints(
+            service_discovery, requested_model, cold_start_timeout
+        )
+
     if not endpoints:
         if not model_ever_existed:
             return JSONResponse(